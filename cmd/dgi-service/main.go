@@ -15,7 +15,9 @@ import (
 	"github.com/hypernova-labs/dgi-service/internal/config"
 	"github.com/hypernova-labs/dgi-service/internal/database"
 	"github.com/hypernova-labs/dgi-service/internal/email"
+	"github.com/hypernova-labs/dgi-service/internal/pac"
 	"github.com/hypernova-labs/dgi-service/internal/services"
+	"github.com/hypernova-labs/dgi-service/internal/storage"
 	"github.com/hypernova-labs/dgi-service/internal/workflows"
 	"github.com/sirupsen/logrus"
 )
@@ -44,6 +46,9 @@ func main() {
 		logger.Fatalf("Error connecting to database: %v", err)
 	}
 	defer db.Close()
+	if db.Secrets == nil {
+		logger.Warn("PAC_ENCRYPTION_KEYS not provided, PAC credentials will be stored in plain text (development only)")
+	}
 
 	// Conectar a Redis
 	redis, err := database.ConnectRedis(cfg)
@@ -54,23 +59,10 @@ func main() {
 		defer redis.Close()
 	}
 
-	// Inicializar cliente de Supabase
-	var supabaseClient *database.SupabaseClient
-	if cfg.Supabase.StorageEndpoint != "" && cfg.Supabase.AccessKeyID != "" && cfg.Supabase.SecretAccessKey != "" {
-		supabaseClient, err = database.NewSupabaseClient(&cfg.Supabase, logger)
-		if err != nil {
-			logger.Warnf("Error initializing Supabase client: %v", err)
-			supabaseClient = nil
-		} else {
-			// Verificar conexión a Supabase
-			if err := supabaseClient.HealthCheck(); err != nil {
-				logger.Warnf("Supabase health check failed: %v", err)
-			} else {
-				logger.Info("Supabase storage connection healthy")
-			}
-		}
-	} else {
-		logger.Warn("Supabase storage credentials not provided, storage service will not be available")
+	// Inicializar storage de archivos (STORAGE_TYPE: local, s3, supabase o memory)
+	blobStore, err := storage.New(context.Background(), cfg.Storage, cfg.Supabase, logger)
+	if err != nil {
+		logger.Warnf("Error initializing %s file storage, files will be kept in the database: %v", cfg.Storage.Type, err)
 	}
 
 	// Inicializar servicios
@@ -99,11 +91,36 @@ func main() {
 		logger.Warn("Inngest credentials not provided, workflows will not be available")
 	}
 
+	// Cliente del PAC: el endpoint (pruebas o producción) se elige por el iAmb de cada documento
+	pacClient := pac.NewClient(cfg.PAC, logger)
+
 	// Inicializar más servicios
-	invoiceService := services.NewInvoiceService(db, inngestClient, resendService, supabaseClient, logger)
+	invoiceService := services.NewInvoiceService(db, inngestClient, resendService, blobStore, cfg.Storage, pacClient, cfg.Idempotency, logger)
 	emitterService := services.NewEmitterService(db, logger)
 	customerService := services.NewCustomerService(db, logger)
 	productService := services.NewProductService(db, logger)
+	exchangeRateService := services.NewExchangeRateService(db, logger)
+	importService := services.NewImportService(db, logger)
+	catalogService := services.NewCatalogService(db, logger)
+	branchService := services.NewBranchService(db, logger)
+	webhookService := services.NewWebhookService(db, logger)
+	auditService := services.NewAuditService(db, logger)
+
+	// Procesos en segundo plano: el relay publica los eventos del outbox (workflows, email y
+	// webhooks), el dispatcher entrega los webhooks encolados y, con STORAGE_METADATA_ONLY, el
+	// migrador mueve al storage los archivos que siguen en la BD
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	outboxRelay := services.NewOutboxRelay(db, invoiceService, inngestClient, cfg.Outbox, logger)
+	go outboxRelay.Run(workersCtx)
+	webhookDispatcher := services.NewWebhookDispatcher(db, cfg.Webhook, logger)
+	go webhookDispatcher.Run(workersCtx)
+	if blobStore != nil && cfg.Storage.MetadataOnly {
+		storageMigrator := services.NewStorageMigrator(db, blobStore, cfg.Storage, logger)
+		go storageMigrator.Run(workersCtx)
+	}
+
+	// Cargar catálogos DGI desde archivo si están configurados
+	loadCatalogs(catalogService, cfg, logger)
 
 	// Inicializar repositorio de API Keys
 	apiKeyRepo := database.NewAPIKeyRepository(db, logger)
@@ -114,6 +131,13 @@ func main() {
 		emitterService,
 		customerService,
 		productService,
+		exchangeRateService,
+		importService,
+		catalogService,
+		branchService,
+		webhookService,
+		webhookDispatcher,
+		auditService,
 		apiKeyRepo,
 		inngestClient,
 		logger,
@@ -146,6 +170,7 @@ func main() {
 	// Esperar señal de terminación
 	<-quit
 	logger.Info("Shutting down server...")
+	stopWorkers()
 
 	// Contexto con timeout para shutdown graceful
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -159,6 +184,31 @@ func main() {
 	logger.Info("Server exited")
 }
 
+// loadCatalogs carga los catálogos DGI configurados (CPBS, tasas de ITBMS, formas de pago)
+func loadCatalogs(catalogService *services.CatalogService, cfg *config.Config, logger *logrus.Logger) {
+	catalogs := []struct {
+		name string
+		path string
+		load func(string) (int, error)
+	}{
+		{"cpbs", cfg.Catalog.CPBSFile, catalogService.LoadCPBSFile},
+		{"tax_rates", cfg.Catalog.TaxRatesFile, catalogService.LoadTaxRatesFile},
+		{"payment_methods", cfg.Catalog.PaymentMethodsFile, catalogService.LoadPaymentMethodsFile},
+	}
+
+	for _, catalog := range catalogs {
+		if catalog.path == "" {
+			continue
+		}
+		count, err := catalog.load(catalog.path)
+		if err != nil {
+			logger.Warnf("Error loading %s catalog from %s: %v", catalog.name, catalog.path, err)
+			continue
+		}
+		logger.Infof("Loaded %d %s catalog entries from %s", count, catalog.name, catalog.path)
+	}
+}
+
 // setupLogger configura el logger según la configuración
 func setupLogger(cfg *config.Config) *logrus.Logger {
 	logger := logrus.New()
@@ -224,16 +274,30 @@ func setupRouter(apiHandler *api.API, cfg *config.Config) *gin.Engine {
 		{
 			// Emitters (público para registro inicial)
 			core.POST("/emitters", apiHandler.CreateEmitter)
+			core.GET("/emitters/me", apiHandler.GetMyEmitter)
+			core.PATCH("/emitters/me", apiHandler.UpdateMyEmitter)
 			
 			// Invoices
 			core.POST("/invoices", apiHandler.CreateInvoice)
 			core.GET("/invoices/:id", apiHandler.GetInvoice)
 			core.GET("/invoices/:id/files", apiHandler.GetInvoiceFiles)
+			core.GET("/invoices/:id/files/:type", apiHandler.DownloadInvoiceFile)
+			core.GET("/invoices/:id/artifacts", apiHandler.ListInvoiceArtifacts)
+			core.GET("/invoices/:id/artifacts/:kind/:version", apiHandler.DownloadInvoiceArtifact)
 			core.POST("/invoices/:id/email", apiHandler.ResendEmail)
 			core.POST("/invoices/:id/retry", apiHandler.RetryWorkflow)
 			
 			// Series
 			core.GET("/series", apiHandler.GetSeries)
+			core.GET("/series/:pto/:kind/gaps", apiHandler.GetSeriesGaps)
+
+			// Catálogos DGI
+			core.GET("/catalogs/cpbs", apiHandler.SearchCPBSCatalog)
+			core.GET("/catalogs/tax_rates", apiHandler.GetTaxRatesCatalog)
+			core.GET("/catalogs/payment_methods", apiHandler.GetPaymentMethodsCatalog)
+
+			// Herramientas
+			core.GET("/tools/ruc/validate", apiHandler.ValidateRUC)
 		}
 
 		// Endpoints PÚBLICOS (sin autenticación)
@@ -249,14 +313,61 @@ func setupRouter(apiHandler *api.API, cfg *config.Config) *gin.Engine {
 		{
 			// Customers
 			admin.POST("/customers", apiHandler.CreateCustomer)
+			admin.GET("/customers", apiHandler.GetCustomers)
+			admin.GET("/customers/:id", apiHandler.GetCustomer)
+			admin.PATCH("/customers/:id", apiHandler.UpdateCustomer)
+			admin.DELETE("/customers/:id", apiHandler.DeleteCustomer)
+			admin.POST("/customers/import", apiHandler.ImportCustomers)
 			
 			// Products
 			admin.POST("/products", apiHandler.CreateProduct)
+			admin.GET("/products", apiHandler.GetProducts)
+			admin.GET("/products/search", apiHandler.SearchProducts)
+			admin.GET("/products/:id", apiHandler.GetProduct)
+			admin.PATCH("/products/:id", apiHandler.UpdateProduct)
+			admin.DELETE("/products/:id", apiHandler.DeleteProduct)
+			admin.POST("/products/import", apiHandler.ImportProducts)
+
+			// Importaciones masivas
+			admin.GET("/imports/:id", apiHandler.GetImport)
+			admin.GET("/imports/:id/errors", apiHandler.GetImportErrors)
+
+			// Exchange rates (documentos en moneda extranjera)
+			admin.GET("/exchange-rates", apiHandler.GetExchangeRates)
+			admin.POST("/exchange-rates", apiHandler.CreateExchangeRate)
+			admin.DELETE("/exchange-rates/:id", apiHandler.DeleteExchangeRate)
 			
 			// Emitters (endpoints protegidos)
+			admin.GET("/emitters/:id", apiHandler.GetEmitter)
+			admin.PATCH("/emitters/:id", apiHandler.UpdateEmitter)
+			admin.DELETE("/emitters/:id", apiHandler.DeleteEmitter)
+			admin.GET("/emitters/:id/series", apiHandler.GetSeries)
 			admin.POST("/emitters/:id/series", apiHandler.CreateSeries)
+			admin.POST("/emitters/:id/series/:series_id/deactivate", apiHandler.DeactivateSeries)
+			admin.POST("/emitters/:id/series/:series_id/reactivate", apiHandler.ReactivateSeries)
+			admin.PATCH("/emitters/:id/settings", apiHandler.UpdateEmitterSettings)
+			admin.GET("/emitters/:id/branches", apiHandler.GetBranches)
+			admin.POST("/emitters/:id/branches", apiHandler.CreateBranch)
+			admin.GET("/emitters/:id/branches/:branch_id", apiHandler.GetBranch)
+			admin.PATCH("/emitters/:id/branches/:branch_id", apiHandler.UpdateBranch)
+			admin.DELETE("/emitters/:id/branches/:branch_id", apiHandler.DeleteBranch)
 			admin.POST("/emitters/:id/apikeys", apiHandler.CreateAPIKey)
 			admin.GET("/emitters/:id/dashboard", apiHandler.GetDashboard)
+
+			// Webhooks
+			admin.POST("/webhooks/endpoints", apiHandler.CreateWebhookEndpoint)
+			admin.GET("/webhooks/endpoints", apiHandler.GetWebhookEndpoints)
+			admin.GET("/webhooks/endpoints/:id", apiHandler.GetWebhookEndpoint)
+			admin.PATCH("/webhooks/endpoints/:id", apiHandler.UpdateWebhookEndpoint)
+			admin.DELETE("/webhooks/endpoints/:id", apiHandler.DeleteWebhookEndpoint)
+			admin.POST("/webhooks/endpoints/:id/test", apiHandler.SendWebhookTestEvent)
+			admin.GET("/webhooks/deliveries", apiHandler.GetWebhookDeliveries)
+			admin.GET("/webhooks/deliveries/:id", apiHandler.GetWebhookDelivery)
+			admin.POST("/webhooks/deliveries/:id/redeliver", apiHandler.RedeliverWebhook)
+			admin.POST("/webhooks/deliveries/:id/replay", apiHandler.ReplayWebhookDelivery)
+
+			// Auditoría
+			admin.GET("/audit", apiHandler.GetAuditLogs)
 		}
 	}
 
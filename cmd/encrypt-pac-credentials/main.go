@@ -0,0 +1,49 @@
+// Comando encrypt-pac-credentials cifra las credenciales PAC y los secretos de firma
+// de webhooks existentes con la llave activa (PAC_ENCRYPTION_KEY_ID). Se usa una vez
+// para migrar los valores en texto plano y cada vez que se rota la llave maestra.
+package main
+
+import (
+	"log"
+
+	"github.com/hypernova-labs/dgi-service/internal/config"
+	"github.com/hypernova-labs/dgi-service/internal/database"
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+
+	db, err := database.Connect(cfg)
+	if err != nil {
+		logger.Fatalf("Error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	if db.Secrets == nil {
+		logger.Fatal("PAC_ENCRYPTION_KEYS is required to encrypt PAC credentials")
+	}
+
+	logger.Infof("Encrypting PAC credentials with key %s (configured keys: %v)", db.Secrets.ActiveKeyID(), db.Secrets.KeyIDs())
+
+	updated, err := database.NewEmitterRepository(db, logger).EncryptPACCredentials(models.SystemActor("encrypt-pac-credentials"))
+	if err != nil {
+		logger.Fatalf("Error encrypting PAC credentials after %d emitters: %v", updated, err)
+	}
+
+	logger.Infof("PAC credentials encrypted for %d emitters", updated)
+
+	updated, err = database.NewWebhookRepository(db, logger).EncryptSecrets()
+	if err != nil {
+		logger.Fatalf("Error encrypting webhook secrets after %d endpoints: %v", updated, err)
+	}
+
+	logger.Infof("Webhook secrets encrypted for %d endpoints", updated)
+}
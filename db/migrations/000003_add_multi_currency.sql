@@ -0,0 +1,40 @@
+-- Soporte multi-moneda para documentos de exportación / extranjeros
+-- Los montos existentes (subtotal, itbms_amount, total_amount) quedan en moneda funcional (USD)
+ALTER TABLE invoices
+ADD COLUMN IF NOT EXISTS currency_code VARCHAR(3) NOT NULL DEFAULT 'USD',
+ADD COLUMN IF NOT EXISTS exchange_rate DECIMAL(18,6) NOT NULL DEFAULT 1 CHECK (exchange_rate > 0),
+ADD COLUMN IF NOT EXISTS doc_subtotal DECIMAL(15,2) NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS doc_itbms_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS doc_total_amount DECIMAL(15,2) NOT NULL DEFAULT 0;
+
+-- Documentos existentes: la moneda del documento es la funcional
+UPDATE invoices
+SET doc_subtotal = subtotal,
+    doc_itbms_amount = itbms_amount,
+    doc_total_amount = total_amount
+WHERE currency_code = 'USD' AND doc_total_amount = 0;
+
+-- Tabla de tasas de cambio por emisor
+-- rate = unidades de moneda funcional por 1 unidad de currency_code
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    emitter_id UUID NOT NULL REFERENCES emitters(id) ON DELETE CASCADE,
+    currency_code VARCHAR(3) NOT NULL,
+    rate DECIMAL(18,6) NOT NULL CHECK (rate > 0),
+    effective_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    UNIQUE(emitter_id, currency_code, effective_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_exchange_rates_lookup ON exchange_rates(emitter_id, currency_code, effective_date DESC);
+CREATE INDEX IF NOT EXISTS idx_invoices_emitter_currency ON invoices(emitter_id, currency_code);
+
+CREATE TRIGGER update_exchange_rates_updated_at BEFORE UPDATE ON exchange_rates
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON COLUMN invoices.currency_code IS 'Código ISO 4217 de la moneda del documento';
+COMMENT ON COLUMN invoices.exchange_rate IS 'Unidades de moneda funcional por 1 unidad de la moneda del documento';
+COMMENT ON COLUMN invoices.doc_total_amount IS 'Total del documento expresado en currency_code';
+COMMENT ON TABLE exchange_rates IS 'Tasas de cambio por emisor usadas para documentos en moneda extranjera';
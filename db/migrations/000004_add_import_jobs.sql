@@ -0,0 +1,24 @@
+-- Importaciones masivas (CSV/XLSX) de clientes y productos
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    emitter_id UUID NOT NULL REFERENCES emitters(id) ON DELETE CASCADE,
+    resource_type VARCHAR(20) NOT NULL CHECK (resource_type IN ('customers', 'products')),
+    file_name VARCHAR(255) NOT NULL,
+    file_format VARCHAR(10) NOT NULL CHECK (file_format IN ('csv', 'xlsx')),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'completed', 'failed')),
+    total_rows INTEGER NOT NULL DEFAULT 0,
+    created_count INTEGER NOT NULL DEFAULT 0,
+    updated_count INTEGER NOT NULL DEFAULT 0,
+    error_count INTEGER NOT NULL DEFAULT 0,
+    errors JSONB NOT NULL DEFAULT '[]',
+    error_message TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    started_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_jobs_emitter ON import_jobs(emitter_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_import_jobs_status ON import_jobs(status);
+
+COMMENT ON TABLE import_jobs IS 'Trabajos asíncronos de importación masiva de clientes y productos';
+COMMENT ON COLUMN import_jobs.errors IS 'Reporte de errores por fila: [{row, field, reason}]';
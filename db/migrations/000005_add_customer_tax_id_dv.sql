@@ -0,0 +1,5 @@
+-- Tipo de contribuyente y dígito verificador del RUC del cliente
+-- tax_id_type: 1 = natural, 2 = jurídico, 3 = extranjero (sin DV)
+ALTER TABLE customers
+ADD COLUMN IF NOT EXISTS tax_id_type VARCHAR(1) CHECK (tax_id_type IN ('1', '2', '3')),
+ADD COLUMN IF NOT EXISTS tax_id_dv VARCHAR(2);
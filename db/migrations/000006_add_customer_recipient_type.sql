@@ -0,0 +1,12 @@
+-- Tipo de receptor (iTipoRec) e identificación de extranjeros
+-- 01 = contribuyente, 02 = consumidor final, 03 = gobierno, 04 = extranjero
+ALTER TABLE customers
+ADD COLUMN IF NOT EXISTS recipient_type VARCHAR(2) NOT NULL DEFAULT '02' CHECK (recipient_type IN ('01', '02', '03', '04')),
+ADD COLUMN IF NOT EXISTS foreign_id VARCHAR(50),
+ADD COLUMN IF NOT EXISTS country_code VARCHAR(2);
+
+-- Clientes existentes con RUC y datos completos se consideran contribuyentes
+UPDATE customers
+SET recipient_type = '01'
+WHERE tax_id IS NOT NULL AND tax_id_dv IS NOT NULL
+  AND address_line IS NOT NULL AND ubi_code IS NOT NULL;
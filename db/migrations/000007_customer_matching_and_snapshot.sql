@@ -0,0 +1,11 @@
+-- Los clientes ya no se identifican solo por email: varias empresas pueden compartir
+-- un email de contabilidad. El RUC/identificación es único entre los clientes activos.
+ALTER TABLE customers DROP CONSTRAINT IF EXISTS customers_emitter_id_email_key;
+
+CREATE INDEX IF NOT EXISTS idx_customers_emitter_email ON customers(emitter_id, email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_customers_emitter_tax_id
+    ON customers(emitter_id, tax_id) WHERE tax_id IS NOT NULL AND is_active = true;
+
+-- Datos del receptor tal como se usaron al emitir el documento
+ALTER TABLE invoices
+ADD COLUMN IF NOT EXISTS customer_snapshot JSONB;
@@ -0,0 +1,34 @@
+-- Snapshot inmutable de emisor, receptor y marca al momento de emitir el documento.
+-- Reemplaza customer_snapshot; los PDF/XML se generan solo a partir de este snapshot.
+ALTER TABLE invoices
+ADD COLUMN IF NOT EXISTS snapshot JSONB;
+
+-- Documentos existentes: congelar los datos actuales (o el receptor ya guardado)
+UPDATE invoices i
+SET snapshot = jsonb_build_object(
+    'version', 1,
+    'emitter', jsonb_build_object(
+        'id', e.id,
+        'name', e.name,
+        'company_code', e.company_code,
+        'ruc_tipo', e.ruc_tipo,
+        'ruc_numero', e.ruc_numero,
+        'ruc_dv', e.ruc_dv,
+        'suc_em', e.suc_em,
+        'email', e.email,
+        'phone', e.phone,
+        'address_line', e.address_line,
+        'ubi_code', e.ubi_code
+    ),
+    'customer', COALESCE(i.customer_snapshot, to_jsonb(c)),
+    'branding', jsonb_build_object(
+        'logo_url', e.brand_logo_url,
+        'primary_color', e.brand_primary_color,
+        'footer_html', e.brand_footer_html
+    ),
+    'captured_at', NOW()
+)
+FROM emitters e, customers c
+WHERE i.emitter_id = e.id AND i.customer_id = c.id AND i.snapshot IS NULL;
+
+ALTER TABLE invoices DROP COLUMN IF EXISTS customer_snapshot;
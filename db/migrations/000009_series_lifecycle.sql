@@ -0,0 +1,16 @@
+-- Ciclo de vida de series: rango autorizado opcional, aviso de folios restantes
+-- y creación automática de series por emisor
+ALTER TABLE emitter_series
+ADD COLUMN IF NOT EXISTS range_start INTEGER CHECK (range_start IS NULL OR range_start >= 1),
+ADD COLUMN IF NOT EXISTS range_end INTEGER,
+ADD COLUMN IF NOT EXISTS low_remaining_threshold INTEGER NOT NULL DEFAULT 100 CHECK (low_remaining_threshold >= 0);
+
+ALTER TABLE emitter_series
+DROP CONSTRAINT IF EXISTS emitter_series_range_check;
+
+ALTER TABLE emitter_series
+ADD CONSTRAINT emitter_series_range_check
+CHECK (range_end IS NULL OR range_end >= COALESCE(range_start, 1));
+
+ALTER TABLE emitters
+ADD COLUMN IF NOT EXISTS auto_create_series BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,90 @@
+-- Sucursales (dSucEm) y puntos de facturación por emisor.
+-- Cada emisor obtiene una sucursal principal con su suc_em actual.
+CREATE TABLE IF NOT EXISTS branches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    emitter_id UUID NOT NULL REFERENCES emitters(id) ON DELETE CASCADE,
+    code VARCHAR(4) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    address_line TEXT,
+    ubi_code VARCHAR(10),
+    phone VARCHAR(20),
+    pto_fac_default VARCHAR(3) NOT NULL DEFAULT '001',
+    brand_logo_url TEXT,
+    brand_primary_color VARCHAR(7),
+    brand_footer_html TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    UNIQUE(emitter_id, code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_branches_emitter ON branches(emitter_id);
+
+INSERT INTO branches (emitter_id, code, name, address_line, ubi_code, phone, pto_fac_default)
+SELECT id, suc_em, name, address_line, ubi_code, phone, pto_fac_default
+FROM emitters
+ON CONFLICT (emitter_id, code) DO NOTHING;
+
+-- Las series pasan a ser por sucursal
+ALTER TABLE emitter_series
+ADD COLUMN IF NOT EXISTS branch_id UUID REFERENCES branches(id) ON DELETE CASCADE;
+
+UPDATE emitter_series s
+SET branch_id = b.id
+FROM emitters e
+JOIN branches b ON b.emitter_id = e.id AND b.code = e.suc_em
+WHERE s.emitter_id = e.id AND s.branch_id IS NULL;
+
+ALTER TABLE emitter_series
+ALTER COLUMN branch_id SET NOT NULL;
+
+ALTER TABLE emitter_series
+DROP CONSTRAINT IF EXISTS emitter_series_emitter_id_pto_fac_df_doc_kind_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_emitter_series_branch
+ON emitter_series(branch_id, pto_fac_df, doc_kind);
+
+-- Sucursal de cada documento
+ALTER TABLE invoices
+ADD COLUMN IF NOT EXISTS branch_id UUID REFERENCES branches(id);
+
+UPDATE invoices i
+SET branch_id = s.branch_id
+FROM emitter_series s
+WHERE i.series_id = s.id AND i.branch_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_invoices_branch ON invoices(branch_id);
+
+-- El folio se asigna por serie: (emisor, punto, tipo) ya no identifica una serie única
+DROP FUNCTION IF EXISTS get_next_folio(UUID, VARCHAR, document_type);
+
+CREATE OR REPLACE FUNCTION get_next_series_folio(p_series_id UUID)
+RETURNS VARCHAR(10) AS $$
+DECLARE
+    next_num INTEGER;
+    max_num INTEGER;
+BEGIN
+    -- Obtener y bloquear el siguiente número
+    SELECT next_number, range_end INTO next_num, max_num
+    FROM emitter_series
+    WHERE id = p_series_id AND is_active = true
+    FOR UPDATE;
+
+    IF next_num IS NULL THEN
+        RAISE EXCEPTION 'series not found or inactive';
+    END IF;
+
+    IF max_num IS NOT NULL AND next_num > max_num THEN
+        RAISE EXCEPTION 'series range exhausted (range_end %)', max_num;
+    END IF;
+
+    UPDATE emitter_series
+    SET next_number = next_number + 1,
+        updated_at = NOW()
+    WHERE id = p_series_id;
+
+    -- Folio de 10 dígitos con left-pad
+    RETURN LPAD(next_num::TEXT, 10, '0');
+END;
+$$ LANGUAGE plpgsql;
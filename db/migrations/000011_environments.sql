@@ -0,0 +1,58 @@
+-- Ambientes de emisión: pruebas (iamb = 2) y producción (iamb = 1).
+-- Las API keys quedan ligadas a un ambiente y las series se separan por ambiente,
+-- para que los documentos de prueba no consuman folios ni aparezcan en reportes de producción.
+
+-- Credenciales PAC del ambiente de pruebas (cifradas igual que las de producción)
+ALTER TABLE emitters
+ADD COLUMN IF NOT EXISTS pac_sandbox_api_key TEXT NOT NULL DEFAULT '',
+ADD COLUMN IF NOT EXISTS pac_sandbox_subscription_key TEXT NOT NULL DEFAULT '';
+
+-- Ambiente de cada API key: 'live' emite en el ambiente del emisor, 'test' siempre en pruebas.
+-- Las keys existentes toman el ambiente actual de su emisor.
+ALTER TABLE api_keys
+ADD COLUMN IF NOT EXISTS environment VARCHAR(4);
+
+UPDATE api_keys k
+SET environment = CASE WHEN e.iamb = 2 THEN 'test' ELSE 'live' END
+FROM emitters e
+WHERE k.emitter_id = e.id AND k.environment IS NULL;
+
+ALTER TABLE api_keys
+ALTER COLUMN environment SET DEFAULT 'live',
+ALTER COLUMN environment SET NOT NULL;
+
+ALTER TABLE api_keys
+DROP CONSTRAINT IF EXISTS api_keys_environment_check;
+
+ALTER TABLE api_keys
+ADD CONSTRAINT api_keys_environment_check CHECK (environment IN ('live', 'test'));
+
+-- Ambiente de cada serie; las existentes toman el ambiente actual de su emisor
+ALTER TABLE emitter_series
+ADD COLUMN IF NOT EXISTS iamb INTEGER;
+
+UPDATE emitter_series s
+SET iamb = e.iamb
+FROM emitters e
+WHERE s.emitter_id = e.id AND s.iamb IS NULL;
+
+ALTER TABLE emitter_series
+ALTER COLUMN iamb SET DEFAULT 1,
+ALTER COLUMN iamb SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_emitter_series_branch;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_emitter_series_branch
+ON emitter_series(branch_id, pto_fac_df, doc_kind, iamb);
+
+CREATE INDEX IF NOT EXISTS idx_invoices_emitter_iamb ON invoices(emitter_id, iamb);
+
+-- Los folios de cada ambiente son independientes: un documento de pruebas no consume ni choca
+-- con los folios de producción
+ALTER TABLE invoices
+DROP CONSTRAINT IF EXISTS invoices_emitter_id_d_ptofacdf_d_nrodf_key;
+
+DROP INDEX IF EXISTS idx_invoices_folio;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invoices_folio
+ON invoices(emitter_id, iamb, d_ptofacdf, d_nrodf);
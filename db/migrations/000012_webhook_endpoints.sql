@@ -0,0 +1,42 @@
+-- Webhooks salientes: endpoints por emisor y entregas firmadas con reintentos.
+-- La tabla webhooks existente pasa a ser la cola de entregas (una fila por evento y endpoint).
+
+-- Documentos anulados (evento invoice.cancelled)
+ALTER TYPE document_status ADD VALUE IF NOT EXISTS 'CANCELLED';
+
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    emitter_id UUID NOT NULL REFERENCES emitters(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    event_types TEXT[] NOT NULL,
+    description TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_emitter ON webhook_endpoints(emitter_id);
+
+ALTER TABLE webhooks
+ADD COLUMN IF NOT EXISTS emitter_id UUID REFERENCES emitters(id) ON DELETE CASCADE,
+ADD COLUMN IF NOT EXISTS endpoint_id UUID REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+ADD COLUMN IF NOT EXISTS event_id UUID,
+ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'pending',
+ADD COLUMN IF NOT EXISTS last_status_code INTEGER,
+ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW();
+
+-- Las filas previas no tienen destino: no se pueden entregar
+UPDATE webhooks
+SET status = CASE WHEN delivered_at IS NOT NULL THEN 'delivered' ELSE 'dead' END
+WHERE endpoint_id IS NULL;
+
+ALTER TABLE webhooks
+DROP CONSTRAINT IF EXISTS webhooks_status_check;
+
+ALTER TABLE webhooks
+ADD CONSTRAINT webhooks_status_check CHECK (status IN ('pending', 'delivered', 'dead'));
+
+-- Cola de entregas pendientes del dispatcher
+CREATE INDEX IF NOT EXISTS idx_webhooks_pending ON webhooks(next_retry_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhooks_emitter_created ON webhooks(emitter_id, created_at);
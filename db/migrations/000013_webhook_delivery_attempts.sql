@@ -0,0 +1,20 @@
+-- Historial de intentos de cada entrega de webhook, para depurar receptores:
+-- headers enviados, respuesta (status y cuerpo), latencia y error de red.
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    delivery_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    attempt INTEGER NOT NULL,
+    request_url TEXT NOT NULL,
+    request_headers JSONB NOT NULL DEFAULT '{}',
+    response_status_code INTEGER,
+    response_body TEXT,
+    latency_ms INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_delivery ON webhook_delivery_attempts(delivery_id, attempt);
+
+-- Filtros del log de entregas
+CREATE INDEX IF NOT EXISTS idx_webhooks_emitter_event_type ON webhooks(emitter_id, event_type, created_at);
+CREATE INDEX IF NOT EXISTS idx_webhooks_emitter_status ON webhooks(emitter_id, status, created_at);
@@ -0,0 +1,28 @@
+-- Outbox transaccional: los eventos de documentos se escriben en la misma transacción
+-- que el cambio (creación, status, status del email) y un relay los publica al motor de
+-- workflows y a los webhooks al menos una vez.
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    aggregate_type VARCHAR(50) NOT NULL,
+    aggregate_id UUID NOT NULL,
+    emitter_id UUID NOT NULL REFERENCES emitters(id) ON DELETE CASCADE,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    dedup_key VARCHAR(255) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outbox_events_dedup_key ON outbox_events(dedup_key);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events(next_attempt_at) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_events_aggregate ON outbox_events(aggregate_type, aggregate_id, created_at);
+
+-- Deduplicación de entregas de webhooks encoladas por el relay: republicar un evento
+-- del outbox no crea una segunda entrega al mismo endpoint
+ALTER TABLE webhooks
+ADD COLUMN IF NOT EXISTS dedup_key VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhooks_dedup_key ON webhooks(dedup_key);
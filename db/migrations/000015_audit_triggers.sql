@@ -0,0 +1,124 @@
+-- Auditoría de cambios: triggers que registran en audit_logs cada alta, cambio y baja de
+-- emisores, series, API keys, clientes y productos, y cada transición de status de un
+-- documento. El autor del cambio llega en la variable de sesión app.actor, que la
+-- aplicación fija dentro de la transacción (DB.WithActor).
+ALTER TABLE audit_logs
+ADD COLUMN IF NOT EXISTS emitter_id UUID;
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_emitter_record ON audit_logs(emitter_id, table_name, record_id, changed_at);
+
+-- audit_actor retorna el autor del cambio en curso; los cambios hechos fuera de la
+-- aplicación (psql, migraciones) quedan a nombre del usuario de la base
+CREATE OR REPLACE FUNCTION audit_actor()
+RETURNS VARCHAR AS $$
+BEGIN
+    RETURN COALESCE(NULLIF(current_setting('app.actor', true), ''), 'db:' || session_user);
+END;
+$$ LANGUAGE plpgsql;
+
+-- audit_redact reemplaza el valor de las columnas con secretos por un marcador
+CREATE OR REPLACE FUNCTION audit_redact(row_data JSONB, columns TEXT[])
+RETURNS JSONB AS $$
+DECLARE
+    col TEXT;
+BEGIN
+    IF row_data IS NULL THEN
+        RETURN NULL;
+    END IF;
+
+    FOREACH col IN ARRAY columns LOOP
+        IF COALESCE(row_data->>col, '') <> '' THEN
+            row_data := jsonb_set(row_data, ARRAY[col], '"[redacted]"');
+        END IF;
+    END LOOP;
+    RETURN row_data;
+END;
+$$ LANGUAGE plpgsql;
+
+-- audit_row_change registra el cambio de una fila. Argumentos del trigger:
+--   1. columnas con secretos (separadas por coma) que no se guardan en audit_logs
+--   2. columnas cuyo cambio por sí solo no se audita (contadores, último uso)
+CREATE OR REPLACE FUNCTION audit_row_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    redacted TEXT[] := string_to_array(TG_ARGV[0], ',');
+    ignored TEXT[] := string_to_array(TG_ARGV[1], ',') || ARRAY['updated_at'];
+    old_row JSONB;
+    new_row JSONB;
+    row_data JSONB;
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        old_row := to_jsonb(OLD);
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        new_row := to_jsonb(NEW);
+    END IF;
+
+    IF TG_OP = 'UPDATE' AND (old_row - ignored) = (new_row - ignored) THEN
+        RETURN NULL;
+    END IF;
+
+    row_data := COALESCE(new_row, old_row);
+
+    INSERT INTO audit_logs (table_name, record_id, emitter_id, action, old_values, new_values, changed_by, changed_at)
+    VALUES (
+        TG_TABLE_NAME,
+        (row_data->>'id')::UUID,
+        CASE WHEN TG_TABLE_NAME = 'emitters' THEN row_data->>'id' ELSE row_data->>'emitter_id' END::UUID,
+        TG_OP,
+        audit_redact(old_row, redacted),
+        audit_redact(new_row, redacted),
+        audit_actor(),
+        clock_timestamp()
+    );
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- audit_invoice_status registra las transiciones de status de un documento
+CREATE OR REPLACE FUNCTION audit_invoice_status()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO audit_logs (table_name, record_id, emitter_id, action, old_values, new_values, changed_by, changed_at)
+    VALUES (
+        TG_TABLE_NAME,
+        NEW.id,
+        NEW.emitter_id,
+        TG_OP,
+        jsonb_build_object('status', OLD.status),
+        jsonb_build_object('status', NEW.status),
+        audit_actor(),
+        clock_timestamp()
+    );
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_emitters ON emitters;
+CREATE TRIGGER audit_emitters AFTER INSERT OR UPDATE OR DELETE ON emitters
+    FOR EACH ROW EXECUTE FUNCTION audit_row_change(
+        'pac_api_key,pac_subscription_key,pac_sandbox_api_key,pac_sandbox_subscription_key', ''
+    );
+
+DROP TRIGGER IF EXISTS audit_emitter_series ON emitter_series;
+CREATE TRIGGER audit_emitter_series AFTER INSERT OR UPDATE OR DELETE ON emitter_series
+    FOR EACH ROW EXECUTE FUNCTION audit_row_change(
+        '', 'next_number,issued_count,authorized_count,rejected_count'
+    );
+
+DROP TRIGGER IF EXISTS audit_api_keys ON api_keys;
+CREATE TRIGGER audit_api_keys AFTER INSERT OR UPDATE OR DELETE ON api_keys
+    FOR EACH ROW EXECUTE FUNCTION audit_row_change('key_hash', 'last_used_at');
+
+DROP TRIGGER IF EXISTS audit_customers ON customers;
+CREATE TRIGGER audit_customers AFTER INSERT OR UPDATE OR DELETE ON customers
+    FOR EACH ROW EXECUTE FUNCTION audit_row_change('', '');
+
+DROP TRIGGER IF EXISTS audit_products ON products;
+CREATE TRIGGER audit_products AFTER INSERT OR UPDATE OR DELETE ON products
+    FOR EACH ROW EXECUTE FUNCTION audit_row_change('', '');
+
+DROP TRIGGER IF EXISTS audit_invoices_status ON invoices;
+CREATE TRIGGER audit_invoices_status AFTER UPDATE OF status ON invoices
+    FOR EACH ROW WHEN (OLD.status IS DISTINCT FROM NEW.status)
+    EXECUTE FUNCTION audit_invoice_status();
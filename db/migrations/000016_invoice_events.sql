@@ -0,0 +1,86 @@
+-- Historial de status de los documentos: cada transición (validada por el repositorio según
+-- las transiciones permitidas) se registra con su autor y motivo en la misma transacción.
+CREATE TABLE IF NOT EXISTS invoice_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    invoice_id UUID NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    emitter_id UUID NOT NULL REFERENCES emitters(id) ON DELETE CASCADE,
+    from_status document_status,
+    to_status document_status NOT NULL,
+    actor VARCHAR(100),
+    reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoice_events_invoice ON invoice_events(invoice_id, created_at);
+
+-- Los documentos existentes parten del status actual
+INSERT INTO invoice_events (invoice_id, emitter_id, from_status, to_status, actor, reason, created_at)
+SELECT i.id, i.emitter_id, NULL, i.status, 'system:migration', 'Status before history was recorded', i.updated_at
+FROM invoices i
+WHERE NOT EXISTS (SELECT 1 FROM invoice_events e WHERE e.invoice_id = i.id);
+
+-- validate_data_integrity acepta CANCELLED y detecta documentos cuyo status no coincide con
+-- la última transición registrada (cambios hechos fuera del repositorio)
+CREATE OR REPLACE FUNCTION validate_data_integrity()
+RETURNS TABLE (
+    issue_type TEXT,
+    table_name TEXT,
+    record_id UUID,
+    description TEXT
+) AS $$
+BEGIN
+    RETURN QUERY
+
+    -- Verificar invoices sin items
+    SELECT
+        'INVOICE_WITHOUT_ITEMS'::TEXT as issue_type,
+        'invoices'::TEXT as table_name,
+        i.id as record_id,
+        'Invoice ' || i.d_nrodf || ' has no items' as description
+    FROM invoices i
+    LEFT JOIN invoice_items ii ON i.id = ii.invoice_id
+    WHERE ii.id IS NULL
+
+    UNION ALL
+
+    -- Verificar series con contadores inconsistentes
+    SELECT
+        'SERIES_COUNTER_MISMATCH'::TEXT as issue_type,
+        'emitter_series'::TEXT as table_name,
+        es.id as record_id,
+        'Series counter mismatch: issued=' || es.issued_count ||
+        ', authorized=' || es.authorized_count ||
+        ', rejected=' || es.rejected_count as description
+    FROM emitter_series es
+    WHERE es.issued_count < (es.authorized_count + es.rejected_count)
+
+    UNION ALL
+
+    -- Verificar invoices con estado inválido
+    SELECT
+        'INVALID_INVOICE_STATUS'::TEXT as issue_type,
+        'invoices'::TEXT as table_name,
+        i.id as record_id,
+        'Invalid status transition for invoice ' || i.d_nrodf as description
+    FROM invoices i
+    WHERE i.status NOT IN ('RECEIVED', 'PREPARING', 'SENDING_TO_PAC', 'AUTHORIZED', 'REJECTED', 'ERROR', 'CANCELLED')
+
+    UNION ALL
+
+    -- Verificar invoices cuyo status no coincide con su historial
+    SELECT
+        'INVOICE_STATUS_HISTORY_MISMATCH'::TEXT as issue_type,
+        'invoices'::TEXT as table_name,
+        i.id as record_id,
+        'Invoice ' || i.d_nrodf || ' is ' || i.status || ' but its last recorded transition is to ' ||
+        COALESCE(last_event.to_status::TEXT, 'none') as description
+    FROM invoices i
+    LEFT JOIN LATERAL (
+        SELECT e.to_status FROM invoice_events e
+        WHERE e.invoice_id = i.id
+        ORDER BY e.created_at DESC
+        LIMIT 1
+    ) last_event ON true
+    WHERE last_event.to_status IS DISTINCT FROM i.status;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,27 @@
+-- Idempotency-Key por emisor: cada key guarda la huella del request y la respuesta original
+-- del documento creado, que se escribe en la misma transacción que el documento. Un reintento
+-- con el mismo cuerpo recibe esa respuesta; con otro cuerpo, 409. Las keys vencen tras
+-- IDEMPOTENCY_KEY_TTL y pueden reutilizarse.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    emitter_id UUID NOT NULL REFERENCES emitters(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(255) NOT NULL,
+    fingerprint VARCHAR(64) NOT NULL,
+    -- claim_id identifica la petición que procesa la key; locked_until vence las peticiones abandonadas
+    claim_id UUID NOT NULL,
+    locked_until TIMESTAMP WITH TIME ZONE NOT NULL,
+    invoice_id UUID REFERENCES invoices(id) ON DELETE CASCADE,
+    response_body JSONB,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (emitter_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires ON idempotency_keys(emitter_id, expires_at);
+
+-- La key de invoices deja de ser única global: dos emisores pueden usar la misma, y una key
+-- vencida puede volver a usarse
+ALTER TABLE invoices DROP CONSTRAINT IF EXISTS invoices_idempotency_key_key;
+DROP INDEX IF EXISTS idx_invoices_idempotency;
+CREATE INDEX IF NOT EXISTS idx_invoices_emitter_idempotency ON invoices(emitter_id, idempotency_key);
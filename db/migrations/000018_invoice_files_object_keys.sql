@@ -0,0 +1,23 @@
+-- Archivos de documentos fuera de Postgres: con STORAGE_METADATA_ONLY sólo se guardan aquí la
+-- key del objeto en el storage y su SHA-256; el migrador mueve los BYTEA existentes al storage,
+-- verifica la copia por checksum y deja pdf_data/xml_data en NULL.
+ALTER TABLE invoice_files
+ADD COLUMN IF NOT EXISTS pdf_key TEXT,
+ADD COLUMN IF NOT EXISTS xml_key TEXT,
+ADD COLUMN IF NOT EXISTS pdf_sha256 CHAR(64),
+ADD COLUMN IF NOT EXISTS xml_sha256 CHAR(64);
+
+-- Checksums de los archivos que siguen en la BD
+UPDATE invoice_files SET pdf_sha256 = encode(sha256(pdf_data), 'hex')
+WHERE pdf_data IS NOT NULL AND pdf_sha256 IS NULL;
+UPDATE invoice_files SET xml_sha256 = encode(sha256(xml_data), 'hex')
+WHERE xml_data IS NOT NULL AND xml_sha256 IS NULL;
+
+-- Pendientes del migrador
+CREATE INDEX IF NOT EXISTS idx_invoice_files_database_blobs ON invoice_files(updated_at)
+WHERE pdf_data IS NOT NULL OR xml_data IS NOT NULL;
+
+COMMENT ON COLUMN invoice_files.pdf_key IS 'Key del PDF en el storage de archivos';
+COMMENT ON COLUMN invoice_files.xml_key IS 'Key del XML en el storage de archivos';
+COMMENT ON COLUMN invoice_files.pdf_sha256 IS 'SHA-256 (hex) del PDF';
+COMMENT ON COLUMN invoice_files.xml_sha256 IS 'SHA-256 (hex) del XML';
@@ -0,0 +1,72 @@
+-- Versiones de los archivos de los documentos: cada PDF/XML generado queda como una versión
+-- inmutable con su tipo, SHA-256, tamaño y versión del generador, para saber qué recibió el
+-- cliente aunque el documento se regenere. El contenido vive en el storage (storage_key, una
+-- key por contenido que nunca se sobreescribe) o, sin storage, en data.
+CREATE TABLE IF NOT EXISTS invoice_artifacts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    invoice_id UUID NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    emitter_id UUID NOT NULL REFERENCES emitters(id) ON DELETE CASCADE,
+    kind VARCHAR(30) NOT NULL CHECK (kind IN ('fe_xml', 'protocol_xml', 'cafe_pdf', 'cancellation_xml')),
+    version INTEGER NOT NULL CHECK (version >= 1),
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    generator_version VARCHAR(50),
+    storage_key TEXT,
+    data BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    UNIQUE (invoice_id, kind, version),
+    CHECK (storage_key IS NOT NULL OR data IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoice_artifacts_database_blobs ON invoice_artifacts(created_at)
+WHERE data IS NOT NULL;
+
+-- Las versiones no cambian; el migrador de storage sólo puede mover data al storage
+CREATE OR REPLACE FUNCTION invoice_artifacts_immutable()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.invoice_id IS DISTINCT FROM OLD.invoice_id
+        OR NEW.emitter_id IS DISTINCT FROM OLD.emitter_id
+        OR NEW.kind IS DISTINCT FROM OLD.kind
+        OR NEW.version IS DISTINCT FROM OLD.version
+        OR NEW.content_type IS DISTINCT FROM OLD.content_type
+        OR NEW.size IS DISTINCT FROM OLD.size
+        OR NEW.sha256 IS DISTINCT FROM OLD.sha256
+        OR NEW.generator_version IS DISTINCT FROM OLD.generator_version
+        OR NEW.created_at IS DISTINCT FROM OLD.created_at
+        OR (NEW.data IS NOT NULL AND NEW.data IS DISTINCT FROM OLD.data)
+        OR (OLD.storage_key IS NOT NULL AND NEW.storage_key IS DISTINCT FROM OLD.storage_key) THEN
+        RAISE EXCEPTION 'invoice artifact % is immutable', OLD.id;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS invoice_artifacts_immutable ON invoice_artifacts;
+CREATE TRIGGER invoice_artifacts_immutable
+    BEFORE UPDATE ON invoice_artifacts
+    FOR EACH ROW EXECUTE FUNCTION invoice_artifacts_immutable();
+
+-- Los archivos ya generados pasan a ser la versión 1 (sin versión de generador conocida);
+-- los que sólo estaban en el storage usan su key o la key por defecto de la factura
+INSERT INTO invoice_artifacts (invoice_id, emitter_id, kind, version, content_type, size, sha256, storage_key, data, created_at)
+SELECT f.invoice_id, i.emitter_id, 'cafe_pdf', 1, 'application/pdf', f.pdf_size,
+    COALESCE(f.pdf_sha256, encode(sha256(f.pdf_data), 'hex')),
+    CASE WHEN f.pdf_data IS NULL THEN COALESCE(f.pdf_key, 'invoices/' || f.invoice_id || '/factura_' || f.invoice_id || '.pdf') END,
+    f.pdf_data, f.generated_at
+FROM invoice_files f
+JOIN invoices i ON i.id = f.invoice_id
+WHERE (f.pdf_data IS NOT NULL OR f.pdf_sha256 IS NOT NULL)
+ON CONFLICT (invoice_id, kind, version) DO NOTHING;
+
+INSERT INTO invoice_artifacts (invoice_id, emitter_id, kind, version, content_type, size, sha256, storage_key, data, created_at)
+SELECT f.invoice_id, i.emitter_id, 'fe_xml', 1, 'application/xml', f.xml_size,
+    COALESCE(f.xml_sha256, encode(sha256(f.xml_data), 'hex')),
+    CASE WHEN f.xml_data IS NULL THEN COALESCE(f.xml_key, 'invoices/' || f.invoice_id || '/factura_' || f.invoice_id || '.xml') END,
+    f.xml_data, f.generated_at
+FROM invoice_files f
+JOIN invoices i ON i.id = f.invoice_id
+WHERE (f.xml_data IS NOT NULL OR f.xml_sha256 IS NOT NULL)
+ON CONFLICT (invoice_id, kind, version) DO NOTHING;
@@ -0,0 +1,10 @@
+-- El folio se asigna por serie (sucursal, punto de facturación, tipo de documento y ambiente),
+-- así que dos sucursales con el mismo punto de facturación empiezan ambas en el folio 1: la
+-- unicidad de los documentos incluye la sucursal y el tipo de documento
+ALTER TABLE invoices
+DROP CONSTRAINT IF EXISTS invoices_emitter_id_d_ptofacdf_d_nrodf_key;
+
+DROP INDEX IF EXISTS idx_invoices_folio;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invoices_folio
+ON invoices(emitter_id, branch_id, doc_kind, iamb, d_ptofacdf, d_nrodf);
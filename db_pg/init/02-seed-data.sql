@@ -12,7 +12,7 @@ INSERT INTO emitters (
     'HYPERNOVA LABS',
     'HYPE',
     '2',
-    '155646463',
+    '155646463-2-2017',
     '86',
     '0001',
     '001',
@@ -230,8 +230,7 @@ BEGIN
         RETURN FALSE;
     END IF;
     
-    -- Aquí se podría agregar algoritmo de validación de dígito verificador
-    -- Por ahora retornamos TRUE si pasa las validaciones básicas
+    -- El dígito verificador se valida en la aplicación (services.ValidateRUC)
     RETURN TRUE;
 END;
 $$ LANGUAGE plpgsql;
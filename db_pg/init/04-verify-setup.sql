@@ -43,7 +43,7 @@ WHERE schemaname = 'public'
     AND indexname LIKE 'idx_%'
 ORDER BY indexname;
 
--- Verificar que las funciones estén creadas
+-- Verificar que las funciones estén creadas (get_next_series_folio la crea db/migrations)
 SELECT 
     'Functions' as check_type,
     proname as name,
@@ -54,7 +54,7 @@ WHERE n.nspname = 'public'
     AND proname IN (
         'update_updated_at_column', 'increment_series_counters',
         'cleanup_old_logs', 'get_database_stats', 'validate_data_integrity',
-        'generate_kpi_report', 'validate_ruc', 'get_next_folio',
+        'generate_kpi_report', 'validate_ruc', 'get_next_series_folio',
         'update_system_config'
     )
 ORDER BY proname;
@@ -1,10 +1,15 @@
 package api
 
 import (
+	"bytes"
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -21,6 +26,13 @@ type API struct {
 	emitterService  *services.EmitterService
 	customerService *services.CustomerService
 	productService  *services.ProductService
+	exchangeRateService *services.ExchangeRateService
+	importService   *services.ImportService
+	catalogService  *services.CatalogService
+	branchService   *services.BranchService
+	webhookService  *services.WebhookService
+	webhookDispatcher *services.WebhookDispatcher
+	auditService    *services.AuditService
 	apiKeyRepo      *database.APIKeyRepository
 	inngestClient   *workflows.InngestClient
 	logger          *logrus.Logger
@@ -32,6 +44,13 @@ func NewAPI(
 	emitterService *services.EmitterService,
 	customerService *services.CustomerService,
 	productService *services.ProductService,
+	exchangeRateService *services.ExchangeRateService,
+	importService *services.ImportService,
+	catalogService *services.CatalogService,
+	branchService *services.BranchService,
+	webhookService *services.WebhookService,
+	webhookDispatcher *services.WebhookDispatcher,
+	auditService *services.AuditService,
 	apiKeyRepo *database.APIKeyRepository,
 	inngestClient *workflows.InngestClient,
 	logger *logrus.Logger,
@@ -41,6 +60,13 @@ func NewAPI(
 		emitterService:  emitterService,
 		customerService: customerService,
 		productService:  productService,
+		exchangeRateService: exchangeRateService,
+		importService:   importService,
+		catalogService:  catalogService,
+		branchService:   branchService,
+		webhookService:  webhookService,
+		webhookDispatcher: webhookDispatcher,
+		auditService:    auditService,
 		apiKeyRepo:      apiKeyRepo,
 		inngestClient:   inngestClient,
 		logger:          logger,
@@ -49,12 +75,13 @@ func NewAPI(
 
 // CreateInvoice crea un nuevo documento fiscal
 func (api *API) CreateInvoice(c *gin.Context) {
-	// Obtener emitter ID del header de autenticación
-	emitterID, err := api.getEmitterIDFromAuth(c)
+	// Obtener emisor y ambiente de la API key
+	apiKey, err := api.getAPIKeyFromAuth(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
 		return
 	}
+	emitterID := apiKey.EmitterID
 
 	// Parsear request
 	var req models.CreateInvoiceRequest
@@ -70,10 +97,70 @@ func (api *API) CreateInvoice(c *gin.Context) {
 	idempotencyKey := c.GetHeader("Idempotency-Key")
 
 	// Crear invoice
-	response, err := api.invoiceService.CreateInvoice(emitterID, &req, idempotencyKey)
+	response, err := api.invoiceService.CreateInvoice(emitterID, apiKey.Environment, &req, idempotencyKey, api.auditActor(c))
 	if err != nil {
 		if strings.Contains(err.Error(), "idempotency key already used") {
-			c.JSON(http.StatusConflict, models.NewConflictError("Document with this idempotency key already exists"))
+			c.JSON(http.StatusConflict, models.NewConflictError("Idempotency-Key was already used with a different request body"))
+			return
+		}
+		if strings.Contains(err.Error(), "idempotency key request in progress") || strings.Contains(err.Error(), "idempotency key claim lost") {
+			c.JSON(http.StatusConflict, models.NewConflictError("A request with this Idempotency-Key is still being processed; retry later"))
+			return
+		}
+		if strings.Contains(err.Error(), "error validating payment") {
+			c.JSON(http.StatusUnprocessableEntity, models.NewValidationError("Invalid payment", []models.ErrorDetail{
+				{Field: "payment.method", Issue: err.Error()},
+			}))
+			return
+		}
+		if strings.Contains(err.Error(), "error resolving items") {
+			c.JSON(http.StatusUnprocessableEntity, models.NewValidationError("Invalid items", []models.ErrorDetail{
+				{Field: "items", Issue: err.Error()},
+			}))
+			return
+		}
+		if strings.Contains(err.Error(), "validation error") {
+			c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid customer", validationDetails(err)))
+			return
+		}
+		if strings.Contains(err.Error(), "customer already exists") {
+			c.JSON(http.StatusConflict, models.NewConflictError("Customer with this tax ID already exists; use customer_match or customer_id"))
+			return
+		}
+		if strings.Contains(err.Error(), "branch not found") {
+			c.JSON(http.StatusUnprocessableEntity, models.NewValidationError("Invalid branch", []models.ErrorDetail{
+				{Field: "overrides.branch", Issue: "Branch not found or inactive for this emitter"},
+			}))
+			return
+		}
+		if strings.Contains(err.Error(), "series not found") {
+			c.JSON(http.StatusUnprocessableEntity, models.NewValidationError("Invalid series", []models.ErrorDetail{
+				{Field: "overrides.pto_fac_df", Issue: "No active series for this pto_fac_df and document_type; create it or enable auto_create_series"},
+			}))
+			return
+		}
+		if strings.Contains(err.Error(), "series is inactive") {
+			c.JSON(http.StatusUnprocessableEntity, models.NewValidationError("Invalid series", []models.ErrorDetail{
+				{Field: "overrides.pto_fac_df", Issue: "Series is inactive; reactivate it to issue documents"},
+			}))
+			return
+		}
+		if strings.Contains(err.Error(), "series range exhausted") {
+			c.JSON(http.StatusUnprocessableEntity, models.NewValidationError("Series range exhausted", []models.ErrorDetail{
+				{Field: "overrides.pto_fac_df", Issue: "The authorized number range of this series has been used up"},
+			}))
+			return
+		}
+		if strings.Contains(err.Error(), "customer not found") {
+			c.JSON(http.StatusUnprocessableEntity, models.NewValidationError("Invalid customer", []models.ErrorDetail{
+				{Field: "customer_id", Issue: "Customer not found for this emitter"},
+			}))
+			return
+		}
+		if strings.Contains(err.Error(), "error resolving exchange rate") {
+			c.JSON(http.StatusUnprocessableEntity, models.NewValidationError("Invalid currency", []models.ErrorDetail{
+				{Field: "currency_code", Issue: err.Error()},
+			}))
 			return
 		}
 		api.logger.WithError(err).Error("Error creating invoice")
@@ -81,6 +168,11 @@ func (api *API) CreateInvoice(c *gin.Context) {
 		return
 	}
 
+	// Reintento con la misma Idempotency-Key: misma respuesta que la creación original
+	if response.Replayed {
+		c.Header("Idempotent-Replayed", "true")
+	}
+
 	c.JSON(http.StatusCreated, response)
 }
 
@@ -103,6 +195,10 @@ func (api *API) GetInvoice(c *gin.Context) {
 		return
 	}
 
+	if !api.checkInvoiceOwnership(c, emitterID, id) {
+		return
+	}
+
 	// Obtener invoice
 	response, err := api.invoiceService.GetInvoice(id)
 	if err != nil {
@@ -115,19 +211,13 @@ func (api *API) GetInvoice(c *gin.Context) {
 		return
 	}
 
-	// Verificar que el documento pertenece al emisor
-	if response.Emitter.RUC != "" && !api.validateEmitterOwnership(emitterID, response.Emitter.RUC) {
-		c.JSON(http.StatusForbidden, models.NewForbiddenError("Access denied to this document"))
-		return
-	}
-
 	c.JSON(http.StatusOK, response)
 }
 
 // GetInvoiceFiles obtiene los archivos de un documento
 func (api *API) GetInvoiceFiles(c *gin.Context) {
 	// Obtener emitter ID del header de autenticación
-	_, err := api.getEmitterIDFromAuth(c)
+	emitterID, err := api.getEmitterIDFromAuth(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
 		return
@@ -143,6 +233,10 @@ func (api *API) GetInvoiceFiles(c *gin.Context) {
 		return
 	}
 
+	if !api.checkInvoiceOwnership(c, emitterID, id) {
+		return
+	}
+
 	// Obtener tipo de archivo desde query parameter
 	fileType := c.Query("file_type")
 	if fileType == "" {
@@ -161,16 +255,61 @@ func (api *API) GetInvoiceFiles(c *gin.Context) {
 		return
 	}
 
+	api.serveInvoiceFile(c, id, fileType, "file_type")
+}
+
+// DownloadInvoiceFile descarga el PDF o XML de un documento (GET /v1/invoices/:id/files/:type)
+func (api *API) DownloadInvoiceFile(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	// Parsear ID del documento
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid document ID", []models.ErrorDetail{
+			{Field: "id", Issue: "Must be a valid UUID"},
+		}))
+		return
+	}
+
+	if !api.checkInvoiceOwnership(c, emitterID, id) {
+		return
+	}
+
+	api.serveInvoiceFile(c, id, c.Param("type"), "type")
+}
+
+// checkInvoiceOwnership verifica que el documento pertenezca al emisor de la API key; si no,
+// responde 404 como si no existiera y retorna false
+func (api *API) checkInvoiceOwnership(c *gin.Context, emitterID, id uuid.UUID) bool {
+	if err := api.invoiceService.CheckOwnership(emitterID, id); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Document not found"))
+			return false
+		}
+		api.logger.WithError(err).Error("Error checking invoice ownership")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error retrieving document"))
+		return false
+	}
+	return true
+}
+
+// serveInvoiceFile valida el tipo de archivo (field es el parámetro que lo trae) y envía el archivo
+func (api *API) serveInvoiceFile(c *gin.Context, id uuid.UUID, fileType, field string) {
 	// Validar tipo de archivo
 	if fileType != "pdf" && fileType != "xml" {
 		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid file type", []models.ErrorDetail{
-			{Field: "file_type", Issue: "Must be 'pdf' or 'xml'"},
+			{Field: field, Issue: "Must be 'pdf' or 'xml'"},
 		}))
 		return
 	}
 
-	// Descargar archivo específico
-	fileData, fileName, err := api.invoiceService.DownloadInvoiceFile(id, fileType)
+	// Obtener archivo específico
+	download, err := api.invoiceService.DownloadInvoiceFile(id, fileType)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			c.JSON(http.StatusNotFound, models.NewNotFoundError("File not found"))
@@ -181,20 +320,127 @@ func (api *API) GetInvoiceFiles(c *gin.Context) {
 		return
 	}
 
-	// Configurar headers para descarga
-	contentType := "application/octet-stream"
-	if fileType == "pdf" {
-		contentType = "application/pdf"
-	} else if fileType == "xml" {
-		contentType = "application/xml"
+	api.sendFileDownload(c, download)
+}
+
+// sendFileDownload entrega un archivo: redirige (302) a su URL prefirmada, o la retorna como JSON
+// con redirect=false; si el storage no genera URLs firmadas envía el contenido
+func (api *API) sendFileDownload(c *gin.Context, download *services.FileDownload) {
+	if download.SHA256 != nil {
+		c.Header("X-Content-SHA256", *download.SHA256)
+	}
+
+	if download.URL != "" {
+		// La URL firmada es una credencial temporal: no debe quedar en caches intermedios
+		c.Header("Cache-Control", "no-store")
+		if c.Query("redirect") == "false" {
+			c.JSON(http.StatusOK, models.FileDownloadURLResponse{
+				URL:       download.URL,
+				ExpiresAt: download.ExpiresAt,
+				SHA256:    download.SHA256,
+			})
+			return
+		}
+		c.Redirect(http.StatusFound, download.URL)
+		return
 	}
 
-	c.Header("Content-Type", contentType)
-	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%s", fileName))
-	c.Header("Content-Length", fmt.Sprintf("%d", len(fileData)))
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%s", download.FileName))
+	c.Header("Content-Length", fmt.Sprintf("%d", len(download.Data)))
 
 	// Enviar archivo
-	c.Data(http.StatusOK, contentType, fileData)
+	c.Data(http.StatusOK, download.ContentType, download.Data)
+}
+
+// ListInvoiceArtifacts lista todas las versiones de los archivos de un documento
+func (api *API) ListInvoiceArtifacts(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	// Parsear ID del documento
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid document ID", []models.ErrorDetail{
+			{Field: "id", Issue: "Must be a valid UUID"},
+		}))
+		return
+	}
+
+	artifacts, err := api.invoiceService.ListArtifacts(emitterID, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Document not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error listing invoice artifacts")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error retrieving artifacts"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.InvoiceArtifactListResponse{
+		Items: artifacts,
+		Total: len(artifacts),
+	})
+}
+
+// DownloadInvoiceArtifact descarga una versión específica de un archivo de un documento
+func (api *API) DownloadInvoiceArtifact(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	// Parsear ID del documento
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid document ID", []models.ErrorDetail{
+			{Field: "id", Issue: "Must be a valid UUID"},
+		}))
+		return
+	}
+
+	kind := models.ArtifactKind(c.Param("kind"))
+	if !kind.IsValid() {
+		kinds := make([]string, 0, len(models.ArtifactKinds()))
+		for _, k := range models.ArtifactKinds() {
+			kinds = append(kinds, string(k))
+		}
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid artifact kind", []models.ErrorDetail{
+			{Field: "kind", Issue: "Must be one of: " + strings.Join(kinds, ", ")},
+		}))
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid artifact version", []models.ErrorDetail{
+			{Field: "version", Issue: "Must be a positive integer"},
+		}))
+		return
+	}
+
+	_, download, err := api.invoiceService.DownloadArtifact(emitterID, id, kind, version)
+	if err != nil {
+		if strings.Contains(err.Error(), "invoice not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Document not found"))
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Artifact version not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error downloading invoice artifact")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error downloading artifact"))
+		return
+	}
+
+	api.sendFileDownload(c, download)
 }
 
 // GetPublicInvoiceFile obtiene un archivo público de una factura (sin autenticación)
@@ -226,8 +472,8 @@ func (api *API) GetPublicInvoiceFile(c *gin.Context) {
 		return
 	}
 
-	// Descargar archivo específico
-	fileData, fileName, err := api.invoiceService.DownloadInvoiceFile(id, fileType)
+	// Obtener archivo específico
+	download, err := api.invoiceService.DownloadInvoiceFile(id, fileType)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			c.JSON(http.StatusNotFound, models.NewNotFoundError("File not found"))
@@ -238,26 +484,13 @@ func (api *API) GetPublicInvoiceFile(c *gin.Context) {
 		return
 	}
 
-	// Configurar headers para descarga
-	contentType := "application/octet-stream"
-	if fileType == "pdf" {
-		contentType = "application/pdf"
-	} else if fileType == "xml" {
-		contentType = "application/xml"
-	}
-
-	c.Header("Content-Type", contentType)
-	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%s", fileName))
-	c.Header("Content-Length", fmt.Sprintf("%d", len(fileData)))
-
-	// Enviar archivo
-	c.Data(http.StatusOK, contentType, fileData)
+	api.sendFileDownload(c, download)
 }
 
 // ResendEmail reenvía el email de un documento
 func (api *API) ResendEmail(c *gin.Context) {
 	// Obtener emitter ID del header de autenticación
-	_, err := api.getEmitterIDFromAuth(c)
+	emitterID, err := api.getEmitterIDFromAuth(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
 		return
@@ -273,6 +506,10 @@ func (api *API) ResendEmail(c *gin.Context) {
 		return
 	}
 
+	if !api.checkInvoiceOwnership(c, emitterID, id) {
+		return
+	}
+
 	// Parsear request
 	var req models.EmailResendRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -301,7 +538,7 @@ func (api *API) ResendEmail(c *gin.Context) {
 // RetryWorkflow reintenta el workflow de un documento
 func (api *API) RetryWorkflow(c *gin.Context) {
 	// Obtener emitter ID del header de autenticación
-	_, err := api.getEmitterIDFromAuth(c)
+	emitterID, err := api.getEmitterIDFromAuth(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
 		return
@@ -317,6 +554,10 @@ func (api *API) RetryWorkflow(c *gin.Context) {
 		return
 	}
 
+	if !api.checkInvoiceOwnership(c, emitterID, id) {
+		return
+	}
+
 	// Reintentar workflow
 	response, err := api.invoiceService.RetryWorkflow(id)
 	if err != nil {
@@ -334,12 +575,13 @@ func (api *API) RetryWorkflow(c *gin.Context) {
 
 // GetSeries obtiene las series de documentos de un emisor
 func (api *API) GetSeries(c *gin.Context) {
-	// Obtener emitter ID del header de autenticación
-	emitterID, err := api.getEmitterIDFromAuth(c)
+	// Obtener emisor y ambiente de la API key
+	apiKey, err := api.getAPIKeyFromAuth(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
 		return
 	}
+	emitterID := apiKey.EmitterID
 
 	// Parsear parámetros de paginación
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -352,8 +594,10 @@ func (api *API) GetSeries(c *gin.Context) {
 		pageSize = 20
 	}
 
+	includeInactive, _ := strconv.ParseBool(c.DefaultQuery("include_inactive", "false"))
+
 	// Obtener series
-	series, total, err := api.emitterService.GetSeries(emitterID, page, pageSize)
+	series, total, err := api.emitterService.GetSeries(emitterID, apiKey.Environment, includeInactive, page, pageSize)
 	if err != nil {
 		api.logger.WithError(err).Error("Error getting series")
 		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error retrieving series"))
@@ -370,6 +614,39 @@ func (api *API) GetSeries(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// GetSeriesGaps obtiene los huecos de numeración de una serie
+func (api *API) GetSeriesGaps(c *gin.Context) {
+	// Obtener emisor y ambiente de la API key
+	apiKey, err := api.getAPIKeyFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+	emitterID := apiKey.EmitterID
+
+	ptoFacDF := c.Param("pto")
+	docKind := models.DocumentType(c.Param("kind"))
+	if !docKind.IsValid() {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid document kind", []models.ErrorDetail{
+			{Field: "kind", Issue: fmt.Sprintf("Unknown document kind: %s", docKind)},
+		}))
+		return
+	}
+
+	response, err := api.emitterService.GetSeriesGaps(emitterID, apiKey.Environment, c.Query("branch"), ptoFacDF, docKind)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Series not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error getting series gaps")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error retrieving series gaps"))
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 // CreateCustomer crea un nuevo cliente (endpoint admin)
 func (api *API) CreateCustomer(c *gin.Context) {
 	// Obtener emitter ID del header de autenticación
@@ -390,22 +667,26 @@ func (api *API) CreateCustomer(c *gin.Context) {
 	}
 
 	// Crear cliente
-	customer, err := api.customerService.Create(&req, emitterID)
+	customer, err := api.customerService.Create(&req, emitterID, api.auditActor(c))
 	if err != nil {
+		if strings.Contains(err.Error(), "validation error") {
+			c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid customer", validationDetails(err)))
+			return
+		}
+		if strings.Contains(err.Error(), "already exists") {
+			c.JSON(http.StatusConflict, models.NewConflictError("Customer already exists; use PATCH /v1/customers/:id to update it"))
+			return
+		}
 		api.logger.WithError(err).Error("Error creating customer")
 		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error creating customer"))
 		return
 	}
 
-	response := models.CustomerResponse{
-		ID: customer.ID.String(),
-	}
-
-	c.JSON(http.StatusCreated, response)
+	c.JSON(http.StatusCreated, customer)
 }
 
-// CreateProduct crea un nuevo producto (endpoint admin)
-func (api *API) CreateProduct(c *gin.Context) {
+// GetCustomers lista los clientes del emisor con paginación y búsqueda (endpoint admin)
+func (api *API) GetCustomers(c *gin.Context) {
 	// Obtener emitter ID del header de autenticación
 	emitterID, err := api.getEmitterIDFromAuth(c)
 	if err != nil {
@@ -413,63 +694,67 @@ func (api *API) CreateProduct(c *gin.Context) {
 		return
 	}
 
-	// Parsear request
-	var req models.CreateProductRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		api.logger.WithError(err).Error("Error binding create product request")
-		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid request format", []models.ErrorDetail{
-			{Field: "body", Issue: err.Error()},
-		}))
-		return
+	// Parsear parámetros de paginación
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
 	}
 
-	// Crear producto
-	product, err := api.productService.Create(&req, emitterID)
+	// Obtener clientes (q busca en nombre, email y tax_id)
+	customers, total, err := api.customerService.List(emitterID, c.Query("q"), page, pageSize)
 	if err != nil {
-		api.logger.WithError(err).Error("Error creating product")
-		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error creating product"))
+		api.logger.WithError(err).Error("Error getting customers")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error retrieving customers"))
 		return
 	}
 
-	response := models.ProductResponse{
-		ID: product.ID.String(),
-	}
-
-	c.JSON(http.StatusCreated, response)
+	c.JSON(http.StatusOK, models.CustomerListResponse{
+		Items:    customers,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	})
 }
 
-// CreateEmitter crea un nuevo emisor (endpoint admin)
-func (api *API) CreateEmitter(c *gin.Context) {
-	// TODO: Implementar autenticación admin
-	// Por ahora permitimos crear emisores sin autenticación especial
-
-	// Parsear request
-	var req models.CreateEmitterRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		api.logger.WithError(err).Error("Error binding create emitter request")
-		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid request format", []models.ErrorDetail{
-			{Field: "body", Issue: err.Error()},
-		}))
+// GetCustomer obtiene un cliente del emisor (endpoint admin)
+func (api *API) GetCustomer(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
 		return
 	}
 
-	// Crear emisor
-	emitter, err := api.emitterService.Create(&req)
+	// Parsear ID del cliente
+	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		api.logger.WithError(err).Error("Error creating emitter")
-		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error creating emitter"))
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid customer ID", []models.ErrorDetail{
+			{Field: "id", Issue: "Must be a valid UUID"},
+		}))
 		return
 	}
 
-	response := models.EmitterResponse{
-		ID: emitter.ID.String(),
+	customer, err := api.customerService.GetByID(emitterID, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Customer not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error getting customer")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error retrieving customer"))
+		return
 	}
 
-	c.JSON(http.StatusCreated, response)
+	c.JSON(http.StatusOK, customer)
 }
 
-// CreateSeries crea una nueva serie para un emisor (endpoint admin)
-func (api *API) CreateSeries(c *gin.Context) {
+// UpdateCustomer actualiza parcialmente un cliente del emisor (endpoint admin)
+func (api *API) UpdateCustomer(c *gin.Context) {
 	// Obtener emitter ID del header de autenticación
 	emitterID, err := api.getEmitterIDFromAuth(c)
 	if err != nil {
@@ -477,39 +762,1351 @@ func (api *API) CreateSeries(c *gin.Context) {
 		return
 	}
 
+	// Parsear ID del cliente
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid customer ID", []models.ErrorDetail{
+			{Field: "id", Issue: "Must be a valid UUID"},
+		}))
+		return
+	}
+
 	// Parsear request
-	var req models.CreateSeriesRequest
+	var req models.UpdateCustomerRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		api.logger.WithError(err).Error("Error binding create series request")
+		api.logger.WithError(err).Error("Error binding update customer request")
 		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid request format", []models.ErrorDetail{
 			{Field: "body", Issue: err.Error()},
 		}))
 		return
 	}
 
-	// Crear serie
-	series, err := api.emitterService.CreateSeries(emitterID, &req)
+	customer, err := api.customerService.Update(emitterID, id, &req, api.auditActor(c))
 	if err != nil {
+		if strings.Contains(err.Error(), "validation error") {
+			c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid customer", validationDetails(err)))
+			return
+		}
 		if strings.Contains(err.Error(), "already exists") {
-			c.JSON(http.StatusConflict, models.NewConflictError("Series already exists"))
+			c.JSON(http.StatusConflict, models.NewConflictError("Customer with this tax ID already exists"))
 			return
 		}
-		api.logger.WithError(err).Error("Error creating series")
-		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error creating series"))
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Customer not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error updating customer")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error updating customer"))
 		return
 	}
 
-	response := models.SeriesResponse{
-		Items: []models.SeriesItem{
-			{
-				PtoFacDF:        series.PtoFacDF,
-				DocKind:         string(series.DocKind),
-				LastAssigned:    series.NextNumber - 1,
-				IssuedCount:     series.IssuedCount,
-				AuthorizedCount: series.AuthorizedCount,
-				RejectedCount:   series.RejectedCount,
-			},
-		},
+	c.JSON(http.StatusOK, customer)
+}
+
+// DeleteCustomer desactiva un cliente del emisor (endpoint admin)
+func (api *API) DeleteCustomer(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	// Parsear ID del cliente
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid customer ID", []models.ErrorDetail{
+			{Field: "id", Issue: "Must be a valid UUID"},
+		}))
+		return
+	}
+
+	if err := api.customerService.Delete(emitterID, id, api.auditActor(c)); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Customer not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error deleting customer")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error deleting customer"))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// CreateProduct crea un nuevo producto (endpoint admin)
+func (api *API) CreateProduct(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	// Parsear request
+	var req models.CreateProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.logger.WithError(err).Error("Error binding create product request")
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid request format", []models.ErrorDetail{
+			{Field: "body", Issue: err.Error()},
+		}))
+		return
+	}
+
+	// Crear producto
+	product, err := api.productService.Create(&req, emitterID, api.auditActor(c))
+	if err != nil {
+		if strings.Contains(err.Error(), "validation error") {
+			c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid product", []models.ErrorDetail{
+				{Field: "body", Issue: err.Error()},
+			}))
+			return
+		}
+		if strings.Contains(err.Error(), "already exists") {
+			c.JSON(http.StatusConflict, models.NewConflictError("Product with this SKU already exists"))
+			return
+		}
+		api.logger.WithError(err).Error("Error creating product")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error creating product"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, product)
+}
+
+// GetProducts lista los productos del emisor con paginación (endpoint admin)
+func (api *API) GetProducts(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	// Parsear parámetros de paginación
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	includeInactive := c.Query("include_inactive") == "true"
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	products, total, err := api.productService.List(emitterID, includeInactive, page, pageSize)
+	if err != nil {
+		api.logger.WithError(err).Error("Error getting products")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error retrieving products"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ProductListResponse{
+		Items:    products,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	})
+}
+
+// SearchProducts busca productos del emisor por descripción o SKU (endpoint admin)
+func (api *API) SearchProducts(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	query := c.Query("q")
+	if strings.TrimSpace(query) == "" {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Search term required", []models.ErrorDetail{
+			{Field: "q", Issue: "Must not be empty"},
+		}))
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	products, err := api.productService.SearchByDescription(emitterID, query, limit)
+	if err != nil {
+		api.logger.WithError(err).Error("Error searching products")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error searching products"))
+		return
+	}
+	if products == nil {
+		products = []models.Product{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": products})
+}
+
+// GetProduct obtiene un producto del emisor (endpoint admin)
+func (api *API) GetProduct(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	// Parsear ID del producto
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid product ID", []models.ErrorDetail{
+			{Field: "id", Issue: "Must be a valid UUID"},
+		}))
+		return
+	}
+
+	product, err := api.productService.GetByID(emitterID, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Product not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error getting product")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error retrieving product"))
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// UpdateProduct actualiza parcialmente un producto del emisor (endpoint admin)
+func (api *API) UpdateProduct(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	// Parsear ID del producto
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid product ID", []models.ErrorDetail{
+			{Field: "id", Issue: "Must be a valid UUID"},
+		}))
+		return
+	}
+
+	// Parsear request
+	var req models.UpdateProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.logger.WithError(err).Error("Error binding update product request")
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid request format", []models.ErrorDetail{
+			{Field: "body", Issue: err.Error()},
+		}))
+		return
+	}
+
+	product, err := api.productService.Update(emitterID, id, &req, api.auditActor(c))
+	if err != nil {
+		if strings.Contains(err.Error(), "validation error") {
+			c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid product", []models.ErrorDetail{
+				{Field: "body", Issue: err.Error()},
+			}))
+			return
+		}
+		if strings.Contains(err.Error(), "already exists") {
+			c.JSON(http.StatusConflict, models.NewConflictError("Product with this SKU already exists"))
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Product not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error updating product")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error updating product"))
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// DeleteProduct desactiva un producto del emisor (endpoint admin)
+func (api *API) DeleteProduct(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	// Parsear ID del producto
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid product ID", []models.ErrorDetail{
+			{Field: "id", Issue: "Must be a valid UUID"},
+		}))
+		return
+	}
+
+	if err := api.productService.Delete(emitterID, id, api.auditActor(c)); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Product not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error deleting product")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error deleting product"))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// CreateBranch crea una sucursal del emisor (endpoint admin)
+func (api *API) CreateBranch(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	var req models.CreateBranchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.logger.WithError(err).Error("Error binding create branch request")
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid request format", []models.ErrorDetail{
+			{Field: "body", Issue: err.Error()},
+		}))
+		return
+	}
+
+	branch, err := api.branchService.Create(emitterID, &req)
+	if err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			c.JSON(http.StatusConflict, models.NewConflictError("Branch with this code already exists"))
+			return
+		}
+		api.logger.WithError(err).Error("Error creating branch")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error creating branch"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, branch)
+}
+
+// GetBranches lista las sucursales del emisor (endpoint admin)
+func (api *API) GetBranches(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	includeInactive, _ := strconv.ParseBool(c.DefaultQuery("include_inactive", "false"))
+
+	branches, err := api.branchService.List(emitterID, includeInactive)
+	if err != nil {
+		api.logger.WithError(err).Error("Error listing branches")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error retrieving branches"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.BranchListResponse{
+		Items: branches,
+		Total: len(branches),
+	})
+}
+
+// GetBranch obtiene una sucursal del emisor (endpoint admin)
+func (api *API) GetBranch(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	// Parsear ID de la sucursal
+	id, err := uuid.Parse(c.Param("branch_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid branch ID", []models.ErrorDetail{
+			{Field: "branch_id", Issue: "Must be a valid UUID"},
+		}))
+		return
+	}
+
+	branch, err := api.branchService.GetByID(emitterID, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Branch not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error getting branch")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error retrieving branch"))
+		return
+	}
+
+	c.JSON(http.StatusOK, branch)
+}
+
+// UpdateBranch actualiza parcialmente una sucursal del emisor (endpoint admin)
+func (api *API) UpdateBranch(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	// Parsear ID de la sucursal
+	id, err := uuid.Parse(c.Param("branch_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid branch ID", []models.ErrorDetail{
+			{Field: "branch_id", Issue: "Must be a valid UUID"},
+		}))
+		return
+	}
+
+	var req models.UpdateBranchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.logger.WithError(err).Error("Error binding update branch request")
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid request format", []models.ErrorDetail{
+			{Field: "body", Issue: err.Error()},
+		}))
+		return
+	}
+
+	branch, err := api.branchService.Update(emitterID, id, &req)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Branch not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error updating branch")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error updating branch"))
+		return
+	}
+
+	c.JSON(http.StatusOK, branch)
+}
+
+// DeleteBranch desactiva una sucursal del emisor (endpoint admin)
+func (api *API) DeleteBranch(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	// Parsear ID de la sucursal
+	id, err := uuid.Parse(c.Param("branch_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid branch ID", []models.ErrorDetail{
+			{Field: "branch_id", Issue: "Must be a valid UUID"},
+		}))
+		return
+	}
+
+	if err := api.branchService.Delete(emitterID, id); err != nil {
+		if strings.Contains(err.Error(), "validation error") {
+			c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid branch", validationDetails(err)))
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Branch not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error deleting branch")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error deleting branch"))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// CreateWebhookEndpoint registra un endpoint de webhooks del emisor (endpoint admin)
+func (api *API) CreateWebhookEndpoint(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	var req models.CreateWebhookEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.logger.WithError(err).Error("Error binding create webhook endpoint request")
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid request format", []models.ErrorDetail{
+			{Field: "body", Issue: err.Error()},
+		}))
+		return
+	}
+
+	endpoint, err := api.webhookService.CreateEndpoint(emitterID, &req)
+	if err != nil {
+		if strings.Contains(err.Error(), "validation error") {
+			c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid webhook endpoint", validationDetails(err)))
+			return
+		}
+		api.logger.WithError(err).Error("Error creating webhook endpoint")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error creating webhook endpoint"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, endpoint)
+}
+
+// GetWebhookEndpoints lista los endpoints de webhooks del emisor (endpoint admin)
+func (api *API) GetWebhookEndpoints(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	endpoints, err := api.webhookService.ListEndpoints(emitterID)
+	if err != nil {
+		api.logger.WithError(err).Error("Error listing webhook endpoints")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error retrieving webhook endpoints"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.WebhookEndpointListResponse{
+		Items: endpoints,
+		Total: len(endpoints),
+	})
+}
+
+// GetWebhookEndpoint obtiene un endpoint de webhooks del emisor (endpoint admin)
+func (api *API) GetWebhookEndpoint(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	id, ok := parseWebhookEndpointID(c)
+	if !ok {
+		return
+	}
+
+	endpoint, err := api.webhookService.GetEndpoint(emitterID, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Webhook endpoint not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error getting webhook endpoint")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error retrieving webhook endpoint"))
+		return
+	}
+
+	c.JSON(http.StatusOK, endpoint)
+}
+
+// UpdateWebhookEndpoint actualiza parcialmente un endpoint de webhooks del emisor (endpoint admin)
+func (api *API) UpdateWebhookEndpoint(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	id, ok := parseWebhookEndpointID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateWebhookEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.logger.WithError(err).Error("Error binding update webhook endpoint request")
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid request format", []models.ErrorDetail{
+			{Field: "body", Issue: err.Error()},
+		}))
+		return
+	}
+
+	endpoint, err := api.webhookService.UpdateEndpoint(emitterID, id, &req)
+	if err != nil {
+		if strings.Contains(err.Error(), "validation error") {
+			c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid webhook endpoint", validationDetails(err)))
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Webhook endpoint not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error updating webhook endpoint")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error updating webhook endpoint"))
+		return
+	}
+
+	c.JSON(http.StatusOK, endpoint)
+}
+
+// DeleteWebhookEndpoint elimina un endpoint de webhooks del emisor (endpoint admin)
+func (api *API) DeleteWebhookEndpoint(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	id, ok := parseWebhookEndpointID(c)
+	if !ok {
+		return
+	}
+
+	if err := api.webhookService.DeleteEndpoint(emitterID, id); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Webhook endpoint not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error deleting webhook endpoint")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error deleting webhook endpoint"))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RedeliverWebhook vuelve a encolar una entrega de webhook, también desde dead-letter (endpoint admin)
+func (api *API) RedeliverWebhook(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	id, ok := parseWebhookDeliveryID(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := api.webhookService.Redeliver(emitterID, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Webhook delivery not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error redelivering webhook")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error redelivering webhook"))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// GetWebhookDeliveries lista el log de entregas de webhooks del emisor con el historial de intentos (endpoint admin)
+func (api *API) GetWebhookDeliveries(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	// Parsear parámetros de paginación
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	// Parsear filtros
+	filter := models.WebhookDeliveryFilter{
+		EventType: models.WebhookEventType(c.Query("event_type")),
+		Status:    models.WebhookDeliveryStatus(c.Query("status")),
+	}
+	var details []models.ErrorDetail
+	switch filter.Status {
+	case "", models.WebhookDeliveryPending, models.WebhookDeliveryDelivered, models.WebhookDeliveryDead:
+	default:
+		details = append(details, models.ErrorDetail{Field: "status", Issue: "Must be pending, delivered or dead"})
+	}
+	if from, ok := parseDeliveryDate(c.Query("from"), false); ok {
+		filter.From = from
+	} else {
+		details = append(details, models.ErrorDetail{Field: "from", Issue: "Must be a date (YYYY-MM-DD) or RFC 3339 timestamp"})
+	}
+	if to, ok := parseDeliveryDate(c.Query("to"), true); ok {
+		filter.To = to
+	} else {
+		details = append(details, models.ErrorDetail{Field: "to", Issue: "Must be a date (YYYY-MM-DD) or RFC 3339 timestamp"})
+	}
+	if len(details) > 0 {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid filters", details))
+		return
+	}
+
+	deliveries, total, err := api.webhookService.ListDeliveries(emitterID, filter, page, pageSize)
+	if err != nil {
+		api.logger.WithError(err).Error("Error listing webhook deliveries")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error retrieving webhook deliveries"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.WebhookDeliveryListResponse{
+		Items:    deliveries,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	})
+}
+
+// GetWebhookDelivery obtiene una entrega de webhook del emisor con su historial de intentos (endpoint admin)
+func (api *API) GetWebhookDelivery(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	id, ok := parseWebhookDeliveryID(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := api.webhookService.GetDelivery(emitterID, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Webhook delivery not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error getting webhook delivery")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error retrieving webhook delivery"))
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// ReplayWebhookDelivery reenvía de inmediato el evento de una entrega y retorna el resultado (endpoint admin)
+func (api *API) ReplayWebhookDelivery(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	id, ok := parseWebhookDeliveryID(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := api.webhookDispatcher.Replay(c.Request.Context(), emitterID, id)
+	if err != nil {
+		if respondRateLimited(c, err) {
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Webhook delivery not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error replaying webhook delivery")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error replaying webhook delivery"))
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// SendWebhookTestEvent envía un evento webhook.test a un endpoint del emisor y retorna el resultado (endpoint admin)
+func (api *API) SendWebhookTestEvent(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	id, ok := parseWebhookEndpointID(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := api.webhookDispatcher.SendTestEvent(c.Request.Context(), emitterID, id)
+	if err != nil {
+		if respondRateLimited(c, err) {
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Webhook endpoint not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error sending webhook test event")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error sending webhook test event"))
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// parseWebhookDeliveryID parsea el ID de la entrega de webhook; si no es válido responde 400
+func parseWebhookDeliveryID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid delivery ID", []models.ErrorDetail{
+			{Field: "id", Issue: "Must be a valid UUID"},
+		}))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// parseDeliveryDate parsea un filtro de fecha del log de entregas (YYYY-MM-DD o RFC 3339).
+// Como límite superior, una fecha sin hora incluye el día completo.
+func parseDeliveryDate(value string, endOfDay bool) (*time.Time, bool) {
+	if value == "" {
+		return nil, true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, true
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, false
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, true
+}
+
+// parseWebhookEndpointID parsea el ID del endpoint de webhooks; si no es válido responde 400
+func parseWebhookEndpointID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid webhook endpoint ID", []models.ErrorDetail{
+			{Field: "id", Issue: "Must be a valid UUID"},
+		}))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// GetAuditLogs obtiene el log de auditoría de un recurso del emisor para revisiones de
+// cumplimiento; con id sólo los cambios de ese registro (endpoint admin)
+func (api *API) GetAuditLogs(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	// Parsear parámetros de paginación
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	var recordID *uuid.UUID
+	if rawID := c.Query("id"); rawID != "" {
+		id, err := uuid.Parse(rawID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid record ID", []models.ErrorDetail{
+				{Field: "id", Issue: "Must be a valid UUID"},
+			}))
+			return
+		}
+		recordID = &id
+	}
+
+	logs, total, err := api.auditService.List(emitterID, models.AuditResource(c.Query("resource")), recordID, page, pageSize)
+	if err != nil {
+		if strings.Contains(err.Error(), "validation error") {
+			c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid filters", validationDetails(err)))
+			return
+		}
+		api.logger.WithError(err).Error("Error listing audit logs")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error retrieving audit logs"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AuditLogListResponse{
+		Items:    logs,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	})
+}
+
+// maxImportFileSize limita el tamaño de los archivos de importación masiva
+const maxImportFileSize = 10 << 20
+
+// ImportCustomers importa clientes desde un archivo CSV/XLSX de forma asíncrona (endpoint admin)
+func (api *API) ImportCustomers(c *gin.Context) {
+	api.startImport(c, models.ImportResourceCustomers)
+}
+
+// ImportProducts importa productos desde un archivo CSV/XLSX de forma asíncrona (endpoint admin)
+func (api *API) ImportProducts(c *gin.Context) {
+	api.startImport(c, models.ImportResourceProducts)
+}
+
+// startImport recibe el archivo multipart (campo "file") y encola la importación
+func (api *API) startImport(c *gin.Context, resourceType models.ImportResourceType) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("File required", []models.ErrorDetail{
+			{Field: "file", Issue: "Must upload a .csv or .xlsx file as multipart field 'file'"},
+		}))
+		return
+	}
+	if fileHeader.Size > maxImportFileSize {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("File too large", []models.ErrorDetail{
+			{Field: "file", Issue: fmt.Sprintf("Must be at most %d MB", maxImportFileSize>>20)},
+		}))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		api.logger.WithError(err).Error("Error opening import file")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error reading file"))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxImportFileSize))
+	if err != nil {
+		api.logger.WithError(err).Error("Error reading import file")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error reading file"))
+		return
+	}
+
+	job, err := api.importService.StartImport(emitterID, resourceType, fileHeader.Filename, data, api.auditActor(c))
+	if err != nil {
+		if strings.Contains(err.Error(), "validation error") {
+			c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid import file", []models.ErrorDetail{
+				{Field: "file", Issue: err.Error()},
+			}))
+			return
+		}
+		api.logger.WithError(err).Error("Error starting import")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error starting import"))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, importJobResponse(job))
+}
+
+// GetImport obtiene el estado de una importación masiva (endpoint admin)
+func (api *API) GetImport(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid import ID", []models.ErrorDetail{
+			{Field: "id", Issue: "Must be a valid UUID"},
+		}))
+		return
+	}
+
+	job, err := api.importService.GetJob(emitterID, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Import not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error getting import")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error retrieving import"))
+		return
+	}
+
+	c.JSON(http.StatusOK, importJobResponse(job))
+}
+
+// GetImportErrors descarga el reporte de errores de una importación (CSV por defecto, ?format=json)
+func (api *API) GetImportErrors(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid import ID", []models.ErrorDetail{
+			{Field: "id", Issue: "Must be a valid UUID"},
+		}))
+		return
+	}
+
+	job, err := api.importService.GetJob(emitterID, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Import not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error getting import")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error retrieving import"))
+		return
+	}
+
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, gin.H{"items": job.Errors, "total": job.ErrorCount})
+		return
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write([]string{"row", "field", "reason"})
+	for _, rowErr := range job.Errors {
+		writer.Write([]string{strconv.Itoa(rowErr.Row), rowErr.Field, rowErr.Reason})
+	}
+	writer.Flush()
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=import-%s-errors.csv", job.ID))
+	c.Data(http.StatusOK, "text/csv", buf.Bytes())
+}
+
+// importJobResponse construye la respuesta de un trabajo de importación
+func importJobResponse(job *models.ImportJob) models.ImportJobResponse {
+	return models.ImportJobResponse{
+		ImportJob: *job,
+		Links: models.ImportLinks{
+			Self:   fmt.Sprintf("/v1/imports/%s", job.ID),
+			Errors: fmt.Sprintf("/v1/imports/%s/errors", job.ID),
+		},
+	}
+}
+
+// SearchCPBSCatalog busca códigos CPBS por descripción o código (?q=)
+func (api *API) SearchCPBSCatalog(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	if _, err := api.getEmitterIDFromAuth(c); err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	entries, err := api.catalogService.SearchCPBS(c.Query("q"), limit)
+	if err != nil {
+		api.logger.WithError(err).Error("Error searching CPBS catalog")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error searching catalog"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CatalogListResponse{Items: entries, Total: len(entries)})
+}
+
+// GetTaxRatesCatalog obtiene el catálogo de tasas de ITBMS
+func (api *API) GetTaxRatesCatalog(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	if _, err := api.getEmitterIDFromAuth(c); err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	entries, err := api.catalogService.GetTaxRates()
+	if err != nil {
+		api.logger.WithError(err).Error("Error getting tax rates catalog")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error retrieving catalog"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CatalogListResponse{Items: entries, Total: len(entries)})
+}
+
+// GetPaymentMethodsCatalog obtiene el catálogo de formas de pago
+func (api *API) GetPaymentMethodsCatalog(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	if _, err := api.getEmitterIDFromAuth(c); err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	entries, err := api.catalogService.GetPaymentMethods()
+	if err != nil {
+		api.logger.WithError(err).Error("Error getting payment methods catalog")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error retrieving catalog"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CatalogListResponse{Items: entries, Total: len(entries)})
+}
+
+// ValidateRUC valida un RUC y su dígito verificador (?tipo=&ruc=&dv=).
+// Si no se envía dv, la respuesta incluye el DV esperado.
+func (api *API) ValidateRUC(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	if _, err := api.getEmitterIDFromAuth(c); err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	ruc := c.Query("ruc")
+	if strings.TrimSpace(ruc) == "" {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Missing RUC", []models.ErrorDetail{
+			{Field: "ruc", Issue: "Query parameter 'ruc' is required"},
+		}))
+		return
+	}
+
+	dv := c.Query("dv")
+	result := services.ValidateRUC(c.Query("tipo"), ruc, dv)
+
+	// Sin DV solo se valida el formato y se informa el DV calculado
+	if dv == "" && result.ExpectedDV != "" {
+		result = services.ValidateRUC(c.Query("tipo"), ruc, result.ExpectedDV)
+		result.DV = ""
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// CreateEmitter crea un nuevo emisor (endpoint admin)
+func (api *API) CreateEmitter(c *gin.Context) {
+	// TODO: Implementar autenticación admin
+	// Por ahora permitimos crear emisores sin autenticación especial
+
+	// Parsear request
+	var req models.CreateEmitterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.logger.WithError(err).Error("Error binding create emitter request")
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid request format", []models.ErrorDetail{
+			{Field: "body", Issue: err.Error()},
+		}))
+		return
+	}
+
+	// Crear emisor
+	emitter, err := api.emitterService.Create(&req, api.auditActor(c))
+	if err != nil {
+		if strings.Contains(err.Error(), "validation error") {
+			c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid emitter", validationDetails(err)))
+			return
+		}
+		if strings.Contains(err.Error(), "already exists") {
+			c.JSON(http.StatusConflict, models.NewConflictError("Emitter with this company code or RUC already exists"))
+			return
+		}
+		api.logger.WithError(err).Error("Error creating emitter")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error creating emitter"))
+		return
+	}
+
+	response := models.EmitterResponse{
+		ID: emitter.ID.String(),
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// GetMyEmitter obtiene el perfil del emisor autenticado
+func (api *API) GetMyEmitter(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	api.getEmitter(c, emitterID)
+}
+
+// UpdateMyEmitter actualiza parcialmente el perfil del emisor autenticado
+func (api *API) UpdateMyEmitter(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	api.updateEmitter(c, emitterID)
+}
+
+// GetEmitter obtiene un emisor (endpoint admin)
+func (api *API) GetEmitter(c *gin.Context) {
+	emitterID, ok := api.adminEmitterID(c)
+	if !ok {
+		return
+	}
+
+	api.getEmitter(c, emitterID)
+}
+
+// UpdateEmitter actualiza parcialmente un emisor (endpoint admin)
+func (api *API) UpdateEmitter(c *gin.Context) {
+	emitterID, ok := api.adminEmitterID(c)
+	if !ok {
+		return
+	}
+
+	api.updateEmitter(c, emitterID)
+}
+
+// DeleteEmitter desactiva un emisor y sus API keys (endpoint admin)
+func (api *API) DeleteEmitter(c *gin.Context) {
+	emitterID, ok := api.adminEmitterID(c)
+	if !ok {
+		return
+	}
+
+	if err := api.emitterService.Delete(emitterID, api.auditActor(c)); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Emitter not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error deleting emitter")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error deleting emitter"))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// adminEmitterID obtiene el emisor de la ruta (:id). Mientras no existan permisos de admin,
+// solo se permite operar sobre el emisor de la API key autenticada.
+func (api *API) adminEmitterID(c *gin.Context) (uuid.UUID, bool) {
+	emitterID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid emitter ID", []models.ErrorDetail{
+			{Field: "id", Issue: "Must be a valid UUID"},
+		}))
+		return uuid.Nil, false
+	}
+
+	// TODO: Permitir otros emisores cuando AdminAuthMiddleware valide permisos de admin
+	if authID, ok := c.Get("emitter_id"); !ok || authID.(uuid.UUID) != emitterID {
+		c.JSON(http.StatusForbidden, models.NewForbiddenError("API key cannot manage this emitter"))
+		return uuid.Nil, false
+	}
+
+	return emitterID, true
+}
+
+// getEmitter responde con el perfil de un emisor (sin credenciales PAC)
+func (api *API) getEmitter(c *gin.Context, emitterID uuid.UUID) {
+	emitter, err := api.emitterService.GetByID(emitterID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Emitter not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error getting emitter")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error retrieving emitter"))
+		return
+	}
+
+	c.JSON(http.StatusOK, emitter)
+}
+
+// updateEmitter aplica un PATCH al perfil de un emisor
+func (api *API) updateEmitter(c *gin.Context, emitterID uuid.UUID) {
+	var req models.UpdateEmitterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.logger.WithError(err).Error("Error binding update emitter request")
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid request format", []models.ErrorDetail{
+			{Field: "body", Issue: err.Error()},
+		}))
+		return
+	}
+
+	emitter, err := api.emitterService.Update(emitterID, &req, api.auditActor(c))
+	if err != nil {
+		if strings.Contains(err.Error(), "validation error") {
+			c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid emitter", validationDetails(err)))
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Emitter not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error updating emitter")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error updating emitter"))
+		return
+	}
+
+	c.JSON(http.StatusOK, emitter)
+}
+
+// CreateSeries crea una nueva serie para un emisor (endpoint admin)
+func (api *API) CreateSeries(c *gin.Context) {
+	// Obtener emisor y ambiente de la API key
+	apiKey, err := api.getAPIKeyFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+	emitterID := apiKey.EmitterID
+
+	// Parsear request
+	var req models.CreateSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.logger.WithError(err).Error("Error binding create series request")
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid request format", []models.ErrorDetail{
+			{Field: "body", Issue: err.Error()},
+		}))
+		return
+	}
+
+	// Crear serie
+	series, err := api.emitterService.CreateSeries(emitterID, apiKey.Environment, &req, api.auditActor(c))
+	if err != nil {
+		if strings.Contains(err.Error(), "validation error") {
+			c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid series", validationDetails(err)))
+			return
+		}
+		if strings.Contains(err.Error(), "branch not found") {
+			c.JSON(http.StatusUnprocessableEntity, models.NewValidationError("Invalid branch", []models.ErrorDetail{
+				{Field: "branch", Issue: "Branch not found or inactive for this emitter"},
+			}))
+			return
+		}
+		if strings.Contains(err.Error(), "already exists (inactive)") {
+			c.JSON(http.StatusConflict, models.NewConflictError("Series already exists but is inactive; reactivate it instead"))
+			return
+		}
+		if strings.Contains(err.Error(), "already exists") {
+			c.JSON(http.StatusConflict, models.NewConflictError("Series already exists"))
+			return
+		}
+		api.logger.WithError(err).Error("Error creating series")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error creating series"))
+		return
+	}
+
+	response := models.SeriesResponse{
+		Items: []models.SeriesItem{
+			models.NewSeriesItem(series),
+		},
 		Page:     1,
 		PageSize: 1,
 		Total:    1,
@@ -518,8 +2115,54 @@ func (api *API) CreateSeries(c *gin.Context) {
 	c.JSON(http.StatusCreated, response)
 }
 
-// CreateAPIKey crea una nueva API key para un emisor (endpoint admin)
-func (api *API) CreateAPIKey(c *gin.Context) {
+// DeactivateSeries desactiva una serie de un emisor (endpoint admin)
+func (api *API) DeactivateSeries(c *gin.Context) {
+	api.setSeriesActive(c, false)
+}
+
+// ReactivateSeries reactiva una serie de un emisor (endpoint admin)
+func (api *API) ReactivateSeries(c *gin.Context) {
+	api.setSeriesActive(c, true)
+}
+
+// setSeriesActive activa o desactiva la serie indicada en la ruta
+func (api *API) setSeriesActive(c *gin.Context, active bool) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	seriesID, err := uuid.Parse(c.Param("series_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid series ID", []models.ErrorDetail{
+			{Field: "series_id", Issue: "Must be a valid UUID"},
+		}))
+		return
+	}
+
+	var series *models.EmitterSeries
+	if active {
+		series, err = api.emitterService.ReactivateSeries(emitterID, seriesID, api.auditActor(c))
+	} else {
+		series, err = api.emitterService.DeactivateSeries(emitterID, seriesID, api.auditActor(c))
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Series not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error updating series")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error updating series"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSeriesItem(series))
+}
+
+// UpdateEmitterSettings actualiza la configuración de un emisor (endpoint admin)
+func (api *API) UpdateEmitterSettings(c *gin.Context) {
 	// Obtener emitter ID del header de autenticación
 	emitterID, err := api.getEmitterIDFromAuth(c)
 	if err != nil {
@@ -527,6 +2170,42 @@ func (api *API) CreateAPIKey(c *gin.Context) {
 		return
 	}
 
+	var req models.UpdateEmitterSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.logger.WithError(err).Error("Error binding emitter settings request")
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid request format", []models.ErrorDetail{
+			{Field: "body", Issue: err.Error()},
+		}))
+		return
+	}
+
+	emitter, err := api.emitterService.UpdateSettings(emitterID, &req, api.auditActor(c))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Emitter not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error updating emitter settings")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error updating emitter settings"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"auto_create_series": emitter.AutoCreateSeries,
+	})
+}
+
+// CreateAPIKey crea una nueva API key para un emisor (endpoint admin). Salvo un admin, una API
+// key sólo crea keys de su propio ambiente: una key de pruebas no puede obtener una de producción.
+func (api *API) CreateAPIKey(c *gin.Context) {
+	// Obtener emisor y ambiente de la API key
+	apiKey, err := api.getAPIKeyFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+	emitterID := apiKey.EmitterID
+
 	// Parsear request
 	var req models.CreateAPIKeyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -537,8 +2216,16 @@ func (api *API) CreateAPIKey(c *gin.Context) {
 		return
 	}
 
+	if req.Environment == "" {
+		req.Environment = apiKey.Environment
+	}
+	if req.Environment != apiKey.Environment && !c.GetBool("admin") {
+		c.JSON(http.StatusForbidden, models.NewForbiddenError("API key cannot create keys for another environment"))
+		return
+	}
+
 	// Crear API key
-	response, err := api.emitterService.CreateAPIKey(emitterID, &req)
+	response, err := api.emitterService.CreateAPIKey(emitterID, &req, api.auditActor(c))
 	if err != nil {
 		api.logger.WithError(err).Error("Error creating API key")
 		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error creating API key"))
@@ -550,15 +2237,16 @@ func (api *API) CreateAPIKey(c *gin.Context) {
 
 // GetDashboard obtiene el dashboard de un emisor (endpoint admin)
 func (api *API) GetDashboard(c *gin.Context) {
-	// Obtener emitter ID del header de autenticación
-	emitterID, err := api.getEmitterIDFromAuth(c)
+	// Obtener emisor y ambiente de la API key
+	apiKey, err := api.getAPIKeyFromAuth(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
 		return
 	}
+	emitterID := apiKey.EmitterID
 
 	// Obtener dashboard
-	response, err := api.emitterService.GetDashboard(emitterID)
+	response, err := api.emitterService.GetDashboard(emitterID, apiKey.Environment)
 	if err != nil {
 		api.logger.WithError(err).Error("Error getting dashboard")
 		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error retrieving dashboard"))
@@ -568,19 +2256,156 @@ func (api *API) GetDashboard(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// CreateExchangeRate registra la tasa de cambio de una moneda (endpoint admin)
+func (api *API) CreateExchangeRate(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	// Parsear request
+	var req models.CreateExchangeRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.logger.WithError(err).Error("Error binding create exchange rate request")
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid request format", []models.ErrorDetail{
+			{Field: "body", Issue: err.Error()},
+		}))
+		return
+	}
+
+	// Registrar tasa
+	rate, err := api.exchangeRateService.Create(emitterID, &req)
+	if err != nil {
+		if strings.Contains(err.Error(), "validation error") {
+			c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid exchange rate", []models.ErrorDetail{
+				{Field: "body", Issue: err.Error()},
+			}))
+			return
+		}
+		api.logger.WithError(err).Error("Error creating exchange rate")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error creating exchange rate"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, rate)
+}
+
+// GetExchangeRates lista las tasas de cambio de un emisor (endpoint admin)
+func (api *API) GetExchangeRates(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	// Parsear parámetros de paginación
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	// Obtener tasas
+	rates, total, err := api.exchangeRateService.List(emitterID, c.Query("currency_code"), page, pageSize)
+	if err != nil {
+		api.logger.WithError(err).Error("Error getting exchange rates")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error retrieving exchange rates"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ExchangeRateListResponse{
+		Items:    rates,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	})
+}
+
+// DeleteExchangeRate elimina una tasa de cambio (endpoint admin)
+func (api *API) DeleteExchangeRate(c *gin.Context) {
+	// Obtener emitter ID del header de autenticación
+	emitterID, err := api.getEmitterIDFromAuth(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewUnauthorizedError("Invalid API key"))
+		return
+	}
+
+	// Parsear ID de la tasa
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewValidationError("Invalid exchange rate ID", []models.ErrorDetail{
+			{Field: "id", Issue: "Must be a valid UUID"},
+		}))
+		return
+	}
+
+	if err := api.exchangeRateService.Delete(emitterID, id); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("Exchange rate not found"))
+			return
+		}
+		api.logger.WithError(err).Error("Error deleting exchange rate")
+		c.JSON(http.StatusInternalServerError, models.NewInternalError("Error deleting exchange rate"))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// respondRateLimited responde 429 con Retry-After si err es un *models.RateLimitError
+func respondRateLimited(c *gin.Context, err error) bool {
+	var rateLimitErr *models.RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		return false
+	}
+
+	retryAfter := int(rateLimitErr.RetryAfter.Round(time.Second) / time.Second)
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, models.NewRateLimitedError("Too many requests. Try later.", rateLimitErr.RetryAfter))
+	return true
+}
+
+// validationDetails obtiene los ErrorDetail estructurados de un error de validación
+// o, si el servicio no los provee, un detalle genérico con el mensaje
+func validationDetails(err error) []models.ErrorDetail {
+	var fieldErrs models.FieldErrors
+	if errors.As(err, &fieldErrs) {
+		return fieldErrs
+	}
+	return []models.ErrorDetail{{Field: "body", Issue: err.Error()}}
+}
+
 // getEmitterIDFromAuth extrae el emitter ID del header de autenticación
 func (api *API) getEmitterIDFromAuth(c *gin.Context) (uuid.UUID, error) {
+	apiKeyModel, err := api.getAPIKeyFromAuth(c)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return apiKeyModel.EmitterID, nil
+}
+
+// getAPIKeyFromAuth valida la API key del header de autenticación; su ambiente (live/test)
+// decide el iAmb de los documentos, series y reportes de la petición
+func (api *API) getAPIKeyFromAuth(c *gin.Context) (*models.APIKey, error) {
 	apiKey := c.GetHeader("X-API-Key")
-	// Debug: Log la API key recibida
-	api.logger.Infof("API Key recibida: %s", apiKey)
 	if apiKey == "" {
-		return uuid.Nil, models.NewAPIError(models.NewUnauthorizedError("API key required"))
+		return nil, models.NewAPIError(models.NewUnauthorizedError("API key required"))
 	}
 
 	// Validar API key usando el repositorio
 	apiKeyModel, err := api.apiKeyRepo.GetByHash(api.apiKeyRepo.HashAPIKey(apiKey))
 	if err != nil {
-		return uuid.Nil, models.NewAPIError(models.NewUnauthorizedError("Invalid API key"))
+		return nil, models.NewAPIError(models.NewUnauthorizedError("Invalid API key"))
 	}
 
 	// Actualizar último uso
@@ -588,7 +2413,26 @@ func (api *API) getEmitterIDFromAuth(c *gin.Context) (uuid.UUID, error) {
 		api.logger.Warnf("Error updating API key last used: %v", err)
 	}
 
-	return apiKeyModel.EmitterID, nil
+	// Guardar la API key para identificar al autor de los cambios (ver auditActor)
+	c.Set("api_key", apiKeyModel)
+
+	return apiKeyModel, nil
+}
+
+// auditActor identifica al autor de los cambios de la petición en el log de auditoría: la
+// API key autenticada (como admin sólo si un middleware verificó permisos de admin y marcó
+// "admin" en el contexto) o la IP del cliente sin autenticación
+func (api *API) auditActor(c *gin.Context) string {
+	value, ok := c.Get("api_key")
+	if !ok {
+		return models.AnonymousActor(c.ClientIP())
+	}
+
+	apiKey := value.(*models.APIKey)
+	if c.GetBool("admin") {
+		return models.AdminActor(apiKey.ID)
+	}
+	return models.APIKeyActor(apiKey.ID)
 }
 
 // AdminAuthMiddleware retorna middleware para autenticación de admin
@@ -603,17 +2447,11 @@ func (api *API) AdminAuthMiddleware() gin.HandlerFunc {
 		}
 
 		// TODO: Implementar validación de permisos de admin
-		// Por ahora solo validamos que la API key sea válida
+		// Por ahora solo validamos que la API key sea válida. Hasta entonces no se marca "admin"
+		// en el contexto: los cambios quedan en auditoría a nombre de la API key, no de un admin.
 		
 		// Agregar emitter ID al contexto para uso posterior
 		c.Set("emitter_id", emitterID)
 		c.Next()
 	}
 }
-
-// validateEmitterOwnership valida que un documento pertenece al emisor
-func (api *API) validateEmitterOwnership(emitterID uuid.UUID, ruc string) bool {
-	// TODO: Implementar validación real
-	// Por ahora retornamos true para desarrollo
-	return true
-}
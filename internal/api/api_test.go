@@ -0,0 +1,364 @@
+package api
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hypernova-labs/dgi-service/internal/config"
+	"github.com/hypernova-labs/dgi-service/internal/database"
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/hypernova-labs/dgi-service/internal/secrets"
+	"github.com/hypernova-labs/dgi-service/internal/services"
+	"github.com/sirupsen/logrus"
+)
+
+// testActor es el autor de los cambios hechos por los tests
+var testActor = models.SystemActor("test")
+
+// openTestDB conecta a la BD de TEST_DATABASE_URL, que debe tener aplicados db_pg/init y
+// db/migrations, con llaves de cifrado de prueba; sin la variable el test se omite
+func openTestDB(t *testing.T) *database.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	sqlDB, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("error opening test database: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(50)
+	if err := sqlDB.Ping(); err != nil {
+		t.Fatalf("error pinging test database: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("error generating test encryption key: %v", err)
+	}
+	envelope, err := secrets.NewEnvelope(map[string][]byte{"test": key}, "")
+	if err != nil {
+		t.Fatalf("error creating test envelope: %v", err)
+	}
+
+	return &database.DB{DB: sqlDB, Secrets: envelope}
+}
+
+// newTestEmitter crea un emisor de pruebas con series automáticas y una API key live; retorna
+// su ID y la API key. El emisor se elimina (en cascada) al terminar el test.
+func newTestEmitter(t *testing.T, db *database.DB, logger *logrus.Logger) (uuid.UUID, string) {
+	t.Helper()
+
+	id := uuid.New()
+	suffix := strings.ReplaceAll(id.String(), "-", "")
+	now := time.Now()
+	emitter := &models.Emitter{
+		ID:                 id,
+		Name:               "Test Emitter " + suffix[:8],
+		CompanyCode:        "T" + suffix[:9],
+		RUCTipo:            "2",
+		RUCNumero:          fmt.Sprintf("%d", now.UnixNano()%1_000_000_000_000),
+		RUCDV:              "00",
+		SucEm:              "0001",
+		PtoFacDefault:      "001",
+		IAmb:               models.IAmbProduction,
+		ITpEmisDefault:     "01",
+		IDocDefault:        "01",
+		Email:              "test-" + suffix[:8] + "@example.com",
+		PACAPIKey:          "test",
+		PACSubscriptionKey: "test",
+		IsActive:           true,
+		AutoCreateSeries:   true,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+
+	if err := database.NewEmitterRepository(db, logger).Create(emitter, testActor); err != nil {
+		t.Fatalf("error creating test emitter: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := db.Exec(`DELETE FROM emitters WHERE id = $1`, emitter.ID); err != nil {
+			t.Logf("error deleting test emitter %s: %v", emitter.ID, err)
+		}
+	})
+
+	_, apiKey, err := database.NewAPIKeyRepository(db, logger).Create(emitter.ID, "test", models.APIKeyEnvironmentLive, 10000, testActor)
+	if err != nil {
+		t.Fatalf("error creating test API key: %v", err)
+	}
+
+	return emitter.ID, apiKey
+}
+
+// newIdempotencyTestRouter arma el endpoint de creación de documentos sobre la BD de pruebas
+func newIdempotencyTestRouter(db *database.DB, logger *logrus.Logger) *gin.Engine {
+	invoiceService := services.NewInvoiceService(db, nil, nil, nil, config.StorageConfig{}, nil, config.IdempotencyConfig{KeyTTL: time.Hour}, logger)
+	api := &API{
+		invoiceService: invoiceService,
+		apiKeyRepo:     database.NewAPIKeyRepository(db, logger),
+		logger:         logger,
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/v1/invoices", api.CreateInvoice)
+	return router
+}
+
+// invoiceRequestBody es el cuerpo de un documento de un ítem por unitPrice más ITBMS 7%
+func invoiceRequestBody(unitPrice float64) []byte {
+	body, _ := json.Marshal(map[string]interface{}{
+		"document_type": models.DocumentTypeInvoice,
+		"customer": map[string]interface{}{
+			"name":  "Test Customer",
+			"email": "customer@example.com",
+		},
+		"items": []map[string]interface{}{{
+			"description": "Test item",
+			"quantity":    1,
+			"unit_price":  unitPrice,
+			"tax_rate":    "01",
+		}},
+		"payment": map[string]interface{}{
+			"method": "01",
+			"amount": unitPrice * 1.07,
+		},
+	})
+	return body
+}
+
+// postInvoice envía una creación de documento con la API key y la Idempotency-Key dadas
+func postInvoice(router *gin.Engine, apiKey, idempotencyKey string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/invoices", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", apiKey)
+	req.Header.Set("Idempotency-Key", idempotencyKey)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// countInvoices cuenta los documentos de un emisor
+func countInvoices(t *testing.T, db *database.DB, emitterID uuid.UUID) int {
+	t.Helper()
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM invoices WHERE emitter_id = $1`, emitterID).Scan(&count); err != nil {
+		t.Fatalf("error counting invoices: %v", err)
+	}
+	return count
+}
+
+// TestCreateInvoiceIdempotencyConcurrent envía la misma Idempotency-Key en paralelo y verifica
+// que se cree un solo documento, que los demás intentos reciban la respuesta original o un 409
+// mientras la primera sigue en curso, que otro cuerpo con la misma key reciba 409 y que la misma
+// key de otro emisor cree su propio documento
+func TestCreateInvoiceIdempotencyConcurrent(t *testing.T) {
+	db := openTestDB(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	router := newIdempotencyTestRouter(db, logger)
+
+	emitterID, apiKey := newTestEmitter(t, db, logger)
+	idempotencyKey := "test-" + uuid.NewString()
+	body := invoiceRequestBody(10)
+
+	const workers = 10
+	var wg sync.WaitGroup
+	responses := make([]*httptest.ResponseRecorder, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = postInvoice(router, apiKey, idempotencyKey, body)
+		}(i)
+	}
+	wg.Wait()
+
+	var created, replayed, inProgress int
+	var invoiceID uuid.UUID
+	for _, recorder := range responses {
+		switch recorder.Code {
+		case http.StatusCreated:
+			var response models.InvoiceResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("error decoding invoice response: %v", err)
+			}
+			if invoiceID == uuid.Nil {
+				invoiceID = response.ID
+			} else if response.ID != invoiceID {
+				t.Errorf("got invoice %s, want the original %s", response.ID, invoiceID)
+			}
+
+			if recorder.Header().Get("Idempotent-Replayed") == "true" {
+				replayed++
+			} else {
+				created++
+			}
+		case http.StatusConflict:
+			inProgress++
+		default:
+			t.Errorf("unexpected status %d: %s", recorder.Code, recorder.Body.String())
+		}
+	}
+
+	if created != 1 {
+		t.Errorf("got %d non-replayed 201 responses, want 1 (replayed %d, in progress %d)", created, replayed, inProgress)
+	}
+	if count := countInvoices(t, db, emitterID); count != 1 {
+		t.Errorf("got %d invoices for the emitter, want 1", count)
+	}
+
+	// Una vez terminada, la misma petición recibe la respuesta original
+	recorder := postInvoice(router, apiKey, idempotencyKey, body)
+	if recorder.Code != http.StatusCreated || recorder.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry got status %d (Idempotent-Replayed=%q), want a 201 replay", recorder.Code, recorder.Header().Get("Idempotent-Replayed"))
+	}
+
+	// Otro cuerpo con la misma key
+	recorder = postInvoice(router, apiKey, idempotencyKey, invoiceRequestBody(20))
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("different body got status %d, want %d", recorder.Code, http.StatusConflict)
+	}
+	if count := countInvoices(t, db, emitterID); count != 1 {
+		t.Errorf("got %d invoices for the emitter after a conflicting body, want 1", count)
+	}
+
+	// La misma key en otro emisor no choca con la del primero
+	otherEmitterID, otherAPIKey := newTestEmitter(t, db, logger)
+	recorder = postInvoice(router, otherAPIKey, idempotencyKey, body)
+	if recorder.Code != http.StatusCreated || recorder.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("other emitter got status %d (Idempotent-Replayed=%q), want a new 201: %s", recorder.Code, recorder.Header().Get("Idempotent-Replayed"), recorder.Body.String())
+	}
+	var otherResponse models.InvoiceResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &otherResponse); err != nil {
+		t.Fatalf("error decoding invoice response: %v", err)
+	}
+	if otherResponse.ID == invoiceID {
+		t.Errorf("other emitter got the first emitter's invoice %s", invoiceID)
+	}
+	if count := countInvoices(t, db, otherEmitterID); count != 1 {
+		t.Errorf("got %d invoices for the other emitter, want 1", count)
+	}
+}
+
+// TestCreateAPIKeyEnvironment verifica que una API key de pruebas no pueda crear una de
+// producción y que, sin environment, la key nueva tome el ambiente de la que la crea
+func TestCreateAPIKeyEnvironment(t *testing.T) {
+	db := openTestDB(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	emitterID, _ := newTestEmitter(t, db, logger)
+	_, testKey, err := database.NewAPIKeyRepository(db, logger).Create(emitterID, "test", models.APIKeyEnvironmentTest, 10000, testActor)
+	if err != nil {
+		t.Fatalf("error creating test API key: %v", err)
+	}
+
+	api := &API{
+		emitterService: services.NewEmitterService(db, logger),
+		apiKeyRepo:     database.NewAPIKeyRepository(db, logger),
+		logger:         logger,
+	}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/v1/emitters/:id/apikeys", api.CreateAPIKey)
+
+	createKey := func(environment models.APIKeyEnvironment) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.CreateAPIKeyRequest{Name: "new", RateLimitPerMin: 60, Environment: environment})
+		req := httptest.NewRequest(http.MethodPost, "/v1/emitters/"+emitterID.String()+"/apikeys", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", testKey)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	if recorder := createKey(models.APIKeyEnvironmentLive); recorder.Code != http.StatusForbidden {
+		t.Errorf("test key creating a live key got status %d, want %d", recorder.Code, http.StatusForbidden)
+	}
+
+	recorder := createKey("")
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("test key creating a key got status %d, want %d: %s", recorder.Code, http.StatusCreated, recorder.Body.String())
+	}
+	var response models.CreateAPIKeyResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("error decoding API key response: %v", err)
+	}
+	if response.Environment != models.APIKeyEnvironmentTest || !strings.HasPrefix(response.APIKey, "sk_test_") {
+		t.Errorf("got a %s key (%.8s...), want a test key", response.Environment, response.APIKey)
+	}
+}
+
+// TestInvoiceEndpointsCrossTenant verifica que la API key de otro emisor reciba 404 al leer un
+// documento, reenviar su email o reintentar su workflow, como si el documento no existiera
+func TestInvoiceEndpointsCrossTenant(t *testing.T) {
+	db := openTestDB(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	router := newIdempotencyTestRouter(db, logger)
+	api := &API{
+		invoiceService: services.NewInvoiceService(db, nil, nil, nil, config.StorageConfig{}, nil, config.IdempotencyConfig{KeyTTL: time.Hour}, logger),
+		apiKeyRepo:     database.NewAPIKeyRepository(db, logger),
+		logger:         logger,
+	}
+	router.GET("/v1/invoices/:id", api.GetInvoice)
+	router.POST("/v1/invoices/:id/email", api.ResendEmail)
+	router.POST("/v1/invoices/:id/retry", api.RetryWorkflow)
+
+	_, apiKey := newTestEmitter(t, db, logger)
+	_, otherAPIKey := newTestEmitter(t, db, logger)
+
+	recorder := postInvoice(router, apiKey, uuid.NewString(), invoiceRequestBody(10))
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("create got status %d, want %d: %s", recorder.Code, http.StatusCreated, recorder.Body.String())
+	}
+	var created models.InvoiceResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &created); err != nil {
+		t.Fatalf("error decoding invoice response: %v", err)
+	}
+
+	request := func(method, path, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", apiKey)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	path := "/v1/invoices/" + created.ID.String()
+	for _, endpoint := range []struct{ method, path string }{
+		{http.MethodGet, path},
+		{http.MethodPost, path + "/email"},
+		{http.MethodPost, path + "/retry"},
+	} {
+		if recorder := request(endpoint.method, endpoint.path, otherAPIKey); recorder.Code != http.StatusNotFound {
+			t.Errorf("%s %s with another emitter's key got status %d, want %d", endpoint.method, endpoint.path, recorder.Code, http.StatusNotFound)
+		}
+	}
+
+	if recorder := request(http.MethodGet, path, apiKey); recorder.Code != http.StatusOK {
+		t.Errorf("GET %s with the owner's key got status %d, want %d", path, recorder.Code, http.StatusOK)
+	}
+}
@@ -21,6 +21,10 @@ type Config struct {
 	PAC      PACConfig
 	Storage  StorageConfig
 	Supabase SupabaseConfig
+	Catalog  CatalogConfig
+	Webhook  WebhookConfig
+	Outbox   OutboxConfig
+	Idempotency IdempotencyConfig
 }
 
 // ServerConfig representa la configuración del servidor HTTP
@@ -87,16 +91,75 @@ type EmailConfig struct {
 
 // PACConfig representa la configuración del PAC
 type PACConfig struct {
+	// APIURL es el endpoint de producción (iAmb = 1); SandboxAPIURL el de pruebas (iAmb = 2)
 	APIURL      string
+	SandboxAPIURL string
 	Timeout     time.Duration
 	MaxRetries  int
+	// EncryptionKeys son las llaves maestras para cifrar credenciales PAC ("id:base64,id:base64")
+	EncryptionKeys  string
+	// EncryptionKeyID es la llave activa con la que se cifran los valores nuevos
+	EncryptionKeyID string
 }
 
+// Backends de almacenamiento de archivos (STORAGE_TYPE)
+const (
+	StorageTypeLocal    = "local"
+	StorageTypeS3       = "s3"
+	StorageTypeSupabase = "supabase"
+	StorageTypeMemory   = "memory"
+)
+
 // StorageConfig representa la configuración de almacenamiento
 type StorageConfig struct {
 	Type   string
+	// Path es el directorio raíz del backend local
 	Path   string
 	Bucket string
+	// Endpoint, Region y credenciales del backend S3 compatible (vacío usa AWS; MinIO en local)
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	UsePathStyle    bool
+	// MetadataOnly guarda en la BD sólo keys y checksums de los archivos; el migrador mueve
+	// al storage los archivos que todavía estén en la BD
+	MetadataOnly       bool
+	MigrationInterval  time.Duration
+	MigrationBatchSize int
+	// SignedURLTTL es la validez de las URLs prefirmadas con que se descargan los archivos
+	SignedURLTTL time.Duration
+}
+
+// CatalogConfig representa los archivos de catálogos DGI a cargar al iniciar (CSV/XLSX, opcionales)
+type CatalogConfig struct {
+	CPBSFile           string
+	TaxRatesFile       string
+	PaymentMethodsFile string
+}
+
+// WebhookConfig representa la configuración del envío de webhooks salientes
+type WebhookConfig struct {
+	// DispatchInterval es cada cuánto se buscan entregas pendientes
+	DispatchInterval time.Duration
+	// Timeout es el tiempo máximo de espera de la respuesta del receptor
+	Timeout   time.Duration
+	BatchSize int
+}
+
+// OutboxConfig representa la configuración del relay del outbox transaccional
+type OutboxConfig struct {
+	// RelayInterval es cada cuánto se publican los eventos pendientes
+	RelayInterval time.Duration
+	BatchSize     int
+	// Retention es cuánto se conservan los eventos ya publicados
+	Retention time.Duration
+}
+
+// IdempotencyConfig representa la configuración de las Idempotency-Key de creación de documentos
+type IdempotencyConfig struct {
+	// KeyTTL es cuánto se recuerda una key; después puede reutilizarse para otro documento
+	KeyTTL time.Duration
 }
 
 // SupabaseConfig representa la configuración de Supabase
@@ -168,13 +231,25 @@ func Load() (*Config, error) {
 		},
 		PAC: PACConfig{
 			APIURL:     getEnv("PAC_API_URL", "https://api.pac-provider.com"),
+			SandboxAPIURL: getEnv("PAC_SANDBOX_API_URL", "https://sandbox.pac-provider.com"),
 			Timeout:    getEnvAsDuration("PAC_TIMEOUT", 30*time.Second),
 			MaxRetries: getEnvAsInt("PAC_MAX_RETRIES", 5),
+			EncryptionKeys:  getEnv("PAC_ENCRYPTION_KEYS", ""),
+			EncryptionKeyID: getEnv("PAC_ENCRYPTION_KEY_ID", ""),
 		},
 		Storage: StorageConfig{
-			Type:   getEnv("STORAGE_TYPE", "local"),
+			Type:   getEnv("STORAGE_TYPE", StorageTypeLocal),
 			Path:   getEnv("STORAGE_PATH", "./storage"),
 			Bucket: getEnv("STORAGE_BUCKET", "dgi-documents"),
+			Endpoint:        getEnv("STORAGE_ENDPOINT", ""),
+			Region:          getEnv("STORAGE_REGION", "us-east-1"),
+			AccessKeyID:     getEnv("STORAGE_ACCESS_KEY_ID", ""),
+			SecretAccessKey: getEnv("STORAGE_SECRET_ACCESS_KEY", ""),
+			UsePathStyle:    getEnvAsBool("STORAGE_USE_PATH_STYLE", true),
+			MetadataOnly:       getEnvAsBool("STORAGE_METADATA_ONLY", false),
+			MigrationInterval:  getEnvAsDuration("STORAGE_MIGRATION_INTERVAL", time.Minute),
+			MigrationBatchSize: getEnvAsInt("STORAGE_MIGRATION_BATCH_SIZE", 20),
+			SignedURLTTL:       getEnvAsDuration("STORAGE_SIGNED_URL_TTL", 15*time.Minute),
 		},
 		Supabase: SupabaseConfig{
 			URL:           getEnv("SUPABASE_URL", ""),
@@ -187,11 +262,39 @@ func Load() (*Config, error) {
 			AccessKeyID:     getEnv("SUPABASE_ACCESS_KEY_ID", ""),
 			SecretAccessKey: getEnv("SUPABASE_SECRET_ACCESS_KEY", ""),
 		},
+		Catalog: CatalogConfig{
+			CPBSFile:           getEnv("CATALOG_CPBS_FILE", ""),
+			TaxRatesFile:       getEnv("CATALOG_TAX_RATES_FILE", ""),
+			PaymentMethodsFile: getEnv("CATALOG_PAYMENT_METHODS_FILE", ""),
+		},
+		Webhook: WebhookConfig{
+			DispatchInterval: getEnvAsDuration("WEBHOOK_DISPATCH_INTERVAL", 5*time.Second),
+			Timeout:          getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+			BatchSize:        getEnvAsInt("WEBHOOK_BATCH_SIZE", 50),
+		},
+		Outbox: OutboxConfig{
+			RelayInterval: getEnvAsDuration("OUTBOX_RELAY_INTERVAL", 2*time.Second),
+			BatchSize:     getEnvAsInt("OUTBOX_BATCH_SIZE", 100),
+			Retention:     getEnvAsDuration("OUTBOX_RETENTION", 7*24*time.Hour),
+		},
+		Idempotency: IdempotencyConfig{
+			KeyTTL: getEnvAsDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+		},
+	}
+
+	// Sin STORAGE_TYPE, las credenciales de Supabase siguen seleccionando su storage
+	if os.Getenv("STORAGE_TYPE") == "" && config.Supabase.HasStorageCredentials() {
+		config.Storage.Type = StorageTypeSupabase
 	}
 
 	return config, nil
 }
 
+// HasStorageCredentials indica si están configurados el endpoint S3 y las credenciales de Supabase Storage
+func (c SupabaseConfig) HasStorageCredentials() bool {
+	return c.StorageEndpoint != "" && c.AccessKeyID != "" && c.SecretAccessKey != ""
+}
+
 // getEnv obtiene una variable de entorno o retorna un valor por defecto
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -26,10 +26,10 @@ func NewAPIKeyRepository(db *DB, logger *logrus.Logger) *APIKeyRepository {
 	}
 }
 
-// Create crea una nueva API key
-func (r *APIKeyRepository) Create(emitterID uuid.UUID, name string, rateLimit int) (*models.APIKey, string, error) {
+// Create crea una nueva API key ligada a un ambiente a nombre de actor; la key lleva el prefijo del ambiente (sk_live_/sk_test_)
+func (r *APIKeyRepository) Create(emitterID uuid.UUID, name string, environment models.APIKeyEnvironment, rateLimit int, actor string) (*models.APIKey, string, error) {
 	// Generar API key única
-	apiKey := r.generateAPIKey()
+	apiKey := environment.KeyPrefix() + r.generateAPIKey()
 	keyHash := r.HashAPIKey(apiKey)
 
 	apiKeyModel := &models.APIKey{
@@ -37,6 +37,7 @@ func (r *APIKeyRepository) Create(emitterID uuid.UUID, name string, rateLimit in
 		EmitterID:       emitterID,
 		Name:            name,
 		KeyHash:         keyHash,
+		Environment:     environment,
 		IsActive:        true,
 		RateLimitPerMin: rateLimit,
 		CreatedAt:       time.Now(),
@@ -44,15 +45,15 @@ func (r *APIKeyRepository) Create(emitterID uuid.UUID, name string, rateLimit in
 
 	query := `
 		INSERT INTO api_keys (
-			id, emitter_id, name, key_hash, is_active, rate_limit_per_min, created_at
+			id, emitter_id, name, key_hash, environment, is_active, rate_limit_per_min, created_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7
+			$1, $2, $3, $4, $5, $6, $7, $8
 		)
 	`
 	
-	_, err := r.db.ExecWithTimeout(query,
+	_, err := r.db.ExecAs(actor, query,
 		apiKeyModel.ID, apiKeyModel.EmitterID, apiKeyModel.Name,
-		apiKeyModel.KeyHash, apiKeyModel.IsActive, apiKeyModel.RateLimitPerMin,
+		apiKeyModel.KeyHash, apiKeyModel.Environment, apiKeyModel.IsActive, apiKeyModel.RateLimitPerMin,
 		apiKeyModel.CreatedAt,
 	)
 	
@@ -93,7 +94,7 @@ func (r *APIKeyRepository) GetByHash(hash string) (*models.APIKey, error) {
 // getByHashWithRetry es la implementación interna con retry
 func (r *APIKeyRepository) getByHashWithRetry(hash string) (*models.APIKey, error) {
 	query := `
-		SELECT id, emitter_id, name, key_hash, is_active, rate_limit_per_min, created_at, last_used_at
+		SELECT id, emitter_id, name, key_hash, environment, is_active, rate_limit_per_min, created_at, last_used_at
 		FROM api_keys
 		WHERE key_hash = $1 AND is_active = true
 	`
@@ -102,7 +103,7 @@ func (r *APIKeyRepository) getByHashWithRetry(hash string) (*models.APIKey, erro
 	
 	var apiKey models.APIKey
 	err := r.db.QueryRowWithTimeout(query, hash).Scan(
-		&apiKey.ID, &apiKey.EmitterID, &apiKey.Name, &apiKey.KeyHash,
+		&apiKey.ID, &apiKey.EmitterID, &apiKey.Name, &apiKey.KeyHash, &apiKey.Environment,
 		&apiKey.IsActive, &apiKey.RateLimitPerMin, &apiKey.CreatedAt, &apiKey.LastUsedAt,
 	)
 	
@@ -121,7 +122,7 @@ func (r *APIKeyRepository) getByHashWithRetry(hash string) (*models.APIKey, erro
 // GetByEmitterID obtiene todas las API keys de un emisor
 func (r *APIKeyRepository) GetByEmitterID(emitterID uuid.UUID) ([]models.APIKey, error) {
 	query := `
-		SELECT id, emitter_id, name, key_hash, is_active, rate_limit_per_min, 
+		SELECT id, emitter_id, name, key_hash, environment, is_active, rate_limit_per_min,
 			   created_at, last_used_at
 		FROM api_keys
 		WHERE emitter_id = $1
@@ -138,7 +139,7 @@ func (r *APIKeyRepository) GetByEmitterID(emitterID uuid.UUID) ([]models.APIKey,
 	for rows.Next() {
 		var apiKey models.APIKey
 		err := rows.Scan(
-			&apiKey.ID, &apiKey.EmitterID, &apiKey.Name, &apiKey.KeyHash,
+			&apiKey.ID, &apiKey.EmitterID, &apiKey.Name, &apiKey.KeyHash, &apiKey.Environment,
 			&apiKey.IsActive, &apiKey.RateLimitPerMin, &apiKey.CreatedAt, &apiKey.LastUsedAt,
 		)
 		if err != nil {
@@ -166,15 +167,15 @@ func (r *APIKeyRepository) UpdateLastUsed(id uuid.UUID) error {
 	return nil
 }
 
-// Deactivate desactiva una API key
-func (r *APIKeyRepository) Deactivate(id uuid.UUID) error {
+// Deactivate desactiva una API key a nombre de actor
+func (r *APIKeyRepository) Deactivate(id uuid.UUID, actor string) error {
 	query := `
 		UPDATE api_keys 
 		SET is_active = false
 		WHERE id = $1
 	`
 	
-	result, err := r.db.ExecWithTimeout(query, id)
+	result, err := r.db.ExecAs(actor, query, id)
 	if err != nil {
 		return fmt.Errorf("error deactivating API key: %w", err)
 	}
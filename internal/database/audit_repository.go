@@ -0,0 +1,67 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// AuditRepository consulta el log de auditoría que escriben los triggers de la base
+type AuditRepository struct {
+	db     *DB
+	logger *logrus.Logger
+}
+
+// NewAuditRepository crea una nueva instancia del repositorio
+func NewAuditRepository(db *DB, logger *logrus.Logger) *AuditRepository {
+	return &AuditRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// List obtiene los cambios de una tabla auditada de un emisor, del más reciente al más antiguo.
+// Con recordID sólo se retornan los cambios de ese registro.
+func (r *AuditRepository) List(emitterID uuid.UUID, table string, recordID *uuid.UUID, page, pageSize int) ([]models.AuditLog, int, error) {
+	where := `WHERE emitter_id = $1 AND table_name = $2 AND ($3::uuid IS NULL OR record_id = $3)`
+
+	var total int
+	if err := r.db.QueryRowWithTimeout(`SELECT COUNT(*) FROM audit_logs `+where, emitterID, table, recordID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting audit logs: %w", err)
+	}
+
+	query := `
+		SELECT id, table_name, record_id, action, old_values, new_values, changed_by, changed_at
+		FROM audit_logs
+		` + where + `
+		ORDER BY changed_at DESC, id
+		LIMIT $4 OFFSET $5
+	`
+
+	rows, err := r.db.QueryWithTimeout(query, emitterID, table, recordID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	logs := []models.AuditLog{}
+	for rows.Next() {
+		var log models.AuditLog
+		var tableName string
+		var oldValues, newValues []byte
+		if err := rows.Scan(&log.ID, &tableName, &log.RecordID, &log.Action, &oldValues, &newValues, &log.ChangedBy, &log.ChangedAt); err != nil {
+			return nil, 0, fmt.Errorf("error scanning audit log: %w", err)
+		}
+		log.Resource = models.AuditResourceForTable(tableName)
+		log.OldValues = oldValues
+		log.NewValues = newValues
+		logs = append(logs, log)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating audit logs: %w", err)
+	}
+
+	return logs, total, nil
+}
@@ -0,0 +1,210 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// BranchRepository maneja las operaciones de base de datos para Branch
+type BranchRepository struct {
+	db     *DB
+	logger *logrus.Logger
+}
+
+// NewBranchRepository crea una nueva instancia del repositorio
+func NewBranchRepository(db *DB, logger *logrus.Logger) *BranchRepository {
+	return &BranchRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// branchColumns son las columnas de branches en el orden de scanBranch
+const branchColumns = `id, emitter_id, code, name, address_line, ubi_code, phone, pto_fac_default,
+	brand_logo_url, brand_primary_color, brand_footer_html, is_active, created_at, updated_at`
+
+// scanBranch lee una sucursal desde una fila con branchColumns
+func scanBranch(row rowScanner) (*models.Branch, error) {
+	var branch models.Branch
+	err := row.Scan(
+		&branch.ID, &branch.EmitterID, &branch.Code, &branch.Name, &branch.AddressLine, &branch.UBICode,
+		&branch.Phone, &branch.PtoFacDefault, &branch.BrandLogoURL, &branch.BrandPrimaryColor,
+		&branch.BrandFooterHTML, &branch.IsActive, &branch.CreatedAt, &branch.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &branch, nil
+}
+
+// Create crea una nueva sucursal para un emisor
+func (r *BranchRepository) Create(emitterID uuid.UUID, req *models.CreateBranchRequest) (*models.Branch, error) {
+	ptoFacDefault := req.PtoFacDefault
+	if ptoFacDefault == "" {
+		ptoFacDefault = "001"
+	}
+
+	query := `
+		INSERT INTO branches (
+			id, emitter_id, code, name, address_line, ubi_code, phone, pto_fac_default,
+			brand_logo_url, brand_primary_color, brand_footer_html, is_active, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, true, $12, $12
+		)
+		ON CONFLICT (emitter_id, code) DO NOTHING
+		RETURNING ` + branchColumns
+
+	branch, err := scanBranch(r.db.QueryRowWithTimeout(query,
+		uuid.New(), emitterID, req.Code, req.Name, req.AddressLine, req.UBICode, req.Phone, ptoFacDefault,
+		req.BrandLogoURL, req.BrandPrimaryColor, req.BrandFooterHTML, time.Now(),
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("branch already exists with code %s", req.Code)
+		}
+		return nil, fmt.Errorf("error creating branch: %w", err)
+	}
+
+	return branch, nil
+}
+
+// EnsureMain obtiene la sucursal principal del emisor (código suc_em), creándola con los
+// datos del emisor si aún no existe
+func (r *BranchRepository) EnsureMain(emitter *models.Emitter) (*models.Branch, error) {
+	query := `
+		INSERT INTO branches (emitter_id, code, name, address_line, ubi_code, phone, pto_fac_default)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (emitter_id, code) DO NOTHING
+	`
+
+	_, err := r.db.ExecWithTimeout(query,
+		emitter.ID, emitter.SucEm, emitter.Name, emitter.AddressLine, emitter.UBICode, emitter.Phone, emitter.PtoFacDefault,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating main branch: %w", err)
+	}
+
+	return r.GetByCode(emitter.ID, emitter.SucEm)
+}
+
+// GetByID obtiene una sucursal de un emisor (activa o no)
+func (r *BranchRepository) GetByID(emitterID, id uuid.UUID) (*models.Branch, error) {
+	query := `
+		SELECT ` + branchColumns + `
+		FROM branches
+		WHERE id = $1 AND emitter_id = $2
+	`
+
+	branch, err := scanBranch(r.db.QueryRowWithTimeout(query, id, emitterID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("branch not found: %s", id)
+		}
+		return nil, fmt.Errorf("error querying branch: %w", err)
+	}
+
+	return branch, nil
+}
+
+// GetByCode obtiene una sucursal activa de un emisor por su código
+func (r *BranchRepository) GetByCode(emitterID uuid.UUID, code string) (*models.Branch, error) {
+	query := `
+		SELECT ` + branchColumns + `
+		FROM branches
+		WHERE emitter_id = $1 AND code = $2 AND is_active = true
+	`
+
+	branch, err := scanBranch(r.db.QueryRowWithTimeout(query, emitterID, code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("branch not found: %s", code)
+		}
+		return nil, fmt.Errorf("error querying branch: %w", err)
+	}
+
+	return branch, nil
+}
+
+// List obtiene las sucursales de un emisor
+func (r *BranchRepository) List(emitterID uuid.UUID, includeInactive bool) ([]models.Branch, error) {
+	query := `
+		SELECT ` + branchColumns + `
+		FROM branches
+		WHERE emitter_id = $1 AND ($2 OR is_active = true)
+		ORDER BY code
+	`
+
+	rows, err := r.db.QueryWithTimeout(query, emitterID, includeInactive)
+	if err != nil {
+		return nil, fmt.Errorf("error querying branches: %w", err)
+	}
+	defer rows.Close()
+
+	branches := []models.Branch{}
+	for rows.Next() {
+		branch, err := scanBranch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning branch: %w", err)
+		}
+		branches = append(branches, *branch)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating branches: %w", err)
+	}
+
+	return branches, nil
+}
+
+// Update actualiza una sucursal activa de un emisor
+func (r *BranchRepository) Update(branch *models.Branch) (*models.Branch, error) {
+	query := `
+		UPDATE branches
+		SET name = $1, address_line = $2, ubi_code = $3, phone = $4, pto_fac_default = $5,
+		    brand_logo_url = $6, brand_primary_color = $7, brand_footer_html = $8, updated_at = $9
+		WHERE id = $10 AND emitter_id = $11 AND is_active = true
+		RETURNING ` + branchColumns
+
+	updated, err := scanBranch(r.db.QueryRowWithTimeout(query,
+		branch.Name, branch.AddressLine, branch.UBICode, branch.Phone, branch.PtoFacDefault,
+		branch.BrandLogoURL, branch.BrandPrimaryColor, branch.BrandFooterHTML, time.Now(),
+		branch.ID, branch.EmitterID,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("branch not found: %s", branch.ID)
+		}
+		return nil, fmt.Errorf("error updating branch: %w", err)
+	}
+
+	return updated, nil
+}
+
+// Delete marca una sucursal de un emisor como inactiva
+func (r *BranchRepository) Delete(emitterID, id uuid.UUID) error {
+	query := `
+		UPDATE branches
+		SET is_active = false, updated_at = $1
+		WHERE id = $2 AND emitter_id = $3 AND is_active = true
+	`
+
+	result, err := r.db.ExecWithTimeout(query, time.Now(), id, emitterID)
+	if err != nil {
+		return fmt.Errorf("error deleting branch: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("branch not found: %s", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// CatalogRepository maneja las operaciones de base de datos para los catálogos DGI
+// (cpbs_catalog, tax_rates y payment_methods)
+type CatalogRepository struct {
+	db     *DB
+	logger *logrus.Logger
+}
+
+// NewCatalogRepository crea una nueva instancia del repositorio
+func NewCatalogRepository(db *DB, logger *logrus.Logger) *CatalogRepository {
+	return &CatalogRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SearchCPBS busca códigos CPBS por descripción o prefijo de código
+func (r *CatalogRepository) SearchCPBS(search string, limit int) ([]models.CPBSEntry, error) {
+	query := `
+		SELECT cpbs_abr, cpbs_cmp, description
+		FROM cpbs_catalog
+		WHERE $1 = ''
+		   OR description ILIKE '%' || $1 || '%'
+		   OR cpbs_cmp LIKE $1 || '%'
+		ORDER BY cpbs_abr, cpbs_cmp
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryWithTimeout(query, search, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error searching CPBS catalog: %w", err)
+	}
+	defer rows.Close()
+
+	return scanCPBSEntries(rows)
+}
+
+// GetAllCPBS obtiene el catálogo CPBS completo
+func (r *CatalogRepository) GetAllCPBS() ([]models.CPBSEntry, error) {
+	query := `SELECT cpbs_abr, cpbs_cmp, description FROM cpbs_catalog ORDER BY cpbs_abr, cpbs_cmp`
+
+	rows, err := r.db.QueryWithTimeout(query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying CPBS catalog: %w", err)
+	}
+	defer rows.Close()
+
+	return scanCPBSEntries(rows)
+}
+
+// GetTaxRates obtiene el catálogo de tasas de ITBMS
+func (r *CatalogRepository) GetTaxRates() ([]models.TaxRateEntry, error) {
+	query := `SELECT code, description, rate, is_active FROM tax_rates ORDER BY code`
+
+	rows, err := r.db.QueryWithTimeout(query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying tax rates: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.TaxRateEntry{}
+	for rows.Next() {
+		var entry models.TaxRateEntry
+		if err := rows.Scan(&entry.Code, &entry.Description, &entry.Rate, &entry.IsActive); err != nil {
+			return nil, fmt.Errorf("error scanning tax rate: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// GetPaymentMethods obtiene el catálogo de formas de pago
+func (r *CatalogRepository) GetPaymentMethods() ([]models.PaymentMethodEntry, error) {
+	query := `SELECT code, description, is_active FROM payment_methods ORDER BY code`
+
+	rows, err := r.db.QueryWithTimeout(query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying payment methods: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.PaymentMethodEntry{}
+	for rows.Next() {
+		var entry models.PaymentMethodEntry
+		if err := rows.Scan(&entry.Code, &entry.Description, &entry.IsActive); err != nil {
+			return nil, fmt.Errorf("error scanning payment method: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// UpsertCPBS carga (crea o actualiza) códigos CPBS en una sola transacción
+func (r *CatalogRepository) UpsertCPBS(entries []models.CPBSEntry) error {
+	return r.db.WithTransaction(func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(`
+			INSERT INTO cpbs_catalog (cpbs_abr, cpbs_cmp, description)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (cpbs_abr, cpbs_cmp) DO UPDATE SET description = EXCLUDED.description
+		`)
+		if err != nil {
+			return fmt.Errorf("error preparing CPBS upsert: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, entry := range entries {
+			if _, err := stmt.Exec(entry.Abr, entry.Cmp, entry.Description); err != nil {
+				return fmt.Errorf("error upserting CPBS %s-%s: %w", entry.Abr, entry.Cmp, err)
+			}
+		}
+		return nil
+	})
+}
+
+// UpsertTaxRates carga (crea o actualiza) tasas de ITBMS en una sola transacción
+func (r *CatalogRepository) UpsertTaxRates(entries []models.TaxRateEntry) error {
+	return r.db.WithTransaction(func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(`
+			INSERT INTO tax_rates (code, description, rate, is_active)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (code) DO UPDATE SET
+				description = EXCLUDED.description, rate = EXCLUDED.rate, is_active = EXCLUDED.is_active
+		`)
+		if err != nil {
+			return fmt.Errorf("error preparing tax rate upsert: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, entry := range entries {
+			if _, err := stmt.Exec(entry.Code, entry.Description, entry.Rate, entry.IsActive); err != nil {
+				return fmt.Errorf("error upserting tax rate %s: %w", entry.Code, err)
+			}
+		}
+		return nil
+	})
+}
+
+// UpsertPaymentMethods carga (crea o actualiza) formas de pago en una sola transacción
+func (r *CatalogRepository) UpsertPaymentMethods(entries []models.PaymentMethodEntry) error {
+	return r.db.WithTransaction(func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(`
+			INSERT INTO payment_methods (code, description, is_active)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (code) DO UPDATE SET
+				description = EXCLUDED.description, is_active = EXCLUDED.is_active
+		`)
+		if err != nil {
+			return fmt.Errorf("error preparing payment method upsert: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, entry := range entries {
+			if _, err := stmt.Exec(entry.Code, entry.Description, entry.IsActive); err != nil {
+				return fmt.Errorf("error upserting payment method %s: %w", entry.Code, err)
+			}
+		}
+		return nil
+	})
+}
+
+// scanCPBSEntries lee filas de cpbs_catalog
+func scanCPBSEntries(rows *sql.Rows) ([]models.CPBSEntry, error) {
+	entries := []models.CPBSEntry{}
+	for rows.Next() {
+		var entry models.CPBSEntry
+		if err := rows.Scan(&entry.Abr, &entry.Cmp, &entry.Description); err != nil {
+			return nil, fmt.Errorf("error scanning CPBS entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
@@ -6,19 +6,39 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/hypernova-labs/dgi-service/internal/config"
+	"github.com/hypernova-labs/dgi-service/internal/secrets"
 	_ "github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 )
 
+// rowScanner es una fila a leer: *sql.Row o *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
 // DB representa la conexión a la base de datos
 type DB struct {
 	*sql.DB
+	// Secrets cifra las credenciales sensibles guardadas en la base; nil si no hay llaves configuradas
+	Secrets *secrets.Envelope
+	// AllowPlaintextSecrets permite guardar las credenciales sin cifrar cuando Secrets es nil
+	// (sólo en desarrollo); si no, guardarlas falla
+	AllowPlaintextSecrets bool
 }
 
 // Connect establece la conexión a PostgreSQL
 func Connect(cfg *config.Config) (*DB, error) {
 	dsn := cfg.GetDSN()
+
+	envelope, err := secrets.NewEnvelopeFromSpec(cfg.PAC.EncryptionKeys, cfg.PAC.EncryptionKeyID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading PAC encryption keys: %w", err)
+	}
+	if envelope == nil && !cfg.IsDevelopment() {
+		return nil, fmt.Errorf("PAC_ENCRYPTION_KEYS is required outside development")
+	}
 	
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
@@ -36,7 +56,37 @@ func Connect(cfg *config.Config) (*DB, error) {
 		return nil, fmt.Errorf("error pinging database: %w", err)
 	}
 
-	return &DB{db}, nil
+	return &DB{DB: db, Secrets: envelope, AllowPlaintextSecrets: cfg.IsDevelopment()}, nil
+}
+
+// secretContext identifica la celda donde se guarda un secreto (tabla.columna y fila), para que
+// su valor cifrado sólo se pueda descifrar ahí
+func secretContext(column string, id uuid.UUID) string {
+	return column + ":" + id.String()
+}
+
+// encryptSecret cifra un secreto a guardar en column de la fila id. Sin llaves configuradas sólo
+// se guarda en texto plano si AllowPlaintextSecrets lo permite.
+func (db *DB) encryptSecret(value, column string, id uuid.UUID) (string, error) {
+	if db.Secrets == nil {
+		if !db.AllowPlaintextSecrets {
+			return "", fmt.Errorf("encryption keys not configured")
+		}
+		return value, nil
+	}
+	return db.Secrets.Encrypt(value, secretContext(column, id))
+}
+
+// decryptSecret descifra un secreto leído de column de la fila id; los valores en texto plano
+// (legado o desarrollo) se retornan tal cual
+func (db *DB) decryptSecret(value, column string, id uuid.UUID) (string, error) {
+	if db.Secrets == nil {
+		if secrets.IsEncrypted(value) {
+			return "", fmt.Errorf("encryption keys not configured")
+		}
+		return value, nil
+	}
+	return db.Secrets.Decrypt(value, secretContext(column, id))
 }
 
 // Close cierra la conexión a la base de datos
@@ -137,6 +187,38 @@ func (db *DB) WithTransaction(fn func(*sql.Tx) error) error {
 	return nil
 }
 
+// WithActor ejecuta una función dentro de una transacción a nombre de actor; los triggers
+// de auditoría lo leen de app.actor para registrarlo como autor de los cambios
+func (db *DB) WithActor(actor string, fn func(*sql.Tx) error) error {
+	return db.WithTransaction(func(tx *sql.Tx) error {
+		if actor != "" {
+			if _, err := tx.Exec(`SELECT set_config('app.actor', $1, true)`, actor); err != nil {
+				return fmt.Errorf("error setting audit actor: %w", err)
+			}
+		}
+		return fn(tx)
+	})
+}
+
+// ExecAs ejecuta una query a nombre de actor (ver WithActor)
+func (db *DB) ExecAs(actor, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := db.WithActor(actor, func(tx *sql.Tx) error {
+		var err error
+		result, err = tx.Exec(query, args...)
+		return err
+	})
+	return result, err
+}
+
+// QueryRowAs ejecuta una query de una fila a nombre de actor (ver WithActor) y la lee con
+// scan dentro de la transacción; los errores de scan (p. ej. sql.ErrNoRows) se retornan tal cual
+func (db *DB) QueryRowAs(actor string, scan func(*sql.Row) error, query string, args ...interface{}) error {
+	return db.WithActor(actor, func(tx *sql.Tx) error {
+		return scan(tx.QueryRow(query, args...))
+	})
+}
+
 // LogStats registra las estadísticas de la base de datos
 func (db *DB) LogStats(logger *logrus.Logger) {
 	stats := db.GetStats()
@@ -24,8 +24,8 @@ func NewCustomerRepository(db *DB, logger *logrus.Logger) *CustomerRepository {
 	}
 }
 
-// Create crea un nuevo cliente
-func (r *CustomerRepository) Create(req *models.CreateCustomerRequest, emitterID uuid.UUID) (*models.Customer, error) {
+// Create crea un nuevo cliente a nombre de actor
+func (r *CustomerRepository) Create(req *models.CreateCustomerRequest, emitterID uuid.UUID, actor string) (*models.Customer, error) {
 	customer := &models.Customer{
 		ID:         uuid.New(),
 		EmitterID:  emitterID,
@@ -34,26 +34,42 @@ func (r *CustomerRepository) Create(req *models.CreateCustomerRequest, emitterID
 		Phone:      req.Phone,
 		AddressLine: req.Address,
 		UBICode:    req.UBICode,
+		TaxID:      req.TaxID,
+		TaxIDType:  req.TaxIDType,
+		TaxIDDV:    req.TaxIDDV,
+		RecipientType: req.RecipientType,
+		ForeignID:  req.ForeignID,
+		CountryCode: req.CountryCode,
 		IsActive:   true,
 		CreatedAt:  time.Now(),
 		UpdatedAt:  time.Now(),
 	}
 
+	// Dos clientes activos del mismo emisor no pueden compartir RUC; el email sí puede repetirse
 	query := `
 		INSERT INTO customers (
-			id, emitter_id, name, email, phone, address_line, ubi_code, 
+			id, emitter_id, name, email, phone, address_line, ubi_code, tax_id, tax_id_type, tax_id_dv,
+			recipient_type, foreign_id, country_code,
 			is_active, created_at, updated_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
 		)
+		ON CONFLICT (emitter_id, tax_id) WHERE tax_id IS NOT NULL AND is_active = true DO NOTHING
+		RETURNING id, created_at
 	`
 	
-	_, err := r.db.ExecWithTimeout(query,
+	err := r.db.QueryRowAs(actor, func(row *sql.Row) error {
+		return row.Scan(&customer.ID, &customer.CreatedAt)
+	}, query,
 		customer.ID, customer.EmitterID, customer.Name, customer.Email,
-		customer.Phone, customer.AddressLine, customer.UBICode,
+		customer.Phone, customer.AddressLine, customer.UBICode, customer.TaxID, customer.TaxIDType, customer.TaxIDDV,
+		customer.RecipientType, customer.ForeignID, customer.CountryCode,
 		customer.IsActive, customer.CreatedAt, customer.UpdatedAt,
 	)
 	
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("customer already exists with tax ID %s", *customer.TaxID)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error creating customer: %w", err)
 	}
@@ -61,19 +77,49 @@ func (r *CustomerRepository) Create(req *models.CreateCustomerRequest, emitterID
 	return customer, nil
 }
 
-// GetByID obtiene un cliente por ID
+// GetByID obtiene un cliente por ID, incluso si está desactivado (documentos emitidos lo siguen referenciando)
 func (r *CustomerRepository) GetByID(id uuid.UUID) (*models.Customer, error) {
 	query := `
-		SELECT id, emitter_id, name, email, phone, address_line, ubi_code,
+		SELECT id, emitter_id, name, email, phone, address_line, ubi_code, tax_id, tax_id_type, tax_id_dv,
+			   recipient_type, foreign_id, country_code,
 			   is_active, created_at, updated_at
 		FROM customers
-		WHERE id = $1 AND is_active = true
+		WHERE id = $1
 	`
 	
 	var customer models.Customer
 	err := r.db.QueryRowWithTimeout(query, id).Scan(
 		&customer.ID, &customer.EmitterID, &customer.Name, &customer.Email,
-		&customer.Phone, &customer.AddressLine, &customer.UBICode,
+		&customer.Phone, &customer.AddressLine, &customer.UBICode, &customer.TaxID, &customer.TaxIDType, &customer.TaxIDDV,
+		&customer.RecipientType, &customer.ForeignID, &customer.CountryCode,
+		&customer.IsActive, &customer.CreatedAt, &customer.UpdatedAt,
+	)
+	
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("customer not found: %s", id)
+		}
+		return nil, fmt.Errorf("error querying customer: %w", err)
+	}
+
+	return &customer, nil
+}
+
+// GetByEmitterAndID obtiene un cliente activo de un emisor
+func (r *CustomerRepository) GetByEmitterAndID(emitterID, id uuid.UUID) (*models.Customer, error) {
+	query := `
+		SELECT id, emitter_id, name, email, phone, address_line, ubi_code, tax_id, tax_id_type, tax_id_dv,
+			   recipient_type, foreign_id, country_code,
+			   is_active, created_at, updated_at
+		FROM customers
+		WHERE id = $1 AND emitter_id = $2 AND is_active = true
+	`
+	
+	var customer models.Customer
+	err := r.db.QueryRowWithTimeout(query, id, emitterID).Scan(
+		&customer.ID, &customer.EmitterID, &customer.Name, &customer.Email,
+		&customer.Phone, &customer.AddressLine, &customer.UBICode, &customer.TaxID, &customer.TaxIDType, &customer.TaxIDDV,
+		&customer.RecipientType, &customer.ForeignID, &customer.CountryCode,
 		&customer.IsActive, &customer.CreatedAt, &customer.UpdatedAt,
 	)
 	
@@ -87,19 +133,23 @@ func (r *CustomerRepository) GetByID(id uuid.UUID) (*models.Customer, error) {
 	return &customer, nil
 }
 
-// GetByEmail obtiene un cliente por email y emisor
+// GetByEmail obtiene el cliente activo más antiguo con ese email (el email no es único por emisor)
 func (r *CustomerRepository) GetByEmail(emitterID uuid.UUID, email string) (*models.Customer, error) {
 	query := `
-		SELECT id, emitter_id, name, email, phone, address_line, ubi_code,
+		SELECT id, emitter_id, name, email, phone, address_line, ubi_code, tax_id, tax_id_type, tax_id_dv,
+			   recipient_type, foreign_id, country_code,
 			   is_active, created_at, updated_at
 		FROM customers
 		WHERE emitter_id = $1 AND email = $2 AND is_active = true
+		ORDER BY created_at
+		LIMIT 1
 	`
 	
 	var customer models.Customer
 	err := r.db.QueryRowWithTimeout(query, emitterID, email).Scan(
 		&customer.ID, &customer.EmitterID, &customer.Name, &customer.Email,
-		&customer.Phone, &customer.AddressLine, &customer.UBICode,
+		&customer.Phone, &customer.AddressLine, &customer.UBICode, &customer.TaxID, &customer.TaxIDType, &customer.TaxIDDV,
+		&customer.RecipientType, &customer.ForeignID, &customer.CountryCode,
 		&customer.IsActive, &customer.CreatedAt, &customer.UpdatedAt,
 	)
 	
@@ -113,10 +163,39 @@ func (r *CustomerRepository) GetByEmail(emitterID uuid.UUID, email string) (*mod
 	return &customer, nil
 }
 
+// GetByTaxID obtiene el cliente activo de un emisor con ese RUC o identificación
+func (r *CustomerRepository) GetByTaxID(emitterID uuid.UUID, taxID string) (*models.Customer, error) {
+	query := `
+		SELECT id, emitter_id, name, email, phone, address_line, ubi_code, tax_id, tax_id_type, tax_id_dv,
+			   recipient_type, foreign_id, country_code,
+			   is_active, created_at, updated_at
+		FROM customers
+		WHERE emitter_id = $1 AND tax_id = $2 AND is_active = true
+	`
+	
+	var customer models.Customer
+	err := r.db.QueryRowWithTimeout(query, emitterID, taxID).Scan(
+		&customer.ID, &customer.EmitterID, &customer.Name, &customer.Email,
+		&customer.Phone, &customer.AddressLine, &customer.UBICode, &customer.TaxID, &customer.TaxIDType, &customer.TaxIDDV,
+		&customer.RecipientType, &customer.ForeignID, &customer.CountryCode,
+		&customer.IsActive, &customer.CreatedAt, &customer.UpdatedAt,
+	)
+	
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("customer not found with tax ID %s for emitter %s", taxID, emitterID)
+		}
+		return nil, fmt.Errorf("error querying customer: %w", err)
+	}
+
+	return &customer, nil
+}
+
 // GetByEmitterID obtiene todos los clientes de un emisor
 func (r *CustomerRepository) GetByEmitterID(emitterID uuid.UUID) ([]models.Customer, error) {
 	query := `
-		SELECT id, emitter_id, name, email, phone, address_line, ubi_code,
+		SELECT id, emitter_id, name, email, phone, address_line, ubi_code, tax_id, tax_id_type, tax_id_dv,
+			   recipient_type, foreign_id, country_code,
 			   is_active, created_at, updated_at
 		FROM customers
 		WHERE emitter_id = $1 AND is_active = true
@@ -134,7 +213,8 @@ func (r *CustomerRepository) GetByEmitterID(emitterID uuid.UUID) ([]models.Custo
 		var customer models.Customer
 		err := rows.Scan(
 			&customer.ID, &customer.EmitterID, &customer.Name, &customer.Email,
-			&customer.Phone, &customer.AddressLine, &customer.UBICode,
+			&customer.Phone, &customer.AddressLine, &customer.UBICode, &customer.TaxID, &customer.TaxIDType, &customer.TaxIDDV,
+			&customer.RecipientType, &customer.ForeignID, &customer.CountryCode,
 			&customer.IsActive, &customer.CreatedAt, &customer.UpdatedAt,
 		)
 		if err != nil {
@@ -146,16 +226,68 @@ func (r *CustomerRepository) GetByEmitterID(emitterID uuid.UUID) ([]models.Custo
 	return customers, nil
 }
 
-// Update actualiza un cliente existente
-func (r *CustomerRepository) Update(id uuid.UUID, req *models.CreateCustomerRequest) (*models.Customer, error) {
+// Search obtiene los clientes activos de un emisor con paginación, filtrando por nombre, email o tax_id
+func (r *CustomerRepository) Search(emitterID uuid.UUID, search string, page, pageSize int) ([]models.Customer, int, error) {
+	countQuery := `
+		SELECT COUNT(*) FROM customers
+		WHERE emitter_id = $1 AND is_active = true
+		  AND ($2 = '' OR name ILIKE '%' || $2 || '%' OR email ILIKE '%' || $2 || '%' OR tax_id ILIKE '%' || $2 || '%')
+	`
+	var total int
+	if err := r.db.QueryRowWithTimeout(countQuery, emitterID, search).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting customers: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	query := `
+		SELECT id, emitter_id, name, email, phone, address_line, ubi_code, tax_id, tax_id_type, tax_id_dv,
+			   recipient_type, foreign_id, country_code,
+			   is_active, created_at, updated_at
+		FROM customers
+		WHERE emitter_id = $1 AND is_active = true
+		  AND ($2 = '' OR name ILIKE '%' || $2 || '%' OR email ILIKE '%' || $2 || '%' OR tax_id ILIKE '%' || $2 || '%')
+		ORDER BY name
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.db.QueryWithTimeout(query, emitterID, search, pageSize, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying customers: %w", err)
+	}
+	defer rows.Close()
+
+	customers := []models.Customer{}
+	for rows.Next() {
+		var customer models.Customer
+		err := rows.Scan(
+			&customer.ID, &customer.EmitterID, &customer.Name, &customer.Email,
+			&customer.Phone, &customer.AddressLine, &customer.UBICode, &customer.TaxID, &customer.TaxIDType, &customer.TaxIDDV,
+			&customer.RecipientType, &customer.ForeignID, &customer.CountryCode,
+			&customer.IsActive, &customer.CreatedAt, &customer.UpdatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning customer: %w", err)
+		}
+		customers = append(customers, customer)
+	}
+
+	return customers, total, nil
+}
+
+// Update actualiza un cliente activo de un emisor a nombre de actor
+func (r *CustomerRepository) Update(emitterID uuid.UUID, customer *models.Customer, actor string) (*models.Customer, error) {
 	query := `
 		UPDATE customers 
-		SET name = $1, phone = $2, address_line = $3, ubi_code = $4, updated_at = $5
-		WHERE id = $6 AND is_active = true
+		SET name = $1, email = $2, phone = $3, address_line = $4, ubi_code = $5, tax_id = $6,
+		    tax_id_type = $7, tax_id_dv = $8, recipient_type = $9, foreign_id = $10,
+		    country_code = $11, updated_at = $12
+		WHERE id = $13 AND emitter_id = $14 AND is_active = true
 	`
 	
-	result, err := r.db.ExecWithTimeout(query,
-		req.Name, req.Phone, req.Address, req.UBICode, time.Now(), id,
+	result, err := r.db.ExecAs(actor, query,
+		customer.Name, customer.Email, customer.Phone, customer.AddressLine, customer.UBICode,
+		customer.TaxID, customer.TaxIDType, customer.TaxIDDV, customer.RecipientType, customer.ForeignID,
+		customer.CountryCode, time.Now(), customer.ID, emitterID,
 	)
 	
 	if err != nil {
@@ -168,22 +300,22 @@ func (r *CustomerRepository) Update(id uuid.UUID, req *models.CreateCustomerRequ
 	}
 
 	if rowsAffected == 0 {
-		return nil, fmt.Errorf("customer not found: %s", id)
+		return nil, fmt.Errorf("customer not found: %s", customer.ID)
 	}
 
 	// Obtener el cliente actualizado
-	return r.GetByID(id)
+	return r.GetByEmitterAndID(emitterID, customer.ID)
 }
 
-// Delete marca un cliente como inactivo
-func (r *CustomerRepository) Delete(id uuid.UUID) error {
+// Delete marca un cliente de un emisor como inactivo a nombre de actor
+func (r *CustomerRepository) Delete(emitterID, id uuid.UUID, actor string) error {
 	query := `
 		UPDATE customers 
 		SET is_active = false, updated_at = $1
-		WHERE id = $2
+		WHERE id = $2 AND emitter_id = $3 AND is_active = true
 	`
 	
-	result, err := r.db.ExecWithTimeout(query, time.Now(), id)
+	result, err := r.db.ExecAs(actor, query, time.Now(), id, emitterID)
 	if err != nil {
 		return fmt.Errorf("error deleting customer: %w", err)
 	}
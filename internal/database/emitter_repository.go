@@ -3,6 +3,7 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -30,7 +31,8 @@ func (r *EmitterRepository) GetByID(id uuid.UUID) (*models.Emitter, error) {
 		SELECT id, name, company_code, ruc_tipo, ruc_numero, ruc_dv, suc_em,
 			   pto_fac_default, iamb, itpemis_default, idoc_default, email, phone,
 			   address_line, ubi_code, brand_logo_url, brand_primary_color, brand_footer_html,
-			   pac_api_key, pac_subscription_key, is_active, created_at, updated_at
+			   pac_api_key, pac_subscription_key, pac_sandbox_api_key, pac_sandbox_subscription_key,
+			   is_active, auto_create_series, created_at, updated_at
 		FROM emitters
 		WHERE id = $1 AND is_active = true
 	`
@@ -40,7 +42,8 @@ func (r *EmitterRepository) GetByID(id uuid.UUID) (*models.Emitter, error) {
 		&emitter.ID, &emitter.Name, &emitter.CompanyCode, &emitter.RUCTipo, &emitter.RUCNumero, &emitter.RUCDV, &emitter.SucEm,
 		&emitter.PtoFacDefault, &emitter.IAmb, &emitter.ITpEmisDefault, &emitter.IDocDefault, &emitter.Email, &emitter.Phone,
 		&emitter.AddressLine, &emitter.UBICode, &emitter.BrandLogoURL, &emitter.BrandPrimaryColor, &emitter.BrandFooterHTML,
-		&emitter.PACAPIKey, &emitter.PACSubscriptionKey, &emitter.IsActive, &emitter.CreatedAt, &emitter.UpdatedAt,
+		&emitter.PACAPIKey, &emitter.PACSubscriptionKey, &emitter.PACSandboxAPIKey, &emitter.PACSandboxSubscriptionKey,
+		&emitter.IsActive, &emitter.AutoCreateSeries, &emitter.CreatedAt, &emitter.UpdatedAt,
 	)
 	
 	if err != nil {
@@ -50,147 +53,505 @@ func (r *EmitterRepository) GetByID(id uuid.UUID) (*models.Emitter, error) {
 		return nil, fmt.Errorf("error querying emitter: %w", err)
 	}
 
+	if err := r.decryptPACCredentials(&emitter); err != nil {
+		return nil, err
+	}
+	emitter.PACConfigured = emitter.PACAPIKey != "" && emitter.PACSubscriptionKey != ""
+	emitter.PACSandboxConfigured = emitter.PACSandboxAPIKey != "" && emitter.PACSandboxSubscriptionKey != ""
+
 	return &emitter, nil
 }
 
-// GetSeries obtiene una serie específica de un emisor
-func (r *EmitterRepository) GetSeries(emitterID uuid.UUID, ptoFacDF string, docKind models.DocumentType) (*models.EmitterSeries, error) {
+// Create crea un nuevo emisor a nombre de actor; company_code y el RUC con sucursal deben ser únicos
+func (r *EmitterRepository) Create(emitter *models.Emitter, actor string) error {
 	query := `
-		SELECT id, emitter_id, pto_fac_df, doc_kind, next_number, issued_count,
-			   authorized_count, rejected_count, is_active, created_at, updated_at
-		FROM emitter_series
-		WHERE emitter_id = $1 AND pto_fac_df = $2 AND doc_kind = $3 AND is_active = true
+		INSERT INTO emitters (
+			id, name, company_code, ruc_tipo, ruc_numero, ruc_dv, suc_em,
+			pto_fac_default, iamb, itpemis_default, idoc_default, email, phone,
+			address_line, ubi_code, brand_logo_url, brand_primary_color, brand_footer_html,
+			pac_api_key, pac_subscription_key, pac_sandbox_api_key, pac_sandbox_subscription_key,
+			is_active, auto_create_series, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18,
+			$19, $20, $21, $22, $23, $24, $25, $26
+		)
+		ON CONFLICT DO NOTHING
 	`
-	
+
+	credentials, err := r.encryptPACCredentials(emitter)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecAs(actor, query,
+		emitter.ID, emitter.Name, emitter.CompanyCode, emitter.RUCTipo, emitter.RUCNumero, emitter.RUCDV, emitter.SucEm,
+		emitter.PtoFacDefault, emitter.IAmb, emitter.ITpEmisDefault, emitter.IDocDefault, emitter.Email, emitter.Phone,
+		emitter.AddressLine, emitter.UBICode, emitter.BrandLogoURL, emitter.BrandPrimaryColor, emitter.BrandFooterHTML,
+		credentials[0], credentials[1], credentials[2], credentials[3], emitter.IsActive, emitter.AutoCreateSeries, emitter.CreatedAt, emitter.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("error creating emitter: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("emitter already exists with company code %s or RUC %s-%s", emitter.CompanyCode, emitter.RUCNumero, emitter.SucEm)
+	}
+
+	return nil
+}
+
+// Update actualiza los datos editables de un emisor activo a nombre de actor
+func (r *EmitterRepository) Update(emitter *models.Emitter, actor string) (*models.Emitter, error) {
+	query := `
+		UPDATE emitters
+		SET name = $1, email = $2, phone = $3, address_line = $4, ubi_code = $5,
+		    pto_fac_default = $6, iamb = $7, itpemis_default = $8, idoc_default = $9,
+		    brand_logo_url = $10, brand_primary_color = $11, brand_footer_html = $12,
+		    pac_api_key = $13, pac_subscription_key = $14, pac_sandbox_api_key = $15,
+		    pac_sandbox_subscription_key = $16, auto_create_series = $17, updated_at = $18
+		WHERE id = $19 AND is_active = true
+	`
+
+	credentials, err := r.encryptPACCredentials(emitter)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.db.ExecAs(actor, query,
+		emitter.Name, emitter.Email, emitter.Phone, emitter.AddressLine, emitter.UBICode,
+		emitter.PtoFacDefault, emitter.IAmb, emitter.ITpEmisDefault, emitter.IDocDefault,
+		emitter.BrandLogoURL, emitter.BrandPrimaryColor, emitter.BrandFooterHTML,
+		credentials[0], credentials[1], credentials[2], credentials[3], emitter.AutoCreateSeries, time.Now(), emitter.ID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error updating emitter: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("error getting rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return nil, fmt.Errorf("emitter not found: %s", emitter.ID)
+	}
+
+	return r.GetByID(emitter.ID)
+}
+
+// Delete marca un emisor como inactivo a nombre de actor; sus API keys dejan de autenticar
+func (r *EmitterRepository) Delete(id uuid.UUID, actor string) error {
+	return r.db.WithActor(actor, func(tx *sql.Tx) error {
+		result, err := tx.Exec(`
+			UPDATE emitters SET is_active = false, updated_at = $1
+			WHERE id = $2 AND is_active = true
+		`, time.Now(), id)
+		if err != nil {
+			return fmt.Errorf("error deleting emitter: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("error getting rows affected: %w", err)
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("emitter not found: %s", id)
+		}
+
+		if _, err := tx.Exec(`UPDATE api_keys SET is_active = false WHERE emitter_id = $1`, id); err != nil {
+			return fmt.Errorf("error deactivating API keys: %w", err)
+		}
+		return nil
+	})
+}
+
+// pacCredentialColumnNames son las columnas con credenciales PAC, en el orden de pacCredentials
+var pacCredentialColumnNames = []string{"pac_api_key", "pac_subscription_key", "pac_sandbox_api_key", "pac_sandbox_subscription_key"}
+
+// pacCredentialColumns son las columnas con credenciales PAC para un SELECT
+var pacCredentialColumns = strings.Join(pacCredentialColumnNames, ", ")
+
+// pacCredentials retorna punteros a las credenciales PAC de un emisor, en el orden de pacCredentialColumns
+func pacCredentials(emitter *models.Emitter) []*string {
+	return []*string{
+		&emitter.PACAPIKey, &emitter.PACSubscriptionKey,
+		&emitter.PACSandboxAPIKey, &emitter.PACSandboxSubscriptionKey,
+	}
+}
+
+// encryptPACCredentials cifra las credenciales PAC de un emisor antes de guardarlas, en el orden de
+// pacCredentialColumnNames; cada valor queda ligado al emisor y a su columna
+func (r *EmitterRepository) encryptPACCredentials(emitter *models.Emitter) ([]string, error) {
+	values := pacCredentials(emitter)
+	encrypted := make([]string, len(values))
+
+	for i, value := range values {
+		var err error
+		if encrypted[i], err = r.db.encryptSecret(*value, "emitters."+pacCredentialColumnNames[i], emitter.ID); err != nil {
+			return nil, fmt.Errorf("error encrypting PAC credentials: %w", err)
+		}
+	}
+
+	return encrypted, nil
+}
+
+// decryptPACCredentials descifra en el lugar las credenciales PAC leídas de la base;
+// los valores en texto plano se mantienen tal cual
+func (r *EmitterRepository) decryptPACCredentials(emitter *models.Emitter) error {
+	for i, value := range pacCredentials(emitter) {
+		plaintext, err := r.db.decryptSecret(*value, "emitters."+pacCredentialColumnNames[i], emitter.ID)
+		if err != nil {
+			return fmt.Errorf("error decrypting PAC credentials: %w", err)
+		}
+		*value = plaintext
+	}
+	return nil
+}
+
+// EncryptPACCredentials cifra con la llave activa las credenciales PAC guardadas en
+// texto plano o con una llave anterior (rotación), a nombre de actor. Retorna cuántos emisores se actualizaron.
+func (r *EmitterRepository) EncryptPACCredentials(actor string) (int, error) {
+	if r.db.Secrets == nil {
+		return 0, fmt.Errorf("PAC encryption keys not configured")
+	}
+
+	rows, err := r.db.QueryWithTimeout(`SELECT id, ` + pacCredentialColumns + ` FROM emitters`)
+	if err != nil {
+		return 0, fmt.Errorf("error querying emitters: %w", err)
+	}
+
+	// Se guardan los valores leídos para actualizar sólo si nadie los cambió mientras tanto
+	type pending struct {
+		emitter *models.Emitter
+		stored  []string
+	}
+	var toUpdate []pending
+	for rows.Next() {
+		emitter := &models.Emitter{}
+		dest := []interface{}{&emitter.ID}
+		for _, value := range pacCredentials(emitter) {
+			dest = append(dest, value)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("error scanning emitter: %w", err)
+		}
+
+		stored := make([]string, 0, 4)
+		needsUpdate := false
+		for _, value := range pacCredentials(emitter) {
+			stored = append(stored, *value)
+			needsUpdate = needsUpdate || r.db.Secrets.NeedsReencryption(*value)
+		}
+		if needsUpdate {
+			toUpdate = append(toUpdate, pending{emitter: emitter, stored: stored})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, fmt.Errorf("error iterating emitters: %w", err)
+	}
+	rows.Close()
+
+	updated := 0
+	for _, p := range toUpdate {
+		if err := r.decryptPACCredentials(p.emitter); err != nil {
+			return updated, fmt.Errorf("emitter %s: %w", p.emitter.ID, err)
+		}
+
+		credentials, err := r.encryptPACCredentials(p.emitter)
+		if err != nil {
+			return updated, fmt.Errorf("emitter %s: %w", p.emitter.ID, err)
+		}
+
+		result, err := r.db.ExecAs(actor, `
+			UPDATE emitters
+			SET pac_api_key = $1, pac_subscription_key = $2, pac_sandbox_api_key = $3, pac_sandbox_subscription_key = $4
+			WHERE id = $5 AND pac_api_key = $6 AND pac_subscription_key = $7
+			  AND pac_sandbox_api_key = $8 AND pac_sandbox_subscription_key = $9
+		`, credentials[0], credentials[1], credentials[2], credentials[3], p.emitter.ID,
+			p.stored[0], p.stored[1], p.stored[2], p.stored[3])
+		if err != nil {
+			return updated, fmt.Errorf("error updating emitter %s: %w", p.emitter.ID, err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			updated++
+		}
+	}
+
+	return updated, nil
+}
+
+// emitterSeriesColumns son las columnas de emitter_series en el orden de scanEmitterSeries
+const emitterSeriesColumns = `id, emitter_id, branch_id, pto_fac_df, doc_kind, next_number, issued_count,
+	authorized_count, rejected_count, is_active, iamb, range_start, range_end, low_remaining_threshold,
+	created_at, updated_at`
+
+// scanEmitterSeries lee una serie desde una fila con emitterSeriesColumns
+func scanEmitterSeries(row rowScanner) (*models.EmitterSeries, error) {
 	var series models.EmitterSeries
-	err := r.db.QueryRowWithTimeout(query, emitterID, ptoFacDF, docKind).Scan(
-		&series.ID, &series.EmitterID, &series.PtoFacDF, &series.DocKind, &series.NextNumber, &series.IssuedCount,
-		&series.AuthorizedCount, &series.RejectedCount, &series.IsActive, &series.CreatedAt, &series.UpdatedAt,
+	err := row.Scan(
+		&series.ID, &series.EmitterID, &series.BranchID, &series.PtoFacDF, &series.DocKind, &series.NextNumber, &series.IssuedCount,
+		&series.AuthorizedCount, &series.RejectedCount, &series.IsActive, &series.IAmb, &series.RangeStart, &series.RangeEnd,
+		&series.LowRemainingThreshold, &series.CreatedAt, &series.UpdatedAt,
 	)
-	
+	if err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+// GetSeries obtiene una serie activa de una sucursal en un ambiente
+func (r *EmitterRepository) GetSeries(emitterID, branchID uuid.UUID, iamb int, ptoFacDF string, docKind models.DocumentType) (*models.EmitterSeries, error) {
+	query := `
+		SELECT ` + emitterSeriesColumns + `
+		FROM emitter_series
+		WHERE emitter_id = $1 AND branch_id = $2 AND pto_fac_df = $3 AND doc_kind = $4 AND iamb = $5 AND is_active = true
+	`
+
+	series, err := scanEmitterSeries(r.db.QueryRowWithTimeout(query, emitterID, branchID, ptoFacDF, docKind, iamb))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("series not found for emitter %s, pto_fac_df %s, doc_kind %s", emitterID, ptoFacDF, docKind)
+			return nil, fmt.Errorf("series not found for emitter %s, branch %s, pto_fac_df %s, doc_kind %s", emitterID, branchID, ptoFacDF, docKind)
 		}
 		return nil, fmt.Errorf("error querying series: %w", err)
 	}
 
-	return &series, nil
+	return series, nil
 }
 
-// GetSeriesList obtiene todas las series de un emisor
-func (r *EmitterRepository) GetSeriesList(emitterID uuid.UUID) ([]models.EmitterSeries, error) {
+// FindSeries obtiene una serie de una sucursal en un ambiente esté activa o no
+func (r *EmitterRepository) FindSeries(emitterID, branchID uuid.UUID, iamb int, ptoFacDF string, docKind models.DocumentType) (*models.EmitterSeries, error) {
 	query := `
-		SELECT id, emitter_id, pto_fac_df, doc_kind, next_number, issued_count,
-			   authorized_count, rejected_count, is_active, created_at, updated_at
+		SELECT ` + emitterSeriesColumns + `
 		FROM emitter_series
-		WHERE emitter_id = $1 AND is_active = true
-		ORDER BY pto_fac_df, doc_kind
+		WHERE emitter_id = $1 AND branch_id = $2 AND pto_fac_df = $3 AND doc_kind = $4 AND iamb = $5
 	`
-	
-	r.logger.Infof("GetSeriesList: querying for emitterID=%s", emitterID)
-	rows, err := r.db.QueryWithTimeout(query, emitterID)
+
+	series, err := scanEmitterSeries(r.db.QueryRowWithTimeout(query, emitterID, branchID, ptoFacDF, docKind, iamb))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("series not found for emitter %s, branch %s, pto_fac_df %s, doc_kind %s", emitterID, branchID, ptoFacDF, docKind)
+		}
+		return nil, fmt.Errorf("error querying series: %w", err)
+	}
+
+	return series, nil
+}
+
+// GetSeriesList obtiene las series de un emisor en un ambiente; includeInactive incluye las desactivadas
+func (r *EmitterRepository) GetSeriesList(emitterID uuid.UUID, iamb int, includeInactive bool) ([]models.EmitterSeries, error) {
+	query := `
+		SELECT ` + emitterSeriesColumns + `
+		FROM emitter_series
+		WHERE emitter_id = $1 AND iamb = $2 AND (is_active = true OR $3)
+		ORDER BY branch_id, pto_fac_df, doc_kind
+	`
+
+	rows, err := r.db.QueryWithTimeout(query, emitterID, iamb, includeInactive)
 	if err != nil {
-		r.logger.Errorf("GetSeriesList: error querying series: %v", err)
 		return nil, fmt.Errorf("error querying series: %w", err)
 	}
 	defer rows.Close()
 
 	var series []models.EmitterSeries
 	for rows.Next() {
-		var s models.EmitterSeries
-		err := rows.Scan(
-			&s.ID, &s.EmitterID, &s.PtoFacDF, &s.DocKind, &s.NextNumber, &s.IssuedCount,
-			&s.AuthorizedCount, &s.RejectedCount, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
-		)
+		s, err := scanEmitterSeries(rows)
 		if err != nil {
-			r.logger.Errorf("GetSeriesList: error scanning series: %v", err)
 			return nil, fmt.Errorf("error scanning series: %w", err)
 		}
-		r.logger.Infof("GetSeriesList: scanned series: %+v", s)
-		series = append(series, s)
+		series = append(series, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating series: %w", err)
 	}
 
-	r.logger.Infof("GetSeriesList: found %d series", len(series))
 	return series, nil
 }
 
-// CreateSeries crea una nueva serie para un emisor
-func (r *EmitterRepository) CreateSeries(emitterID uuid.UUID, req *models.CreateSeriesRequest) (*models.EmitterSeries, error) {
-	r.logger.Infof("CreateSeries: emitterID=%s, req=%+v", emitterID, req)
-	
-	// Verificar que la serie no exista
-	existingSeries, err := r.GetSeries(emitterID, req.PtoFacDF, req.DocKind)
-	if err == nil && existingSeries != nil {
-		r.logger.Warnf("Series already exists: %+v", existingSeries)
-		return nil, fmt.Errorf("series already exists for emitter %s, pto_fac_df %s, doc_kind %s", emitterID, req.PtoFacDF, req.DocKind)
+// SetSeriesActive activa o desactiva una serie de un emisor a nombre de actor
+func (r *EmitterRepository) SetSeriesActive(emitterID, seriesID uuid.UUID, active bool, actor string) (*models.EmitterSeries, error) {
+	query := `
+		UPDATE emitter_series
+		SET is_active = $3, updated_at = NOW()
+		WHERE id = $1 AND emitter_id = $2
+		RETURNING ` + emitterSeriesColumns
+
+	var series *models.EmitterSeries
+	err := r.db.QueryRowAs(actor, func(row *sql.Row) error {
+		var err error
+		series, err = scanEmitterSeries(row)
+		return err
+	}, query, seriesID, emitterID, active)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("series not found: %s", seriesID)
+		}
+		return nil, fmt.Errorf("error updating series: %w", err)
+	}
+
+	return series, nil
+}
+
+// UpdateSettings actualiza la configuración de un emisor (solo los campos enviados) a nombre de actor
+func (r *EmitterRepository) UpdateSettings(emitterID uuid.UUID, req *models.UpdateEmitterSettingsRequest, actor string) error {
+	query := `
+		UPDATE emitters
+		SET auto_create_series = COALESCE($2, auto_create_series), updated_at = NOW()
+		WHERE id = $1 AND is_active = true
+	`
+
+	result, err := r.db.ExecAs(actor, query, emitterID, req.AutoCreateSeries)
+	if err != nil {
+		return fmt.Errorf("error updating emitter settings: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("emitter not found: %s", emitterID)
+	}
+
+	return nil
+}
+
+// GetSeriesGaps obtiene los rangos de folios asignados por la serie que no tienen documento.
+// Los huecos se calculan entre el inicio del rango, los números emitidos (d_nrodf) y el último número asignado.
+func (r *EmitterRepository) GetSeriesGaps(seriesID uuid.UUID) ([]models.SeriesGap, error) {
+	query := `
+		WITH series AS (
+			SELECT next_number, COALESCE(range_start, 1) AS first_number FROM emitter_series
+			WHERE id = $1
+		), numbers AS (
+			SELECT first_number::bigint - 1 AS n FROM series
+			UNION ALL
+			SELECT d_nrodf::bigint FROM invoices
+			WHERE series_id = $1
+			UNION ALL
+			SELECT next_number::bigint FROM series
+		), ordered AS (
+			SELECT n, LEAD(n) OVER (ORDER BY n) AS next_n FROM numbers
+		)
+		SELECT n + 1, next_n - 1
+		FROM ordered
+		WHERE next_n > n + 1
+		ORDER BY n
+	`
+
+	rows, err := r.db.QueryWithTimeout(query, seriesID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying series gaps: %w", err)
+	}
+	defer rows.Close()
+
+	gaps := []models.SeriesGap{}
+	for rows.Next() {
+		var from, to int64
+		if err := rows.Scan(&from, &to); err != nil {
+			return nil, fmt.Errorf("error scanning series gap: %w", err)
+		}
+		gaps = append(gaps, models.SeriesGap{
+			From:  fmt.Sprintf("%010d", from),
+			To:    fmt.Sprintf("%010d", to),
+			Count: to - from + 1,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating series gaps: %w", err)
+	}
+
+	return gaps, nil
+}
+
+// CreateSeries crea una nueva serie en una sucursal de un emisor para un ambiente. La numeración empieza en
+// range_start (o 1); si otra petición crea la misma serie a la vez, retorna "series already exists".
+// El alta queda en el log de auditoría a nombre de actor.
+func (r *EmitterRepository) CreateSeries(emitterID, branchID uuid.UUID, iamb int, req *models.CreateSeriesRequest, actor string) (*models.EmitterSeries, error) {
+	// Verificar que la serie no exista (activa o desactivada)
+	existingSeries, err := r.FindSeries(emitterID, branchID, iamb, req.PtoFacDF, req.DocKind)
+	if err == nil {
+		if !existingSeries.IsActive {
+			return nil, fmt.Errorf("series already exists (inactive) for emitter %s, branch %s, pto_fac_df %s, doc_kind %s", emitterID, branchID, req.PtoFacDF, req.DocKind)
+		}
+		return nil, fmt.Errorf("series already exists for emitter %s, branch %s, pto_fac_df %s, doc_kind %s", emitterID, branchID, req.PtoFacDF, req.DocKind)
+	}
+
+	nextNumber := 1
+	if req.RangeStart != nil {
+		nextNumber = *req.RangeStart
+	}
+	threshold := models.DefaultLowRemainingThreshold
+	if req.LowRemainingThreshold != nil {
+		threshold = *req.LowRemainingThreshold
 	}
-	
-	r.logger.Infof("No existing series found, creating new one")
 
 	series := &models.EmitterSeries{
-		ID:               uuid.New(),
-		EmitterID:        emitterID,
-		PtoFacDF:         req.PtoFacDF,
-		DocKind:          req.DocKind,
-		NextNumber:       1,
-		IssuedCount:      0,
-		AuthorizedCount:  0,
-		RejectedCount:    0,
-		IsActive:         true,
-		CreatedAt:        time.Now(),
-		UpdatedAt:        time.Now(),
+		ID:                    uuid.New(),
+		EmitterID:             emitterID,
+		BranchID:              branchID,
+		PtoFacDF:              req.PtoFacDF,
+		DocKind:               req.DocKind,
+		NextNumber:            nextNumber,
+		IsActive:              true,
+		IAmb:                  iamb,
+		RangeStart:            req.RangeStart,
+		RangeEnd:              req.RangeEnd,
+		LowRemainingThreshold: threshold,
+		CreatedAt:             time.Now(),
+		UpdatedAt:             time.Now(),
 	}
 
 	query := `
 		INSERT INTO emitter_series (
-			id, emitter_id, pto_fac_df, doc_kind, next_number, issued_count,
-			authorized_count, rejected_count, is_active, created_at, updated_at
+			id, emitter_id, branch_id, pto_fac_df, doc_kind, next_number, issued_count,
+			authorized_count, rejected_count, is_active, iamb, range_start, range_end,
+			low_remaining_threshold, created_at, updated_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
 		)
+		ON CONFLICT (branch_id, pto_fac_df, doc_kind, iamb) DO NOTHING
 	`
-	
-	r.logger.Infof("Executing query: %s", query)
-	r.logger.Infof("With params: %s, %s, %s, %s, %d, %d, %d, %d, %t, %s, %s", 
-		series.ID, series.EmitterID, series.PtoFacDF, series.DocKind, series.NextNumber, series.IssuedCount,
-		series.AuthorizedCount, series.RejectedCount, series.IsActive, series.CreatedAt, series.UpdatedAt)
-	
-	_, err = r.db.ExecWithTimeout(query,
-		series.ID, series.EmitterID, series.PtoFacDF, series.DocKind, series.NextNumber, series.IssuedCount,
-		series.AuthorizedCount, series.RejectedCount, series.IsActive, series.CreatedAt, series.UpdatedAt,
+
+	result, err := r.db.ExecAs(actor, query,
+		series.ID, series.EmitterID, series.BranchID, series.PtoFacDF, series.DocKind, series.NextNumber, series.IssuedCount,
+		series.AuthorizedCount, series.RejectedCount, series.IsActive, series.IAmb, series.RangeStart, series.RangeEnd,
+		series.LowRemainingThreshold, series.CreatedAt, series.UpdatedAt,
 	)
-	
 	if err != nil {
-		r.logger.Errorf("Error executing query: %v", err)
 		return nil, fmt.Errorf("error creating series: %w", err)
 	}
-	
-	r.logger.Infof("Series created successfully: %+v", series)
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return nil, fmt.Errorf("series already exists for emitter %s, branch %s, pto_fac_df %s, doc_kind %s", emitterID, branchID, req.PtoFacDF, req.DocKind)
+	}
 
 	return series, nil
 }
 
-// GetDashboard obtiene el dashboard de un emisor
-func (r *EmitterRepository) GetDashboard(emitterID uuid.UUID) (*models.DashboardResponse, error) {
+// GetDashboard obtiene el dashboard de un emisor con los documentos de un ambiente
+func (r *EmitterRepository) GetDashboard(emitterID uuid.UUID, iamb int) (*models.DashboardResponse, error) {
 	// Obtener estadísticas por mes actual
 	currentMonth := time.Now().Format("2006-01")
 	
 	query := `
 		SELECT 
-			d_ptofacdf,
-			doc_kind,
+			i.d_ptofacdf,
+			i.doc_kind,
+			s.id,
+			s.is_active,
+			s.created_at,
 			COUNT(*) as total_issued,
-			COUNT(CASE WHEN status = 'AUTHORIZED' THEN 1 END) as total_authorized,
-			COUNT(CASE WHEN status = 'REJECTED' THEN 1 END) as total_rejected
-		FROM invoices
-		WHERE emitter_id = $1 
-		AND DATE_TRUNC('month', created_at) = DATE_TRUNC('month', CURRENT_DATE)
-		GROUP BY d_ptofacdf, doc_kind
-		ORDER BY d_ptofacdf, doc_kind
+			COUNT(CASE WHEN i.status = 'AUTHORIZED' THEN 1 END) as total_authorized,
+			COUNT(CASE WHEN i.status = 'REJECTED' THEN 1 END) as total_rejected,
+			COALESCE(SUM(i.total_amount), 0) as total_amount
+		FROM invoices i
+		JOIN emitter_series s ON s.id = i.series_id
+		WHERE i.emitter_id = $1 AND i.iamb = $2
+		AND DATE_TRUNC('month', i.created_at) = DATE_TRUNC('month', CURRENT_DATE)
+		GROUP BY i.d_ptofacdf, i.doc_kind, s.id, s.is_active, s.created_at
+		ORDER BY i.d_ptofacdf, i.doc_kind
 	`
 	
-	rows, err := r.db.QueryWithTimeout(query, emitterID)
+	rows, err := r.db.QueryWithTimeout(query, emitterID, iamb)
 	if err != nil {
 		return nil, fmt.Errorf("error querying dashboard: %w", err)
 	}
@@ -198,13 +559,16 @@ func (r *EmitterRepository) GetDashboard(emitterID uuid.UUID) (*models.Dashboard
 
 	var series []models.SeriesItem
 	var totalIssued, totalAuthorized, totalRejected int
+	var totalAmount float64
 
 	for rows.Next() {
 		var item models.SeriesItem
 		var issued, authorized, rejected int
+		var amount float64
 		
 		err := rows.Scan(
-			&item.PtoFacDF, &item.DocKind, &issued, &authorized, &rejected,
+			&item.PtoFacDF, &item.DocKind, &item.ID, &item.IsActive, &item.CreatedAt,
+			&issued, &authorized, &rejected, &amount,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning dashboard item: %w", err)
@@ -213,21 +577,26 @@ func (r *EmitterRepository) GetDashboard(emitterID uuid.UUID) (*models.Dashboard
 		item.IssuedCount = issued
 		item.AuthorizedCount = authorized
 		item.RejectedCount = rejected
+		item.IAmb = iamb
 		item.LastAssigned = issued // Por simplicidad, usamos el total emitido
 
 		series = append(series, item)
 		totalIssued += issued
 		totalAuthorized += authorized
 		totalRejected += rejected
+		totalAmount += amount
 	}
 
 	response := &models.DashboardResponse{
 		Month: currentMonth,
+		IAmb:  iamb,
 		Series: series,
 		Totals: models.DashboardTotals{
 			Issued:     totalIssued,
 			Authorized: totalAuthorized,
 			Rejected:   totalRejected,
+			Currency:   models.FunctionalCurrency,
+			Amount:     totalAmount,
 		},
 	}
 
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// ExchangeRateRepository maneja las operaciones de base de datos para tasas de cambio
+type ExchangeRateRepository struct {
+	db     *DB
+	logger *logrus.Logger
+}
+
+// NewExchangeRateRepository crea una nueva instancia del repositorio
+func NewExchangeRateRepository(db *DB, logger *logrus.Logger) *ExchangeRateRepository {
+	return &ExchangeRateRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Upsert crea o actualiza la tasa de una moneda para una fecha efectiva
+func (r *ExchangeRateRepository) Upsert(rate *models.ExchangeRate) error {
+	query := `
+		INSERT INTO exchange_rates (
+			id, emitter_id, currency_code, rate, effective_date, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7
+		)
+		ON CONFLICT (emitter_id, currency_code, effective_date)
+		DO UPDATE SET rate = EXCLUDED.rate, updated_at = EXCLUDED.updated_at
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRowWithTimeout(query,
+		rate.ID, rate.EmitterID, rate.CurrencyCode, rate.Rate, rate.EffectiveDate,
+		rate.CreatedAt, rate.UpdatedAt,
+	).Scan(&rate.ID, &rate.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("error upserting exchange rate: %w", err)
+	}
+
+	return nil
+}
+
+// GetEffective obtiene la tasa vigente de una moneda para una fecha (la más reciente <= fecha)
+func (r *ExchangeRateRepository) GetEffective(emitterID uuid.UUID, currencyCode string, at time.Time) (*models.ExchangeRate, error) {
+	query := `
+		SELECT id, emitter_id, currency_code, rate, effective_date, created_at, updated_at
+		FROM exchange_rates
+		WHERE emitter_id = $1 AND currency_code = $2 AND effective_date <= $3
+		ORDER BY effective_date DESC
+		LIMIT 1
+	`
+
+	var rate models.ExchangeRate
+	err := r.db.QueryRowWithTimeout(query, emitterID, currencyCode, at).Scan(
+		&rate.ID, &rate.EmitterID, &rate.CurrencyCode, &rate.Rate, &rate.EffectiveDate,
+		&rate.CreatedAt, &rate.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("exchange rate not found for currency %s at %s", currencyCode, at.Format("2006-01-02"))
+		}
+		return nil, fmt.Errorf("error querying exchange rate: %w", err)
+	}
+
+	return &rate, nil
+}
+
+// GetByEmitterID obtiene las tasas de un emisor con paginación, opcionalmente filtradas por moneda
+func (r *ExchangeRateRepository) GetByEmitterID(emitterID uuid.UUID, currencyCode string, page, pageSize int) ([]models.ExchangeRate, int, error) {
+	countQuery := `
+		SELECT COUNT(*) FROM exchange_rates
+		WHERE emitter_id = $1 AND ($2 = '' OR currency_code = $2)
+	`
+	var total int
+	if err := r.db.QueryRowWithTimeout(countQuery, emitterID, currencyCode).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting exchange rates: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	query := `
+		SELECT id, emitter_id, currency_code, rate, effective_date, created_at, updated_at
+		FROM exchange_rates
+		WHERE emitter_id = $1 AND ($2 = '' OR currency_code = $2)
+		ORDER BY currency_code, effective_date DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.db.QueryWithTimeout(query, emitterID, currencyCode, pageSize, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying exchange rates: %w", err)
+	}
+	defer rows.Close()
+
+	rates := []models.ExchangeRate{}
+	for rows.Next() {
+		var rate models.ExchangeRate
+		err := rows.Scan(
+			&rate.ID, &rate.EmitterID, &rate.CurrencyCode, &rate.Rate, &rate.EffectiveDate,
+			&rate.CreatedAt, &rate.UpdatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning exchange rate: %w", err)
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, total, nil
+}
+
+// Delete elimina una tasa de cambio de un emisor
+func (r *ExchangeRateRepository) Delete(emitterID, id uuid.UUID) error {
+	query := `DELETE FROM exchange_rates WHERE id = $1 AND emitter_id = $2`
+
+	result, err := r.db.ExecWithTimeout(query, id, emitterID)
+	if err != nil {
+		return fmt.Errorf("error deleting exchange rate: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("exchange rate not found: %s", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// IdempotencyRepository maneja las operaciones de base de datos de las Idempotency-Key
+type IdempotencyRepository struct {
+	db     *DB
+	logger *logrus.Logger
+}
+
+// NewIdempotencyRepository crea una nueva instancia del repositorio
+func NewIdempotencyRepository(db *DB, logger *logrus.Logger) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// idempotencyKeyColumns son las columnas de idempotency_keys en el orden de scanIdempotencyKey
+const idempotencyKeyColumns = `emitter_id, idempotency_key, fingerprint, claim_id, locked_until, invoice_id,
+	response_body, expires_at, completed_at, created_at`
+
+// scanIdempotencyKey lee una key desde una fila con idempotencyKeyColumns
+func scanIdempotencyKey(row rowScanner) (*models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	var response []byte
+	err := row.Scan(
+		&record.EmitterID, &record.Key, &record.Fingerprint, &record.ClaimID, &record.LockedUntil, &record.InvoiceID,
+		&response, &record.ExpiresAt, &record.CompletedAt, &record.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	record.Response = response
+	return &record, nil
+}
+
+// Claim reserva la key de un emisor para una petición con la huella fingerprint. Si la key está
+// libre, vencida (ttl) o su reserva expiró (lease) la toma y devuelve claimed=true; si no, devuelve
+// la key existente para que el llamador compare la huella o devuelva la respuesta guardada. El
+// insert con ON CONFLICT hace que de dos peticiones concurrentes sólo una obtenga la reserva.
+func (r *IdempotencyRepository) Claim(emitterID uuid.UUID, key, fingerprint string, ttl, lease time.Duration) (*models.IdempotencyKey, bool, error) {
+	// Las keys vencidas del emisor ya no protegen nada
+	if _, err := r.db.ExecWithTimeout(`
+		DELETE FROM idempotency_keys WHERE emitter_id = $1 AND expires_at <= NOW()
+	`, emitterID); err != nil {
+		return nil, false, fmt.Errorf("error deleting expired idempotency keys: %w", err)
+	}
+
+	now := time.Now()
+	query := `
+		INSERT INTO idempotency_keys (
+			emitter_id, idempotency_key, fingerprint, claim_id, locked_until, expires_at, created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, NOW()
+		)
+		ON CONFLICT (emitter_id, idempotency_key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint,
+			claim_id = EXCLUDED.claim_id,
+			locked_until = EXCLUDED.locked_until,
+			invoice_id = NULL,
+			response_body = NULL,
+			expires_at = EXCLUDED.expires_at,
+			completed_at = NULL,
+			created_at = NOW()
+		WHERE idempotency_keys.expires_at <= NOW()
+			OR (idempotency_keys.response_body IS NULL AND idempotency_keys.locked_until <= NOW())
+		RETURNING ` + idempotencyKeyColumns
+
+	record, err := scanIdempotencyKey(r.db.QueryRowWithTimeout(query,
+		emitterID, key, fingerprint, uuid.New(), now.Add(lease), now.Add(ttl),
+	))
+	if err == nil {
+		return record, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, fmt.Errorf("error claiming idempotency key: %w", err)
+	}
+
+	// La key está en uso por otra petición o ya tiene respuesta
+	record, err = scanIdempotencyKey(r.db.QueryRowWithTimeout(`
+		SELECT `+idempotencyKeyColumns+` FROM idempotency_keys
+		WHERE emitter_id = $1 AND idempotency_key = $2
+	`, emitterID, key))
+	if err != nil {
+		return nil, false, fmt.Errorf("error getting idempotency key: %w", err)
+	}
+
+	return record, false, nil
+}
+
+// Release libera una reserva cuya petición falló antes de crear el documento, para que el
+// cliente pueda reintentar con la misma key
+func (r *IdempotencyRepository) Release(claim *models.IdempotencyKey) error {
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE emitter_id = $1 AND idempotency_key = $2 AND claim_id = $3 AND response_body IS NULL
+	`
+
+	_, err := r.db.ExecWithTimeout(query, claim.EmitterID, claim.Key, claim.ClaimID)
+	if err != nil {
+		return fmt.Errorf("error releasing idempotency key: %w", err)
+	}
+	return nil
+}
+
+// completeIdempotencyKey guarda la respuesta del documento creado dentro de la transacción del
+// documento. Si la reserva expiró y la tomó otra petición, la transacción debe revertirse.
+func completeIdempotencyKey(tx *sql.Tx, claim *models.IdempotencyKey, invoiceID uuid.UUID, response interface{}) error {
+	body, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("error encoding idempotent response: %w", err)
+	}
+
+	query := `
+		UPDATE idempotency_keys
+		SET invoice_id = $4, response_body = $5, completed_at = NOW()
+		WHERE emitter_id = $1 AND idempotency_key = $2 AND claim_id = $3 AND response_body IS NULL
+	`
+
+	result, err := tx.Exec(query, claim.EmitterID, claim.Key, claim.ClaimID, invoiceID, body)
+	if err != nil {
+		return fmt.Errorf("error storing idempotent response: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error storing idempotent response: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("idempotency key claim lost: %s", claim.Key)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// ImportJobRepository maneja las operaciones de base de datos para importaciones masivas
+type ImportJobRepository struct {
+	db     *DB
+	logger *logrus.Logger
+}
+
+// NewImportJobRepository crea una nueva instancia del repositorio
+func NewImportJobRepository(db *DB, logger *logrus.Logger) *ImportJobRepository {
+	return &ImportJobRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create registra un nuevo trabajo de importación
+func (r *ImportJobRepository) Create(job *models.ImportJob) error {
+	query := `
+		INSERT INTO import_jobs (
+			id, emitter_id, resource_type, file_name, file_format, status, created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7
+		)
+	`
+
+	_, err := r.db.ExecWithTimeout(query,
+		job.ID, job.EmitterID, job.ResourceType, job.FileName, job.FileFormat,
+		job.Status, job.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("error creating import job: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID obtiene un trabajo de importación de un emisor
+func (r *ImportJobRepository) GetByID(emitterID, id uuid.UUID) (*models.ImportJob, error) {
+	query := `
+		SELECT id, emitter_id, resource_type, file_name, file_format, status,
+			   total_rows, created_count, updated_count, error_count, errors,
+			   error_message, created_at, started_at, completed_at
+		FROM import_jobs
+		WHERE id = $1 AND emitter_id = $2
+	`
+
+	var job models.ImportJob
+	var errorsJSON []byte
+	err := r.db.QueryRowWithTimeout(query, id, emitterID).Scan(
+		&job.ID, &job.EmitterID, &job.ResourceType, &job.FileName, &job.FileFormat, &job.Status,
+		&job.TotalRows, &job.CreatedCount, &job.UpdatedCount, &job.ErrorCount, &errorsJSON,
+		&job.ErrorMessage, &job.CreatedAt, &job.StartedAt, &job.CompletedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("import job not found: %s", id)
+		}
+		return nil, fmt.Errorf("error querying import job: %w", err)
+	}
+
+	if err := json.Unmarshal(errorsJSON, &job.Errors); err != nil {
+		return nil, fmt.Errorf("error decoding import errors: %w", err)
+	}
+
+	return &job, nil
+}
+
+// MarkProcessing marca un trabajo como en proceso
+func (r *ImportJobRepository) MarkProcessing(id uuid.UUID) error {
+	query := `UPDATE import_jobs SET status = $1, started_at = $2 WHERE id = $3`
+
+	_, err := r.db.ExecWithTimeout(query, models.ImportStatusProcessing, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("error updating import job: %w", err)
+	}
+
+	return nil
+}
+
+// Complete guarda el resultado final de un trabajo de importación
+func (r *ImportJobRepository) Complete(job *models.ImportJob) error {
+	errorsJSON, err := json.Marshal(job.Errors)
+	if err != nil {
+		return fmt.Errorf("error encoding import errors: %w", err)
+	}
+
+	query := `
+		UPDATE import_jobs
+		SET status = $1, total_rows = $2, created_count = $3, updated_count = $4,
+		    error_count = $5, errors = $6, error_message = $7, completed_at = $8
+		WHERE id = $9
+	`
+
+	_, err = r.db.ExecWithTimeout(query,
+		job.Status, job.TotalRows, job.CreatedCount, job.UpdatedCount,
+		job.ErrorCount, errorsJSON, job.ErrorMessage, job.CompletedAt, job.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("error completing import job: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,219 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// InvoiceArtifactRepository maneja las operaciones de base de datos de las versiones de los
+// archivos de los documentos
+type InvoiceArtifactRepository struct {
+	db     *DB
+	logger *logrus.Logger
+}
+
+// NewInvoiceArtifactRepository crea una nueva instancia del repositorio
+func NewInvoiceArtifactRepository(db *DB, logger *logrus.Logger) *InvoiceArtifactRepository {
+	return &InvoiceArtifactRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// invoiceArtifactColumns son las columnas de invoice_artifacts, sin el contenido, en el orden de
+// scanInvoiceArtifact
+const invoiceArtifactColumns = `id, invoice_id, emitter_id, kind, version, content_type, size, sha256,
+	generator_version, storage_key, created_at`
+
+// scanInvoiceArtifact lee una versión desde una fila con invoiceArtifactColumns seguidas de
+// las columnas de extra
+func scanInvoiceArtifact(row rowScanner, extra ...interface{}) (*models.InvoiceArtifact, error) {
+	var artifact models.InvoiceArtifact
+	dest := []interface{}{
+		&artifact.ID, &artifact.InvoiceID, &artifact.EmitterID, &artifact.Kind, &artifact.Version,
+		&artifact.ContentType, &artifact.Size, &artifact.SHA256, &artifact.GeneratorVersion,
+		&artifact.StorageKey, &artifact.CreatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &artifact, nil
+}
+
+// Create registra una versión nueva de un archivo de un documento. Si el contenido (SHA-256) es
+// igual al de la última versión de ese tipo, no crea otra y retorna la existente con created=false.
+// La fila del documento se bloquea para que dos regeneraciones concurrentes no tomen el mismo número.
+func (r *InvoiceArtifactRepository) Create(artifact *models.InvoiceArtifact) (*models.InvoiceArtifact, bool, error) {
+	var result *models.InvoiceArtifact
+	created := false
+
+	err := r.db.WithTransaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`SELECT id FROM invoices WHERE id = $1 FOR UPDATE`, artifact.InvoiceID); err != nil {
+			return fmt.Errorf("error locking invoice: %w", err)
+		}
+
+		latest, err := scanInvoiceArtifact(tx.QueryRow(`
+			SELECT `+invoiceArtifactColumns+` FROM invoice_artifacts
+			WHERE invoice_id = $1 AND kind = $2
+			ORDER BY version DESC
+			LIMIT 1
+		`, artifact.InvoiceID, artifact.Kind))
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("error getting latest invoice artifact: %w", err)
+		}
+		if latest != nil && latest.SHA256 == artifact.SHA256 {
+			result = latest
+			return nil
+		}
+
+		version := 1
+		if latest != nil {
+			version = latest.Version + 1
+		}
+
+		query := `
+			INSERT INTO invoice_artifacts (
+				invoice_id, emitter_id, kind, version, content_type, size, sha256,
+				generator_version, storage_key, data, created_at
+			) VALUES (
+				$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW()
+			)
+			RETURNING ` + invoiceArtifactColumns
+
+		result, err = scanInvoiceArtifact(tx.QueryRow(query,
+			artifact.InvoiceID, artifact.EmitterID, artifact.Kind, version, artifact.ContentType,
+			artifact.Size, artifact.SHA256, artifact.GeneratorVersion, artifact.StorageKey, artifact.Data,
+		))
+		if err != nil {
+			return fmt.Errorf("error inserting invoice artifact: %w", err)
+		}
+		created = true
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return result, created, nil
+}
+
+// ListByInvoiceID obtiene las versiones de los archivos de un documento, por tipo y de la más
+// reciente a la más antigua
+func (r *InvoiceArtifactRepository) ListByInvoiceID(invoiceID uuid.UUID) ([]models.InvoiceArtifact, error) {
+	query := `
+		SELECT ` + invoiceArtifactColumns + `
+		FROM invoice_artifacts
+		WHERE invoice_id = $1
+		ORDER BY kind, version DESC
+	`
+
+	rows, err := r.db.QueryWithTimeout(query, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying invoice artifacts: %w", err)
+	}
+	defer rows.Close()
+
+	artifacts := []models.InvoiceArtifact{}
+	for rows.Next() {
+		artifact, err := scanInvoiceArtifact(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning invoice artifact: %w", err)
+		}
+		artifacts = append(artifacts, *artifact)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating invoice artifacts: %w", err)
+	}
+
+	return artifacts, nil
+}
+
+// GetVersion obtiene una versión de un archivo de un documento, con su contenido si está en la BD
+func (r *InvoiceArtifactRepository) GetVersion(invoiceID uuid.UUID, kind models.ArtifactKind, version int) (*models.InvoiceArtifact, error) {
+	query := `
+		SELECT ` + invoiceArtifactColumns + `, data
+		FROM invoice_artifacts
+		WHERE invoice_id = $1 AND kind = $2 AND version = $3
+	`
+
+	var data []byte
+	artifact, err := scanInvoiceArtifact(r.db.QueryRowWithTimeout(query, invoiceID, kind, version), &data)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("invoice artifact not found: %s v%d", kind, version)
+		}
+		return nil, fmt.Errorf("error querying invoice artifact: %w", err)
+	}
+	artifact.Data = data
+
+	return artifact, nil
+}
+
+// LatestVersion obtiene la última versión de un tipo de archivo de un documento, o 0 si no tiene
+func (r *InvoiceArtifactRepository) LatestVersion(invoiceID uuid.UUID, kind models.ArtifactKind) (int, error) {
+	query := `
+		SELECT COALESCE(MAX(version), 0)
+		FROM invoice_artifacts
+		WHERE invoice_id = $1 AND kind = $2
+	`
+
+	var version int
+	if err := r.db.QueryRowWithTimeout(query, invoiceID, kind).Scan(&version); err != nil {
+		return 0, fmt.Errorf("error querying latest invoice artifact version: %w", err)
+	}
+
+	return version, nil
+}
+
+// ListWithDatabaseBlobs obtiene, de las más antiguas a las más recientes, las versiones que
+// todavía guardan su contenido en la BD
+func (r *InvoiceArtifactRepository) ListWithDatabaseBlobs(limit int) ([]models.InvoiceArtifact, error) {
+	query := `
+		SELECT ` + invoiceArtifactColumns + `, data
+		FROM invoice_artifacts
+		WHERE data IS NOT NULL
+		ORDER BY created_at
+		LIMIT $1
+	`
+
+	rows, err := r.db.QueryWithTimeout(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying invoice artifacts with database blobs: %w", err)
+	}
+	defer rows.Close()
+
+	var artifacts []models.InvoiceArtifact
+	for rows.Next() {
+		var data []byte
+		artifact, err := scanInvoiceArtifact(rows, &data)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning invoice artifact: %w", err)
+		}
+		artifact.Data = data
+		artifacts = append(artifacts, *artifact)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating invoice artifacts: %w", err)
+	}
+
+	return artifacts, nil
+}
+
+// MarkMovedToStorage deja en NULL el contenido de una versión copiada al storage y guarda su key
+func (r *InvoiceArtifactRepository) MarkMovedToStorage(id uuid.UUID, storageKey string) error {
+	query := `
+		UPDATE invoice_artifacts
+		SET storage_key = $2, data = NULL
+		WHERE id = $1 AND data IS NOT NULL AND storage_key IS NULL
+	`
+
+	_, err := r.db.ExecWithTimeout(query, id, storageKey)
+	if err != nil {
+		return fmt.Errorf("error marking invoice artifact as moved: %w", err)
+	}
+	return nil
+}
@@ -46,15 +46,17 @@ func (r *InvoiceFilesRepository) Create(files *models.InvoiceFiles) error {
 	query := `
 		INSERT INTO invoice_files (
 			id, invoice_id, pdf_data, xml_data, pdf_size, xml_size, 
-			pdf_url, xml_url, generated_at, updated_at
+			pdf_url, xml_url, pdf_key, xml_key, pdf_sha256, xml_sha256,
+			generated_at, updated_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
 		)
 	`
 	
 	_, err := r.db.ExecWithTimeout(query,
 		files.ID, files.InvoiceID, files.PDFData, files.XMLData,
 		files.PDFSize, files.XMLSize, files.PDFURL, files.XMLURL,
+		files.PDFKey, files.XMLKey, files.PDFSHA256, files.XMLSHA256,
 		files.GeneratedAt, files.UpdatedAt,
 	)
 	
@@ -68,19 +70,12 @@ func (r *InvoiceFilesRepository) Create(files *models.InvoiceFiles) error {
 // GetByInvoiceID obtiene los archivos de una factura
 func (r *InvoiceFilesRepository) GetByInvoiceID(invoiceID uuid.UUID) (*models.InvoiceFiles, error) {
 	query := `
-		SELECT id, invoice_id, pdf_data, xml_data, pdf_size, xml_size, 
-			   pdf_url, xml_url, generated_at, updated_at
+		SELECT ` + invoiceFilesColumns + `
 		FROM invoice_files
 		WHERE invoice_id = $1
 	`
 	
-	var files models.InvoiceFiles
-	err := r.db.QueryRowWithTimeout(query, invoiceID).Scan(
-		&files.ID, &files.InvoiceID, &files.PDFData, &files.XMLData,
-		&files.PDFSize, &files.XMLSize, &files.PDFURL, &files.XMLURL,
-		&files.GeneratedAt, &files.UpdatedAt,
-	)
-	
+	files, err := scanInvoiceFiles(r.db.QueryRowWithTimeout(query, invoiceID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("invoice files not found for invoice %s", invoiceID)
@@ -88,6 +83,25 @@ func (r *InvoiceFilesRepository) GetByInvoiceID(invoiceID uuid.UUID) (*models.In
 		return nil, fmt.Errorf("error querying invoice files: %w", err)
 	}
 	
+	return files, nil
+}
+
+// invoiceFilesColumns son las columnas de invoice_files en el orden de scanInvoiceFiles
+const invoiceFilesColumns = `id, invoice_id, pdf_data, xml_data, pdf_size, xml_size,
+	pdf_url, xml_url, pdf_key, xml_key, pdf_sha256, xml_sha256, generated_at, updated_at`
+
+// scanInvoiceFiles lee los archivos de una factura desde una fila con invoiceFilesColumns
+func scanInvoiceFiles(row rowScanner) (*models.InvoiceFiles, error) {
+	var files models.InvoiceFiles
+	err := row.Scan(
+		&files.ID, &files.InvoiceID, &files.PDFData, &files.XMLData,
+		&files.PDFSize, &files.XMLSize, &files.PDFURL, &files.XMLURL,
+		&files.PDFKey, &files.XMLKey, &files.PDFSHA256, &files.XMLSHA256,
+		&files.GeneratedAt, &files.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
 	return &files, nil
 }
 
@@ -96,13 +110,15 @@ func (r *InvoiceFilesRepository) Update(files *models.InvoiceFiles) error {
 	query := `
 		UPDATE invoice_files 
 		SET pdf_data = $1, xml_data = $2, pdf_size = $3, xml_size = $4, 
-		    pdf_url = $5, xml_url = $6, updated_at = $7
-		WHERE invoice_id = $8
+		    pdf_url = $5, xml_url = $6, pdf_key = $7, xml_key = $8,
+		    pdf_sha256 = $9, xml_sha256 = $10, updated_at = $11
+		WHERE invoice_id = $12
 	`
 	
 	_, err := r.db.ExecWithTimeout(query,
 		files.PDFData, files.XMLData, files.PDFSize, files.XMLSize,
-		files.PDFURL, files.XMLURL, time.Now(), files.InvoiceID,
+		files.PDFURL, files.XMLURL, files.PDFKey, files.XMLKey,
+		files.PDFSHA256, files.XMLSHA256, time.Now(), files.InvoiceID,
 	)
 	
 	if err != nil {
@@ -136,3 +152,61 @@ func (r *InvoiceFilesRepository) Exists(invoiceID uuid.UUID) (bool, error) {
 	
 	return count > 0, nil
 }
+
+// ListWithDatabaseBlobs obtiene, de los más antiguos a los más recientes, los archivos que
+// todavía guardan su contenido en la BD
+func (r *InvoiceFilesRepository) ListWithDatabaseBlobs(limit int) ([]models.InvoiceFiles, error) {
+	query := `
+		SELECT ` + invoiceFilesColumns + `
+		FROM invoice_files
+		WHERE pdf_data IS NOT NULL OR xml_data IS NOT NULL
+		ORDER BY updated_at
+		LIMIT $1
+	`
+
+	rows, err := r.db.QueryWithTimeout(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying invoice files with database blobs: %w", err)
+	}
+	defer rows.Close()
+
+	var result []models.InvoiceFiles
+	for rows.Next() {
+		files, err := scanInvoiceFiles(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning invoice files: %w", err)
+		}
+		result = append(result, *files)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating invoice files: %w", err)
+	}
+
+	return result, nil
+}
+
+// MarkMovedToStorage deja en NULL el contenido de los archivos copiados al storage y guarda sus
+// keys, checksums y URLs. Sólo actualiza si el registro no cambió desde que se leyó (updated_at);
+// retorna false si se regeneró entretanto, para reintentarlo en la siguiente pasada.
+func (r *InvoiceFilesRepository) MarkMovedToStorage(files *models.InvoiceFiles) (bool, error) {
+	query := `
+		UPDATE invoice_files
+		SET pdf_data = NULL, xml_data = NULL, pdf_key = $1, xml_key = $2,
+		    pdf_sha256 = $3, xml_sha256 = $4, pdf_url = $5, xml_url = $6
+		WHERE invoice_id = $7 AND updated_at = $8
+	`
+
+	result, err := r.db.ExecWithTimeout(query,
+		files.PDFKey, files.XMLKey, files.PDFSHA256, files.XMLSHA256,
+		files.PDFURL, files.XMLURL, files.InvoiceID, files.UpdatedAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("error marking invoice files as moved: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error marking invoice files as moved: %w", err)
+	}
+	return rows > 0, nil
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -25,18 +26,41 @@ func NewInvoiceRepository(db *DB, logger *logrus.Logger) *InvoiceRepository {
 	}
 }
 
-// Create crea un nuevo invoice con sus items
-func (r *InvoiceRepository) Create(invoice *models.Invoice, items []models.InvoiceItem) error {
+// Create crea un nuevo invoice con sus items. El folio se asigna dentro de la misma
+// transacción: get_next_series_folio bloquea la serie hasta el commit y, si el insert falla,
+// el rollback devuelve el número para que la secuencia no quede con huecos. El status inicial
+// queda en el historial del documento a nombre de actor. Con claim, la respuesta que arma
+// response (ya con el folio asignado) se guarda en la Idempotency-Key en la misma transacción.
+func (r *InvoiceRepository) Create(invoice *models.Invoice, items []models.InvoiceItem, actor string, claim *models.IdempotencyKey, response func() interface{}) error {
+	// Snapshot de emisor, receptor y marca usados en el documento
+	var snapshot []byte
+	if invoice.Snapshot != nil {
+		encoded, err := json.Marshal(invoice.Snapshot)
+		if err != nil {
+			return fmt.Errorf("error encoding invoice snapshot: %w", err)
+		}
+		snapshot = encoded
+	}
+
 	return r.db.WithTransaction(func(tx *sql.Tx) error {
+		// Asignar folio de la serie
+		var documentNumber string
+		if err := tx.QueryRow(`SELECT get_next_series_folio($1)`, invoice.SeriesID).Scan(&documentNumber); err != nil {
+			return fmt.Errorf("error getting next document number: %w", err)
+		}
+		invoice.DocumentNumber = documentNumber
+
 		// Insertar invoice
 		query := `
 			INSERT INTO invoices (
 				id, emitter_id, series_id, customer_id, doc_kind, d_nrodf, d_ptofacdf,
 				status, email_status, ref_cufe, ref_nrodf, ref_ptofacdf, iamb, itpemis, idoc,
-				subtotal, itbms_amount, total_amount, idempotency_key, created_at, updated_at
+				subtotal, itbms_amount, total_amount, currency_code, exchange_rate,
+				doc_subtotal, doc_itbms_amount, doc_total_amount, idempotency_key, snapshot,
+				created_at, updated_at, branch_id
 			) VALUES (
 				$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15,
-				$16, $17, $18, $19, $20, $21
+				$16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28
 			)
 		`
 		
@@ -46,7 +70,9 @@ func (r *InvoiceRepository) Create(invoice *models.Invoice, items []models.Invoi
 			invoice.Status, invoice.EmailStatus, invoice.ReferenceCUFE, invoice.ReferenceNumber, invoice.ReferencePtoFac,
 			invoice.IAmb, invoice.ITpEmis, invoice.IDoc,
 			invoice.Subtotal, invoice.ITBMSAmount, invoice.TotalAmount,
-			invoice.IdempotencyKey, invoice.CreatedAt, invoice.UpdatedAt,
+			invoice.CurrencyCode, invoice.ExchangeRate,
+			invoice.DocSubtotal, invoice.DocITBMSAmount, invoice.DocTotalAmount,
+			invoice.IdempotencyKey, snapshot, invoice.CreatedAt, invoice.UpdatedAt, invoice.BranchID,
 		)
 		
 		if err != nil {
@@ -75,7 +101,32 @@ func (r *InvoiceRepository) Create(invoice *models.Invoice, items []models.Invoi
 			}
 		}
 
-		return nil
+		if err := insertInvoiceEvent(tx, invoice.ID, invoice.EmitterID, nil, invoice.Status, actor, "Document created"); err != nil {
+			return err
+		}
+
+		if claim != nil {
+			if err := completeIdempotencyKey(tx, claim, invoice.ID, response()); err != nil {
+				return err
+			}
+		}
+
+		// Evento de creación para el relay del outbox (workflows, email y webhooks)
+		data := &models.InvoiceOutboxData{
+			InvoiceID:      invoice.ID,
+			Status:         invoice.Status,
+			EmailStatus:    invoice.EmailStatus,
+			DocumentType:   invoice.DocumentType,
+			PtoFacDF:       invoice.PtoFacDF,
+			DocumentNumber: invoice.DocumentNumber,
+			CUFE:           invoice.CUFE,
+			IAmb:           invoice.IAmb,
+		}
+		if invoice.Snapshot != nil {
+			data.Recipient = invoice.Snapshot.Customer.Email
+		}
+		dedupKey := fmt.Sprintf("%s:%s", models.OutboxInvoiceCreated, invoice.ID)
+		return insertInvoiceOutboxEvent(tx, invoice.EmitterID, models.OutboxInvoiceCreated, data, dedupKey)
 	})
 }
 
@@ -87,9 +138,10 @@ func (r *InvoiceRepository) GetByID(id uuid.UUID) (*models.Invoice, error) {
 			i.status, i.email_status, i.ref_cufe, i.ref_nrodf, i.ref_ptofacdf, i.cufe, i.url_cufe,
 			i.xml_in, i.xml_response, i.xml_fe, i.xml_protocolo, i.cafe_pdf_url,
 			i.iamb, i.itpemis, i.idoc, i.subtotal, i.itbms_amount, i.total_amount,
-			i.idempotency_key, i.created_at, i.updated_at,
+			i.currency_code, i.exchange_rate, i.doc_subtotal, i.doc_itbms_amount, i.doc_total_amount,
+			i.idempotency_key, i.created_at, i.updated_at, i.branch_id,
 			e.name as emitter_name, e.company_code as emitter_company_code,
-			c.name as customer_name, c.email as customer_email
+			c.name as customer_name, c.email as customer_email, i.snapshot
 		FROM invoices i
 		JOIN emitters e ON i.emitter_id = e.id
 		JOIN customers c ON i.customer_id = c.id
@@ -99,6 +151,7 @@ func (r *InvoiceRepository) GetByID(id uuid.UUID) (*models.Invoice, error) {
 	var invoice models.Invoice
 	var emitter models.Emitter
 	var customer models.Customer
+	var snapshot []byte
 	
 	err := r.db.QueryRowWithTimeout(query, id).Scan(
 		&invoice.ID, &invoice.EmitterID, &invoice.SeriesID, &invoice.CustomerID,
@@ -106,8 +159,9 @@ func (r *InvoiceRepository) GetByID(id uuid.UUID) (*models.Invoice, error) {
 		&invoice.Status, &invoice.EmailStatus, &invoice.ReferenceCUFE, &invoice.ReferenceNumber, &invoice.ReferencePtoFac,
 		&invoice.CUFE, &invoice.URLCUFE, &invoice.XMLIn, &invoice.XMLResponse, &invoice.XMLFE, &invoice.XMLProtocolo, &invoice.CAFEPDFURL,
 		&invoice.IAmb, &invoice.ITpEmis, &invoice.IDoc, &invoice.Subtotal, &invoice.ITBMSAmount, &invoice.TotalAmount,
-		&invoice.IdempotencyKey, &invoice.CreatedAt, &invoice.UpdatedAt,
-		&emitter.Name, &emitter.CompanyCode, &customer.Name, &customer.Email,
+		&invoice.CurrencyCode, &invoice.ExchangeRate, &invoice.DocSubtotal, &invoice.DocITBMSAmount, &invoice.DocTotalAmount,
+		&invoice.IdempotencyKey, &invoice.CreatedAt, &invoice.UpdatedAt, &invoice.BranchID,
+		&emitter.Name, &emitter.CompanyCode, &customer.Name, &customer.Email, &snapshot,
 	)
 	
 	if err != nil {
@@ -117,6 +171,14 @@ func (r *InvoiceRepository) GetByID(id uuid.UUID) (*models.Invoice, error) {
 		return nil, fmt.Errorf("error querying invoice: %w", err)
 	}
 
+	// Los documentos con snapshot exponen los datos completos tal como se emitieron
+	if snapshot != nil {
+		invoice.Snapshot = &models.InvoiceSnapshot{}
+		if err := json.Unmarshal(snapshot, invoice.Snapshot); err != nil {
+			return nil, fmt.Errorf("error decoding invoice snapshot: %w", err)
+		}
+	}
+
 	// Obtener items
 	items, err := r.GetItemsByInvoiceID(id)
 	if err != nil {
@@ -126,26 +188,12 @@ func (r *InvoiceRepository) GetByID(id uuid.UUID) (*models.Invoice, error) {
 	invoice.Items = items
 	invoice.Emitter = &emitter
 	invoice.Customer = &customer
-
-	return &invoice, nil
-}
-
-// GetByIdempotencyKey obtiene un invoice por clave de idempotencia
-func (r *InvoiceRepository) GetByIdempotencyKey(key string) (*models.Invoice, error) {
-	query := `
-		SELECT id FROM invoices WHERE idempotency_key = $1
-	`
-	
-	var id uuid.UUID
-	err := r.db.QueryRowWithTimeout(query, key).Scan(&id)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("error querying invoice by idempotency key: %w", err)
+	if invoice.Snapshot != nil {
+		invoice.Emitter = invoice.Snapshot.EmitterData()
+		invoice.Customer = &invoice.Snapshot.Customer
 	}
 
-	return r.GetByID(id)
+	return &invoice, nil
 }
 
 // GetItemsByInvoiceID obtiene los items de un invoice
@@ -181,31 +229,211 @@ func (r *InvoiceRepository) GetItemsByInvoiceID(invoiceID uuid.UUID) ([]models.I
 	return items, nil
 }
 
-// UpdateStatus actualiza el estado de un invoice
-func (r *InvoiceRepository) UpdateStatus(id uuid.UUID, status models.DocumentStatus) error {
-	query := `
-		UPDATE invoices 
-		SET status = $1, updated_at = $2
-		WHERE id = $3
-	`
-	
-	result, err := r.db.ExecWithTimeout(query, status, time.Now(), id)
+// SaveSnapshot guarda el snapshot de un documento que aún no lo tiene (el snapshot nunca se reemplaza)
+func (r *InvoiceRepository) SaveSnapshot(id uuid.UUID, snapshot *models.InvoiceSnapshot) error {
+	encoded, err := json.Marshal(snapshot)
 	if err != nil {
-		return fmt.Errorf("error updating invoice status: %w", err)
+		return fmt.Errorf("error encoding invoice snapshot: %w", err)
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	query := `UPDATE invoices SET snapshot = $1 WHERE id = $2 AND snapshot IS NULL`
+
+	if _, err := r.db.ExecWithTimeout(query, encoded, id); err != nil {
+		return fmt.Errorf("error saving invoice snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// invoiceOutboxReturning son las columnas de un UPDATE de invoices que alimentan su evento de outbox
+const invoiceOutboxReturning = `RETURNING emitter_id, status, email_status, doc_kind, d_ptofacdf, d_nrodf, cufe, iamb,
+	COALESCE(snapshot->'customer'->>'email', '')`
+
+// updateInvoiceReturning ejecuta dentro de tx un UPDATE de un invoice (con invoiceOutboxReturning)
+// y retorna el emisor y los datos del evento del outbox
+func updateInvoiceReturning(tx *sql.Tx, id uuid.UUID, emailError string, query string, args ...interface{}) (uuid.UUID, *models.InvoiceOutboxData, error) {
+	var emitterID uuid.UUID
+	data := &models.InvoiceOutboxData{InvoiceID: id, EmailError: emailError}
+	err := tx.QueryRow(query, args...).Scan(
+		&emitterID, &data.Status, &data.EmailStatus, &data.DocumentType, &data.PtoFacDF,
+		&data.DocumentNumber, &data.CUFE, &data.IAmb, &data.Recipient,
+	)
 	if err != nil {
-		return fmt.Errorf("error getting rows affected: %w", err)
+		if err == sql.ErrNoRows {
+			return uuid.Nil, nil, fmt.Errorf("invoice not found: %s", id)
+		}
+		return uuid.Nil, nil, fmt.Errorf("error updating invoice: %w", err)
 	}
+	return emitterID, data, nil
+}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("invoice not found: %s", id)
+// updateWithOutboxEvent ejecuta un UPDATE de un invoice (con invoiceOutboxReturning) y escribe
+// el evento del cambio en el outbox dentro de la misma transacción
+func (r *InvoiceRepository) updateWithOutboxEvent(id uuid.UUID, eventType models.OutboxEventType, emailError string, query string, args ...interface{}) error {
+	return r.db.WithTransaction(func(tx *sql.Tx) error {
+		emitterID, data, err := updateInvoiceReturning(tx, id, emailError, query, args...)
+		if err != nil {
+			return err
+		}
+		return insertInvoiceOutboxEvent(tx, emitterID, eventType, data, "")
+	})
+}
+
+// UpdateStatus cambia el status de un invoice a nombre de actor si la transición está permitida
+// (ver DocumentStatus.CanTransitionTo). La fila queda bloqueada mientras se valida, y la transición
+// se registra en el historial (con reason) y en el outbox en la misma transacción. Pedir el
+// status actual no hace nada.
+func (r *InvoiceRepository) UpdateStatus(id uuid.UUID, status models.DocumentStatus, actor, reason string) error {
+	err := r.db.WithActor(actor, func(tx *sql.Tx) error {
+		var current models.DocumentStatus
+		if err := tx.QueryRow(`SELECT status FROM invoices WHERE id = $1 FOR UPDATE`, id).Scan(&current); err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("invoice not found: %s", id)
+			}
+			return fmt.Errorf("error getting invoice status: %w", err)
+		}
+
+		if current == status {
+			return nil
+		}
+		if !current.CanTransitionTo(status) {
+			return fmt.Errorf("invalid status transition from %s to %s", current, status)
+		}
+
+		query := `
+			UPDATE invoices
+			SET status = $1, updated_at = $2
+			WHERE id = $3
+			` + invoiceOutboxReturning
+
+		emitterID, data, err := updateInvoiceReturning(tx, id, "", query, status, time.Now(), id)
+		if err != nil {
+			return err
+		}
+
+		if err := insertInvoiceEvent(tx, id, emitterID, &current, status, actor, reason); err != nil {
+			return err
+		}
+		return insertInvoiceOutboxEvent(tx, emitterID, models.OutboxInvoiceStatusChanged, data, "")
+	})
+	if err != nil {
+		return fmt.Errorf("error updating invoice status: %w", err)
 	}
+	return nil
+}
+
+// ClaimStatus toma un invoice para un proceso pasándolo a status a nombre de actor, sólo si su
+// status actual es uno de from (y la transición está permitida). Si ya está en status, lo vuelve a
+// tomar sólo cuando lleva más de staleAfter ahí (el proceso anterior se interrumpió) y registra la
+// nueva toma en el historial con reason. Retorna si lo tomó y el status en que lo encontró; la
+// fila queda bloqueada mientras se decide, así que dos procesos no toman el mismo invoice.
+func (r *InvoiceRepository) ClaimStatus(id uuid.UUID, status models.DocumentStatus, from []models.DocumentStatus, staleAfter time.Duration, actor, reason string) (bool, models.DocumentStatus, error) {
+	var claimed bool
+	var current models.DocumentStatus
+	err := r.db.WithActor(actor, func(tx *sql.Tx) error {
+		if err := tx.QueryRow(`SELECT status FROM invoices WHERE id = $1 FOR UPDATE`, id).Scan(&current); err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("invoice not found: %s", id)
+			}
+			return fmt.Errorf("error getting invoice status: %w", err)
+		}
+
+		if current == status {
+			var since sql.NullTime
+			err := tx.QueryRow(`
+				SELECT MAX(created_at) FROM invoice_events
+				WHERE invoice_id = $1 AND to_status = $2
+			`, id, status).Scan(&since)
+			if err != nil {
+				return fmt.Errorf("error getting invoice status age: %w", err)
+			}
+			if since.Valid && time.Since(since.Time) < staleAfter {
+				return nil
+			}
+
+			// Nueva toma de un proceso interrumpido: sólo queda en el historial
+			var emitterID uuid.UUID
+			err = tx.QueryRow(`UPDATE invoices SET updated_at = $1 WHERE id = $2 RETURNING emitter_id`, time.Now(), id).Scan(&emitterID)
+			if err != nil {
+				return fmt.Errorf("error updating invoice: %w", err)
+			}
+			claimed = true
+			return insertInvoiceEvent(tx, id, emitterID, &current, status, actor, reason)
+		}
+
+		allowed := false
+		for _, candidate := range from {
+			allowed = allowed || candidate == current
+		}
+		if !allowed || !current.CanTransitionTo(status) {
+			return nil
+		}
+
+		query := `
+			UPDATE invoices
+			SET status = $1, updated_at = $2
+			WHERE id = $3
+			` + invoiceOutboxReturning
 
+		emitterID, data, err := updateInvoiceReturning(tx, id, "", query, status, time.Now(), id)
+		if err != nil {
+			return err
+		}
+		if err := insertInvoiceEvent(tx, id, emitterID, &current, status, actor, ""); err != nil {
+			return err
+		}
+		claimed = true
+		return insertInvoiceOutboxEvent(tx, emitterID, models.OutboxInvoiceStatusChanged, data, "")
+	})
+	if err != nil {
+		return false, current, fmt.Errorf("error claiming invoice status: %w", err)
+	}
+	return claimed, current, nil
+}
+
+// insertInvoiceEvent registra una transición de status de un invoice dentro de la transacción del cambio
+func insertInvoiceEvent(tx *sql.Tx, invoiceID, emitterID uuid.UUID, from *models.DocumentStatus, to models.DocumentStatus, actor, reason string) error {
+	query := `
+		INSERT INTO invoice_events (invoice_id, emitter_id, from_status, to_status, actor, reason, created_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), clock_timestamp())
+	`
+
+	if _, err := tx.Exec(query, invoiceID, emitterID, from, to, actor, reason); err != nil {
+		return fmt.Errorf("error recording invoice event: %w", err)
+	}
 	return nil
 }
 
+// GetEvents obtiene el historial de status de un invoice en orden cronológico
+func (r *InvoiceRepository) GetEvents(invoiceID uuid.UUID) ([]models.InvoiceEvent, error) {
+	query := `
+		SELECT id, invoice_id, from_status, to_status, actor, reason, created_at
+		FROM invoice_events
+		WHERE invoice_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.QueryWithTimeout(query, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying invoice events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.InvoiceEvent{}
+	for rows.Next() {
+		var event models.InvoiceEvent
+		if err := rows.Scan(&event.ID, &event.InvoiceID, &event.FromStatus, &event.ToStatus, &event.Actor, &event.Reason, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning invoice event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating invoice events: %w", err)
+	}
+
+	return events, nil
+}
+
 // UpdatePACResponse actualiza la respuesta del PAC
 func (r *InvoiceRepository) UpdatePACResponse(id uuid.UUID, cufe, urlCUFE, xmlFE, xmlProtocolo string) error {
 	query := `
@@ -231,28 +459,18 @@ func (r *InvoiceRepository) UpdatePACResponse(id uuid.UUID, cufe, urlCUFE, xmlFE
 	return nil
 }
 
-// UpdateEmailStatus actualiza el estado del email
-func (r *InvoiceRepository) UpdateEmailStatus(id uuid.UUID, status models.EmailStatus) error {
+// UpdateEmailStatus actualiza el estado del email y registra el cambio en el outbox;
+// emailError es el motivo de un envío fallido
+func (r *InvoiceRepository) UpdateEmailStatus(id uuid.UUID, status models.EmailStatus, emailError string) error {
 	query := `
-		UPDATE invoices 
+		UPDATE invoices
 		SET email_status = $1, updated_at = $2
 		WHERE id = $3
-	`
-	
-	result, err := r.db.ExecWithTimeout(query, status, time.Now(), id)
-	if err != nil {
-		return fmt.Errorf("error updating email status: %w", err)
-	}
+		` + invoiceOutboxReturning
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("error getting rows affected: %w", err)
-	}
-
-	if rowsAffected == 0 {
-		return fmt.Errorf("invoice not found: %s", id)
+	if err := r.updateWithOutboxEvent(id, models.OutboxInvoiceEmailStatusChanged, emailError, query, status, time.Now(), id); err != nil {
+		return fmt.Errorf("error updating email status: %w", err)
 	}
-
 	return nil
 }
 
@@ -270,7 +488,8 @@ func (r *InvoiceRepository) GetByEmitterID(emitterID uuid.UUID, page, pageSize i
 	offset := (page - 1) * pageSize
 	query := `
 		SELECT id, doc_kind, d_nrodf, d_ptofacdf, status, email_status, 
-			   subtotal, itbms_amount, total_amount, created_at
+			   subtotal, itbms_amount, total_amount, currency_code, exchange_rate,
+			   doc_subtotal, doc_itbms_amount, doc_total_amount, created_at
 		FROM invoices
 		WHERE emitter_id = $1
 		ORDER BY created_at DESC
@@ -289,7 +508,8 @@ func (r *InvoiceRepository) GetByEmitterID(emitterID uuid.UUID, page, pageSize i
 		err := rows.Scan(
 			&invoice.ID, &invoice.DocumentType, &invoice.DocumentNumber, &invoice.PtoFacDF,
 			&invoice.Status, &invoice.EmailStatus, &invoice.Subtotal, &invoice.ITBMSAmount,
-			&invoice.TotalAmount, &invoice.CreatedAt,
+			&invoice.TotalAmount, &invoice.CurrencyCode, &invoice.ExchangeRate,
+			&invoice.DocSubtotal, &invoice.DocITBMSAmount, &invoice.DocTotalAmount, &invoice.CreatedAt,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("error scanning invoice: %w", err)
@@ -300,21 +520,6 @@ func (r *InvoiceRepository) GetByEmitterID(emitterID uuid.UUID, page, pageSize i
 	return invoices, total, nil
 }
 
-// GetNextDocumentNumber obtiene el siguiente número de documento para una serie
-func (r *InvoiceRepository) GetNextDocumentNumber(emitterID uuid.UUID, ptoFacDF string, docKind models.DocumentType) (string, error) {
-	query := `
-		SELECT get_next_folio($1, $2, $3)
-	`
-	
-	var nextNumber string
-	err := r.db.QueryRowWithTimeout(query, emitterID, ptoFacDF, docKind).Scan(&nextNumber)
-	if err != nil {
-		return "", fmt.Errorf("error getting next document number: %w", err)
-	}
-
-	return nextNumber, nil
-}
-
 // SearchByFilters busca invoices con filtros
 func (r *InvoiceRepository) SearchByFilters(filters map[string]interface{}, page, pageSize int) ([]models.Invoice, int, error) {
 	// Construir query dinámica
@@ -364,7 +569,8 @@ func (r *InvoiceRepository) SearchByFilters(filters map[string]interface{}, page
 	queryArgs := append(args, pageSize, offset)
 	query := fmt.Sprintf(`
 		SELECT id, doc_kind, d_nrodf, d_ptofacdf, status, email_status, 
-			   subtotal, itbms_amount, total_amount, created_at
+			   subtotal, itbms_amount, total_amount, currency_code, exchange_rate,
+			   doc_subtotal, doc_itbms_amount, doc_total_amount, created_at
 		FROM invoices
 		WHERE %s
 		ORDER BY created_at DESC
@@ -383,7 +589,8 @@ func (r *InvoiceRepository) SearchByFilters(filters map[string]interface{}, page
 		err := rows.Scan(
 			&invoice.ID, &invoice.DocumentType, &invoice.DocumentNumber, &invoice.PtoFacDF,
 			&invoice.Status, &invoice.EmailStatus, &invoice.Subtotal, &invoice.ITBMSAmount,
-			&invoice.TotalAmount, &invoice.CreatedAt,
+			&invoice.TotalAmount, &invoice.CurrencyCode, &invoice.ExchangeRate,
+			&invoice.DocSubtotal, &invoice.DocITBMSAmount, &invoice.DocTotalAmount, &invoice.CreatedAt,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("error scanning invoices: %w", err)
@@ -0,0 +1,281 @@
+package database
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/hypernova-labs/dgi-service/internal/secrets"
+	"github.com/sirupsen/logrus"
+)
+
+// testActor es el autor de los cambios hechos por los tests
+var testActor = models.SystemActor("test")
+
+// openTestDB conecta a la BD de TEST_DATABASE_URL, que debe tener aplicados db_pg/init y
+// db/migrations, con llaves de cifrado de prueba; sin la variable el test se omite
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	sqlDB, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("error opening test database: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(50)
+	if err := sqlDB.Ping(); err != nil {
+		t.Fatalf("error pinging test database: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("error generating test encryption key: %v", err)
+	}
+	envelope, err := secrets.NewEnvelope(map[string][]byte{"test": key}, "")
+	if err != nil {
+		t.Fatalf("error creating test envelope: %v", err)
+	}
+
+	return &DB{DB: sqlDB, Secrets: envelope}
+}
+
+// testLogger retorna un logger que sólo muestra errores
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	return logger
+}
+
+// invoiceFixture es un emisor de pruebas con su sucursal principal, una serie de facturas y un cliente
+type invoiceFixture struct {
+	emitter  *models.Emitter
+	branch   *models.Branch
+	series   *models.EmitterSeries
+	customer *models.Customer
+}
+
+// newInvoiceFixture crea un emisor de pruebas con datos únicos; se elimina (en cascada) al terminar el test
+func newInvoiceFixture(t *testing.T, db *DB, logger *logrus.Logger) *invoiceFixture {
+	t.Helper()
+
+	id := uuid.New()
+	suffix := strings.ReplaceAll(id.String(), "-", "")
+	now := time.Now()
+	emitter := &models.Emitter{
+		ID:                 id,
+		Name:               "Test Emitter " + suffix[:8],
+		CompanyCode:        "T" + suffix[:9],
+		RUCTipo:            "2",
+		RUCNumero:          fmt.Sprintf("%d", now.UnixNano()%1_000_000_000_000),
+		RUCDV:              "00",
+		SucEm:              "0001",
+		PtoFacDefault:      "001",
+		IAmb:               models.IAmbProduction,
+		ITpEmisDefault:     "01",
+		IDocDefault:        "01",
+		Email:              "test-" + suffix[:8] + "@example.com",
+		PACAPIKey:          "test",
+		PACSubscriptionKey: "test",
+		IsActive:           true,
+		AutoCreateSeries:   true,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+
+	emitterRepo := NewEmitterRepository(db, logger)
+	if err := emitterRepo.Create(emitter, testActor); err != nil {
+		t.Fatalf("error creating test emitter: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := db.Exec(`DELETE FROM emitters WHERE id = $1`, emitter.ID); err != nil {
+			t.Logf("error deleting test emitter %s: %v", emitter.ID, err)
+		}
+	})
+
+	branch, err := NewBranchRepository(db, logger).EnsureMain(emitter)
+	if err != nil {
+		t.Fatalf("error creating test branch: %v", err)
+	}
+
+	series, err := emitterRepo.CreateSeries(emitter.ID, branch.ID, emitter.IAmb, &models.CreateSeriesRequest{
+		PtoFacDF: emitter.PtoFacDefault,
+		DocKind:  models.DocumentTypeInvoice,
+	}, testActor)
+	if err != nil {
+		t.Fatalf("error creating test series: %v", err)
+	}
+
+	customer, err := NewCustomerRepository(db, logger).Create(&models.CreateCustomerRequest{
+		Name:          "Test Customer",
+		Email:         "customer-" + suffix[:8] + "@example.com",
+		RecipientType: models.RecipientTypeConsumidorFinal,
+	}, emitter.ID, testActor)
+	if err != nil {
+		t.Fatalf("error creating test customer: %v", err)
+	}
+
+	return &invoiceFixture{emitter: emitter, branch: branch, series: series, customer: customer}
+}
+
+// withBranch crea otra sucursal del emisor con el mismo punto de facturación y su propia serie de
+// facturas; retorna un fixture que emite en ella
+func (f *invoiceFixture) withBranch(t *testing.T, db *DB, logger *logrus.Logger, code string) *invoiceFixture {
+	t.Helper()
+
+	branch, err := NewBranchRepository(db, logger).Create(f.emitter.ID, &models.CreateBranchRequest{
+		Code:          code,
+		Name:          "Test Branch " + code,
+		PtoFacDefault: f.series.PtoFacDF,
+	})
+	if err != nil {
+		t.Fatalf("error creating test branch %s: %v", code, err)
+	}
+
+	series, err := NewEmitterRepository(db, logger).CreateSeries(f.emitter.ID, branch.ID, f.series.IAmb, &models.CreateSeriesRequest{
+		PtoFacDF: f.series.PtoFacDF,
+		DocKind:  models.DocumentTypeInvoice,
+	}, testActor)
+	if err != nil {
+		t.Fatalf("error creating test series for branch %s: %v", code, err)
+	}
+
+	return &invoiceFixture{emitter: f.emitter, branch: branch, series: series, customer: f.customer}
+}
+
+// newInvoice arma un documento de la serie del fixture con un ítem; el folio lo asigna Create
+func (f *invoiceFixture) newInvoice() (*models.Invoice, []models.InvoiceItem) {
+	now := time.Now()
+	invoice := &models.Invoice{
+		ID:             uuid.New(),
+		EmitterID:      f.emitter.ID,
+		SeriesID:       f.series.ID,
+		BranchID:       &f.branch.ID,
+		CustomerID:     f.customer.ID,
+		DocumentType:   models.DocumentTypeInvoice,
+		PtoFacDF:       f.series.PtoFacDF,
+		Status:         models.DocumentStatusReceived,
+		EmailStatus:    models.EmailStatusPending,
+		IAmb:           f.series.IAmb,
+		ITpEmis:        f.emitter.ITpEmisDefault,
+		IDoc:           f.emitter.IDocDefault,
+		Subtotal:       1,
+		TotalAmount:    1,
+		CurrencyCode:   "USD",
+		ExchangeRate:   1,
+		DocSubtotal:    1,
+		DocTotalAmount: 1,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	items := []models.InvoiceItem{{
+		ID:          uuid.New(),
+		InvoiceID:   invoice.ID,
+		LineNo:      1,
+		Description: "Test item",
+		Quantity:    1,
+		UnitPrice:   1,
+		ITBMSRate:   "00",
+		LineTotal:   1,
+		CreatedAt:   now,
+	}}
+	return invoice, items
+}
+
+// TestInvoiceRepositoryCreateConcurrentFolios crea documentos de una misma serie en paralelo y
+// verifica que los folios asignados sean únicos y consecutivos, sin huecos en la serie
+func TestInvoiceRepositoryCreateConcurrentFolios(t *testing.T) {
+	db := openTestDB(t)
+	logger := testLogger()
+	fixture := newInvoiceFixture(t, db, logger)
+	invoiceRepo := NewInvoiceRepository(db, logger)
+
+	const workers = 40
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			invoice, items := fixture.newInvoice()
+			errs <- invoiceRepo.Create(invoice, items, testActor, nil, nil)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("error creating invoice: %v", err)
+		}
+	}
+
+	rows, err := db.Query(`SELECT d_nrodf FROM invoices WHERE series_id = $1`, fixture.series.ID)
+	if err != nil {
+		t.Fatalf("error querying invoice numbers: %v", err)
+	}
+	defer rows.Close()
+
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var number string
+		if err := rows.Scan(&number); err != nil {
+			t.Fatalf("error scanning invoice number: %v", err)
+		}
+		if seen[number] {
+			t.Errorf("duplicate document_number %s", number)
+		}
+		seen[number] = true
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("error iterating invoice numbers: %v", err)
+	}
+
+	if len(seen) != workers {
+		t.Fatalf("got %d distinct document numbers, want %d", len(seen), workers)
+	}
+	for n := 1; n <= workers; n++ {
+		if number := fmt.Sprintf("%010d", n); !seen[number] {
+			t.Errorf("document_number %s was not assigned", number)
+		}
+	}
+
+	gaps, err := NewEmitterRepository(db, logger).GetSeriesGaps(fixture.series.ID)
+	if err != nil {
+		t.Fatalf("error getting series gaps: %v", err)
+	}
+	if len(gaps) != 0 {
+		t.Errorf("series has gaps: %+v", gaps)
+	}
+}
+
+// TestInvoiceRepositoryCreateSameFolioInTwoBranches emite el primer documento de dos sucursales
+// que comparten punto de facturación: ambas series asignan el folio 1 sin chocar entre sí
+func TestInvoiceRepositoryCreateSameFolioInTwoBranches(t *testing.T) {
+	db := openTestDB(t)
+	logger := testLogger()
+	mainBranch := newInvoiceFixture(t, db, logger)
+	otherBranch := mainBranch.withBranch(t, db, logger, "0002")
+	invoiceRepo := NewInvoiceRepository(db, logger)
+
+	for _, fixture := range []*invoiceFixture{mainBranch, otherBranch} {
+		invoice, items := fixture.newInvoice()
+		if err := invoiceRepo.Create(invoice, items, testActor, nil, nil); err != nil {
+			t.Fatalf("error creating invoice for branch %s: %v", fixture.branch.Code, err)
+		}
+		if want := fmt.Sprintf("%010d", 1); invoice.DocumentNumber != want {
+			t.Errorf("branch %s got document_number %s, want %s", fixture.branch.Code, invoice.DocumentNumber, want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// outboxAggregateInvoice identifica los eventos de documentos fiscales en el outbox
+const outboxAggregateInvoice = "invoice"
+
+// OutboxRepository maneja las operaciones de base de datos del outbox transaccional
+type OutboxRepository struct {
+	db     *DB
+	logger *logrus.Logger
+}
+
+// NewOutboxRepository crea una nueva instancia del repositorio
+func NewOutboxRepository(db *DB, logger *logrus.Logger) *OutboxRepository {
+	return &OutboxRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// insertInvoiceOutboxEvent escribe un evento de un documento dentro de la transacción del cambio.
+// Un dedupKey vacío usa el ID del evento, para cambios que pueden repetirse (p. ej. status).
+func insertInvoiceOutboxEvent(tx *sql.Tx, emitterID uuid.UUID, eventType models.OutboxEventType, data *models.InvoiceOutboxData, dedupKey string) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("error encoding outbox event: %w", err)
+	}
+
+	id := uuid.New()
+	if dedupKey == "" {
+		dedupKey = fmt.Sprintf("%s:%s:%s", eventType, data.InvoiceID, id)
+	}
+
+	query := `
+		INSERT INTO outbox_events (
+			id, aggregate_type, aggregate_id, emitter_id, event_type, payload, dedup_key,
+			next_attempt_at, created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, NOW(), clock_timestamp()
+		)
+		ON CONFLICT (dedup_key) DO NOTHING
+	`
+
+	_, err = tx.Exec(query, id, outboxAggregateInvoice, data.InvoiceID, emitterID, eventType, payload, dedupKey)
+	if err != nil {
+		return fmt.Errorf("error writing outbox event: %w", err)
+	}
+	return nil
+}
+
+// outboxEventColumns son las columnas de outbox_events en el orden de scanOutboxEvent
+const outboxEventColumns = `id, aggregate_type, aggregate_id, emitter_id, event_type, payload, dedup_key,
+	attempts, last_error, next_attempt_at, published_at, created_at`
+
+// scanOutboxEvent lee un evento desde una fila con outboxEventColumns
+func scanOutboxEvent(row rowScanner) (*models.OutboxEvent, error) {
+	var event models.OutboxEvent
+	var payload []byte
+	err := row.Scan(
+		&event.ID, &event.AggregateType, &event.AggregateID, &event.EmitterID, &event.EventType, &payload,
+		&event.DedupKey, &event.Attempts, &event.LastError, &event.NextAttemptAt, &event.PublishedAt, &event.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	event.Payload = payload
+	return &event, nil
+}
+
+// ClaimDue toma hasta limit eventos sin publicar cuyo intento ya venció, en orden de creación.
+// Su next_attempt_at se mueve a leaseUntil para que otra instancia no los tome mientras se publican.
+func (r *OutboxRepository) ClaimDue(limit int, leaseUntil time.Time) ([]models.OutboxEvent, error) {
+	query := `
+		UPDATE outbox_events
+		SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM outbox_events
+			WHERE published_at IS NULL AND next_attempt_at <= NOW()
+			ORDER BY created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + outboxEventColumns
+
+	rows, err := r.db.QueryWithTimeout(query, limit, leaseUntil)
+	if err != nil {
+		return nil, fmt.Errorf("error claiming outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.OutboxEvent
+	for rows.Next() {
+		event, err := scanOutboxEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning outbox event: %w", err)
+		}
+		events = append(events, *event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating outbox events: %w", err)
+	}
+
+	// RETURNING no garantiza el orden del subquery
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].CreatedAt.Before(events[j].CreatedAt)
+	})
+	return events, nil
+}
+
+// MarkPublished registra que un evento llegó a todos sus destinos
+func (r *OutboxRepository) MarkPublished(id uuid.UUID) error {
+	query := `
+		UPDATE outbox_events
+		SET published_at = NOW(), attempts = attempts + 1, last_error = NULL
+		WHERE id = $1
+	`
+
+	if _, err := r.db.ExecWithTimeout(query, id); err != nil {
+		return fmt.Errorf("error marking outbox event as published: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed registra un intento fallido de publicación y programa el siguiente
+func (r *OutboxRepository) MarkFailed(id uuid.UUID, lastError string, nextAttemptAt time.Time) error {
+	query := `
+		UPDATE outbox_events
+		SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3
+		WHERE id = $1
+	`
+
+	if _, err := r.db.ExecWithTimeout(query, id, lastError, nextAttemptAt); err != nil {
+		return fmt.Errorf("error marking outbox event as failed: %w", err)
+	}
+	return nil
+}
+
+// DeletePublishedBefore elimina los eventos publicados antes de una fecha
+func (r *OutboxRepository) DeletePublishedBefore(before time.Time) (int64, error) {
+	result, err := r.db.ExecWithTimeout(`DELETE FROM outbox_events WHERE published_at IS NOT NULL AND published_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting published outbox events: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error getting rows affected: %w", err)
+	}
+	return deleted, nil
+}
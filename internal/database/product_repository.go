@@ -24,8 +24,8 @@ func NewProductRepository(db *DB, logger *logrus.Logger) *ProductRepository {
 	}
 }
 
-// Create crea un nuevo producto
-func (r *ProductRepository) Create(req *models.CreateProductRequest, emitterID uuid.UUID) (*models.Product, error) {
+// Create crea un nuevo producto a nombre de actor
+func (r *ProductRepository) Create(req *models.CreateProductRequest, emitterID uuid.UUID, actor string) (*models.Product, error) {
 	product := &models.Product{
 		ID:          uuid.New(),
 		EmitterID:   emitterID,
@@ -40,6 +40,7 @@ func (r *ProductRepository) Create(req *models.CreateProductRequest, emitterID u
 		UpdatedAt:   time.Now(),
 	}
 
+	// Un producto desactivado con el mismo SKU se reactiva con los nuevos datos
 	query := `
 		INSERT INTO products (
 			id, emitter_id, sku, description, cpbs_abr, cpbs_cmp,
@@ -47,14 +48,25 @@ func (r *ProductRepository) Create(req *models.CreateProductRequest, emitterID u
 		) VALUES (
 			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
 		)
+		ON CONFLICT (emitter_id, sku) DO UPDATE SET
+			description = EXCLUDED.description, cpbs_abr = EXCLUDED.cpbs_abr, cpbs_cmp = EXCLUDED.cpbs_cmp,
+			unit_price = EXCLUDED.unit_price, tax_rate = EXCLUDED.tax_rate,
+			is_active = true, updated_at = EXCLUDED.updated_at
+		WHERE products.is_active = false
+		RETURNING id, created_at
 	`
 	
-	_, err := r.db.ExecWithTimeout(query,
+	err := r.db.QueryRowAs(actor, func(row *sql.Row) error {
+		return row.Scan(&product.ID, &product.CreatedAt)
+	}, query,
 		product.ID, product.EmitterID, product.SKU, product.Description,
 		product.CPBSAbr, product.CPBSCmp, product.UnitPrice, product.TaxRate,
 		product.IsActive, product.CreatedAt, product.UpdatedAt,
 	)
 	
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("product with SKU %s already exists", product.SKU)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error creating product: %w", err)
 	}
@@ -62,6 +74,37 @@ func (r *ProductRepository) Create(req *models.CreateProductRequest, emitterID u
 	return product, nil
 }
 
+// Upsert crea o actualiza un producto por (emitter_id, sku), reactivándolo si estaba inactivo.
+// Retorna true si el producto fue creado. El cambio queda a nombre de actor.
+func (r *ProductRepository) Upsert(req *models.CreateProductRequest, emitterID uuid.UUID, actor string) (bool, error) {
+	query := `
+		INSERT INTO products (
+			id, emitter_id, sku, description, cpbs_abr, cpbs_cmp,
+			unit_price, tax_rate, is_active, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, true, $9, $9
+		)
+		ON CONFLICT (emitter_id, sku) DO UPDATE SET
+			description = EXCLUDED.description, cpbs_abr = EXCLUDED.cpbs_abr, cpbs_cmp = EXCLUDED.cpbs_cmp,
+			unit_price = EXCLUDED.unit_price, tax_rate = EXCLUDED.tax_rate,
+			is_active = true, updated_at = EXCLUDED.updated_at
+		RETURNING (xmax = 0) AS inserted
+	`
+
+	var inserted bool
+	err := r.db.QueryRowAs(actor, func(row *sql.Row) error {
+		return row.Scan(&inserted)
+	}, query,
+		uuid.New(), emitterID, req.SKU, req.Description, req.CPBSAbr, req.CPBSCmp,
+		req.UnitPrice, req.TaxRate, time.Now(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("error upserting product: %w", err)
+	}
+
+	return inserted, nil
+}
+
 // GetByID obtiene un producto por ID
 func (r *ProductRepository) GetByID(id uuid.UUID) (*models.Product, error) {
 	query := `
@@ -88,6 +131,32 @@ func (r *ProductRepository) GetByID(id uuid.UUID) (*models.Product, error) {
 	return &product, nil
 }
 
+// GetByEmitterAndID obtiene un producto activo de un emisor
+func (r *ProductRepository) GetByEmitterAndID(emitterID, id uuid.UUID) (*models.Product, error) {
+	query := `
+		SELECT id, emitter_id, sku, description, cpbs_abr, cpbs_cmp,
+			   unit_price, tax_rate, is_active, created_at, updated_at
+		FROM products
+		WHERE id = $1 AND emitter_id = $2 AND is_active = true
+	`
+	
+	var product models.Product
+	err := r.db.QueryRowWithTimeout(query, id, emitterID).Scan(
+		&product.ID, &product.EmitterID, &product.SKU, &product.Description,
+		&product.CPBSAbr, &product.CPBSCmp, &product.UnitPrice, &product.TaxRate,
+		&product.IsActive, &product.CreatedAt, &product.UpdatedAt,
+	)
+	
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("product not found: %s", id)
+		}
+		return nil, fmt.Errorf("error querying product: %w", err)
+	}
+
+	return &product, nil
+}
+
 // GetBySKU obtiene un producto por SKU y emisor
 func (r *ProductRepository) GetBySKU(emitterID uuid.UUID, sku string) (*models.Product, error) {
 	query := `
@@ -147,18 +216,62 @@ func (r *ProductRepository) GetByEmitterID(emitterID uuid.UUID) ([]models.Produc
 	return products, nil
 }
 
-// Update actualiza un producto existente
-func (r *ProductRepository) Update(id uuid.UUID, req *models.CreateProductRequest) (*models.Product, error) {
+// List obtiene los productos de un emisor con paginación
+func (r *ProductRepository) List(emitterID uuid.UUID, includeInactive bool, page, pageSize int) ([]models.Product, int, error) {
+	countQuery := `
+		SELECT COUNT(*) FROM products
+		WHERE emitter_id = $1 AND ($2 OR is_active = true)
+	`
+	var total int
+	if err := r.db.QueryRowWithTimeout(countQuery, emitterID, includeInactive).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting products: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	query := `
+		SELECT id, emitter_id, sku, description, cpbs_abr, cpbs_cmp,
+			   unit_price, tax_rate, is_active, created_at, updated_at
+		FROM products
+		WHERE emitter_id = $1 AND ($2 OR is_active = true)
+		ORDER BY description
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.db.QueryWithTimeout(query, emitterID, includeInactive, pageSize, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying products: %w", err)
+	}
+	defer rows.Close()
+
+	products := []models.Product{}
+	for rows.Next() {
+		var product models.Product
+		err := rows.Scan(
+			&product.ID, &product.EmitterID, &product.SKU, &product.Description,
+			&product.CPBSAbr, &product.CPBSCmp, &product.UnitPrice, &product.TaxRate,
+			&product.IsActive, &product.CreatedAt, &product.UpdatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning product: %w", err)
+		}
+		products = append(products, product)
+	}
+
+	return products, total, nil
+}
+
+// Update actualiza un producto activo de un emisor a nombre de actor
+func (r *ProductRepository) Update(emitterID uuid.UUID, product *models.Product, actor string) (*models.Product, error) {
 	query := `
 		UPDATE products 
-		SET description = $1, cpbs_abr = $2, cpbs_cmp = $3, 
-		    unit_price = $4, tax_rate = $5, updated_at = $6
-		WHERE id = $7 AND is_active = true
+		SET sku = $1, description = $2, cpbs_abr = $3, cpbs_cmp = $4, 
+		    unit_price = $5, tax_rate = $6, updated_at = $7
+		WHERE id = $8 AND emitter_id = $9 AND is_active = true
 	`
 	
-	result, err := r.db.ExecWithTimeout(query,
-		req.Description, req.CPBSAbr, req.CPBSCmp,
-		req.UnitPrice, req.TaxRate, time.Now(), id,
+	result, err := r.db.ExecAs(actor, query,
+		product.SKU, product.Description, product.CPBSAbr, product.CPBSCmp,
+		product.UnitPrice, product.TaxRate, time.Now(), product.ID, emitterID,
 	)
 	
 	if err != nil {
@@ -171,22 +284,22 @@ func (r *ProductRepository) Update(id uuid.UUID, req *models.CreateProductReques
 	}
 
 	if rowsAffected == 0 {
-		return nil, fmt.Errorf("product not found: %s", id)
+		return nil, fmt.Errorf("product not found: %s", product.ID)
 	}
 
 	// Obtener el producto actualizado
-	return r.GetByID(id)
+	return r.GetByEmitterAndID(emitterID, product.ID)
 }
 
-// Delete marca un producto como inactivo
-func (r *ProductRepository) Delete(id uuid.UUID) error {
+// Delete marca un producto de un emisor como inactivo a nombre de actor
+func (r *ProductRepository) Delete(emitterID, id uuid.UUID, actor string) error {
 	query := `
 		UPDATE products 
 		SET is_active = false, updated_at = $1
-		WHERE id = $2
+		WHERE id = $2 AND emitter_id = $3 AND is_active = true
 	`
 	
-	result, err := r.db.ExecWithTimeout(query, time.Now(), id)
+	result, err := r.db.ExecAs(actor, query, time.Now(), id, emitterID)
 	if err != nil {
 		return fmt.Errorf("error deleting product: %w", err)
 	}
@@ -203,19 +316,20 @@ func (r *ProductRepository) Delete(id uuid.UUID) error {
 	return nil
 }
 
-// SearchByDescription busca productos por descripción
-func (r *ProductRepository) SearchByDescription(emitterID uuid.UUID, description string) ([]models.Product, error) {
+// SearchByDescription busca productos por descripción o SKU
+func (r *ProductRepository) SearchByDescription(emitterID uuid.UUID, description string, limit int) ([]models.Product, error) {
 	query := `
 		SELECT id, emitter_id, sku, description, cpbs_abr, cpbs_cmp,
 			   unit_price, tax_rate, is_active, created_at, updated_at
 		FROM products
 		WHERE emitter_id = $1 AND is_active = true 
-		AND description ILIKE $2
+		AND (description ILIKE $2 OR sku ILIKE $2)
 		ORDER BY description
+		LIMIT $3
 	`
 	
 	searchTerm := "%" + description + "%"
-	rows, err := r.db.QueryWithTimeout(query, emitterID, searchTerm)
+	rows, err := r.db.QueryWithTimeout(query, emitterID, searchTerm, limit)
 	if err != nil {
 		return nil, fmt.Errorf("error searching products: %w", err)
 	}
@@ -0,0 +1,577 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// WebhookRepository maneja las operaciones de base de datos para endpoints y entregas de webhooks
+type WebhookRepository struct {
+	db     *DB
+	logger *logrus.Logger
+}
+
+// NewWebhookRepository crea una nueva instancia del repositorio
+func NewWebhookRepository(db *DB, logger *logrus.Logger) *WebhookRepository {
+	return &WebhookRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// webhookEndpointColumns son las columnas de webhook_endpoints en el orden de scanWebhookEndpoint
+const webhookEndpointColumns = `id, emitter_id, url, secret, event_types, description, is_active, created_at, updated_at`
+
+// scanWebhookEndpoint lee un endpoint desde una fila con webhookEndpointColumns
+func scanWebhookEndpoint(row rowScanner) (*models.WebhookEndpoint, error) {
+	var endpoint models.WebhookEndpoint
+	var eventTypes pq.StringArray
+	err := row.Scan(
+		&endpoint.ID, &endpoint.EmitterID, &endpoint.URL, &endpoint.Secret, &eventTypes,
+		&endpoint.Description, &endpoint.IsActive, &endpoint.CreatedAt, &endpoint.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint.EventTypes = make([]models.WebhookEventType, len(eventTypes))
+	for i, eventType := range eventTypes {
+		endpoint.EventTypes[i] = models.WebhookEventType(eventType)
+	}
+	return &endpoint, nil
+}
+
+// eventTypesArray convierte los eventos de un endpoint al arreglo de PostgreSQL
+func eventTypesArray(eventTypes []models.WebhookEventType) pq.StringArray {
+	array := make(pq.StringArray, len(eventTypes))
+	for i, eventType := range eventTypes {
+		array[i] = string(eventType)
+	}
+	return array
+}
+
+// webhookSecretColumn es la columna del secreto de firma, cifrado como las credenciales PAC
+const webhookSecretColumn = "webhook_endpoints.secret"
+
+// decryptEndpointSecret descifra en el lugar el secreto de firma leído de la base
+func (r *WebhookRepository) decryptEndpointSecret(endpoint *models.WebhookEndpoint) error {
+	secret, err := r.db.decryptSecret(endpoint.Secret, webhookSecretColumn, endpoint.ID)
+	if err != nil {
+		return fmt.Errorf("error decrypting webhook secret: %w", err)
+	}
+	endpoint.Secret = secret
+	return nil
+}
+
+// CreateEndpoint registra un endpoint de webhooks; el secreto de firma se guarda cifrado
+func (r *WebhookRepository) CreateEndpoint(endpoint *models.WebhookEndpoint) error {
+	secret, err := r.db.encryptSecret(endpoint.Secret, webhookSecretColumn, endpoint.ID)
+	if err != nil {
+		return fmt.Errorf("error encrypting webhook secret: %w", err)
+	}
+
+	query := `
+		INSERT INTO webhook_endpoints (
+			id, emitter_id, url, secret, event_types, description, is_active, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9
+		)
+	`
+
+	_, err = r.db.ExecWithTimeout(query,
+		endpoint.ID, endpoint.EmitterID, endpoint.URL, secret, eventTypesArray(endpoint.EventTypes),
+		endpoint.Description, endpoint.IsActive, endpoint.CreatedAt, endpoint.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("error creating webhook endpoint: %w", err)
+	}
+
+	return nil
+}
+
+// GetEndpoint obtiene un endpoint de un emisor
+func (r *WebhookRepository) GetEndpoint(emitterID, id uuid.UUID) (*models.WebhookEndpoint, error) {
+	query := `SELECT ` + webhookEndpointColumns + ` FROM webhook_endpoints WHERE id = $1 AND emitter_id = $2`
+
+	endpoint, err := scanWebhookEndpoint(r.db.QueryRowWithTimeout(query, id, emitterID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook endpoint not found: %s", id)
+		}
+		return nil, fmt.Errorf("error querying webhook endpoint: %w", err)
+	}
+
+	if err := r.decryptEndpointSecret(endpoint); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// ListEndpoints obtiene los endpoints de un emisor
+func (r *WebhookRepository) ListEndpoints(emitterID uuid.UUID) ([]models.WebhookEndpoint, error) {
+	query := `SELECT ` + webhookEndpointColumns + ` FROM webhook_endpoints WHERE emitter_id = $1 ORDER BY created_at`
+	return r.queryEndpoints(query, emitterID)
+}
+
+// ListSubscribedEndpoints obtiene los endpoints activos de un emisor suscritos a un evento
+func (r *WebhookRepository) ListSubscribedEndpoints(emitterID uuid.UUID, eventType models.WebhookEventType) ([]models.WebhookEndpoint, error) {
+	query := `
+		SELECT ` + webhookEndpointColumns + `
+		FROM webhook_endpoints
+		WHERE emitter_id = $1 AND is_active = true AND $2 = ANY(event_types)
+		ORDER BY created_at
+	`
+	return r.queryEndpoints(query, emitterID, string(eventType))
+}
+
+func (r *WebhookRepository) queryEndpoints(query string, args ...interface{}) ([]models.WebhookEndpoint, error) {
+	rows, err := r.db.QueryWithTimeout(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying webhook endpoints: %w", err)
+	}
+	defer rows.Close()
+
+	endpoints := []models.WebhookEndpoint{}
+	for rows.Next() {
+		endpoint, err := scanWebhookEndpoint(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning webhook endpoint: %w", err)
+		}
+		if err := r.decryptEndpointSecret(endpoint); err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, *endpoint)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook endpoints: %w", err)
+	}
+
+	return endpoints, nil
+}
+
+// UpdateEndpoint actualiza la URL, los eventos, la descripción y el estado de un endpoint
+func (r *WebhookRepository) UpdateEndpoint(endpoint *models.WebhookEndpoint) (*models.WebhookEndpoint, error) {
+	query := `
+		UPDATE webhook_endpoints
+		SET url = $3, event_types = $4, description = $5, is_active = $6, updated_at = NOW()
+		WHERE id = $1 AND emitter_id = $2
+		RETURNING ` + webhookEndpointColumns
+
+	updated, err := scanWebhookEndpoint(r.db.QueryRowWithTimeout(query,
+		endpoint.ID, endpoint.EmitterID, endpoint.URL, eventTypesArray(endpoint.EventTypes),
+		endpoint.Description, endpoint.IsActive,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook endpoint not found: %s", endpoint.ID)
+		}
+		return nil, fmt.Errorf("error updating webhook endpoint: %w", err)
+	}
+
+	if err := r.decryptEndpointSecret(updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// EncryptSecrets cifra con la llave activa los secretos de firma guardados en texto plano o con
+// una llave anterior (rotación). Retorna cuántos endpoints se actualizaron.
+func (r *WebhookRepository) EncryptSecrets() (int, error) {
+	if r.db.Secrets == nil {
+		return 0, fmt.Errorf("encryption keys not configured")
+	}
+
+	rows, err := r.db.QueryWithTimeout(`SELECT id, secret FROM webhook_endpoints`)
+	if err != nil {
+		return 0, fmt.Errorf("error querying webhook endpoints: %w", err)
+	}
+
+	type pending struct {
+		id     uuid.UUID
+		stored string
+	}
+	var toUpdate []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.stored); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("error scanning webhook endpoint: %w", err)
+		}
+		if r.db.Secrets.NeedsReencryption(p.stored) {
+			toUpdate = append(toUpdate, p)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, fmt.Errorf("error iterating webhook endpoints: %w", err)
+	}
+	rows.Close()
+
+	updated := 0
+	for _, p := range toUpdate {
+		secret, err := r.db.decryptSecret(p.stored, webhookSecretColumn, p.id)
+		if err != nil {
+			return updated, fmt.Errorf("webhook endpoint %s: %w", p.id, err)
+		}
+		encrypted, err := r.db.encryptSecret(secret, webhookSecretColumn, p.id)
+		if err != nil {
+			return updated, fmt.Errorf("webhook endpoint %s: %w", p.id, err)
+		}
+
+		// Sólo si nadie cambió el secreto mientras tanto
+		result, err := r.db.ExecWithTimeout(`UPDATE webhook_endpoints SET secret = $1 WHERE id = $2 AND secret = $3`, encrypted, p.id, p.stored)
+		if err != nil {
+			return updated, fmt.Errorf("error updating webhook endpoint %s: %w", p.id, err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			updated++
+		}
+	}
+
+	return updated, nil
+}
+
+// DeleteEndpoint elimina un endpoint y sus entregas
+func (r *WebhookRepository) DeleteEndpoint(emitterID, id uuid.UUID) error {
+	result, err := r.db.ExecWithTimeout(`DELETE FROM webhook_endpoints WHERE id = $1 AND emitter_id = $2`, id, emitterID)
+	if err != nil {
+		return fmt.Errorf("error deleting webhook endpoint: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("webhook endpoint not found: %s", id)
+	}
+
+	return nil
+}
+
+// webhookDeliveryColumns son las columnas de webhooks en el orden de scanWebhookDelivery
+const webhookDeliveryColumns = `id, emitter_id, endpoint_id, event_id, event_type, payload, status, attempts,
+	max_attempts, last_error, last_status_code, next_retry_at, delivered_at, created_at, updated_at`
+
+// scanWebhookDelivery lee una entrega desde una fila con webhookDeliveryColumns
+func scanWebhookDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var payload []byte
+	err := row.Scan(
+		&delivery.ID, &delivery.EmitterID, &delivery.EndpointID, &delivery.EventID, &delivery.EventType,
+		&payload, &delivery.Status, &delivery.Attempts, &delivery.MaxAttempts, &delivery.LastError,
+		&delivery.LastStatusCode, &delivery.NextRetryAt, &delivery.DeliveredAt, &delivery.CreatedAt, &delivery.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	delivery.Payload = payload
+	return &delivery, nil
+}
+
+// enqueueDeliveryQuery inserta una entrega para su primer intento, salvo que ya exista su DedupKey
+const enqueueDeliveryQuery = `
+	INSERT INTO webhooks (
+		id, emitter_id, endpoint_id, event_id, event_type, payload, status, attempts,
+		max_attempts, next_retry_at, dedup_key, created_at, updated_at
+	) VALUES (
+		$1, $2, $3, $4, $5, $6, $7, 0, $8, $9, $10, $11, $11
+	)
+	ON CONFLICT (dedup_key) DO NOTHING
+`
+
+// enqueueDeliveryArgs retorna los parámetros de enqueueDeliveryQuery
+func enqueueDeliveryArgs(delivery *models.WebhookDelivery) []interface{} {
+	return []interface{}{
+		delivery.ID, delivery.EmitterID, delivery.EndpointID, delivery.EventID, delivery.EventType,
+		[]byte(delivery.Payload), delivery.Status, delivery.MaxAttempts, delivery.NextRetryAt,
+		delivery.DedupKey, delivery.CreatedAt,
+	}
+}
+
+// EnqueueDelivery encola una entrega para su primer intento. Si ya existe una entrega
+// con el mismo DedupKey no se encola de nuevo.
+func (r *WebhookRepository) EnqueueDelivery(delivery *models.WebhookDelivery) error {
+	if _, err := r.db.ExecWithTimeout(enqueueDeliveryQuery, enqueueDeliveryArgs(delivery)...); err != nil {
+		return fmt.Errorf("error enqueuing webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// EnqueueImmediateDelivery encola una entrega de un solo intento (replay o evento de prueba) si su
+// endpoint tuvo menos de limit entregas de un solo intento en la última window; si no, retorna un
+// *models.RateLimitError. El endpoint queda bloqueado mientras se cuenta para que dos pedidos
+// simultáneos no superen el límite.
+func (r *WebhookRepository) EnqueueImmediateDelivery(delivery *models.WebhookDelivery, limit int, window time.Duration) error {
+	return r.db.WithTransaction(func(tx *sql.Tx) error {
+		var endpointID uuid.UUID
+		err := tx.QueryRow(`SELECT id FROM webhook_endpoints WHERE id = $1 FOR UPDATE`, delivery.EndpointID).Scan(&endpointID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("webhook endpoint not found: %s", delivery.EndpointID)
+			}
+			return fmt.Errorf("error locking webhook endpoint: %w", err)
+		}
+
+		since := time.Now().Add(-window)
+		var count int
+		var oldest sql.NullTime
+		err = tx.QueryRow(`
+			SELECT COUNT(*), MIN(created_at)
+			FROM webhooks
+			WHERE endpoint_id = $1 AND max_attempts = 1 AND created_at > $2
+		`, endpointID, since).Scan(&count, &oldest)
+		if err != nil {
+			return fmt.Errorf("error counting immediate webhook deliveries: %w", err)
+		}
+		if count >= limit {
+			return &models.RateLimitError{
+				Message:    fmt.Sprintf("webhook endpoint %s reached %d immediate deliveries per %s", endpointID, limit, window),
+				RetryAfter: time.Until(oldest.Time.Add(window)),
+			}
+		}
+
+		if _, err := tx.Exec(enqueueDeliveryQuery, enqueueDeliveryArgs(delivery)...); err != nil {
+			return fmt.Errorf("error enqueuing webhook delivery: %w", err)
+		}
+		return nil
+	})
+}
+
+// ClaimDueDeliveries toma hasta limit entregas pendientes cuyo reintento ya venció. Su next_retry_at
+// se mueve a leaseUntil para que otra instancia no las tome; si el proceso muere, se reintentan al vencer.
+func (r *WebhookRepository) ClaimDueDeliveries(limit int, leaseUntil time.Time) ([]models.WebhookDelivery, error) {
+	query := `
+		UPDATE webhooks
+		SET next_retry_at = $2, updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM webhooks
+			WHERE status = 'pending' AND next_retry_at <= NOW()
+			ORDER BY next_retry_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + webhookDeliveryColumns
+
+	rows, err := r.db.QueryWithTimeout(query, limit, leaseUntil)
+	if err != nil {
+		return nil, fmt.Errorf("error claiming webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// insertAttempt guarda un intento en el historial de la entrega
+func insertAttempt(tx *sql.Tx, attempt *models.WebhookDeliveryAttempt) error {
+	headers, err := json.Marshal(attempt.RequestHeaders)
+	if err != nil {
+		return fmt.Errorf("error marshaling webhook request headers: %w", err)
+	}
+
+	query := `
+		INSERT INTO webhook_delivery_attempts (
+			id, delivery_id, attempt, request_url, request_headers, response_status_code,
+			response_body, latency_ms, error, created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+		)
+	`
+
+	_, err = tx.Exec(query,
+		attempt.ID, attempt.DeliveryID, attempt.Attempt, attempt.RequestURL, headers, attempt.ResponseStatusCode,
+		attempt.ResponseBody, attempt.LatencyMs, attempt.Error, attempt.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("error recording webhook delivery attempt: %w", err)
+	}
+	return nil
+}
+
+// MarkDelivered registra un intento exitoso y lo agrega al historial de la entrega
+func (r *WebhookRepository) MarkDelivered(attempt *models.WebhookDeliveryAttempt) error {
+	query := `
+		UPDATE webhooks
+		SET status = 'delivered', attempts = attempts + 1, last_status_code = $2, last_error = NULL,
+		    next_retry_at = NULL, delivered_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+	`
+
+	return r.db.WithTransaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(query, attempt.DeliveryID, attempt.ResponseStatusCode); err != nil {
+			return fmt.Errorf("error marking webhook delivery as delivered: %w", err)
+		}
+		return insertAttempt(tx, attempt)
+	})
+}
+
+// MarkFailed registra un intento fallido y lo agrega al historial de la entrega.
+// Con nextRetryAt nil la entrega pasa a dead-letter.
+func (r *WebhookRepository) MarkFailed(attempt *models.WebhookDeliveryAttempt, nextRetryAt *time.Time) error {
+	status := models.WebhookDeliveryPending
+	if nextRetryAt == nil {
+		status = models.WebhookDeliveryDead
+	}
+
+	query := `
+		UPDATE webhooks
+		SET status = $2, attempts = attempts + 1, last_status_code = $3, last_error = $4,
+		    next_retry_at = $5, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	return r.db.WithTransaction(func(tx *sql.Tx) error {
+		_, err := tx.Exec(query, attempt.DeliveryID, status, attempt.ResponseStatusCode, attempt.Error, nextRetryAt)
+		if err != nil {
+			return fmt.Errorf("error marking webhook delivery as failed: %w", err)
+		}
+		return insertAttempt(tx, attempt)
+	})
+}
+
+// ListDeliveries obtiene el log de entregas de un emisor, de la más reciente a la más antigua
+func (r *WebhookRepository) ListDeliveries(emitterID uuid.UUID, filter models.WebhookDeliveryFilter, page, pageSize int) ([]models.WebhookDelivery, int, error) {
+	where := `
+		WHERE emitter_id = $1
+		  AND ($2 = '' OR event_type = $2)
+		  AND ($3 = '' OR status = $3)
+		  AND ($4::timestamptz IS NULL OR created_at >= $4)
+		  AND ($5::timestamptz IS NULL OR created_at < $5)
+	`
+	args := []interface{}{emitterID, string(filter.EventType), string(filter.Status), filter.From, filter.To}
+
+	var total int
+	if err := r.db.QueryRowWithTimeout(`SELECT COUNT(*) FROM webhooks`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting webhook deliveries: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhooks` + where + `
+		ORDER BY created_at DESC, id
+		LIMIT $6 OFFSET $7
+	`
+
+	rows, err := r.db.QueryWithTimeout(query, append(args, pageSize, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+
+	return deliveries, total, nil
+}
+
+// LoadAttempts completa el historial de intentos (AttemptLog) de las entregas
+func (r *WebhookRepository) LoadAttempts(deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	ids := make(pq.StringArray, len(deliveries))
+	for i, delivery := range deliveries {
+		ids[i] = delivery.ID.String()
+	}
+
+	query := `
+		SELECT id, delivery_id, attempt, request_url, request_headers, response_status_code,
+		       response_body, latency_ms, error, created_at
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = ANY($1::uuid[])
+		ORDER BY delivery_id, attempt, created_at
+	`
+
+	rows, err := r.db.QueryWithTimeout(query, ids)
+	if err != nil {
+		return fmt.Errorf("error querying webhook delivery attempts: %w", err)
+	}
+	defer rows.Close()
+
+	attempts := make(map[uuid.UUID][]models.WebhookDeliveryAttempt, len(deliveries))
+	for rows.Next() {
+		var attempt models.WebhookDeliveryAttempt
+		var headers []byte
+		err := rows.Scan(
+			&attempt.ID, &attempt.DeliveryID, &attempt.Attempt, &attempt.RequestURL, &headers,
+			&attempt.ResponseStatusCode, &attempt.ResponseBody, &attempt.LatencyMs, &attempt.Error, &attempt.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("error scanning webhook delivery attempt: %w", err)
+		}
+		if err := json.Unmarshal(headers, &attempt.RequestHeaders); err != nil {
+			return fmt.Errorf("error decoding webhook request headers: %w", err)
+		}
+		attempts[attempt.DeliveryID] = append(attempts[attempt.DeliveryID], attempt)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating webhook delivery attempts: %w", err)
+	}
+
+	for i := range deliveries {
+		deliveries[i].AttemptLog = attempts[deliveries[i].ID]
+	}
+	return nil
+}
+
+// GetDelivery obtiene una entrega de un emisor
+func (r *WebhookRepository) GetDelivery(emitterID, id uuid.UUID) (*models.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhooks WHERE id = $1 AND emitter_id = $2`
+
+	delivery, err := scanWebhookDelivery(r.db.QueryRowWithTimeout(query, id, emitterID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook delivery not found: %s", id)
+		}
+		return nil, fmt.Errorf("error querying webhook delivery: %w", err)
+	}
+
+	return delivery, nil
+}
+
+// Redeliver vuelve a encolar una entrega (también desde dead-letter) con un nuevo ciclo de intentos
+func (r *WebhookRepository) Redeliver(emitterID, id uuid.UUID) (*models.WebhookDelivery, error) {
+	query := `
+		UPDATE webhooks
+		SET status = 'pending', max_attempts = attempts + $3, next_retry_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND emitter_id = $2
+		RETURNING ` + webhookDeliveryColumns
+
+	delivery, err := scanWebhookDelivery(r.db.QueryRowWithTimeout(query, id, emitterID, models.DefaultWebhookMaxAttempts))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook delivery not found: %s", id)
+		}
+		return nil, fmt.Errorf("error redelivering webhook: %w", err)
+	}
+
+	return delivery, nil
+}
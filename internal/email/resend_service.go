@@ -67,7 +67,7 @@ func (s *ResendService) SendInvoiceEmail(invoice *models.Invoice, customer *mode
                 <li><strong>Emisor:</strong> %s</li>
                 <li><strong>RUC:</strong> %s-%s-%s-%s</li>
                 <li><strong>Documento:</strong> %s</li>
-                <li><strong>Total:</strong> <span class="total">$%.2f</span></li>
+                <li><strong>Total:</strong> <span class="total">%s%.2f</span></li>
             </ul>
             
             <p>Puedes descargar tu factura en los siguientes formatos:</p>
@@ -93,7 +93,8 @@ func (s *ResendService) SendInvoiceEmail(invoice *models.Invoice, customer *mode
 		emitter.Name,
 		emitter.RUCTipo, emitter.RUCNumero, emitter.RUCDV, emitter.SucEm,
 		invoice.DocumentType,
-		invoice.TotalAmount,
+		models.CurrencySymbol(invoice.CurrencyCode),
+		invoice.DocTotalAmount,
 		s.baseURL,
 		invoice.ID,
 		s.baseURL,
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ArtifactKind representa el tipo de un archivo de un documento
+type ArtifactKind string
+
+const (
+	ArtifactFEXML           ArtifactKind = "fe_xml"
+	ArtifactProtocolXML     ArtifactKind = "protocol_xml"
+	ArtifactCAFEPDF         ArtifactKind = "cafe_pdf"
+	ArtifactCancellationXML ArtifactKind = "cancellation_xml"
+)
+
+// ArtifactKinds retorna los tipos de archivo en el orden en que se documentan
+func ArtifactKinds() []ArtifactKind {
+	return []ArtifactKind{ArtifactFEXML, ArtifactProtocolXML, ArtifactCAFEPDF, ArtifactCancellationXML}
+}
+
+// IsValid indica si el tipo de archivo es conocido
+func (k ArtifactKind) IsValid() bool {
+	for _, kind := range ArtifactKinds() {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// ContentType retorna el tipo MIME de los archivos de este tipo
+func (k ArtifactKind) ContentType() string {
+	if k == ArtifactCAFEPDF {
+		return "application/pdf"
+	}
+	return "application/xml"
+}
+
+// Extension retorna la extensión de los archivos de este tipo
+func (k ArtifactKind) Extension() string {
+	if k == ArtifactCAFEPDF {
+		return "pdf"
+	}
+	return "xml"
+}
+
+// InvoiceArtifact es una versión inmutable de un archivo de un documento. Cada regeneración con
+// contenido distinto crea una versión nueva; las anteriores se conservan tal como se entregaron.
+type InvoiceArtifact struct {
+	ID               uuid.UUID    `json:"id" db:"id"`
+	InvoiceID        uuid.UUID    `json:"invoice_id" db:"invoice_id"`
+	EmitterID        uuid.UUID    `json:"-" db:"emitter_id"`
+	Kind             ArtifactKind `json:"kind" db:"kind"`
+	Version          int          `json:"version" db:"version"`
+	ContentType      string       `json:"content_type" db:"content_type"`
+	Size             int64        `json:"size" db:"size"`
+	SHA256           string       `json:"sha256" db:"sha256"`
+	GeneratorVersion *string      `json:"generator_version" db:"generator_version"`
+	StorageKey       *string      `json:"-" db:"storage_key"`
+	Data             []byte       `json:"-" db:"data"`
+	CreatedAt        time.Time    `json:"created_at" db:"created_at"`
+	DownloadURL      string       `json:"download_url" db:"-"`
+}
+
+// InvoiceArtifactListResponse representa las versiones de los archivos de un documento
+type InvoiceArtifactListResponse struct {
+	Items []InvoiceArtifact `json:"items"`
+	Total int               `json:"total"`
+}
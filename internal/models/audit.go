@@ -0,0 +1,103 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditResource es un tipo de recurso consultable en el log de auditoría
+type AuditResource string
+
+const (
+	AuditResourceEmitter  AuditResource = "emitter"
+	AuditResourceSeries   AuditResource = "series"
+	AuditResourceAPIKey   AuditResource = "api_key"
+	AuditResourceCustomer AuditResource = "customer"
+	AuditResourceProduct  AuditResource = "product"
+	AuditResourceInvoice  AuditResource = "invoice"
+)
+
+// auditResourceTables relaciona cada recurso con la tabla auditada
+var auditResourceTables = map[AuditResource]string{
+	AuditResourceEmitter:  "emitters",
+	AuditResourceSeries:   "emitter_series",
+	AuditResourceAPIKey:   "api_keys",
+	AuditResourceCustomer: "customers",
+	AuditResourceProduct:  "products",
+	AuditResourceInvoice:  "invoices",
+}
+
+// AuditResources retorna los recursos auditados
+func AuditResources() []AuditResource {
+	return []AuditResource{
+		AuditResourceEmitter, AuditResourceSeries, AuditResourceAPIKey,
+		AuditResourceCustomer, AuditResourceProduct, AuditResourceInvoice,
+	}
+}
+
+// Table retorna la tabla auditada del recurso, o false si el recurso no existe
+func (r AuditResource) Table() (string, bool) {
+	table, ok := auditResourceTables[r]
+	return table, ok
+}
+
+// AuditResourceForTable retorna el recurso de una tabla auditada
+func AuditResourceForTable(table string) AuditResource {
+	for resource, t := range auditResourceTables {
+		if t == table {
+			return resource
+		}
+	}
+	return AuditResource(table)
+}
+
+// Prefijos de los autores de cambios registrados en audit_logs
+const (
+	actorAPIKey    = "api_key:"
+	actorAdmin     = "admin:"
+	actorSystem    = "system:"
+	actorAnonymous = "anonymous:"
+)
+
+// APIKeyActor identifica los cambios hechos con una API key en los endpoints core
+func APIKeyActor(apiKeyID uuid.UUID) string {
+	return actorAPIKey + apiKeyID.String()
+}
+
+// AdminActor identifica los cambios hechos por un administrador con permisos verificados
+func AdminActor(apiKeyID uuid.UUID) string {
+	return actorAdmin + apiKeyID.String()
+}
+
+// SystemActor identifica los cambios hechos por un proceso del servicio (workers, CLIs)
+func SystemActor(name string) string {
+	return actorSystem + name
+}
+
+// AnonymousActor identifica los cambios hechos sin autenticación (registro de emisores)
+func AnonymousActor(clientIP string) string {
+	return actorAnonymous + clientIP
+}
+
+// AuditLog es un cambio registrado por los triggers de auditoría. Las credenciales
+// (PAC, hash de API keys) aparecen como "[redacted]" en OldValues y NewValues.
+type AuditLog struct {
+	ID        uuid.UUID       `json:"id" db:"id"`
+	Resource  AuditResource   `json:"resource" db:"table_name"`
+	RecordID  uuid.UUID       `json:"record_id" db:"record_id"`
+	Action    string          `json:"action" db:"action"`
+	OldValues json.RawMessage `json:"old_values,omitempty" db:"old_values"`
+	NewValues json.RawMessage `json:"new_values,omitempty" db:"new_values"`
+	ChangedBy *string         `json:"changed_by,omitempty" db:"changed_by"`
+	ChangedAt time.Time       `json:"changed_at" db:"changed_at"`
+}
+
+// AuditLogListResponse representa una página del log de auditoría
+type AuditLogListResponse struct {
+	Items    []AuditLog `json:"items"`
+	Page     int        `json:"page"`
+	PageSize int        `json:"page_size"`
+	Total    int        `json:"total"`
+}
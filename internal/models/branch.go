@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Branch representa una sucursal (dSucEm) de un emisor con su propio punto de facturación
+type Branch struct {
+	ID                uuid.UUID `json:"id" db:"id"`
+	EmitterID         uuid.UUID `json:"emitter_id" db:"emitter_id"`
+	Code              string    `json:"code" db:"code"`
+	Name              string    `json:"name" db:"name"`
+	AddressLine       *string   `json:"address_line,omitempty" db:"address_line"`
+	UBICode           *string   `json:"ubi_code,omitempty" db:"ubi_code"`
+	Phone             *string   `json:"phone,omitempty" db:"phone"`
+	PtoFacDefault     string    `json:"pto_fac_default" db:"pto_fac_default"`
+	BrandLogoURL      *string   `json:"brand_logo_url,omitempty" db:"brand_logo_url"`
+	BrandPrimaryColor *string   `json:"brand_primary_color,omitempty" db:"brand_primary_color"`
+	BrandFooterHTML   *string   `json:"brand_footer_html,omitempty" db:"brand_footer_html"`
+	IsActive          bool      `json:"is_active" db:"is_active"`
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CreateBranchRequest representa el request para crear una sucursal
+type CreateBranchRequest struct {
+	Code              string  `json:"code" binding:"required,len=4,numeric"`
+	Name              string  `json:"name" binding:"required"`
+	AddressLine       *string `json:"address_line,omitempty"`
+	UBICode           *string `json:"ubi_code,omitempty"`
+	Phone             *string `json:"phone,omitempty"`
+	PtoFacDefault     string  `json:"pto_fac_default,omitempty" binding:"omitempty,len=3,numeric"`
+	BrandLogoURL      *string `json:"brand_logo_url,omitempty"`
+	BrandPrimaryColor *string `json:"brand_primary_color,omitempty"`
+	BrandFooterHTML   *string `json:"brand_footer_html,omitempty"`
+}
+
+// UpdateBranchRequest representa el request para actualizar parcialmente una sucursal (PATCH).
+// El código no se puede cambiar porque identifica a la sucursal en los documentos emitidos.
+type UpdateBranchRequest struct {
+	Name              *string `json:"name,omitempty" binding:"omitempty,min=1"`
+	AddressLine       *string `json:"address_line,omitempty"`
+	UBICode           *string `json:"ubi_code,omitempty"`
+	Phone             *string `json:"phone,omitempty"`
+	PtoFacDefault     *string `json:"pto_fac_default,omitempty" binding:"omitempty,len=3,numeric"`
+	BrandLogoURL      *string `json:"brand_logo_url,omitempty"`
+	BrandPrimaryColor *string `json:"brand_primary_color,omitempty"`
+	BrandFooterHTML   *string `json:"brand_footer_html,omitempty"`
+}
+
+// BranchListResponse representa la respuesta de listado de sucursales
+type BranchListResponse struct {
+	Items []Branch `json:"items"`
+	Total int      `json:"total"`
+}
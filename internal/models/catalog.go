@@ -0,0 +1,29 @@
+package models
+
+// CPBSEntry representa un código de la Clasificación Panameña de Bienes y Servicios (CPBS)
+type CPBSEntry struct {
+	Abr         string `json:"cpbs_abr" db:"cpbs_abr"`
+	Cmp         string `json:"cpbs_cmp" db:"cpbs_cmp"`
+	Description string `json:"description" db:"description"`
+}
+
+// TaxRateEntry representa una tasa de ITBMS del catálogo
+type TaxRateEntry struct {
+	Code        string  `json:"code" db:"code"`
+	Description string  `json:"description" db:"description"`
+	Rate        float64 `json:"rate" db:"rate"`
+	IsActive    bool    `json:"is_active" db:"is_active"`
+}
+
+// PaymentMethodEntry representa una forma de pago del catálogo
+type PaymentMethodEntry struct {
+	Code        string `json:"code" db:"code"`
+	Description string `json:"description" db:"description"`
+	IsActive    bool   `json:"is_active" db:"is_active"`
+}
+
+// CatalogListResponse representa la respuesta de consulta de un catálogo
+type CatalogListResponse struct {
+	Items interface{} `json:"items"`
+	Total int         `json:"total"`
+}
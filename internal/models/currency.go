@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FunctionalCurrency es la moneda funcional en la que se reportan los montos (USD/PAB a la par)
+const FunctionalCurrency = "USD"
+
+// ExchangeRate representa una tasa de cambio configurada por un emisor
+// Rate expresa cuántas unidades de la moneda funcional equivalen a 1 unidad de CurrencyCode
+type ExchangeRate struct {
+	ID            uuid.UUID `json:"id" db:"id"`
+	EmitterID     uuid.UUID `json:"emitter_id" db:"emitter_id"`
+	CurrencyCode  string    `json:"currency_code" db:"currency_code"`
+	Rate          float64   `json:"rate" db:"rate"`
+	EffectiveDate time.Time `json:"effective_date" db:"effective_date"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CreateExchangeRateRequest representa el request para registrar una tasa de cambio
+type CreateExchangeRateRequest struct {
+	CurrencyCode  string  `json:"currency_code" binding:"required,len=3"`
+	Rate          float64 `json:"rate" binding:"required,gt=0"`
+	EffectiveDate string  `json:"effective_date,omitempty"` // YYYY-MM-DD, por defecto hoy
+}
+
+// ExchangeRateListResponse representa la respuesta al listar tasas de cambio
+type ExchangeRateListResponse struct {
+	Items    []ExchangeRate `json:"items"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"page_size"`
+	Total    int            `json:"total"`
+}
+
+// IsFunctionalCurrency indica si la moneda es la funcional o está a la par con ella (PAB); sin
+// código se asume la funcional. Estas monedas siempre usan tasa 1.
+func IsFunctionalCurrency(code string) bool {
+	switch code {
+	case "", FunctionalCurrency, "PAB":
+		return true
+	default:
+		return false
+	}
+}
+
+// CurrencySymbol retorna el símbolo a imprimir para un código de moneda
+func CurrencySymbol(code string) string {
+	if IsFunctionalCurrency(code) {
+		return "$"
+	}
+	return code + " "
+}
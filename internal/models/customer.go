@@ -6,6 +6,44 @@ import (
 	"github.com/google/uuid"
 )
 
+// RecipientType representa el tipo de receptor del documento (iTipoRec)
+type RecipientType string
+
+const (
+	RecipientTypeContribuyente   RecipientType = "01"
+	RecipientTypeConsumidorFinal RecipientType = "02"
+	RecipientTypeGobierno        RecipientType = "03"
+	RecipientTypeExtranjero      RecipientType = "04"
+)
+
+// Description retorna el nombre del tipo de receptor
+func (t RecipientType) Description() string {
+	switch t {
+	case RecipientTypeContribuyente:
+		return "Contribuyente"
+	case RecipientTypeConsumidorFinal:
+		return "Consumidor final"
+	case RecipientTypeGobierno:
+		return "Gobierno"
+	case RecipientTypeExtranjero:
+		return "Extranjero"
+	default:
+		return string(t)
+	}
+}
+
+// CustomerMatchMode define cómo se identifica un cliente existente a partir de sus datos
+type CustomerMatchMode string
+
+const (
+	// CustomerMatchTaxID busca por RUC/identificación y, si no hay, por email entre clientes sin RUC
+	CustomerMatchTaxID CustomerMatchMode = "tax_id"
+	// CustomerMatchEmail busca solo por email
+	CustomerMatchEmail CustomerMatchMode = "email"
+	// CustomerMatchNone siempre crea un cliente nuevo
+	CustomerMatchNone CustomerMatchMode = "none"
+)
+
 // Customer representa un cliente de un emisor
 type Customer struct {
 	ID         uuid.UUID `json:"id" db:"id"`
@@ -16,6 +54,11 @@ type Customer struct {
 	AddressLine *string  `json:"address_line,omitempty" db:"address_line"`
 	UBICode    *string   `json:"ubi_code,omitempty" db:"ubi_code"`
 	TaxID      *string   `json:"tax_id,omitempty" db:"tax_id"`
+	TaxIDType  *string   `json:"tax_id_type,omitempty" db:"tax_id_type"`
+	TaxIDDV    *string   `json:"tax_id_dv,omitempty" db:"tax_id_dv"`
+	RecipientType RecipientType `json:"recipient_type" db:"recipient_type"`
+	ForeignID  *string   `json:"foreign_id,omitempty" db:"foreign_id"`
+	CountryCode *string  `json:"country_code,omitempty" db:"country_code"`
 	IsActive   bool      `json:"is_active" db:"is_active"`
 	CreatedAt  time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
@@ -29,9 +72,34 @@ type CreateCustomerRequest struct {
 	Address    *string `json:"address,omitempty"`
 	UBICode    *string `json:"ubi_code,omitempty"`
 	TaxID      *string `json:"tax_id,omitempty"`
+	TaxIDType  *string `json:"tax_id_type,omitempty" binding:"omitempty,oneof=1 2 3"`
+	TaxIDDV    *string `json:"tax_id_dv,omitempty"`
+	// Tipo de receptor (01 contribuyente, 02 consumidor final, 03 gobierno, 04 extranjero); por defecto 02
+	RecipientType RecipientType `json:"recipient_type,omitempty" binding:"omitempty,oneof=01 02 03 04"`
+	// Pasaporte o identificación extranjera y país (ISO 3166-1 alfa-2), requeridos para extranjeros
+	ForeignID  *string `json:"foreign_id,omitempty"`
+	CountryCode *string `json:"country_code,omitempty" binding:"omitempty,len=2"`
+}
+
+// UpdateCustomerRequest representa el request para actualizar parcialmente un cliente (PATCH)
+type UpdateCustomerRequest struct {
+	Name       *string `json:"name,omitempty" binding:"omitempty,min=1"`
+	Email      *string `json:"email,omitempty" binding:"omitempty,email"`
+	Phone      *string `json:"phone,omitempty"`
+	Address    *string `json:"address,omitempty"`
+	UBICode    *string `json:"ubi_code,omitempty"`
+	TaxID      *string `json:"tax_id,omitempty"`
+	TaxIDType  *string `json:"tax_id_type,omitempty" binding:"omitempty,oneof=1 2 3"`
+	TaxIDDV    *string `json:"tax_id_dv,omitempty"`
+	RecipientType *RecipientType `json:"recipient_type,omitempty" binding:"omitempty,oneof=01 02 03 04"`
+	ForeignID  *string `json:"foreign_id,omitempty"`
+	CountryCode *string `json:"country_code,omitempty" binding:"omitempty,len=2"`
 }
 
-// CustomerResponse representa la respuesta al crear un cliente
-type CustomerResponse struct {
-	ID string `json:"id"`
+// CustomerListResponse representa la respuesta de listado de clientes
+type CustomerListResponse struct {
+	Items    []Customer `json:"items"`
+	Page     int        `json:"page"`
+	PageSize int        `json:"page_size"`
+	Total    int        `json:"total"`
 }
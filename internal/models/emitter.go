@@ -26,9 +26,16 @@ type Emitter struct {
 	BrandLogoURL        *string   `json:"brand_logo_url,omitempty" db:"brand_logo_url"`
 	BrandPrimaryColor   *string   `json:"brand_primary_color,omitempty" db:"brand_primary_color"`
 	BrandFooterHTML     *string   `json:"brand_footer_html,omitempty" db:"brand_footer_html"`
-	PACAPIKey           string    `json:"pac_api_key" db:"pac_api_key"`
-	PACSubscriptionKey  string    `json:"pac_subscription_key" db:"pac_subscription_key"`
+	// Las credenciales PAC son de solo escritura: nunca se serializan
+	PACAPIKey           string    `json:"-" db:"pac_api_key"`
+	PACSubscriptionKey  string    `json:"-" db:"pac_subscription_key"`
+	PACConfigured       bool      `json:"pac_configured" db:"-"`
+	// Credenciales PAC del ambiente de pruebas (iAmb = 2)
+	PACSandboxAPIKey          string `json:"-" db:"pac_sandbox_api_key"`
+	PACSandboxSubscriptionKey string `json:"-" db:"pac_sandbox_subscription_key"`
+	PACSandboxConfigured      bool   `json:"pac_sandbox_configured" db:"-"`
 	IsActive            bool      `json:"is_active" db:"is_active"`
+	AutoCreateSeries    bool      `json:"auto_create_series" db:"auto_create_series"`
 	CreatedAt           time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -37,6 +44,7 @@ type Emitter struct {
 type EmitterSeries struct {
 	ID               uuid.UUID   `json:"id" db:"id"`
 	EmitterID        uuid.UUID   `json:"emitter_id" db:"emitter_id"`
+	BranchID         uuid.UUID   `json:"branch_id" db:"branch_id"`
 	PtoFacDF         string      `json:"pto_fac_df" db:"pto_fac_df"`
 	DocKind          DocumentType `json:"doc_kind" db:"doc_kind"`
 	NextNumber       int         `json:"next_number" db:"next_number"`
@@ -44,16 +52,77 @@ type EmitterSeries struct {
 	AuthorizedCount  int         `json:"authorized_count" db:"authorized_count"`
 	RejectedCount    int         `json:"rejected_count" db:"rejected_count"`
 	IsActive         bool        `json:"is_active" db:"is_active"`
+	// IAmb es el ambiente de la serie: las series de pruebas y producción numeran por separado
+	IAmb             int         `json:"i_amb" db:"iamb"`
+	// Rango autorizado opcional; sin range_end la serie no tiene límite
+	RangeStart            *int   `json:"range_start,omitempty" db:"range_start"`
+	RangeEnd              *int   `json:"range_end,omitempty" db:"range_end"`
+	LowRemainingThreshold int    `json:"low_remaining_threshold" db:"low_remaining_threshold"`
 	CreatedAt        time.Time   `json:"created_at" db:"created_at"`
 	UpdatedAt        time.Time   `json:"updated_at" db:"updated_at"`
 }
 
+// DefaultLowRemainingThreshold es el número de folios restantes bajo el cual se avisa que el rango se agota
+const DefaultLowRemainingThreshold = 100
+
+// Remaining devuelve los folios que quedan en el rango autorizado (nil si la serie no tiene límite)
+func (s *EmitterSeries) Remaining() *int {
+	if s.RangeEnd == nil {
+		return nil
+	}
+	remaining := *s.RangeEnd - s.NextNumber + 1
+	if remaining < 0 {
+		remaining = 0
+	}
+	return &remaining
+}
+
+// IsLowRemaining indica si los folios restantes están en o bajo el umbral de aviso
+func (s *EmitterSeries) IsLowRemaining() bool {
+	remaining := s.Remaining()
+	return remaining != nil && *remaining <= s.LowRemainingThreshold
+}
+
+// Ambientes de emisión (iAmb)
+const (
+	IAmbProduction = 1
+	IAmbTesting    = 2
+)
+
+// APIKeyEnvironment es el ambiente al que está ligada una API key
+type APIKeyEnvironment string
+
+const (
+	APIKeyEnvironmentLive APIKeyEnvironment = "live"
+	APIKeyEnvironmentTest APIKeyEnvironment = "test"
+)
+
+// IsValid verifica si el ambiente es válido
+func (e APIKeyEnvironment) IsValid() bool {
+	return e == APIKeyEnvironmentLive || e == APIKeyEnvironmentTest
+}
+
+// KeyPrefix retorna el prefijo de las API keys del ambiente (sk_live_ o sk_test_)
+func (e APIKeyEnvironment) KeyPrefix() string {
+	return "sk_" + string(e) + "_"
+}
+
+// IAmb retorna el ambiente de emisión de los documentos creados con una key de este ambiente:
+// las keys de prueba siempre emiten en pruebas y las live en el ambiente configurado del emisor
+func (e APIKeyEnvironment) IAmb(emitter *Emitter) int {
+	if e == APIKeyEnvironmentTest {
+		return IAmbTesting
+	}
+	return emitter.IAmb
+}
+
 // APIKey representa una clave de API para integración
 type APIKey struct {
 	ID              uuid.UUID  `json:"id" db:"id"`
 	EmitterID       uuid.UUID  `json:"emitter_id" db:"emitter_id"`
 	Name            string     `json:"name" db:"name"`
 	KeyHash         string     `json:"key_hash" db:"key_hash"`
+	Environment     APIKeyEnvironment `json:"environment" db:"environment"`
 	IsActive        bool       `json:"is_active" db:"is_active"`
 	RateLimitPerMin int        `json:"rate_limit_per_min" db:"rate_limit_per_min"`
 	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
@@ -81,18 +150,56 @@ type CreateEmitterRequest struct {
 	BrandFooterHTML     *string `json:"brand_footer_html,omitempty"`
 	PACAPIKey           string  `json:"pac_api_key" binding:"required"`
 	PACSubscriptionKey  string  `json:"pac_subscription_key" binding:"required"`
+	PACSandboxAPIKey          string `json:"pac_sandbox_api_key,omitempty"`
+	PACSandboxSubscriptionKey string `json:"pac_sandbox_subscription_key,omitempty"`
+	AutoCreateSeries    bool    `json:"auto_create_series"`
+}
+
+// UpdateEmitterRequest representa el request para actualizar parcialmente un emisor (PATCH).
+// El RUC, la sucursal principal (suc_em) y el company_code no se pueden cambiar.
+type UpdateEmitterRequest struct {
+	Name               *string `json:"name,omitempty"`
+	Email              *string `json:"email,omitempty"`
+	Phone              *string `json:"phone,omitempty"`
+	AddressLine        *string `json:"address_line,omitempty"`
+	UBICode            *string `json:"ubi_code,omitempty"`
+	PtoFacDefault      *string `json:"pto_fac_default,omitempty"`
+	IAmb               *int    `json:"iamb,omitempty"`
+	ITpEmisDefault     *string `json:"itpemis_default,omitempty"`
+	IDocDefault        *string `json:"idoc_default,omitempty"`
+	BrandLogoURL       *string `json:"brand_logo_url,omitempty"`
+	BrandPrimaryColor  *string `json:"brand_primary_color,omitempty"`
+	BrandFooterHTML    *string `json:"brand_footer_html,omitempty"`
+	PACAPIKey          *string `json:"pac_api_key,omitempty"`
+	PACSubscriptionKey *string `json:"pac_subscription_key,omitempty"`
+	PACSandboxAPIKey          *string `json:"pac_sandbox_api_key,omitempty"`
+	PACSandboxSubscriptionKey *string `json:"pac_sandbox_subscription_key,omitempty"`
+	AutoCreateSeries   *bool   `json:"auto_create_series,omitempty"`
+}
+
+// UpdateEmitterSettingsRequest representa el request para actualizar la configuración de un emisor
+type UpdateEmitterSettingsRequest struct {
+	AutoCreateSeries *bool `json:"auto_create_series,omitempty"`
 }
 
 // CreateSeriesRequest representa el request para crear una serie
 type CreateSeriesRequest struct {
+	// Branch es el código de sucursal; por defecto la sucursal principal del emisor
+	Branch   string      `json:"branch,omitempty" binding:"omitempty,len=4,numeric"`
 	PtoFacDF string      `json:"pto_fac_df" binding:"required"`
 	DocKind  DocumentType `json:"doc_kind" binding:"required"`
+	// Rango autorizado opcional: la numeración empieza en range_start y no pasa de range_end
+	RangeStart            *int `json:"range_start,omitempty" binding:"omitempty,min=1"`
+	RangeEnd              *int `json:"range_end,omitempty" binding:"omitempty,min=1"`
+	LowRemainingThreshold *int `json:"low_remaining_threshold,omitempty" binding:"omitempty,min=0"`
 }
 
 // CreateAPIKeyRequest representa el request para crear una API key
 type CreateAPIKeyRequest struct {
 	Name            string `json:"name" binding:"required"`
 	RateLimitPerMin int    `json:"rate_limit_per_min" binding:"required,min=1,max=10000"`
+	// Environment es "live" (por defecto) o "test"
+	Environment     APIKeyEnvironment `json:"environment,omitempty" binding:"omitempty,oneof=live test"`
 }
 
 // CreateAPIKeyResponse representa la respuesta al crear una API key
@@ -100,6 +207,7 @@ type CreateAPIKeyResponse struct {
 	ID              uuid.UUID `json:"id"`
 	Name            string    `json:"name"`
 	APIKey          string    `json:"api_key"`
+	Environment     APIKeyEnvironment `json:"environment"`
 	RateLimitPerMin int       `json:"rate_limit_per_min"`
 }
 
@@ -113,26 +221,79 @@ type SeriesResponse struct {
 
 // SeriesItem representa un ítem de serie en la respuesta
 type SeriesItem struct {
-	PtoFacDF        string `json:"pto_fac_df"`
-	DocKind         string `json:"doc_kind"`
-	LastAssigned    int    `json:"last_assigned"`
-	IssuedCount     int    `json:"issued_count"`
-	AuthorizedCount int    `json:"authorized_count"`
-	RejectedCount   int    `json:"rejected_count"`
+	ID              uuid.UUID `json:"id"`
+	BranchID        uuid.UUID `json:"branch_id"`
+	PtoFacDF        string    `json:"pto_fac_df"`
+	DocKind         string    `json:"doc_kind"`
+	LastAssigned    int       `json:"last_assigned"`
+	IssuedCount     int       `json:"issued_count"`
+	AuthorizedCount int       `json:"authorized_count"`
+	RejectedCount   int       `json:"rejected_count"`
+	IsActive        bool      `json:"is_active"`
+	IAmb            int       `json:"i_amb"`
+	RangeStart      *int      `json:"range_start,omitempty"`
+	RangeEnd        *int      `json:"range_end,omitempty"`
+	Remaining       *int      `json:"remaining,omitempty"`
+	LowRemaining    bool      `json:"low_remaining"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// NewSeriesItem convierte una serie al ítem de la respuesta
+func NewSeriesItem(series *EmitterSeries) SeriesItem {
+	return SeriesItem{
+		ID:              series.ID,
+		BranchID:        series.BranchID,
+		PtoFacDF:        series.PtoFacDF,
+		DocKind:         string(series.DocKind),
+		LastAssigned:    series.NextNumber - 1,
+		IssuedCount:     series.IssuedCount,
+		AuthorizedCount: series.AuthorizedCount,
+		RejectedCount:   series.RejectedCount,
+		IsActive:        series.IsActive,
+		IAmb:            series.IAmb,
+		RangeStart:      series.RangeStart,
+		RangeEnd:        series.RangeEnd,
+		Remaining:       series.Remaining(),
+		LowRemaining:    series.IsLowRemaining(),
+		CreatedAt:       series.CreatedAt,
+	}
+}
+
+// SeriesGap representa un rango de folios consumidos sin documento emitido
+type SeriesGap struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Count int64  `json:"count"`
+}
+
+// SeriesGapsResponse representa la respuesta del reporte de huecos de una serie
+type SeriesGapsResponse struct {
+	Branch       string      `json:"branch"`
+	IAmb         int         `json:"i_amb"`
+	PtoFacDF     string      `json:"pto_fac_df"`
+	DocKind      string      `json:"doc_kind"`
+	LastAssigned int         `json:"last_assigned"`
+	Gaps         []SeriesGap `json:"gaps"`
+	TotalMissing int64       `json:"total_missing"`
 }
 
 // DashboardResponse representa la respuesta del dashboard
 type DashboardResponse struct {
 	Month  string        `json:"month"`
+	// IAmb es el ambiente reportado: nunca se mezclan documentos de pruebas y producción
+	IAmb   int           `json:"i_amb"`
 	Series []SeriesItem  `json:"series"`
 	Totals DashboardTotals `json:"totals"`
 }
 
 // DashboardTotals representa los totales del dashboard
+// Los montos se agregan siempre en moneda funcional
 type DashboardTotals struct {
-	Issued     int `json:"issued"`
-	Authorized int `json:"authorized"`
-	Rejected   int `json:"rejected"`
+	Issued     int     `json:"issued"`
+	Authorized int     `json:"authorized"`
+	Rejected   int     `json:"rejected"`
+	Currency   string  `json:"currency"`
+	Amount     float64 `json:"amount"`
 }
 
 // EmitterResponse representa la respuesta al crear un emisor
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // ErrorCode representa el código de error
 type ErrorCode string
@@ -21,6 +24,30 @@ type ErrorDetail struct {
 	Issue string `json:"issue"`
 }
 
+// FieldErrors representa errores de validación por campo que los servicios propagan
+// para que la API los devuelva como ErrorDetail estructurados
+type FieldErrors []ErrorDetail
+
+// Error implementa la interfaz error
+func (e FieldErrors) Error() string {
+	issues := make([]string, len(e))
+	for i, detail := range e {
+		issues[i] = detail.Field + ": " + detail.Issue
+	}
+	return strings.Join(issues, "; ")
+}
+
+// RateLimitError indica que se superó un límite de uso; RetryAfter es cuánto falta para poder reintentar
+type RateLimitError struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+// Error implementa la interfaz error
+func (e *RateLimitError) Error() string {
+	return e.Message
+}
+
 // ErrorResponse representa la respuesta de error estandarizada
 type ErrorResponse struct {
 	Error ErrorInfo `json:"error"`
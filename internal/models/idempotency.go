@@ -0,0 +1,23 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKey es una Idempotency-Key de un emisor. Mientras Response es nil la key está
+// reservada por la petición ClaimID; al crearse el documento guarda la respuesta original.
+type IdempotencyKey struct {
+	EmitterID   uuid.UUID       `json:"emitter_id" db:"emitter_id"`
+	Key         string          `json:"idempotency_key" db:"idempotency_key"`
+	Fingerprint string          `json:"fingerprint" db:"fingerprint"`
+	ClaimID     uuid.UUID       `json:"claim_id" db:"claim_id"`
+	LockedUntil time.Time       `json:"locked_until" db:"locked_until"`
+	InvoiceID   *uuid.UUID      `json:"invoice_id,omitempty" db:"invoice_id"`
+	Response    json.RawMessage `json:"response_body,omitempty" db:"response_body"`
+	ExpiresAt   time.Time       `json:"expires_at" db:"expires_at"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty" db:"completed_at"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ImportResourceType representa el tipo de recurso de una importación masiva
+type ImportResourceType string
+
+const (
+	ImportResourceCustomers ImportResourceType = "customers"
+	ImportResourceProducts  ImportResourceType = "products"
+)
+
+// ImportStatus representa el estado de una importación masiva
+type ImportStatus string
+
+const (
+	ImportStatusPending    ImportStatus = "pending"
+	ImportStatusProcessing ImportStatus = "processing"
+	ImportStatusCompleted  ImportStatus = "completed"
+	ImportStatusFailed     ImportStatus = "failed"
+)
+
+// ImportJob representa un trabajo de importación masiva (CSV/XLSX)
+type ImportJob struct {
+	ID           uuid.UUID          `json:"id" db:"id"`
+	EmitterID    uuid.UUID          `json:"emitter_id" db:"emitter_id"`
+	ResourceType ImportResourceType `json:"resource_type" db:"resource_type"`
+	FileName     string             `json:"file_name" db:"file_name"`
+	FileFormat   string             `json:"file_format" db:"file_format"`
+	Status       ImportStatus       `json:"status" db:"status"`
+	TotalRows    int                `json:"total_rows" db:"total_rows"`
+	CreatedCount int                `json:"created_count" db:"created_count"`
+	UpdatedCount int                `json:"updated_count" db:"updated_count"`
+	ErrorCount   int                `json:"error_count" db:"error_count"`
+	Errors       []ImportRowError   `json:"-" db:"errors"`
+	ErrorMessage *string            `json:"error_message,omitempty" db:"error_message"`
+	CreatedAt    time.Time          `json:"created_at" db:"created_at"`
+	StartedAt    *time.Time         `json:"started_at,omitempty" db:"started_at"`
+	CompletedAt  *time.Time         `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+// ImportRowError representa un error de validación de una fila importada
+type ImportRowError struct {
+	Row    int    `json:"row"`
+	Field  string `json:"field,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// ImportJobResponse representa la respuesta de un trabajo de importación
+type ImportJobResponse struct {
+	ImportJob
+	Links ImportLinks `json:"links"`
+}
+
+// ImportLinks representa los enlaces de un trabajo de importación
+type ImportLinks struct {
+	Self   string `json:"self"`
+	Errors string `json:"errors"`
+}
@@ -20,6 +20,16 @@ const (
 	DocumentTypeForeignInvoice DocumentType = "foreign_invoice"
 )
 
+// IsValid indica si el tipo de documento es uno de los soportados
+func (d DocumentType) IsValid() bool {
+	switch d {
+	case DocumentTypeInvoice, DocumentTypeImportInvoice, DocumentTypeExportInvoice, DocumentTypeCreditNote,
+		DocumentTypeDebitNote, DocumentTypeZoneFranca, DocumentTypeReembolso, DocumentTypeForeignInvoice:
+		return true
+	}
+	return false
+}
+
 // DocumentStatus representa el estado del documento
 type DocumentStatus string
 
@@ -30,8 +40,38 @@ const (
 	DocumentStatusAuthorized    DocumentStatus = "AUTHORIZED"
 	DocumentStatusRejected      DocumentStatus = "REJECTED"
 	DocumentStatusError         DocumentStatus = "ERROR"
+	DocumentStatusCancelled     DocumentStatus = "CANCELLED"
 )
 
+// documentStatusTransitions son las transiciones de status permitidas. AUTHORIZED sólo puede
+// anularse (CANCELLED); REJECTED y CANCELLED son finales. Un documento en ERROR puede
+// reintentarse desde la preparación o el envío al PAC.
+var documentStatusTransitions = map[DocumentStatus][]DocumentStatus{
+	DocumentStatusReceived:     {DocumentStatusPreparing, DocumentStatusError, DocumentStatusCancelled},
+	DocumentStatusPreparing:    {DocumentStatusSendingToPAC, DocumentStatusError, DocumentStatusCancelled},
+	DocumentStatusSendingToPAC: {DocumentStatusAuthorized, DocumentStatusRejected, DocumentStatusError},
+	DocumentStatusError:        {DocumentStatusPreparing, DocumentStatusSendingToPAC, DocumentStatusCancelled},
+	DocumentStatusAuthorized:   {DocumentStatusCancelled},
+	DocumentStatusRejected:     {},
+	DocumentStatusCancelled:    {},
+}
+
+// IsValid indica si el status existe
+func (s DocumentStatus) IsValid() bool {
+	_, ok := documentStatusTransitions[s]
+	return ok
+}
+
+// CanTransitionTo indica si un documento puede pasar del status s a next
+func (s DocumentStatus) CanTransitionTo(next DocumentStatus) bool {
+	for _, allowed := range documentStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
 // EmailStatus representa el estado del email
 type EmailStatus string
 
@@ -63,6 +103,7 @@ type Invoice struct {
 	ID              uuid.UUID      `json:"id" db:"id"`
 	EmitterID       uuid.UUID      `json:"emitter_id" db:"emitter_id"`
 	SeriesID        uuid.UUID      `json:"series_id" db:"series_id"`
+	BranchID        *uuid.UUID     `json:"branch_id,omitempty" db:"branch_id"`
 	CustomerID      uuid.UUID      `json:"customer_id" db:"customer_id"`
 	
 	// Información del documento
@@ -91,16 +132,26 @@ type Invoice struct {
 	ITpEmis         string         `json:"i_tp_emis" db:"itpemis"`
 	IDoc            string         `json:"i_doc" db:"idoc"`
 	
-	// Totales calculados
+	// Totales calculados (moneda funcional)
 	Subtotal        float64        `json:"subtotal" db:"subtotal"`
 	ITBMSAmount     float64        `json:"itbms_amount" db:"itbms_amount"`
 	TotalAmount     float64        `json:"total_amount" db:"total_amount"`
-	
+
+	// Moneda del documento y totales expresados en ella
+	CurrencyCode    string         `json:"currency_code" db:"currency_code"`
+	ExchangeRate    float64        `json:"exchange_rate" db:"exchange_rate"`
+	DocSubtotal     float64        `json:"doc_subtotal" db:"doc_subtotal"`
+	DocITBMSAmount  float64        `json:"doc_itbms_amount" db:"doc_itbms_amount"`
+	DocTotalAmount  float64        `json:"doc_total_amount" db:"doc_total_amount"`
+
 	// Metadatos
 	IdempotencyKey  *string        `json:"idempotency_key,omitempty" db:"idempotency_key"`
 	CreatedAt       time.Time      `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time      `json:"updated_at" db:"updated_at"`
 	
+	// Datos de emisor, receptor y marca congelados al emitir
+	Snapshot        *InvoiceSnapshot `json:"-" db:"snapshot"`
+
 	// Relaciones (populadas en consultas)
 	Emitter         *Emitter       `json:"emitter,omitempty"`
 	Customer        *Customer      `json:"customer,omitempty"`
@@ -127,10 +178,17 @@ type InvoiceItem struct {
 type CreateInvoiceRequest struct {
 	DocumentType DocumentType     `json:"document_type" binding:"required,oneof=invoice import_invoice export_invoice credit_note debit_note zone_franca reembolso foreign_invoice"`
 	Reference    *Reference       `json:"reference,omitempty"`
-	Customer     CustomerRequest  `json:"customer" binding:"required"`
+	CustomerID   *string          `json:"customer_id,omitempty" binding:"omitempty,uuid"`
+	Customer     *CustomerRequest `json:"customer,omitempty" binding:"required_without=CustomerID"`
+	// Cómo buscar un cliente existente a partir de customer (tax_id por defecto, email o none)
+	CustomerMatch CustomerMatchMode `json:"customer_match,omitempty" binding:"omitempty,oneof=tax_id email none"`
+	// Si es true, los datos de customer actualizan el cliente encontrado; si no, solo se usan en este documento
+	UpdateCustomer bool          `json:"update_customer,omitempty"`
 	Items        []ItemRequest    `json:"items" binding:"required,min=1"`
 	Payment      PaymentRequest   `json:"payment" binding:"required"`
 	Overrides    *Overrides       `json:"overrides,omitempty"`
+	CurrencyCode string           `json:"currency_code,omitempty" binding:"omitempty,len=3"`
+	ExchangeRate *float64         `json:"exchange_rate,omitempty" binding:"omitempty,gt=0"`
 }
 
 // Reference representa la referencia para notas de crédito/débito
@@ -147,16 +205,27 @@ type CustomerRequest struct {
 	Phone   *string `json:"phone,omitempty"`
 	Address *string `json:"address,omitempty"`
 	UBICode *string `json:"ubi_code,omitempty"`
+	// RUC del receptor; el DV se calcula si no se envía
+	TaxID     *string `json:"tax_id,omitempty"`
+	TaxIDType *string `json:"tax_id_type,omitempty" binding:"omitempty,oneof=1 2 3"`
+	TaxIDDV   *string `json:"tax_id_dv,omitempty"`
+	// Tipo de receptor (iTipoRec) e identificación extranjera
+	RecipientType RecipientType `json:"recipient_type,omitempty" binding:"omitempty,oneof=01 02 03 04"`
+	ForeignID     *string       `json:"foreign_id,omitempty"`
+	CountryCode   *string       `json:"country_code,omitempty" binding:"omitempty,len=2"`
 }
 
-// ItemRequest representa el request para un ítem del documento
+// ItemRequest representa el request para un ítem del documento.
+// Si se envía product_id, la descripción, precio, tasa y códigos CPBS omitidos se heredan del producto.
 type ItemRequest struct {
-	ProductID   *string  `json:"product_id,omitempty"`
+	ProductID   *string  `json:"product_id,omitempty" binding:"omitempty,uuid"`
 	SKU         *string  `json:"sku,omitempty"`
-	Description string   `json:"description" binding:"required"`
+	Description string   `json:"description" binding:"required_without=ProductID"`
 	Quantity    float64  `json:"quantity" binding:"required,gt=0"`
-	UnitPrice   float64  `json:"unit_price" binding:"required,gt=0"`
-	TaxRate     string   `json:"tax_rate" binding:"required"`
+	UnitPrice   float64  `json:"unit_price" binding:"required_without=ProductID,gte=0"`
+	TaxRate     string   `json:"tax_rate" binding:"required_without=ProductID"`
+	CPBSAbr     *string  `json:"cpbs_abr,omitempty"`
+	CPBSCmp     *string  `json:"cpbs_cmp,omitempty"`
 }
 
 // PaymentRequest representa el request para el pago
@@ -167,6 +236,8 @@ type PaymentRequest struct {
 
 // Overrides representa configuraciones que sobrescriben los defaults
 type Overrides struct {
+	// Branch es el código de sucursal (dSucEm); por defecto la sucursal principal del emisor
+	Branch   string `json:"branch,omitempty" binding:"omitempty,len=4,numeric"`
 	PtoFacDF string `json:"pto_fac_df,omitempty"`
 	ITpEmis  string `json:"i_tp_emis,omitempty"`
 	IDoc     string `json:"i_doc,omitempty"`
@@ -179,7 +250,12 @@ type InvoiceResponse struct {
 	DocumentType DocumentType   `json:"document_type"`
 	Emitter      EmitterInfo   `json:"emitter"`
 	Totals       Totals        `json:"totals"`
+	FunctionalTotals *Totals   `json:"functional_totals,omitempty"`
+	ExchangeRate *float64      `json:"exchange_rate,omitempty"`
 	Links        Links         `json:"links"`
+	Warnings     []string      `json:"warnings,omitempty"`
+	// Replayed indica que es la respuesta guardada de un reintento con la misma Idempotency-Key
+	Replayed     bool          `json:"-"`
 }
 
 // EmitterInfo representa información del emisor en la respuesta
@@ -191,9 +267,15 @@ type EmitterInfo struct {
 
 // Totals representa los totales del documento
 type Totals struct {
-	Net   float64 `json:"net"`
-	ITBMS float64 `json:"itbms"`
-	Total float64 `json:"total"`
+	Currency string  `json:"currency,omitempty"`
+	Net      float64 `json:"net"`
+	ITBMS    float64 `json:"itbms"`
+	Total    float64 `json:"total"`
+}
+
+// IsForeignCurrency indica si el documento está expresado en una moneda distinta a la funcional
+func (i *Invoice) IsForeignCurrency() bool {
+	return !IsFunctionalCurrency(i.CurrencyCode)
 }
 
 // Links representa los enlaces relacionados
@@ -212,7 +294,10 @@ type InvoiceStatusResponse struct {
 	URLCUFE      *string       `json:"url_cufe,omitempty"`
 	Emitter      EmitterInfo   `json:"emitter"`
 	Totals       Totals        `json:"totals"`
+	FunctionalTotals *Totals   `json:"functional_totals,omitempty"`
+	ExchangeRate *float64      `json:"exchange_rate,omitempty"`
 	CreatedAt    time.Time     `json:"created_at"`
+	Timeline     []InvoiceEvent `json:"timeline"`
 	Links        Links         `json:"links"`
 }
 
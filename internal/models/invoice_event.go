@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InvoiceEvent es una transición de status de un documento. FromStatus es nil en el evento
+// de creación; Actor identifica al autor igual que changed_by en el log de auditoría.
+type InvoiceEvent struct {
+	ID         uuid.UUID       `json:"id" db:"id"`
+	InvoiceID  uuid.UUID       `json:"-" db:"invoice_id"`
+	FromStatus *DocumentStatus `json:"from_status" db:"from_status"`
+	ToStatus   DocumentStatus  `json:"to_status" db:"to_status"`
+	Actor      *string         `json:"actor,omitempty" db:"actor"`
+	Reason     *string         `json:"reason,omitempty" db:"reason"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+}
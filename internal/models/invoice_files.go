@@ -16,6 +16,11 @@ type InvoiceFiles struct {
 	XMLSize     int64     `json:"xml_size" db:"xml_size"`
 	PDFURL      *string   `json:"pdf_url" db:"pdf_url"`
 	XMLURL      *string   `json:"xml_url" db:"xml_url"`
+	// Keys de los archivos en el storage y su SHA-256 (hex)
+	PDFKey      *string   `json:"pdf_key,omitempty" db:"pdf_key"`
+	XMLKey      *string   `json:"xml_key,omitempty" db:"xml_key"`
+	PDFSHA256   *string   `json:"pdf_sha256,omitempty" db:"pdf_sha256"`
+	XMLSHA256   *string   `json:"xml_sha256,omitempty" db:"xml_sha256"`
 	GeneratedAt time.Time `json:"generated_at" db:"generated_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -24,3 +29,10 @@ type InvoiceFiles struct {
 type FileDownloadRequest struct {
 	FileType string `json:"file_type" binding:"required,oneof=pdf xml"`
 }
+
+// FileDownloadURLResponse representa la URL prefirmada de descarga de un archivo (redirect=false)
+type FileDownloadURLResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+	SHA256    *string   `json:"sha256,omitempty"`
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxEventType representa un cambio de un documento registrado en el outbox
+type OutboxEventType string
+
+const (
+	OutboxInvoiceCreated            OutboxEventType = "invoice.created"
+	OutboxInvoiceStatusChanged      OutboxEventType = "invoice.status_changed"
+	OutboxInvoiceEmailStatusChanged OutboxEventType = "invoice.email_status_changed"
+)
+
+// OutboxEvent es un evento escrito en la misma transacción que el cambio que lo origina.
+// El relay lo publica al motor de workflows y a los webhooks al menos una vez; DedupKey
+// identifica el evento ante los consumidores para que descarten las publicaciones repetidas.
+type OutboxEvent struct {
+	ID            uuid.UUID       `json:"id" db:"id"`
+	AggregateType string          `json:"aggregate_type" db:"aggregate_type"`
+	AggregateID   uuid.UUID       `json:"aggregate_id" db:"aggregate_id"`
+	EmitterID     uuid.UUID       `json:"emitter_id" db:"emitter_id"`
+	EventType     OutboxEventType `json:"event_type" db:"event_type"`
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+	DedupKey      string          `json:"dedup_key" db:"dedup_key"`
+	Attempts      int             `json:"attempts" db:"attempts"`
+	LastError     *string         `json:"last_error,omitempty" db:"last_error"`
+	NextAttemptAt time.Time       `json:"next_attempt_at" db:"next_attempt_at"`
+	PublishedAt   *time.Time      `json:"published_at,omitempty" db:"published_at"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+}
+
+// InvoiceOutboxData son los datos de los eventos de documentos, tomados al momento del cambio
+type InvoiceOutboxData struct {
+	InvoiceID      uuid.UUID      `json:"invoice_id"`
+	Status         DocumentStatus `json:"status"`
+	EmailStatus    EmailStatus    `json:"email_status"`
+	DocumentType   DocumentType   `json:"document_type"`
+	PtoFacDF       string         `json:"pto_fac_df"`
+	DocumentNumber string         `json:"document_number"`
+	CUFE           *string        `json:"cufe,omitempty"`
+	IAmb           int            `json:"i_amb"`
+	Recipient      string         `json:"recipient,omitempty"`
+	EmailError     string         `json:"email_error,omitempty"`
+}
+
+// WebhookData retorna los datos del evento de webhook que notifica el cambio
+func (d *InvoiceOutboxData) WebhookData(eventType OutboxEventType) interface{} {
+	if eventType == OutboxInvoiceEmailStatusChanged {
+		return EmailWebhookData{
+			InvoiceID:      d.InvoiceID,
+			DocumentNumber: d.DocumentNumber,
+			EmailStatus:    d.EmailStatus,
+			Recipient:      d.Recipient,
+			Error:          d.EmailError,
+		}
+	}
+	return InvoiceWebhookData{
+		InvoiceID:      d.InvoiceID,
+		Status:         d.Status,
+		DocumentType:   d.DocumentType,
+		PtoFacDF:       d.PtoFacDF,
+		DocumentNumber: d.DocumentNumber,
+		CUFE:           d.CUFE,
+		IAmb:           d.IAmb,
+	}
+}
@@ -31,7 +31,20 @@ type CreateProductRequest struct {
 	TaxRate     string  `json:"tax_rate" binding:"required"`
 }
 
-// ProductResponse representa la respuesta al crear un producto
-type ProductResponse struct {
-	ID string `json:"id"`
+// UpdateProductRequest representa el request para actualizar parcialmente un producto (PATCH)
+type UpdateProductRequest struct {
+	SKU         *string  `json:"sku,omitempty" binding:"omitempty,min=1"`
+	Description *string  `json:"description,omitempty" binding:"omitempty,min=1"`
+	CPBSAbr     *string  `json:"cpbs_abr,omitempty"`
+	CPBSCmp     *string  `json:"cpbs_cmp,omitempty"`
+	UnitPrice   *float64 `json:"unit_price,omitempty" binding:"omitempty,gt=0"`
+	TaxRate     *string  `json:"tax_rate,omitempty"`
+}
+
+// ProductListResponse representa la respuesta de listado de productos
+type ProductListResponse struct {
+	Items    []Product `json:"items"`
+	Page     int       `json:"page"`
+	PageSize int       `json:"page_size"`
+	Total    int       `json:"total"`
 }
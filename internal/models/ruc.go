@@ -0,0 +1,22 @@
+package models
+
+// RUCKind representa la clase de contribuyente identificada a partir del formato del RUC
+type RUCKind string
+
+const (
+	RUCKindNatural    RUCKind = "natural"
+	RUCKindJuridica   RUCKind = "juridica"
+	RUCKindNT         RUCKind = "nt"
+	RUCKindExtranjero RUCKind = "extranjero"
+)
+
+// RUCValidationResult representa el resultado de validar un RUC y su dígito verificador
+type RUCValidationResult struct {
+	Valid      bool          `json:"valid"`
+	RUC        string        `json:"ruc"`
+	Tipo       string        `json:"tipo,omitempty"`
+	Kind       RUCKind       `json:"kind,omitempty"`
+	DV         string        `json:"dv,omitempty"`
+	ExpectedDV string        `json:"expected_dv,omitempty"`
+	Errors     []ErrorDetail `json:"errors,omitempty"`
+}
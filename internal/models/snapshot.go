@@ -0,0 +1,123 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InvoiceSnapshotVersion identifica el formato actual del snapshot (2: incluye la sucursal)
+const InvoiceSnapshotVersion = 2
+
+// InvoiceSnapshot representa los datos del emisor, receptor y marca congelados al emitir un documento.
+// Los archivos del documento se generan solo a partir del snapshot para que sean reproducibles.
+type InvoiceSnapshot struct {
+	Version    int              `json:"version"`
+	Emitter    EmitterSnapshot  `json:"emitter"`
+	Branch     *BranchSnapshot  `json:"branch,omitempty"`
+	Customer   Customer         `json:"customer"`
+	Branding   BrandingSnapshot `json:"branding"`
+	CapturedAt time.Time        `json:"captured_at"`
+}
+
+// EmitterSnapshot representa los datos fiscales y de contacto del emisor (sin credenciales PAC)
+type EmitterSnapshot struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	CompanyCode string    `json:"company_code"`
+	RUCTipo     string    `json:"ruc_tipo"`
+	RUCNumero   string    `json:"ruc_numero"`
+	RUCDV       string    `json:"ruc_dv"`
+	SucEm       string    `json:"suc_em"`
+	Email       string    `json:"email"`
+	Phone       *string   `json:"phone,omitempty"`
+	AddressLine *string   `json:"address_line,omitempty"`
+	UBICode     *string   `json:"ubi_code,omitempty"`
+}
+
+// BranchSnapshot identifica la sucursal que emitió el documento
+type BranchSnapshot struct {
+	ID   uuid.UUID `json:"id"`
+	Code string    `json:"code"`
+	Name string    `json:"name"`
+}
+
+// BrandingSnapshot representa la marca del emisor usada en el PDF y el email
+type BrandingSnapshot struct {
+	LogoURL      *string `json:"logo_url,omitempty"`
+	PrimaryColor *string `json:"primary_color,omitempty"`
+	FooterHTML   *string `json:"footer_html,omitempty"`
+}
+
+// NewInvoiceSnapshot congela los datos actuales del emisor y del receptor. Si se indica la
+// sucursal, su código (dSucEm), dirección, ubicación, teléfono y marca reemplazan a los del emisor.
+func NewInvoiceSnapshot(emitter *Emitter, branch *Branch, customer *Customer) *InvoiceSnapshot {
+	snapshot := &InvoiceSnapshot{
+		Version: InvoiceSnapshotVersion,
+		Emitter: EmitterSnapshot{
+			ID:          emitter.ID,
+			Name:        emitter.Name,
+			CompanyCode: emitter.CompanyCode,
+			RUCTipo:     emitter.RUCTipo,
+			RUCNumero:   emitter.RUCNumero,
+			RUCDV:       emitter.RUCDV,
+			SucEm:       emitter.SucEm,
+			Email:       emitter.Email,
+			Phone:       emitter.Phone,
+			AddressLine: emitter.AddressLine,
+			UBICode:     emitter.UBICode,
+		},
+		Customer: *customer,
+		Branding: BrandingSnapshot{
+			LogoURL:      emitter.BrandLogoURL,
+			PrimaryColor: emitter.BrandPrimaryColor,
+			FooterHTML:   emitter.BrandFooterHTML,
+		},
+		CapturedAt: time.Now(),
+	}
+
+	if branch != nil {
+		snapshot.Branch = &BranchSnapshot{ID: branch.ID, Code: branch.Code, Name: branch.Name}
+		snapshot.Emitter.SucEm = branch.Code
+		if branch.AddressLine != nil {
+			snapshot.Emitter.AddressLine = branch.AddressLine
+		}
+		if branch.UBICode != nil {
+			snapshot.Emitter.UBICode = branch.UBICode
+		}
+		if branch.Phone != nil {
+			snapshot.Emitter.Phone = branch.Phone
+		}
+		if branch.BrandLogoURL != nil {
+			snapshot.Branding.LogoURL = branch.BrandLogoURL
+		}
+		if branch.BrandPrimaryColor != nil {
+			snapshot.Branding.PrimaryColor = branch.BrandPrimaryColor
+		}
+		if branch.BrandFooterHTML != nil {
+			snapshot.Branding.FooterHTML = branch.BrandFooterHTML
+		}
+	}
+
+	return snapshot
+}
+
+// EmitterData reconstruye el emisor (con su marca) tal como estaba al emitir el documento
+func (s *InvoiceSnapshot) EmitterData() *Emitter {
+	return &Emitter{
+		ID:                s.Emitter.ID,
+		Name:              s.Emitter.Name,
+		CompanyCode:       s.Emitter.CompanyCode,
+		RUCTipo:           s.Emitter.RUCTipo,
+		RUCNumero:         s.Emitter.RUCNumero,
+		RUCDV:             s.Emitter.RUCDV,
+		SucEm:             s.Emitter.SucEm,
+		Email:             s.Emitter.Email,
+		Phone:             s.Emitter.Phone,
+		AddressLine:       s.Emitter.AddressLine,
+		UBICode:           s.Emitter.UBICode,
+		BrandLogoURL:      s.Branding.LogoURL,
+		BrandPrimaryColor: s.Branding.PrimaryColor,
+		BrandFooterHTML:   s.Branding.FooterHTML,
+	}
+}
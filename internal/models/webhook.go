@@ -0,0 +1,224 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookEventType representa un evento del ciclo de vida de un documento notificado por webhook
+type WebhookEventType string
+
+const (
+	WebhookEventInvoiceAuthorized WebhookEventType = "invoice.authorized"
+	WebhookEventInvoiceRejected   WebhookEventType = "invoice.rejected"
+	WebhookEventInvoiceCancelled  WebhookEventType = "invoice.cancelled"
+	WebhookEventEmailSent         WebhookEventType = "email.sent"
+	WebhookEventEmailFailed       WebhookEventType = "email.failed"
+)
+
+// WebhookEventTest es el evento de prueba que se envía a demanda a un endpoint; no admite suscripción
+const WebhookEventTest WebhookEventType = "webhook.test"
+
+// WebhookEventTypes son todos los eventos a los que se puede suscribir un endpoint
+var WebhookEventTypes = []WebhookEventType{
+	WebhookEventInvoiceAuthorized,
+	WebhookEventInvoiceRejected,
+	WebhookEventInvoiceCancelled,
+	WebhookEventEmailSent,
+	WebhookEventEmailFailed,
+}
+
+// IsValid verifica si el evento es válido
+func (t WebhookEventType) IsValid() bool {
+	for _, eventType := range WebhookEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEventForStatus retorna el evento que notifica que un documento pasó a status, si existe
+func WebhookEventForStatus(status DocumentStatus) (WebhookEventType, bool) {
+	switch status {
+	case DocumentStatusAuthorized:
+		return WebhookEventInvoiceAuthorized, true
+	case DocumentStatusRejected:
+		return WebhookEventInvoiceRejected, true
+	case DocumentStatusCancelled:
+		return WebhookEventInvoiceCancelled, true
+	}
+	return "", false
+}
+
+// WebhookEventForEmailStatus retorna el evento que notifica el resultado del envío del email, si existe
+func WebhookEventForEmailStatus(status EmailStatus) (WebhookEventType, bool) {
+	switch status {
+	case EmailStatusSent:
+		return WebhookEventEmailSent, true
+	case EmailStatusFailed:
+		return WebhookEventEmailFailed, true
+	}
+	return "", false
+}
+
+// WebhookDeliveryStatus representa el estado de una entrega de webhook
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryPending espera su primer intento o un reintento (next_retry_at)
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending"
+	// WebhookDeliveryDelivered recibió una respuesta 2xx
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	// WebhookDeliveryDead agotó sus intentos (dead-letter); sólo se reenvía manualmente
+	WebhookDeliveryDead WebhookDeliveryStatus = "dead"
+)
+
+// DefaultWebhookMaxAttempts es el número de intentos de una entrega antes de pasar a dead-letter
+const DefaultWebhookMaxAttempts = 8
+
+// WebhookEndpoint representa una URL de un emisor que recibe eventos firmados
+type WebhookEndpoint struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	EmitterID uuid.UUID `json:"emitter_id" db:"emitter_id"`
+	URL       string    `json:"url" db:"url"`
+	// Secret firma las entregas (HMAC-SHA256); sólo se devuelve al crear el endpoint
+	Secret      string             `json:"-" db:"secret"`
+	EventTypes  []WebhookEventType `json:"event_types" db:"event_types"`
+	Description *string            `json:"description,omitempty" db:"description"`
+	IsActive    bool               `json:"is_active" db:"is_active"`
+	CreatedAt   time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at" db:"updated_at"`
+}
+
+// Subscribes indica si el endpoint está suscrito a un evento
+func (e *WebhookEndpoint) Subscribes(eventType WebhookEventType) bool {
+	for _, t := range e.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateWebhookEndpointRequest representa el request para registrar un endpoint de webhooks
+type CreateWebhookEndpointRequest struct {
+	URL         string             `json:"url" binding:"required"`
+	EventTypes  []WebhookEventType `json:"event_types" binding:"required,min=1"`
+	Description *string            `json:"description,omitempty"`
+}
+
+// UpdateWebhookEndpointRequest representa el request para actualizar parcialmente un endpoint (PATCH)
+type UpdateWebhookEndpointRequest struct {
+	URL         *string             `json:"url,omitempty"`
+	EventTypes  *[]WebhookEventType `json:"event_types,omitempty"`
+	Description *string             `json:"description,omitempty"`
+	IsActive    *bool               `json:"is_active,omitempty"`
+}
+
+// CreateWebhookEndpointResponse representa la respuesta al crear un endpoint; el secreto se devuelve una sola vez
+type CreateWebhookEndpointResponse struct {
+	WebhookEndpoint
+	Secret string `json:"secret"`
+}
+
+// WebhookEndpointListResponse representa la respuesta de listado de endpoints
+type WebhookEndpointListResponse struct {
+	Items []WebhookEndpoint `json:"items"`
+	Total int               `json:"total"`
+}
+
+// WebhookEvent es el cuerpo JSON que recibe un endpoint
+type WebhookEvent struct {
+	ID        uuid.UUID        `json:"id"`
+	Type      WebhookEventType `json:"type"`
+	EmitterID uuid.UUID        `json:"emitter_id"`
+	CreatedAt time.Time        `json:"created_at"`
+	Data      interface{}      `json:"data"`
+}
+
+// InvoiceWebhookData son los datos de los eventos invoice.*
+type InvoiceWebhookData struct {
+	InvoiceID      uuid.UUID      `json:"invoice_id"`
+	Status         DocumentStatus `json:"status"`
+	DocumentType   DocumentType   `json:"document_type"`
+	PtoFacDF       string         `json:"pto_fac_df"`
+	DocumentNumber string         `json:"document_number"`
+	CUFE           *string        `json:"cufe,omitempty"`
+	IAmb           int            `json:"i_amb"`
+}
+
+// EmailWebhookData son los datos de los eventos email.*
+type EmailWebhookData struct {
+	InvoiceID      uuid.UUID   `json:"invoice_id"`
+	DocumentNumber string      `json:"document_number"`
+	EmailStatus    EmailStatus `json:"email_status"`
+	Recipient      string      `json:"recipient"`
+	Error          string      `json:"error,omitempty"`
+}
+
+// WebhookDelivery representa el envío de un evento a un endpoint (tabla webhooks)
+type WebhookDelivery struct {
+	ID             uuid.UUID             `json:"id" db:"id"`
+	EmitterID      uuid.UUID             `json:"emitter_id" db:"emitter_id"`
+	EndpointID     uuid.UUID             `json:"endpoint_id" db:"endpoint_id"`
+	EventID        uuid.UUID             `json:"event_id" db:"event_id"`
+	EventType      WebhookEventType      `json:"event_type" db:"event_type"`
+	Payload        json.RawMessage       `json:"payload" db:"payload"`
+	Status         WebhookDeliveryStatus `json:"status" db:"status"`
+	Attempts       int                   `json:"attempts" db:"attempts"`
+	MaxAttempts    int                   `json:"max_attempts" db:"max_attempts"`
+	LastError      *string               `json:"last_error,omitempty" db:"last_error"`
+	LastStatusCode *int                  `json:"last_status_code,omitempty" db:"last_status_code"`
+	NextRetryAt    *time.Time            `json:"next_retry_at,omitempty" db:"next_retry_at"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty" db:"delivered_at"`
+	CreatedAt      time.Time             `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at" db:"updated_at"`
+	// DedupKey evita encolar dos veces la misma entrega de un evento del outbox
+	DedupKey *string `json:"-" db:"dedup_key"`
+	// AttemptLog es el historial de intentos; Payload es el cuerpo enviado en cada uno
+	AttemptLog []WebhookDeliveryAttempt `json:"attempt_log,omitempty" db:"-"`
+}
+
+// WebhookDeliveryAttempt representa un intento de envío de una entrega y la respuesta del receptor
+type WebhookDeliveryAttempt struct {
+	ID                 uuid.UUID         `json:"id" db:"id"`
+	DeliveryID         uuid.UUID         `json:"delivery_id" db:"delivery_id"`
+	Attempt            int               `json:"attempt" db:"attempt"`
+	RequestURL         string            `json:"request_url" db:"request_url"`
+	RequestHeaders     map[string]string `json:"request_headers" db:"request_headers"`
+	ResponseStatusCode *int              `json:"response_status_code,omitempty" db:"response_status_code"`
+	ResponseBody       *string           `json:"response_body,omitempty" db:"response_body"`
+	LatencyMs          int64             `json:"latency_ms" db:"latency_ms"`
+	Error              *string           `json:"error,omitempty" db:"error"`
+	CreatedAt          time.Time         `json:"created_at" db:"created_at"`
+}
+
+// Succeeded indica si el receptor respondió 2xx
+func (a *WebhookDeliveryAttempt) Succeeded() bool {
+	return a.Error == nil && a.ResponseStatusCode != nil && *a.ResponseStatusCode >= 200 && *a.ResponseStatusCode < 300
+}
+
+// WebhookDeliveryFilter son los filtros del log de entregas
+type WebhookDeliveryFilter struct {
+	EventType WebhookEventType
+	Status    WebhookDeliveryStatus
+	From      *time.Time
+	To        *time.Time
+}
+
+// WebhookDeliveryListResponse representa la respuesta del log de entregas
+type WebhookDeliveryListResponse struct {
+	Items    []WebhookDelivery `json:"items"`
+	Page     int               `json:"page"`
+	PageSize int               `json:"page_size"`
+	Total    int               `json:"total"`
+}
+
+// WebhookTestData son los datos del evento webhook.test
+type WebhookTestData struct {
+	EndpointID uuid.UUID `json:"endpoint_id"`
+	Message    string    `json:"message"`
+}
@@ -0,0 +1,125 @@
+package pac
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/hypernova-labs/dgi-service/internal/config"
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Environment es el ambiente del PAC al que se envía un documento
+type Environment string
+
+const (
+	EnvironmentProduction Environment = "production"
+	EnvironmentSandbox    Environment = "sandbox"
+)
+
+// EnvironmentForIAmb retorna el ambiente del PAC que corresponde al iAmb del documento
+func EnvironmentForIAmb(iamb int) Environment {
+	if iamb == models.IAmbProduction {
+		return EnvironmentProduction
+	}
+	return EnvironmentSandbox
+}
+
+// Credentials son las credenciales del emisor ante el PAC en un ambiente
+type Credentials struct {
+	APIKey          string
+	SubscriptionKey string
+}
+
+// CredentialsFor retorna las credenciales del emisor para un ambiente
+func CredentialsFor(emitter *models.Emitter, env Environment) (Credentials, error) {
+	creds := Credentials{APIKey: emitter.PACAPIKey, SubscriptionKey: emitter.PACSubscriptionKey}
+	if env == EnvironmentSandbox {
+		creds = Credentials{APIKey: emitter.PACSandboxAPIKey, SubscriptionKey: emitter.PACSandboxSubscriptionKey}
+	}
+
+	if creds.APIKey == "" || creds.SubscriptionKey == "" {
+		return Credentials{}, fmt.Errorf("PAC credentials not configured for %s environment", env)
+	}
+	return creds, nil
+}
+
+// SendResult es la respuesta del PAC a un envío
+type SendResult struct {
+	Environment Environment
+	StatusCode  int
+	Body        []byte
+}
+
+// Client envía documentos al PAC, al endpoint de pruebas o de producción según el documento
+type Client struct {
+	httpClient *http.Client
+	endpoints  map[Environment]string
+	logger     *logrus.Logger
+}
+
+// NewClient crea un cliente del PAC con los endpoints de la configuración
+func NewClient(cfg config.PACConfig, logger *logrus.Logger) *Client {
+	return &Client{
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+		endpoints: map[Environment]string{
+			EnvironmentProduction: strings.TrimRight(cfg.APIURL, "/"),
+			EnvironmentSandbox:    strings.TrimRight(cfg.SandboxAPIURL, "/"),
+		},
+		logger: logger,
+	}
+}
+
+// Endpoint retorna la URL base del PAC para un ambiente
+func (c *Client) Endpoint(env Environment) string {
+	return c.endpoints[env]
+}
+
+// SendDocument envía el XML de un documento al PAC. El ambiente (y con él el endpoint y
+// las credenciales) se decide por el iAmb del documento, nunca por la configuración actual
+// del emisor, para que un documento de pruebas no llegue a producción.
+func (c *Client) SendDocument(ctx context.Context, emitter *models.Emitter, invoice *models.Invoice, xmlDoc []byte) (*SendResult, error) {
+	env := EnvironmentForIAmb(invoice.IAmb)
+
+	endpoint := c.Endpoint(env)
+	if endpoint == "" {
+		return nil, fmt.Errorf("PAC endpoint not configured for %s environment", env)
+	}
+
+	creds, err := CredentialsFor(emitter, env)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/documents", bytes.NewReader(xmlDoc))
+	if err != nil {
+		return nil, fmt.Errorf("error building PAC request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("X-API-Key", creds.APIKey)
+	req.Header.Set("Ocp-Apim-Subscription-Key", creds.SubscriptionKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending document to PAC: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading PAC response: %w", err)
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"invoice_id":  invoice.ID,
+		"emitter_id":  emitter.ID,
+		"environment": env,
+		"status_code": resp.StatusCode,
+	}).Info("Document sent to PAC")
+
+	return &SendResult{Environment: env, StatusCode: resp.StatusCode, Body: body}, nil
+}
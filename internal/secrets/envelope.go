@@ -0,0 +1,253 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// prefix identifica los valores cifrados; el formato completo es
+// enc:<key_id>:<dek cifrada en base64>:<dato cifrado en base64>
+const prefix = "enc:"
+
+// keySize es el tamaño de las llaves maestras y de datos (AES-256)
+const keySize = 32
+
+// Envelope cifra secretos con envelope encryption: cada valor usa una llave de
+// datos aleatoria (DEK), que a su vez se cifra con la llave maestra activa (KEK).
+// Las llaves maestras se identifican por ID para poder rotarlas. Cada valor se cifra
+// para un contexto (p. ej. la columna y la fila donde se guarda): descifrarlo con otro
+// contexto falla, así que un valor cifrado no sirve si se copia a otra fila o columna.
+type Envelope struct {
+	keys        map[string][]byte
+	activeKeyID string
+}
+
+// ParseKeys interpreta una lista de llaves maestras con formato "id:base64,id:base64"
+func ParseKeys(spec string) (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid key entry, expected id:base64")
+		}
+
+		id := parts[0]
+		if _, exists := keys[id]; exists {
+			return nil, fmt.Errorf("duplicate key id: %s", id)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("error decoding key %s: %w", id, err)
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("key %s must be %d bytes, got %d", id, keySize, len(key))
+		}
+
+		keys[id] = key
+	}
+
+	return keys, nil
+}
+
+// NewEnvelope crea un Envelope con las llaves maestras y la llave activa indicadas.
+// Si activeKeyID está vacío y sólo hay una llave, se usa esa.
+func NewEnvelope(keys map[string][]byte, activeKeyID string) (*Envelope, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("at least one encryption key is required")
+	}
+
+	if activeKeyID == "" {
+		if len(keys) > 1 {
+			return nil, fmt.Errorf("active key id is required when several keys are configured")
+		}
+		for id := range keys {
+			activeKeyID = id
+		}
+	}
+
+	if _, ok := keys[activeKeyID]; !ok {
+		return nil, fmt.Errorf("active key %s is not configured", activeKeyID)
+	}
+
+	return &Envelope{keys: keys, activeKeyID: activeKeyID}, nil
+}
+
+// NewEnvelopeFromSpec crea un Envelope a partir de la configuración.
+// Retorna nil sin error si no hay llaves configuradas.
+func NewEnvelopeFromSpec(spec, activeKeyID string) (*Envelope, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+
+	keys, err := ParseKeys(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEnvelope(keys, activeKeyID)
+}
+
+// ActiveKeyID retorna el ID de la llave con la que se cifran los valores nuevos
+func (e *Envelope) ActiveKeyID() string {
+	return e.activeKeyID
+}
+
+// KeyIDs retorna los IDs de las llaves configuradas, ordenados
+func (e *Envelope) KeyIDs() []string {
+	ids := make([]string, 0, len(e.keys))
+	for id := range e.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Encrypt cifra un valor para context con la llave activa. Los valores vacíos se mantienen vacíos.
+func (e *Envelope) Encrypt(plaintext, context string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	dek := make([]byte, keySize)
+	if _, err := rand.Read(dek); err != nil {
+		return "", fmt.Errorf("error generating data key: %w", err)
+	}
+
+	aad := additionalData(e.activeKeyID, context)
+
+	wrappedDEK, err := seal(e.keys[e.activeKeyID], dek, aad)
+	if err != nil {
+		return "", fmt.Errorf("error wrapping data key: %w", err)
+	}
+
+	ciphertext, err := seal(dek, []byte(plaintext), aad)
+	if err != nil {
+		return "", fmt.Errorf("error encrypting value: %w", err)
+	}
+
+	return prefix + e.activeKeyID + ":" +
+		base64.StdEncoding.EncodeToString(wrappedDEK) + ":" +
+		base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt descifra un valor cifrado para context. Los valores sin cifrar (legado) se retornan tal cual.
+func (e *Envelope) Decrypt(value, context string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+
+	keyID := parts[0]
+	kek, ok := e.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("encryption key %s is not configured", keyID)
+	}
+
+	wrappedDEK, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("malformed data key: %w", err)
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("malformed ciphertext: %w", err)
+	}
+
+	aad := additionalData(keyID, context)
+
+	dek, err := open(kek, wrappedDEK, aad)
+	if err != nil {
+		return "", fmt.Errorf("error unwrapping data key: %w", err)
+	}
+
+	plaintext, err := open(dek, ciphertext, aad)
+	if err != nil {
+		return "", fmt.Errorf("error decrypting value: %w", err)
+	}
+
+	return string(plaintext), nil
+}
+
+// NeedsReencryption indica si un valor está en texto plano o cifrado con una llave distinta a la activa
+func (e *Envelope) NeedsReencryption(value string) bool {
+	if value == "" {
+		return false
+	}
+	return KeyID(value) != e.activeKeyID
+}
+
+// IsEncrypted indica si un valor tiene el formato de valor cifrado
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// KeyID retorna el ID de la llave con la que se cifró un valor, o "" si está en texto plano
+func KeyID(value string) string {
+	if !IsEncrypted(value) {
+		return ""
+	}
+	rest := strings.TrimPrefix(value, prefix)
+	if i := strings.Index(rest, ":"); i >= 0 {
+		return rest[:i]
+	}
+	return ""
+}
+
+// additionalData arma el dato autenticado de un valor: el ID de la llave y el contexto, para
+// que ninguno de los dos pueda alterarse. Los IDs de llave no contienen ":".
+func additionalData(keyID, context string) []byte {
+	return []byte(keyID + ":" + context)
+}
+
+// seal cifra con AES-GCM anteponiendo el nonce al resultado
+func seal(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// open descifra un valor producido por seal
+func open(key, data, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,211 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+// testKey genera una llave maestra aleatoria
+func testKey(t *testing.T) []byte {
+	t.Helper()
+
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	return key
+}
+
+// testEnvelope crea un Envelope con las llaves indicadas y activeKeyID como llave activa
+func testEnvelope(t *testing.T, keys map[string][]byte, activeKeyID string) *Envelope {
+	t.Helper()
+
+	envelope, err := NewEnvelope(keys, activeKeyID)
+	if err != nil {
+		t.Fatalf("error creating envelope: %v", err)
+	}
+	return envelope
+}
+
+const testContext = "emitters.pac_api_key:0b6f8c1e-3f1a-4c61-9a55-0f3e0a7d2c11"
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	envelope := testEnvelope(t, map[string][]byte{"k1": testKey(t)}, "")
+
+	for _, plaintext := range []string{"pac-api-key", "ñandú €", strings.Repeat("x", 4096)} {
+		encrypted, err := envelope.Encrypt(plaintext, testContext)
+		if err != nil {
+			t.Fatalf("Encrypt(%q) error: %v", plaintext, err)
+		}
+		if !IsEncrypted(encrypted) || KeyID(encrypted) != "k1" {
+			t.Errorf("Encrypt(%q) = %q, want an enc:k1: value", plaintext, encrypted)
+		}
+		if strings.Contains(encrypted, plaintext) {
+			t.Errorf("Encrypt(%q) leaks the plaintext", plaintext)
+		}
+
+		decrypted, err := envelope.Decrypt(encrypted, testContext)
+		if err != nil {
+			t.Fatalf("Decrypt error: %v", err)
+		}
+		if decrypted != plaintext {
+			t.Errorf("Decrypt = %q, want %q", decrypted, plaintext)
+		}
+	}
+}
+
+func TestEnvelopeEmptyAndPlaintextValues(t *testing.T) {
+	envelope := testEnvelope(t, map[string][]byte{"k1": testKey(t)}, "")
+
+	encrypted, err := envelope.Encrypt("", testContext)
+	if err != nil || encrypted != "" {
+		t.Errorf("Encrypt(\"\") = %q, %v; want an empty value", encrypted, err)
+	}
+
+	decrypted, err := envelope.Decrypt("legacy-plaintext", testContext)
+	if err != nil || decrypted != "legacy-plaintext" {
+		t.Errorf("Decrypt(legacy) = %q, %v; want the value unchanged", decrypted, err)
+	}
+}
+
+func TestEnvelopeWrongKey(t *testing.T) {
+	encrypted, err := testEnvelope(t, map[string][]byte{"k1": testKey(t)}, "").Encrypt("secret", testContext)
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+
+	// Misma ID de llave con otro material
+	if _, err := testEnvelope(t, map[string][]byte{"k1": testKey(t)}, "").Decrypt(encrypted, testContext); err == nil {
+		t.Error("Decrypt with a different k1 key succeeded, want an error")
+	}
+
+	// La llave con que se cifró no está configurada
+	if _, err := testEnvelope(t, map[string][]byte{"k2": testKey(t)}, "").Decrypt(encrypted, testContext); err == nil {
+		t.Error("Decrypt without key k1 succeeded, want an error")
+	}
+}
+
+func TestEnvelopeWrongContext(t *testing.T) {
+	envelope := testEnvelope(t, map[string][]byte{"k1": testKey(t)}, "")
+
+	encrypted, err := envelope.Encrypt("secret", "emitters.pac_api_key:emitter-a")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+
+	for _, context := range []string{
+		"emitters.pac_api_key:emitter-b",          // otro emisor
+		"emitters.pac_subscription_key:emitter-a", // otra columna
+		"",
+	} {
+		if _, err := envelope.Decrypt(encrypted, context); err == nil {
+			t.Errorf("Decrypt with context %q succeeded, want an error", context)
+		}
+	}
+}
+
+func TestEnvelopeTamperedCiphertext(t *testing.T) {
+	envelope := testEnvelope(t, map[string][]byte{"k1": testKey(t), "k2": testKey(t)}, "k1")
+
+	encrypted, err := envelope.Encrypt("secret", testContext)
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	parts := strings.Split(strings.TrimPrefix(encrypted, prefix), ":")
+
+	flip := func(encoded string) string {
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			t.Fatalf("error decoding: %v", err)
+		}
+		data[len(data)-1] ^= 0x01
+		return base64.StdEncoding.EncodeToString(data)
+	}
+
+	tampered := map[string]string{
+		"data key":   prefix + parts[0] + ":" + flip(parts[1]) + ":" + parts[2],
+		"ciphertext": prefix + parts[0] + ":" + parts[1] + ":" + flip(parts[2]),
+		"key id":     prefix + "k2:" + parts[1] + ":" + parts[2],
+		"truncated":  prefix + parts[0] + ":" + parts[1] + ":" + base64.StdEncoding.EncodeToString([]byte("x")),
+		"malformed":  prefix + parts[0] + ":" + parts[1],
+		"bad base64": prefix + parts[0] + ":" + parts[1] + ":%%%",
+	}
+	for name, value := range tampered {
+		if _, err := envelope.Decrypt(value, testContext); err == nil {
+			t.Errorf("Decrypt with tampered %s succeeded, want an error", name)
+		}
+	}
+}
+
+func TestEnvelopeRotation(t *testing.T) {
+	oldKey, newKey := testKey(t), testKey(t)
+
+	before := testEnvelope(t, map[string][]byte{"k1": oldKey}, "")
+	encrypted, err := before.Encrypt("secret", testContext)
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+
+	// Llave nueva activa con la anterior todavía configurada
+	during := testEnvelope(t, map[string][]byte{"k1": oldKey, "k2": newKey}, "k2")
+	if !during.NeedsReencryption(encrypted) {
+		t.Error("NeedsReencryption = false for a value under the previous key")
+	}
+	if !during.NeedsReencryption("legacy-plaintext") {
+		t.Error("NeedsReencryption = false for a plaintext value")
+	}
+	if during.NeedsReencryption("") {
+		t.Error("NeedsReencryption = true for an empty value")
+	}
+
+	decrypted, err := during.Decrypt(encrypted, testContext)
+	if err != nil || decrypted != "secret" {
+		t.Fatalf("Decrypt under previous key = %q, %v; want the original value", decrypted, err)
+	}
+	reencrypted, err := during.Encrypt(decrypted, testContext)
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	if KeyID(reencrypted) != "k2" || during.NeedsReencryption(reencrypted) {
+		t.Errorf("re-encrypted value uses key %q, want k2", KeyID(reencrypted))
+	}
+
+	// Retirada la llave anterior, el valor re-cifrado se sigue leyendo y el viejo no
+	after := testEnvelope(t, map[string][]byte{"k2": newKey}, "")
+	if decrypted, err := after.Decrypt(reencrypted, testContext); err != nil || decrypted != "secret" {
+		t.Errorf("Decrypt after rotation = %q, %v; want the original value", decrypted, err)
+	}
+	if _, err := after.Decrypt(encrypted, testContext); err == nil {
+		t.Error("Decrypt of a value under a retired key succeeded, want an error")
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	valid := base64.StdEncoding.EncodeToString(make([]byte, keySize))
+
+	keys, err := ParseKeys("k1:" + valid + ", k2:" + valid)
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("ParseKeys = %v, %v; want 2 keys", keys, err)
+	}
+
+	for _, spec := range []string{
+		"k1",             // sin llave
+		":" + valid,      // sin ID
+		"k1:not-base64!", // base64 inválido
+		"k1:" + base64.StdEncoding.EncodeToString([]byte("short")),
+		"k1:" + valid + ",k1:" + valid, // ID duplicado
+	} {
+		if _, err := ParseKeys(spec); err == nil {
+			t.Errorf("ParseKeys(%q) succeeded, want an error", spec)
+		}
+	}
+
+	if _, err := NewEnvelope(keys, ""); err == nil {
+		t.Error("NewEnvelope with several keys and no active key succeeded, want an error")
+	}
+	if _, err := NewEnvelope(keys, "k3"); err == nil {
+		t.Error("NewEnvelope with an unknown active key succeeded, want an error")
+	}
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hypernova-labs/dgi-service/internal/database"
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/hypernova-labs/dgi-service/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// ArtifactService guarda cada archivo generado de un documento como una versión inmutable. El
+// contenido va al storage con una key por contenido que nunca se sobreescribe; sin storage, o si
+// el storage falla, queda en la BD hasta que el migrador lo mueva.
+type ArtifactService struct {
+	artifactRepo *database.InvoiceArtifactRepository
+	invoiceRepo  *database.InvoiceRepository
+	store        storage.BlobStore
+	signedURLTTL time.Duration
+	logger       *logrus.Logger
+}
+
+// NewArtifactService crea una nueva instancia del servicio; store puede ser nil y signedURLTTL es
+// la validez de las URLs prefirmadas de descarga
+func NewArtifactService(db *database.DB, store storage.BlobStore, signedURLTTL time.Duration, logger *logrus.Logger) *ArtifactService {
+	return &ArtifactService{
+		artifactRepo: database.NewInvoiceArtifactRepository(db, logger),
+		invoiceRepo:  database.NewInvoiceRepository(db, logger),
+		store:        store,
+		signedURLTTL: signedURLTTL,
+		logger:       logger,
+	}
+}
+
+// artifactKey es la key de un archivo en el storage: depende del contenido, así que una versión
+// nunca reemplaza a otra
+func artifactKey(invoiceID uuid.UUID, kind models.ArtifactKind, checksum string) string {
+	return fmt.Sprintf("invoices/%s/artifacts/%s/%s.%s", invoiceID, kind, checksum, kind.Extension())
+}
+
+// artifactURL es la ruta de la API que descarga una versión
+func artifactURL(invoiceID uuid.UUID, kind models.ArtifactKind, version int) string {
+	return fmt.Sprintf("/v1/invoices/%s/artifacts/%s/%d", invoiceID, kind, version)
+}
+
+// Record guarda data como la siguiente versión del archivo kind del documento. Si es igual a la
+// última versión de ese tipo retorna la existente.
+func (s *ArtifactService) Record(ctx context.Context, invoice *models.Invoice, kind models.ArtifactKind, data []byte, generatorVersion string) (*models.InvoiceArtifact, error) {
+	checksum := sha256Hex(data)
+	artifact := &models.InvoiceArtifact{
+		InvoiceID:        invoice.ID,
+		EmitterID:        invoice.EmitterID,
+		Kind:             kind,
+		ContentType:      kind.ContentType(),
+		Size:             int64(len(data)),
+		SHA256:           checksum,
+		GeneratorVersion: &generatorVersion,
+	}
+
+	if s.store != nil {
+		key := artifactKey(invoice.ID, kind, checksum)
+		if err := s.store.Put(ctx, key, data, artifact.ContentType); err != nil {
+			s.logger.WithError(err).WithFields(logrus.Fields{
+				"invoice_id": invoice.ID,
+				"kind":       kind,
+			}).Warn("Failed to store invoice artifact, keeping it in the database")
+			artifact.Data = data
+		} else {
+			artifact.StorageKey = &key
+		}
+	} else {
+		artifact.Data = data
+	}
+
+	recorded, created, err := s.artifactRepo.Create(artifact)
+	if err != nil {
+		return nil, fmt.Errorf("error recording invoice artifact: %w", err)
+	}
+	recorded.DownloadURL = artifactURL(recorded.InvoiceID, recorded.Kind, recorded.Version)
+
+	if created {
+		s.logger.WithFields(logrus.Fields{
+			"invoice_id": invoice.ID,
+			"kind":       kind,
+			"version":    recorded.Version,
+			"sha256":     checksum,
+			"size":       recorded.Size,
+		}).Info("Invoice artifact version recorded")
+	}
+
+	return recorded, nil
+}
+
+// List obtiene las versiones de los archivos de un documento del emisor
+func (s *ArtifactService) List(emitterID, invoiceID uuid.UUID) ([]models.InvoiceArtifact, error) {
+	if err := s.checkOwnership(emitterID, invoiceID); err != nil {
+		return nil, err
+	}
+
+	artifacts, err := s.artifactRepo.ListByInvoiceID(invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing invoice artifacts: %w", err)
+	}
+	for i := range artifacts {
+		artifacts[i].DownloadURL = artifactURL(invoiceID, artifacts[i].Kind, artifacts[i].Version)
+	}
+
+	return artifacts, nil
+}
+
+// LatestURL retorna la ruta de descarga de la última versión de un tipo de archivo del
+// documento, o nil si todavía no tiene ninguna
+func (s *ArtifactService) LatestURL(invoiceID uuid.UUID, kind models.ArtifactKind) (*string, error) {
+	version, err := s.artifactRepo.LatestVersion(invoiceID, kind)
+	if err != nil || version == 0 {
+		return nil, err
+	}
+
+	url := artifactURL(invoiceID, kind, version)
+	return &url, nil
+}
+
+// Download obtiene una versión de un archivo de un documento del emisor: una URL prefirmada si
+// está en un storage que las genera o, si no, su contenido verificado contra el SHA-256 registrado
+func (s *ArtifactService) Download(ctx context.Context, emitterID, invoiceID uuid.UUID, kind models.ArtifactKind, version int) (*models.InvoiceArtifact, *FileDownload, error) {
+	if err := s.checkOwnership(emitterID, invoiceID); err != nil {
+		return nil, nil, err
+	}
+
+	artifact, err := s.artifactRepo.GetVersion(invoiceID, kind, version)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	download := &FileDownload{
+		FileName:    fmt.Sprintf("%s_%s_v%d.%s", invoiceID, kind, version, kind.Extension()),
+		ContentType: artifact.ContentType,
+		SHA256:      &artifact.SHA256,
+	}
+	data := artifact.Data
+	artifact.Data = nil
+	artifact.DownloadURL = artifactURL(invoiceID, kind, version)
+
+	if data == nil {
+		if s.store == nil || artifact.StorageKey == nil {
+			return nil, nil, fmt.Errorf("storage service not available")
+		}
+
+		download.URL, download.ExpiresAt = signedURL(ctx, s.store, *artifact.StorageKey, download.FileName, s.signedURLTTL, s.logger)
+		if download.URL != "" {
+			return artifact, download, nil
+		}
+
+		data, err = s.store.Get(ctx, *artifact.StorageKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error downloading invoice artifact: %w", err)
+		}
+	}
+
+	if sha256Hex(data) != artifact.SHA256 {
+		return nil, nil, fmt.Errorf("invoice artifact checksum mismatch: %s v%d of invoice %s", kind, version, invoiceID)
+	}
+
+	download.Data = data
+	return artifact, download, nil
+}
+
+// MoveToStorage copia al storage el contenido de una versión guardada en la BD, verifica la copia
+// por SHA-256 y sólo entonces lo deja en NULL en la BD
+func (s *ArtifactService) MoveToStorage(ctx context.Context, artifact *models.InvoiceArtifact) error {
+	if sha256Hex(artifact.Data) != artifact.SHA256 {
+		return fmt.Errorf("%s v%d checksum mismatch in database for invoice %s", artifact.Kind, artifact.Version, artifact.InvoiceID)
+	}
+
+	key := artifactKey(artifact.InvoiceID, artifact.Kind, artifact.SHA256)
+	if err := s.store.Put(ctx, key, artifact.Data, artifact.ContentType); err != nil {
+		return fmt.Errorf("error uploading invoice artifact to storage: %w", err)
+	}
+
+	stored, err := s.store.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("error verifying invoice artifact in storage: %w", err)
+	}
+	if sha256Hex(stored) != artifact.SHA256 {
+		return fmt.Errorf("%s v%d checksum mismatch in storage for invoice %s", artifact.Kind, artifact.Version, artifact.InvoiceID)
+	}
+
+	return s.artifactRepo.MarkMovedToStorage(artifact.ID, key)
+}
+
+// checkOwnership verifica que el documento exista y pertenezca al emisor
+func (s *ArtifactService) checkOwnership(emitterID, invoiceID uuid.UUID) error {
+	invoice, err := s.invoiceRepo.GetByID(invoiceID)
+	if err != nil {
+		return err
+	}
+	if invoice.EmitterID != emitterID {
+		return fmt.Errorf("invoice not found: %s", invoiceID)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hypernova-labs/dgi-service/internal/database"
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// AuditService consulta el log de auditoría de los emisores para revisiones de cumplimiento
+type AuditService struct {
+	auditRepo *database.AuditRepository
+	logger    *logrus.Logger
+}
+
+// NewAuditService crea una nueva instancia del servicio
+func NewAuditService(db *database.DB, logger *logrus.Logger) *AuditService {
+	return &AuditService{
+		auditRepo: database.NewAuditRepository(db, logger),
+		logger:    logger,
+	}
+}
+
+// List obtiene los cambios de un recurso de un emisor, del más reciente al más antiguo;
+// con recordID sólo los de ese registro
+func (s *AuditService) List(emitterID uuid.UUID, resource models.AuditResource, recordID *uuid.UUID, page, pageSize int) ([]models.AuditLog, int, error) {
+	table, ok := resource.Table()
+	if !ok {
+		resources := make([]string, 0, len(models.AuditResources()))
+		for _, r := range models.AuditResources() {
+			resources = append(resources, string(r))
+		}
+		return nil, 0, fmt.Errorf("validation error: %w", models.FieldErrors{
+			{Field: "resource", Issue: "Must be one of: " + strings.Join(resources, ", ")},
+		})
+	}
+
+	logs, total, err := s.auditRepo.List(emitterID, table, recordID, page, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing audit logs: %w", err)
+	}
+
+	return logs, total, nil
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/hypernova-labs/dgi-service/internal/database"
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// BranchService maneja la lógica de negocio para Branch
+type BranchService struct {
+	branchRepo  *database.BranchRepository
+	emitterRepo *database.EmitterRepository
+	logger      *logrus.Logger
+}
+
+// NewBranchService crea una nueva instancia del servicio
+func NewBranchService(db *database.DB, logger *logrus.Logger) *BranchService {
+	return &BranchService{
+		branchRepo:  database.NewBranchRepository(db, logger),
+		emitterRepo: database.NewEmitterRepository(db, logger),
+		logger:      logger,
+	}
+}
+
+// Create crea una nueva sucursal para un emisor
+func (s *BranchService) Create(emitterID uuid.UUID, req *models.CreateBranchRequest) (*models.Branch, error) {
+	// La sucursal principal se crea a partir del emisor para que el código no quede tomado
+	emitter, err := s.emitterRepo.GetByID(emitterID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting emitter: %w", err)
+	}
+	if _, err := s.branchRepo.EnsureMain(emitter); err != nil {
+		return nil, fmt.Errorf("error getting main branch: %w", err)
+	}
+
+	branch, err := s.branchRepo.Create(emitterID, req)
+	if err != nil {
+		return nil, fmt.Errorf("error creating branch: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"emitter_id": emitterID,
+		"branch_id":  branch.ID,
+		"code":       branch.Code,
+	}).Info("Branch created successfully")
+
+	return branch, nil
+}
+
+// GetByID obtiene una sucursal de un emisor
+func (s *BranchService) GetByID(emitterID, id uuid.UUID) (*models.Branch, error) {
+	branch, err := s.branchRepo.GetByID(emitterID, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting branch: %w", err)
+	}
+	return branch, nil
+}
+
+// List obtiene las sucursales de un emisor, incluyendo siempre la principal
+func (s *BranchService) List(emitterID uuid.UUID, includeInactive bool) ([]models.Branch, error) {
+	emitter, err := s.emitterRepo.GetByID(emitterID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting emitter: %w", err)
+	}
+	if _, err := s.branchRepo.EnsureMain(emitter); err != nil {
+		return nil, fmt.Errorf("error getting main branch: %w", err)
+	}
+
+	branches, err := s.branchRepo.List(emitterID, includeInactive)
+	if err != nil {
+		return nil, fmt.Errorf("error listing branches: %w", err)
+	}
+	return branches, nil
+}
+
+// Update actualiza parcialmente una sucursal de un emisor
+func (s *BranchService) Update(emitterID, id uuid.UUID, req *models.UpdateBranchRequest) (*models.Branch, error) {
+	branch, err := s.branchRepo.GetByID(emitterID, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting existing branch: %w", err)
+	}
+
+	// Aplicar solo los campos enviados
+	if req.Name != nil {
+		branch.Name = *req.Name
+	}
+	if req.AddressLine != nil {
+		branch.AddressLine = req.AddressLine
+	}
+	if req.UBICode != nil {
+		branch.UBICode = req.UBICode
+	}
+	if req.Phone != nil {
+		branch.Phone = req.Phone
+	}
+	if req.PtoFacDefault != nil {
+		branch.PtoFacDefault = *req.PtoFacDefault
+	}
+	if req.BrandLogoURL != nil {
+		branch.BrandLogoURL = req.BrandLogoURL
+	}
+	if req.BrandPrimaryColor != nil {
+		branch.BrandPrimaryColor = req.BrandPrimaryColor
+	}
+	if req.BrandFooterHTML != nil {
+		branch.BrandFooterHTML = req.BrandFooterHTML
+	}
+
+	branch, err = s.branchRepo.Update(branch)
+	if err != nil {
+		return nil, fmt.Errorf("error updating branch: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"emitter_id": emitterID,
+		"branch_id":  id,
+		"code":       branch.Code,
+	}).Info("Branch updated successfully")
+
+	return branch, nil
+}
+
+// Delete marca una sucursal como inactiva; la sucursal principal no se puede desactivar
+func (s *BranchService) Delete(emitterID, id uuid.UUID) error {
+	branch, err := s.branchRepo.GetByID(emitterID, id)
+	if err != nil {
+		return fmt.Errorf("error getting branch: %w", err)
+	}
+
+	emitter, err := s.emitterRepo.GetByID(emitterID)
+	if err != nil {
+		return fmt.Errorf("error getting emitter: %w", err)
+	}
+	if branch.Code == emitter.SucEm {
+		return fmt.Errorf("validation error: %w", models.FieldErrors{
+			{Field: "branch_id", Issue: "The main branch cannot be deactivated"},
+		})
+	}
+
+	if err := s.branchRepo.Delete(emitterID, id); err != nil {
+		return fmt.Errorf("error deleting branch: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"emitter_id": emitterID,
+		"branch_id":  id,
+		"code":       branch.Code,
+	}).Info("Branch deleted successfully")
+
+	return nil
+}
+
+// Resolve obtiene la sucursal activa indicada por código, o la principal del emisor si no se indica
+func (s *BranchService) Resolve(emitter *models.Emitter, code string) (*models.Branch, error) {
+	if code == "" || code == emitter.SucEm {
+		return s.branchRepo.EnsureMain(emitter)
+	}
+	return s.branchRepo.GetByCode(emitter.ID, code)
+}
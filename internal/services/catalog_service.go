@@ -0,0 +1,333 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hypernova-labs/dgi-service/internal/database"
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// catalogCacheTTL define cada cuánto se recargan los catálogos en memoria
+const catalogCacheTTL = 10 * time.Minute
+
+// CatalogService maneja los catálogos DGI (CPBS, tasas de ITBMS y formas de pago)
+type CatalogService struct {
+	catalogRepo *database.CatalogRepository
+	logger      *logrus.Logger
+
+	mu             sync.RWMutex
+	loadedAt       time.Time
+	cpbs           map[string]string
+	taxRates       map[string]models.TaxRateEntry
+	paymentMethods map[string]models.PaymentMethodEntry
+}
+
+// NewCatalogService crea una nueva instancia del servicio
+func NewCatalogService(db *database.DB, logger *logrus.Logger) *CatalogService {
+	return &CatalogService{
+		catalogRepo: database.NewCatalogRepository(db, logger),
+		logger:      logger,
+	}
+}
+
+// SearchCPBS busca códigos CPBS por descripción o prefijo de código
+func (s *CatalogService) SearchCPBS(search string, limit int) ([]models.CPBSEntry, error) {
+	entries, err := s.catalogRepo.SearchCPBS(strings.TrimSpace(search), limit)
+	if err != nil {
+		return nil, fmt.Errorf("error searching CPBS catalog: %w", err)
+	}
+
+	return entries, nil
+}
+
+// GetTaxRates obtiene el catálogo de tasas de ITBMS
+func (s *CatalogService) GetTaxRates() ([]models.TaxRateEntry, error) {
+	entries, err := s.catalogRepo.GetTaxRates()
+	if err != nil {
+		return nil, fmt.Errorf("error getting tax rates: %w", err)
+	}
+
+	return entries, nil
+}
+
+// GetPaymentMethods obtiene el catálogo de formas de pago
+func (s *CatalogService) GetPaymentMethods() ([]models.PaymentMethodEntry, error) {
+	entries, err := s.catalogRepo.GetPaymentMethods()
+	if err != nil {
+		return nil, fmt.Errorf("error getting payment methods: %w", err)
+	}
+
+	return entries, nil
+}
+
+// ValidateCPBS valida que el par CPBS exista en el catálogo. Ambos códigos son opcionales,
+// pero si se envía uno se debe enviar el otro.
+func (s *CatalogService) ValidateCPBS(abr, cmp *string) error {
+	if abr == nil && cmp == nil {
+		return nil
+	}
+	if abr == nil || cmp == nil {
+		return fmt.Errorf("cpbs_abr and cpbs_cmp must be provided together")
+	}
+	if !strings.HasPrefix(*cmp, *abr) {
+		return fmt.Errorf("cpbs_cmp %s does not belong to segment %s", *cmp, *abr)
+	}
+
+	if err := s.ensureLoaded(); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Sin catálogo cargado no se puede validar
+	if len(s.cpbs) == 0 {
+		s.logger.Warn("CPBS catalog is empty, skipping CPBS validation")
+		return nil
+	}
+	if _, ok := s.cpbs[cpbsKey(*abr, *cmp)]; !ok {
+		return fmt.Errorf("CPBS code %s-%s not found in catalog", *abr, *cmp)
+	}
+
+	return nil
+}
+
+// ValidateTaxRate valida que la tasa de ITBMS exista y esté activa en el catálogo
+func (s *CatalogService) ValidateTaxRate(code string) error {
+	if err := s.ensureLoaded(); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.taxRates[code]
+	if !ok || !entry.IsActive {
+		return fmt.Errorf("invalid tax rate: %s", code)
+	}
+
+	return nil
+}
+
+// ValidatePaymentMethod valida que la forma de pago exista y esté activa en el catálogo
+func (s *CatalogService) ValidatePaymentMethod(code string) error {
+	if err := s.ensureLoaded(); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.paymentMethods[code]
+	if !ok || !entry.IsActive {
+		return fmt.Errorf("invalid payment method: %s", code)
+	}
+
+	return nil
+}
+
+// LoadCPBSFile carga el catálogo CPBS oficial desde un archivo CSV/XLSX
+// con columnas cpbs_abr, cpbs_cmp, description
+func (s *CatalogService) LoadCPBSFile(path string) (int, error) {
+	rows, header, err := readCatalogFile(path, []string{"cpbs_abr", "cpbs_cmp", "description"})
+	if err != nil {
+		return 0, err
+	}
+
+	entries := make([]models.CPBSEntry, 0, len(rows))
+	for i, row := range rows {
+		if isEmptyRow(row) {
+			continue
+		}
+		entry := models.CPBSEntry{
+			Abr:         importCell(row, header, "cpbs_abr"),
+			Cmp:         importCell(row, header, "cpbs_cmp"),
+			Description: importCell(row, header, "description"),
+		}
+		if len(entry.Abr) != 2 || len(entry.Cmp) != 4 || entry.Description == "" {
+			return 0, fmt.Errorf("invalid CPBS entry at row %d", i+2)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := s.catalogRepo.UpsertCPBS(entries); err != nil {
+		return 0, fmt.Errorf("error loading CPBS catalog: %w", err)
+	}
+
+	s.invalidate()
+	return len(entries), nil
+}
+
+// LoadTaxRatesFile carga el catálogo de tasas de ITBMS desde un archivo CSV/XLSX
+// con columnas code, description, rate (porcentaje) e is_active (opcional)
+func (s *CatalogService) LoadTaxRatesFile(path string) (int, error) {
+	rows, header, err := readCatalogFile(path, []string{"code", "description", "rate", "is_active"})
+	if err != nil {
+		return 0, err
+	}
+
+	entries := make([]models.TaxRateEntry, 0, len(rows))
+	for i, row := range rows {
+		if isEmptyRow(row) {
+			continue
+		}
+		rate, err := strconv.ParseFloat(importCell(row, header, "rate"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid tax rate at row %d: rate must be a number", i+2)
+		}
+		entry := models.TaxRateEntry{
+			Code:        importCell(row, header, "code"),
+			Description: importCell(row, header, "description"),
+			Rate:        rate,
+			IsActive:    parseCatalogActive(importCell(row, header, "is_active")),
+		}
+		if len(entry.Code) != 2 || entry.Description == "" {
+			return 0, fmt.Errorf("invalid tax rate at row %d", i+2)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := s.catalogRepo.UpsertTaxRates(entries); err != nil {
+		return 0, fmt.Errorf("error loading tax rates: %w", err)
+	}
+
+	s.invalidate()
+	return len(entries), nil
+}
+
+// LoadPaymentMethodsFile carga el catálogo de formas de pago desde un archivo CSV/XLSX
+// con columnas code, description e is_active (opcional)
+func (s *CatalogService) LoadPaymentMethodsFile(path string) (int, error) {
+	rows, header, err := readCatalogFile(path, []string{"code", "description", "is_active"})
+	if err != nil {
+		return 0, err
+	}
+
+	entries := make([]models.PaymentMethodEntry, 0, len(rows))
+	for i, row := range rows {
+		if isEmptyRow(row) {
+			continue
+		}
+		entry := models.PaymentMethodEntry{
+			Code:        importCell(row, header, "code"),
+			Description: importCell(row, header, "description"),
+			IsActive:    parseCatalogActive(importCell(row, header, "is_active")),
+		}
+		if len(entry.Code) != 2 || entry.Description == "" {
+			return 0, fmt.Errorf("invalid payment method at row %d", i+2)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := s.catalogRepo.UpsertPaymentMethods(entries); err != nil {
+		return 0, fmt.Errorf("error loading payment methods: %w", err)
+	}
+
+	s.invalidate()
+	return len(entries), nil
+}
+
+// ensureLoaded carga los catálogos en memoria si no están cargados o expiraron
+func (s *CatalogService) ensureLoaded() error {
+	s.mu.RLock()
+	fresh := s.cpbs != nil && time.Since(s.loadedAt) < catalogCacheTTL
+	s.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	cpbsEntries, err := s.catalogRepo.GetAllCPBS()
+	if err != nil {
+		return fmt.Errorf("error loading CPBS catalog: %w", err)
+	}
+	taxRates, err := s.catalogRepo.GetTaxRates()
+	if err != nil {
+		return fmt.Errorf("error loading tax rates: %w", err)
+	}
+	paymentMethods, err := s.catalogRepo.GetPaymentMethods()
+	if err != nil {
+		return fmt.Errorf("error loading payment methods: %w", err)
+	}
+
+	cpbs := make(map[string]string, len(cpbsEntries))
+	for _, entry := range cpbsEntries {
+		cpbs[cpbsKey(entry.Abr, entry.Cmp)] = entry.Description
+	}
+	taxRateMap := make(map[string]models.TaxRateEntry, len(taxRates))
+	for _, entry := range taxRates {
+		taxRateMap[entry.Code] = entry
+	}
+	paymentMethodMap := make(map[string]models.PaymentMethodEntry, len(paymentMethods))
+	for _, entry := range paymentMethods {
+		paymentMethodMap[entry.Code] = entry
+	}
+
+	s.mu.Lock()
+	s.cpbs = cpbs
+	s.taxRates = taxRateMap
+	s.paymentMethods = paymentMethodMap
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+
+	return nil
+}
+
+// invalidate fuerza la recarga de los catálogos en memoria
+func (s *CatalogService) invalidate() {
+	s.mu.Lock()
+	s.cpbs = nil
+	s.mu.Unlock()
+}
+
+// readCatalogFile lee un archivo de catálogo CSV/XLSX y ubica sus columnas
+func readCatalogFile(path string, columns []string) ([][]string, map[string]int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading catalog file: %w", err)
+	}
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	rows, err := readSpreadsheet(data, format)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rows) < 2 {
+		return nil, nil, fmt.Errorf("catalog file %s has no data rows", path)
+	}
+
+	// Todas las columnas excepto is_active son requeridas
+	var required []string
+	for _, column := range columns {
+		if column != "is_active" {
+			required = append(required, column)
+		}
+	}
+
+	header, err := mapImportHeader(rows[0], columns, required)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return rows[1:], header, nil
+}
+
+// parseCatalogActive interpreta la columna is_active (vacía = activo)
+func parseCatalogActive(value string) bool {
+	if value == "" {
+		return true
+	}
+	active, err := strconv.ParseBool(value)
+	return err != nil || active
+}
+
+// cpbsKey construye la clave de búsqueda de un par CPBS
+func cpbsKey(abr, cmp string) string {
+	return abr + "-" + cmp
+}
@@ -24,29 +24,24 @@ func NewCustomerService(db *database.DB, logger *logrus.Logger) *CustomerService
 	}
 }
 
-// Create crea un nuevo cliente
-func (s *CustomerService) Create(req *models.CreateCustomerRequest, emitterID uuid.UUID) (*models.Customer, error) {
+// Create crea un nuevo cliente a nombre de actor
+func (s *CustomerService) Create(req *models.CreateCustomerRequest, emitterID uuid.UUID, actor string) (*models.Customer, error) {
 	// Validar datos del cliente
 	if err := s.validateCustomerData(req); err != nil {
 		return nil, fmt.Errorf("validation error: %w", err)
 	}
 
-	// Verificar si el cliente ya existe
-	existingCustomer, err := s.customerRepo.GetByEmail(emitterID, req.Email)
-	if err == nil && existingCustomer != nil {
-		// Cliente ya existe, actualizar datos si es necesario
-		s.logger.WithFields(logrus.Fields{
-			"emitter_id": emitterID,
-			"email":      req.Email,
-			"customer_id": existingCustomer.ID,
-		}).Info("Customer already exists, updating if needed")
-
-		// TODO: Implementar lógica de actualización si los datos son diferentes
-		return existingCustomer, nil
+	// Verificar si el cliente ya existe; los cambios a un cliente existente se hacen con Update
+	existing, err := s.Match(emitterID, req, models.CustomerMatchTaxID)
+	if err != nil {
+		return nil, fmt.Errorf("error matching customer: %w", err)
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("customer already exists: %s", existing.ID)
 	}
 
 	// Crear nuevo cliente
-	customer, err := s.customerRepo.Create(req, emitterID)
+	customer, err := s.customerRepo.Create(req, emitterID, actor)
 	if err != nil {
 		return nil, fmt.Errorf("error creating customer: %w", err)
 	}
@@ -61,9 +56,9 @@ func (s *CustomerService) Create(req *models.CreateCustomerRequest, emitterID uu
 	return customer, nil
 }
 
-// GetByID obtiene un cliente por ID
-func (s *CustomerService) GetByID(id uuid.UUID) (*models.Customer, error) {
-	customer, err := s.customerRepo.GetByID(id)
+// GetByID obtiene un cliente activo de un emisor
+func (s *CustomerService) GetByID(emitterID, id uuid.UUID) (*models.Customer, error) {
+	customer, err := s.customerRepo.GetByEmitterAndID(emitterID, id)
 	if err != nil {
 		return nil, fmt.Errorf("error getting customer: %w", err)
 	}
@@ -71,6 +66,16 @@ func (s *CustomerService) GetByID(id uuid.UUID) (*models.Customer, error) {
 	return customer, nil
 }
 
+// List obtiene los clientes de un emisor con paginación y búsqueda por nombre, email o tax_id
+func (s *CustomerService) List(emitterID uuid.UUID, search string, page, pageSize int) ([]models.Customer, int, error) {
+	customers, total, err := s.customerRepo.Search(emitterID, strings.TrimSpace(search), page, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting customers: %w", err)
+	}
+
+	return customers, total, nil
+}
+
 // GetByEmail obtiene un cliente por email y emisor
 func (s *CustomerService) GetByEmail(emitterID uuid.UUID, email string) (*models.Customer, error) {
 	customer, err := s.customerRepo.GetByEmail(emitterID, email)
@@ -91,20 +96,90 @@ func (s *CustomerService) GetByEmitterID(emitterID uuid.UUID) ([]models.Customer
 	return customers, nil
 }
 
-// Update actualiza un cliente existente
-func (s *CustomerService) Update(id uuid.UUID, req *models.CreateCustomerRequest) (*models.Customer, error) {
-	// Validar datos del cliente
-	if err := s.validateCustomerData(req); err != nil {
+// Update actualiza parcialmente un cliente de un emisor a nombre de actor
+func (s *CustomerService) Update(emitterID, id uuid.UUID, req *models.UpdateCustomerRequest, actor string) (*models.Customer, error) {
+	customer, err := s.customerRepo.GetByEmitterAndID(emitterID, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting customer: %w", err)
+	}
+
+	// Aplicar solo los campos enviados
+	if req.Name != nil {
+		customer.Name = *req.Name
+	}
+	if req.Email != nil {
+		customer.Email = *req.Email
+	}
+	if req.Phone != nil {
+		customer.Phone = req.Phone
+	}
+	if req.Address != nil {
+		customer.AddressLine = req.Address
+	}
+	if req.UBICode != nil {
+		customer.UBICode = req.UBICode
+	}
+	if req.TaxID != nil {
+		// Un RUC nuevo invalida el tipo y DV anteriores si no se envían
+		customer.TaxID = req.TaxID
+		customer.TaxIDType = req.TaxIDType
+		customer.TaxIDDV = req.TaxIDDV
+	}
+	if req.TaxIDType != nil {
+		customer.TaxIDType = req.TaxIDType
+	}
+	if req.TaxIDDV != nil {
+		customer.TaxIDDV = req.TaxIDDV
+	}
+	if req.RecipientType != nil {
+		customer.RecipientType = *req.RecipientType
+	}
+	if req.ForeignID != nil {
+		customer.ForeignID = req.ForeignID
+	}
+	if req.CountryCode != nil {
+		customer.CountryCode = req.CountryCode
+	}
+
+	// Validar datos resultantes
+	validated := &models.CreateCustomerRequest{
+		Name:          customer.Name,
+		Email:         customer.Email,
+		Phone:         customer.Phone,
+		Address:       customer.AddressLine,
+		UBICode:       customer.UBICode,
+		TaxID:         customer.TaxID,
+		TaxIDType:     customer.TaxIDType,
+		TaxIDDV:       customer.TaxIDDV,
+		RecipientType: customer.RecipientType,
+		ForeignID:     customer.ForeignID,
+		CountryCode:   customer.CountryCode,
+	}
+	if err := s.validateCustomerData(validated); err != nil {
 		return nil, fmt.Errorf("validation error: %w", err)
 	}
+	customer.TaxID = validated.TaxID
+	customer.TaxIDType = validated.TaxIDType
+	customer.TaxIDDV = validated.TaxIDDV
+	customer.RecipientType = validated.RecipientType
+	customer.CountryCode = validated.CountryCode
+
+	// Verificar que el RUC no pertenezca a otro cliente (el email sí puede compartirse)
+	if customer.TaxID != nil {
+		existing, err := s.customerRepo.GetByTaxID(emitterID, *customer.TaxID)
+		if err == nil && existing.ID != customer.ID {
+			return nil, fmt.Errorf("customer already exists with tax ID %s", *customer.TaxID)
+		}
+	}
 
 	// Actualizar cliente
-	customer, err := s.customerRepo.Update(id, req)
+	customer, err = s.customerRepo.Update(emitterID, customer, actor)
 	if err != nil {
 		return nil, fmt.Errorf("error updating customer: %w", err)
 	}
 
 	s.logger.WithFields(logrus.Fields{
+		"emitter_id":  emitterID,
 		"customer_id": id,
 		"email":       customer.Email,
 		"name":        customer.Name,
@@ -113,14 +188,48 @@ func (s *CustomerService) Update(id uuid.UUID, req *models.CreateCustomerRequest
 	return customer, nil
 }
 
-// Delete marca un cliente como inactivo
-func (s *CustomerService) Delete(id uuid.UUID) error {
-	err := s.customerRepo.Delete(id)
+// Match busca un cliente activo que corresponda a los datos enviados según el modo de búsqueda.
+// Con CustomerMatchTaxID se busca primero por RUC y luego por email, descartando clientes con otro
+// RUC (dos empresas pueden compartir el email de contabilidad). Retorna nil si no hay coincidencia.
+func (s *CustomerService) Match(emitterID uuid.UUID, req *models.CreateCustomerRequest, mode models.CustomerMatchMode) (*models.Customer, error) {
+	if mode == models.CustomerMatchNone {
+		return nil, nil
+	}
+
+	if mode != models.CustomerMatchEmail && req.TaxID != nil {
+		customer, err := s.customerRepo.GetByTaxID(emitterID, *req.TaxID)
+		if err == nil {
+			return customer, nil
+		}
+		if !strings.Contains(err.Error(), "not found") {
+			return nil, err
+		}
+	}
+
+	customer, err := s.customerRepo.GetByEmail(emitterID, req.Email)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if mode != models.CustomerMatchEmail && req.TaxID != nil && customer.TaxID != nil {
+		return nil, nil
+	}
+
+	return customer, nil
+}
+
+// Delete marca un cliente de un emisor como inactivo a nombre de actor
+func (s *CustomerService) Delete(emitterID, id uuid.UUID, actor string) error {
+	err := s.customerRepo.Delete(emitterID, id, actor)
 	if err != nil {
 		return fmt.Errorf("error deleting customer: %w", err)
 	}
 
 	s.logger.WithFields(logrus.Fields{
+		"emitter_id":  emitterID,
 		"customer_id": id,
 	}).Info("Customer deleted successfully")
 
@@ -169,6 +278,95 @@ func (s *CustomerService) validateCustomerData(req *models.CreateCustomerRequest
 		return fmt.Errorf("UBI code too long (max 10 characters)")
 	}
 
+	// Validar longitud del tax ID si se proporciona
+	if req.TaxID != nil && len(*req.TaxID) > 20 {
+		return fmt.Errorf("tax ID too long (max 20 characters)")
+	}
+
+	// Validar RUC y dígito verificador (el DV se calcula si no se envía)
+	if err := resolveTaxID(&req.TaxID, &req.TaxIDType, &req.TaxIDDV, ""); err != nil {
+		return err
+	}
+
+	// Validar campos requeridos según el tipo de receptor
+	if err := validateRecipient(req, ""); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// applyCustomerRequest copia los datos enviados sobre un cliente existente
+func applyCustomerRequest(customer *models.Customer, req *models.CreateCustomerRequest) {
+	customer.Name = req.Name
+	customer.Email = req.Email
+	customer.Phone = req.Phone
+	customer.AddressLine = req.Address
+	customer.UBICode = req.UBICode
+	customer.TaxID = req.TaxID
+	customer.TaxIDType = req.TaxIDType
+	customer.TaxIDDV = req.TaxIDDV
+	customer.RecipientType = req.RecipientType
+	customer.ForeignID = req.ForeignID
+	customer.CountryCode = req.CountryCode
+}
+
+// validateRecipient valida los campos requeridos según el tipo de receptor (iTipoRec) y asigna
+// consumidor final si no se envía. Contribuyentes y gobierno requieren RUC, dirección y código
+// de ubicación; los extranjeros requieren identificación y país.
+func validateRecipient(req *models.CreateCustomerRequest, fieldPrefix string) error {
+	if req.RecipientType == "" {
+		req.RecipientType = models.RecipientTypeConsumidorFinal
+	}
+
+	var errs models.FieldErrors
+	required := func(value *string, field, issue string) {
+		if value == nil || strings.TrimSpace(*value) == "" {
+			errs = append(errs, models.ErrorDetail{Field: fieldPrefix + field, Issue: issue})
+		}
+	}
+
+	switch req.RecipientType {
+	case models.RecipientTypeContribuyente, models.RecipientTypeGobierno:
+		required(req.TaxID, "tax_id", "RUC is required for this recipient type")
+		required(req.Address, "address", "Address is required for this recipient type")
+		required(req.UBICode, "ubi_code", "UBI code is required for this recipient type")
+		if req.TaxIDType != nil && *req.TaxIDType == "3" {
+			errs = append(errs, models.ErrorDetail{Field: fieldPrefix + "tax_id_type", Issue: "Foreign tax IDs are only allowed for recipient type 04"})
+		}
+		if req.ForeignID != nil {
+			errs = append(errs, models.ErrorDetail{Field: fieldPrefix + "foreign_id", Issue: "Only allowed for recipient type 04"})
+		}
+
+	case models.RecipientTypeConsumidorFinal:
+		if req.ForeignID != nil {
+			errs = append(errs, models.ErrorDetail{Field: fieldPrefix + "foreign_id", Issue: "Only allowed for recipient type 04"})
+		}
+
+	case models.RecipientTypeExtranjero:
+		required(req.ForeignID, "foreign_id", "Passport or foreign ID is required for foreign recipients")
+		required(req.CountryCode, "country_code", "Country is required for foreign recipients")
+		if req.ForeignID != nil && len(*req.ForeignID) > 50 {
+			errs = append(errs, models.ErrorDetail{Field: fieldPrefix + "foreign_id", Issue: "Too long (max 50 characters)"})
+		}
+		if req.CountryCode != nil {
+			country := strings.ToUpper(strings.TrimSpace(*req.CountryCode))
+			req.CountryCode = &country
+			if country == "PA" {
+				errs = append(errs, models.ErrorDetail{Field: fieldPrefix + "country_code", Issue: "Foreign recipients cannot be from PA"})
+			}
+		}
+		if req.TaxID != nil && (req.TaxIDType == nil || *req.TaxIDType != "3") {
+			errs = append(errs, models.ErrorDetail{Field: fieldPrefix + "tax_id", Issue: "Foreign recipients are identified by foreign_id"})
+		}
+
+	default:
+		errs = append(errs, models.ErrorDetail{Field: fieldPrefix + "recipient_type", Issue: "Must be 01, 02, 03 or 04"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
 	return nil
 }
 
@@ -2,7 +2,9 @@ package services
 
 import (
 	"bytes"
+	"encoding/xml"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,6 +13,10 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// documentGeneratorVersion identifica el formato de los PDF/XML generados; se registra en cada
+// versión de archivo y debe cambiar cuando cambian las plantillas
+const documentGeneratorVersion = "1.0"
+
 // DocumentGenerator maneja la generación de archivos PDF y XML
 type DocumentGenerator struct {
 	logger *logrus.Logger
@@ -23,16 +29,16 @@ func NewDocumentGenerator(logger *logrus.Logger) *DocumentGenerator {
 	}
 }
 
-// GenerateInvoiceFiles genera los archivos PDF y XML para una factura
-func (d *DocumentGenerator) GenerateInvoiceFiles(invoice *models.Invoice, customer *models.Customer, emitter *models.Emitter, items []models.InvoiceItem) (*models.InvoiceFiles, error) {
+// GenerateInvoiceFiles genera los archivos PDF y XML para una factura a partir de su snapshot
+func (d *DocumentGenerator) GenerateInvoiceFiles(invoice *models.Invoice, items []models.InvoiceItem) (*models.InvoiceFiles, error) {
 	// Generar PDF
-	pdfData, err := d.GenerateInvoicePDF(invoice, customer, emitter, items)
+	pdfData, err := d.GenerateInvoicePDF(invoice, items)
 	if err != nil {
 		return nil, fmt.Errorf("error generating PDF: %w", err)
 	}
 
 	// Generar XML
-	xmlData, err := d.GenerateInvoiceXML(invoice, customer, emitter, items)
+	xmlData, err := d.GenerateInvoiceXML(invoice, items)
 	if err != nil {
 		return nil, fmt.Errorf("error generating XML: %w", err)
 	}
@@ -59,7 +65,12 @@ func (d *DocumentGenerator) GenerateInvoiceFiles(invoice *models.Invoice, custom
 }
 
 // GenerateInvoicePDF genera un archivo PDF para la factura
-func (d *DocumentGenerator) GenerateInvoicePDF(invoice *models.Invoice, customer *models.Customer, emitter *models.Emitter, items []models.InvoiceItem) ([]byte, error) {
+func (d *DocumentGenerator) GenerateInvoicePDF(invoice *models.Invoice, items []models.InvoiceItem) ([]byte, error) {
+	customer, emitter, err := snapshotParties(invoice)
+	if err != nil {
+		return nil, err
+	}
+
 	pdf := gofpdf.New("P", "mm", "A4", "")
 	pdf.AddPage()
 
@@ -112,6 +123,10 @@ func (d *DocumentGenerator) GenerateInvoicePDF(invoice *models.Invoice, customer
 	pdf.Ln(6)
 	pdf.Cell(95, 6, fmt.Sprintf("RUC: %s", emitterRUC))
 	pdf.Ln(6)
+	if branch := invoice.Snapshot.Branch; branch != nil {
+		pdf.Cell(95, 6, fmt.Sprintf("Sucursal: %s - %s", branch.Code, branch.Name))
+		pdf.Ln(6)
+	}
 	pdf.Cell(95, 6, emitterAddress)
 	pdf.Ln(6)
 	
@@ -136,15 +151,33 @@ func (d *DocumentGenerator) GenerateInvoicePDF(invoice *models.Invoice, customer
 		customerAddress = *customer.AddressLine
 	}
 	
-	customerRUC := "N/A"
-	if customer.TaxID != nil {
-		customerRUC = *customer.TaxID
+	// Identificación según el tipo de receptor
+	recipientType := customer.RecipientType
+	if recipientType == "" {
+		recipientType = models.RecipientTypeConsumidorFinal
+	}
+	customerID := "RUC: N/A"
+	if recipientType == models.RecipientTypeExtranjero {
+		customerID = "Pasaporte/ID: N/A"
+		if customer.ForeignID != nil {
+			customerID = fmt.Sprintf("Pasaporte/ID: %s", *customer.ForeignID)
+			if customer.CountryCode != nil {
+				customerID += fmt.Sprintf(" (%s)", *customer.CountryCode)
+			}
+		}
+	} else if customer.TaxID != nil {
+		customerID = fmt.Sprintf("RUC: %s", *customer.TaxID)
+		if customer.TaxIDDV != nil {
+			customerID += fmt.Sprintf(" DV %s", *customer.TaxIDDV)
+		}
 	}
 	
 	pdf.SetFont("Arial", "", 10)
 	pdf.Cell(95, 6, customerName)
 	pdf.Ln(6)
-	pdf.Cell(95, 6, fmt.Sprintf("RUC: %s", customerRUC))
+	pdf.Cell(95, 6, fmt.Sprintf("Tipo: %s", recipientType.Description()))
+	pdf.Ln(6)
+	pdf.Cell(95, 6, customerID)
 	pdf.Ln(6)
 	pdf.Cell(95, 6, customerEmail)
 	pdf.Ln(6)
@@ -205,16 +238,17 @@ func (d *DocumentGenerator) GenerateInvoicePDF(invoice *models.Invoice, customer
 	pdf.Line(120, totalY, 200, totalY)
 	pdf.Ln(5)
 	
-	// Totales
+	// Totales (en moneda del documento)
+	symbol := models.CurrencySymbol(invoice.CurrencyCode)
 	pdf.SetFont("Arial", "B", 12)
 	pdf.SetX(120)
 	pdf.Cell(50, 8, "Subtotal:")
-	pdf.Cell(30, 8, fmt.Sprintf("$%.2f", invoice.Subtotal))
+	pdf.Cell(30, 8, fmt.Sprintf("%s%.2f", symbol, invoice.DocSubtotal))
 	pdf.Ln(8)
 	
 	pdf.SetX(120)
 	pdf.Cell(50, 8, "ITBMS (7%):")
-	pdf.Cell(30, 8, fmt.Sprintf("$%.2f", invoice.ITBMSAmount))
+	pdf.Cell(30, 8, fmt.Sprintf("%s%.2f", symbol, invoice.DocITBMSAmount))
 	pdf.Ln(8)
 	
 	// Total final destacado
@@ -222,9 +256,22 @@ func (d *DocumentGenerator) GenerateInvoicePDF(invoice *models.Invoice, customer
 	pdf.SetTextColor(255, 255, 255)
 	pdf.SetX(120)
 	pdf.Cell(50, 12, "TOTAL:")
-	pdf.Cell(30, 12, fmt.Sprintf("$%.2f", invoice.TotalAmount))
+	pdf.Cell(30, 12, fmt.Sprintf("%s%.2f", symbol, invoice.DocTotalAmount))
 	pdf.Ln(12)
 
+	// Equivalente en moneda funcional para documentos en moneda extranjera
+	if invoice.IsForeignCurrency() {
+		pdf.SetTextColor(44, 62, 80)
+		pdf.SetFont("Arial", "", 10)
+		pdf.SetX(120)
+		pdf.Cell(80, 6, fmt.Sprintf("Tasa de cambio: 1 %s = %.6f %s", invoice.CurrencyCode, invoice.ExchangeRate, models.FunctionalCurrency))
+		pdf.Ln(6)
+		pdf.SetX(120)
+		pdf.Cell(50, 6, fmt.Sprintf("Total %s:", models.FunctionalCurrency))
+		pdf.Cell(30, 6, fmt.Sprintf("$%.2f", invoice.TotalAmount))
+		pdf.Ln(6)
+	}
+
 	// Footer
 	pdf.SetY(270)
 	pdf.SetTextColor(149, 165, 166)
@@ -235,7 +282,7 @@ func (d *DocumentGenerator) GenerateInvoicePDF(invoice *models.Invoice, customer
 
 	// Generar bytes del PDF usando buffer
 	var buf bytes.Buffer
-	err := pdf.Output(&buf)
+	err = pdf.Output(&buf)
 	if err != nil {
 		return nil, fmt.Errorf("error generating PDF: %w", err)
 	}
@@ -244,7 +291,12 @@ func (d *DocumentGenerator) GenerateInvoicePDF(invoice *models.Invoice, customer
 }
 
 // GenerateInvoiceXML genera un archivo XML para la factura
-func (d *DocumentGenerator) GenerateInvoiceXML(invoice *models.Invoice, customer *models.Customer, emitter *models.Emitter, items []models.InvoiceItem) ([]byte, error) {
+func (d *DocumentGenerator) GenerateInvoiceXML(invoice *models.Invoice, items []models.InvoiceItem) ([]byte, error) {
+	customer, emitter, err := snapshotParties(invoice)
+	if err != nil {
+		return nil, err
+	}
+
 	// XML básico para la factura
 	xmlContent := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
 <factura>
@@ -252,13 +304,9 @@ func (d *DocumentGenerator) GenerateInvoiceXML(invoice *models.Invoice, customer
         <ruc>%s-%s-%s-%s</ruc>
         <nombre>%s</nombre>
         <direccion>%s</direccion>
+%s
     </emisor>
-    <cliente>
-        <ruc>%s</ruc>
-        <nombre>%s</nombre>
-        <email>%s</email>
-        <direccion>%s</direccion>
-    </cliente>
+%s
     <documento>
         <numero>%s</numero>
         <fecha>%s</fecha>
@@ -274,20 +322,8 @@ func (d *DocumentGenerator) GenerateInvoiceXML(invoice *models.Invoice, customer
 			}
 			return "N/A"
 		}(),
-		func() string {
-			if customer.TaxID != nil {
-				return *customer.TaxID
-			}
-			return "N/A"
-		}(),
-		customer.Name,
-		customer.Email,
-		func() string {
-			if customer.AddressLine != nil {
-				return *customer.AddressLine
-			}
-			return "N/A"
-		}(),
+		branchXML(invoice.Snapshot, emitter),
+		recipientXML(customer),
 		invoice.DocumentNumber,
 		invoice.CreatedAt.Format("2006-01-02"),
 		invoice.PtoFacDF,
@@ -315,14 +351,122 @@ func (d *DocumentGenerator) GenerateInvoiceXML(invoice *models.Invoice, customer
 	// Cerrar XML
 	xmlContent += `
     </items>
+    <moneda>
+        <codigo>%s</codigo>
+        <tasaCambio>%.6f</tasaCambio>
+    </moneda>
     <totales>
         <subtotal>%.2f</subtotal>
         <itbms>%.2f</itbms>
         <total>%.2f</total>
     </totales>
+    <totalesFuncionales moneda="%s">
+        <subtotal>%.2f</subtotal>
+        <itbms>%.2f</itbms>
+        <total>%.2f</total>
+    </totalesFuncionales>
 </factura>`
 
-	xmlContent = fmt.Sprintf(xmlContent, invoice.Subtotal, invoice.ITBMSAmount, invoice.TotalAmount)
+	xmlContent = fmt.Sprintf(xmlContent,
+		invoice.CurrencyCode, invoice.ExchangeRate,
+		invoice.DocSubtotal, invoice.DocITBMSAmount, invoice.DocTotalAmount,
+		models.FunctionalCurrency, invoice.Subtotal, invoice.ITBMSAmount, invoice.TotalAmount,
+	)
 
 	return []byte(xmlContent), nil
 }
+
+// snapshotParties obtiene el receptor y el emisor congelados en el snapshot del documento.
+// Los archivos nunca se generan con los datos actuales del emisor o del cliente.
+func snapshotParties(invoice *models.Invoice) (*models.Customer, *models.Emitter, error) {
+	if invoice.Snapshot == nil {
+		return nil, nil, fmt.Errorf("invoice %s has no snapshot", invoice.ID)
+	}
+	return &invoice.Snapshot.Customer, invoice.Snapshot.EmitterData(), nil
+}
+
+// branchXML genera los datos de la sucursal emisora: código (dSucEm), nombre y ubicación
+func branchXML(snapshot *models.InvoiceSnapshot, emitter *models.Emitter) string {
+	var sb strings.Builder
+	element := func(indent, name, value string) {
+		sb.WriteString(indent + "<" + name + ">")
+		xml.EscapeText(&sb, []byte(value))
+		sb.WriteString("</" + name + ">\n")
+	}
+
+	element("        ", "dSucEm", emitter.SucEm)
+	if snapshot.Branch != nil {
+		element("        ", "dNombSuc", snapshot.Branch.Name)
+	}
+	if emitter.UBICode != nil {
+		sb.WriteString("        <gUbiEm>\n")
+		element("            ", "dCodUbi", *emitter.UBICode)
+		sb.WriteString("        </gUbiEm>\n")
+	}
+
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// recipientXML genera la sección gDatRec (datos del receptor) según el tipo de receptor
+func recipientXML(customer *models.Customer) string {
+	var sb strings.Builder
+	element := func(indent, name, value string) {
+		sb.WriteString(indent + "<" + name + ">")
+		xml.EscapeText(&sb, []byte(value))
+		sb.WriteString("</" + name + ">\n")
+	}
+
+	recipientType := customer.RecipientType
+	if recipientType == "" {
+		recipientType = models.RecipientTypeConsumidorFinal
+	}
+
+	sb.WriteString("    <gDatRec>\n")
+	element("        ", "iTipoRec", string(recipientType))
+
+	// RUC del receptor (contribuyente, gobierno o consumidor final identificado)
+	if customer.TaxID != nil && recipientType != models.RecipientTypeExtranjero {
+		sb.WriteString("        <gRucRec>\n")
+		if customer.TaxIDType != nil {
+			element("            ", "dTipoRuc", *customer.TaxIDType)
+		}
+		element("            ", "dRuc", *customer.TaxID)
+		if customer.TaxIDDV != nil {
+			element("            ", "dDV", *customer.TaxIDDV)
+		}
+		sb.WriteString("        </gRucRec>\n")
+	}
+
+	element("        ", "dNombRec", customer.Name)
+	if customer.AddressLine != nil {
+		element("        ", "dDirecRec", *customer.AddressLine)
+	}
+	if customer.UBICode != nil {
+		sb.WriteString("        <gUbiRec>\n")
+		element("            ", "dCodUbi", *customer.UBICode)
+		sb.WriteString("        </gUbiRec>\n")
+	}
+
+	// Identificación del receptor extranjero
+	country := "PA"
+	if recipientType == models.RecipientTypeExtranjero {
+		sb.WriteString("        <gIdExt>\n")
+		if customer.ForeignID != nil {
+			element("            ", "dIdExt", *customer.ForeignID)
+		}
+		if customer.CountryCode != nil {
+			element("            ", "dPaisExt", *customer.CountryCode)
+			country = *customer.CountryCode
+		}
+		sb.WriteString("        </gIdExt>\n")
+	}
+
+	if customer.Phone != nil {
+		element("        ", "dTfnRec", *customer.Phone)
+	}
+	element("        ", "dCorElectRec", customer.Email)
+	element("        ", "cPaisRec", country)
+	sb.WriteString("    </gDatRec>")
+
+	return sb.String()
+}
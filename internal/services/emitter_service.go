@@ -2,6 +2,9 @@ package services
 
 import (
 	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,25 +15,27 @@ import (
 
 // EmitterService maneja la lógica de negocio para Emitter
 type EmitterService struct {
-	emitterRepo *database.EmitterRepository
-	apiKeyRepo  *database.APIKeyRepository
-	logger      *logrus.Logger
+	emitterRepo   *database.EmitterRepository
+	apiKeyRepo    *database.APIKeyRepository
+	branchService *BranchService
+	logger        *logrus.Logger
 }
 
 // NewEmitterService crea una nueva instancia del servicio
 func NewEmitterService(db *database.DB, logger *logrus.Logger) *EmitterService {
 	return &EmitterService{
-		emitterRepo: database.NewEmitterRepository(db, logger),
-		apiKeyRepo:  database.NewAPIKeyRepository(db, logger),
-		logger:      logger,
+		emitterRepo:   database.NewEmitterRepository(db, logger),
+		apiKeyRepo:    database.NewAPIKeyRepository(db, logger),
+		branchService: NewBranchService(db, logger),
+		logger:        logger,
 	}
 }
 
-// Create crea un nuevo emisor
-func (s *EmitterService) Create(req *models.CreateEmitterRequest) (*models.Emitter, error) {
+// Create crea un nuevo emisor a nombre de actor
+func (s *EmitterService) Create(req *models.CreateEmitterRequest, actor string) (*models.Emitter, error) {
 	// Validar RUC
 	if err := s.validateRUC(req.RUCTipo, req.RUCNumero, req.RUCDV); err != nil {
-		return nil, fmt.Errorf("invalid RUC: %w", err)
+		return nil, fmt.Errorf("validation error: invalid RUC: %w", err)
 	}
 
 	emitter := &models.Emitter{
@@ -54,13 +59,20 @@ func (s *EmitterService) Create(req *models.CreateEmitterRequest) (*models.Emitt
 		BrandFooterHTML:     req.BrandFooterHTML,
 		PACAPIKey:           req.PACAPIKey,
 		PACSubscriptionKey:  req.PACSubscriptionKey,
+		PACSandboxAPIKey:          req.PACSandboxAPIKey,
+		PACSandboxSubscriptionKey: req.PACSandboxSubscriptionKey,
 		IsActive:            true,
+		AutoCreateSeries:    req.AutoCreateSeries,
 		CreatedAt:           time.Now(),
 		UpdatedAt:           time.Now(),
 	}
 
-	// TODO: Implementar persistencia en base de datos
-	// Por ahora solo retornamos el objeto creado
+	if err := s.emitterRepo.Create(emitter, actor); err != nil {
+		return nil, fmt.Errorf("error creating emitter: %w", err)
+	}
+	emitter.PACConfigured = true
+	emitter.PACSandboxConfigured = emitter.PACSandboxAPIKey != "" && emitter.PACSandboxSubscriptionKey != ""
+
 	s.logger.WithFields(logrus.Fields{
 		"emitter_id":   emitter.ID,
 		"company_code": emitter.CompanyCode,
@@ -70,26 +82,123 @@ func (s *EmitterService) Create(req *models.CreateEmitterRequest) (*models.Emitt
 	return emitter, nil
 }
 
-// GetSeries obtiene las series de un emisor con paginación
-func (s *EmitterService) GetSeries(emitterID uuid.UUID, page, pageSize int) ([]models.SeriesItem, int, error) {
+// GetByID obtiene un emisor activo
+func (s *EmitterService) GetByID(id uuid.UUID) (*models.Emitter, error) {
+	emitter, err := s.emitterRepo.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting emitter: %w", err)
+	}
+	return emitter, nil
+}
+
+// Update actualiza parcialmente un emisor a nombre de actor. Las credenciales PAC solo se reemplazan si se envían.
+func (s *EmitterService) Update(id uuid.UUID, req *models.UpdateEmitterRequest, actor string) (*models.Emitter, error) {
+	if err := validateEmitterUpdate(req); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+
+	emitter, err := s.emitterRepo.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting existing emitter: %w", err)
+	}
+
+	// Aplicar solo los campos enviados
+	if req.Name != nil {
+		emitter.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Email != nil {
+		emitter.Email = strings.TrimSpace(*req.Email)
+	}
+	if req.Phone != nil {
+		emitter.Phone = req.Phone
+	}
+	if req.AddressLine != nil {
+		emitter.AddressLine = req.AddressLine
+	}
+	if req.UBICode != nil {
+		emitter.UBICode = req.UBICode
+	}
+	if req.PtoFacDefault != nil {
+		emitter.PtoFacDefault = *req.PtoFacDefault
+	}
+	if req.IAmb != nil {
+		emitter.IAmb = *req.IAmb
+	}
+	if req.ITpEmisDefault != nil {
+		emitter.ITpEmisDefault = *req.ITpEmisDefault
+	}
+	if req.IDocDefault != nil {
+		emitter.IDocDefault = *req.IDocDefault
+	}
+	if req.BrandLogoURL != nil {
+		emitter.BrandLogoURL = req.BrandLogoURL
+	}
+	if req.BrandPrimaryColor != nil {
+		emitter.BrandPrimaryColor = req.BrandPrimaryColor
+	}
+	if req.BrandFooterHTML != nil {
+		emitter.BrandFooterHTML = req.BrandFooterHTML
+	}
+	if req.PACAPIKey != nil {
+		emitter.PACAPIKey = *req.PACAPIKey
+	}
+	if req.PACSubscriptionKey != nil {
+		emitter.PACSubscriptionKey = *req.PACSubscriptionKey
+	}
+	// Las credenciales de pruebas se pueden vaciar enviando ""
+	if req.PACSandboxAPIKey != nil {
+		emitter.PACSandboxAPIKey = strings.TrimSpace(*req.PACSandboxAPIKey)
+	}
+	if req.PACSandboxSubscriptionKey != nil {
+		emitter.PACSandboxSubscriptionKey = strings.TrimSpace(*req.PACSandboxSubscriptionKey)
+	}
+	if req.AutoCreateSeries != nil {
+		emitter.AutoCreateSeries = *req.AutoCreateSeries
+	}
+
+	emitter, err = s.emitterRepo.Update(emitter, actor)
+	if err != nil {
+		return nil, fmt.Errorf("error updating emitter: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"emitter_id":          id,
+		"company_code":        emitter.CompanyCode,
+		"pac_credentials_set": req.PACAPIKey != nil || req.PACSubscriptionKey != nil,
+		"pac_sandbox_credentials_set": req.PACSandboxAPIKey != nil || req.PACSandboxSubscriptionKey != nil,
+	}).Info("Emitter updated successfully")
+
+	return emitter, nil
+}
+
+// Delete desactiva un emisor y sus API keys a nombre de actor
+func (s *EmitterService) Delete(id uuid.UUID, actor string) error {
+	if err := s.emitterRepo.Delete(id, actor); err != nil {
+		return fmt.Errorf("error deleting emitter: %w", err)
+	}
+
+	s.logger.WithField("emitter_id", id).Info("Emitter deleted successfully")
+	return nil
+}
+
+// GetSeries obtiene las series de un emisor en el ambiente de la API key con paginación;
+// includeInactive incluye las desactivadas
+func (s *EmitterService) GetSeries(emitterID uuid.UUID, environment models.APIKeyEnvironment, includeInactive bool, page, pageSize int) ([]models.SeriesItem, int, error) {
+	emitter, err := s.emitterRepo.GetByID(emitterID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting emitter: %w", err)
+	}
+
 	// Obtener series del repositorio
-	series, err := s.emitterRepo.GetSeriesList(emitterID)
+	series, err := s.emitterRepo.GetSeriesList(emitterID, environment.IAmb(emitter), includeInactive)
 	if err != nil {
 		return nil, 0, fmt.Errorf("error getting series: %w", err)
 	}
 
 	// Convertir a SeriesItem para la respuesta
 	var items []models.SeriesItem
-	for _, s := range series {
-		item := models.SeriesItem{
-			PtoFacDF:        s.PtoFacDF,
-			DocKind:         string(s.DocKind),
-			LastAssigned:    s.NextNumber - 1,
-			IssuedCount:     s.IssuedCount,
-			AuthorizedCount: s.AuthorizedCount,
-			RejectedCount:   s.RejectedCount,
-		}
-		items = append(items, item)
+	for i := range series {
+		items = append(items, models.NewSeriesItem(&series[i]))
 	}
 
 	// Calcular total
@@ -110,8 +219,56 @@ func (s *EmitterService) GetSeries(emitterID uuid.UUID, page, pageSize int) ([]m
 	return items[start:end], total, nil
 }
 
-// CreateSeries crea una nueva serie para un emisor
-func (s *EmitterService) CreateSeries(emitterID uuid.UUID, req *models.CreateSeriesRequest) (*models.EmitterSeries, error) {
+// GetSeriesGaps obtiene los huecos de numeración de una serie de una sucursal (la principal si branchCode es vacío)
+// en el ambiente de la API key
+func (s *EmitterService) GetSeriesGaps(emitterID uuid.UUID, environment models.APIKeyEnvironment, branchCode, ptoFacDF string, docKind models.DocumentType) (*models.SeriesGapsResponse, error) {
+	emitter, err := s.emitterRepo.GetByID(emitterID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting emitter: %w", err)
+	}
+	branch, err := s.branchService.Resolve(emitter, branchCode)
+	if err != nil {
+		return nil, fmt.Errorf("error getting branch: %w", err)
+	}
+
+	iamb := environment.IAmb(emitter)
+	series, err := s.emitterRepo.FindSeries(emitterID, branch.ID, iamb, ptoFacDF, docKind)
+	if err != nil {
+		return nil, fmt.Errorf("error getting series: %w", err)
+	}
+
+	gaps, err := s.emitterRepo.GetSeriesGaps(series.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting series gaps: %w", err)
+	}
+
+	response := &models.SeriesGapsResponse{
+		Branch:       branch.Code,
+		IAmb:         iamb,
+		PtoFacDF:     series.PtoFacDF,
+		DocKind:      string(series.DocKind),
+		LastAssigned: series.NextNumber - 1,
+		Gaps:         gaps,
+	}
+	for _, gap := range gaps {
+		response.TotalMissing += gap.Count
+	}
+
+	if response.TotalMissing > 0 {
+		s.logger.WithFields(logrus.Fields{
+			"emitter_id":    emitterID,
+			"branch":        branch.Code,
+			"pto_fac_df":    ptoFacDF,
+			"doc_kind":      docKind,
+			"total_missing": response.TotalMissing,
+		}).Warn("Series has numbering gaps")
+	}
+
+	return response, nil
+}
+
+// CreateSeries crea una nueva serie para un emisor en el ambiente de la API key, a nombre de actor
+func (s *EmitterService) CreateSeries(emitterID uuid.UUID, environment models.APIKeyEnvironment, req *models.CreateSeriesRequest, actor string) (*models.EmitterSeries, error) {
 	s.logger.Infof("CreateSeries: emitterID=%s, req=%+v", emitterID, req)
 	
 	// Validar que el emisor existe
@@ -121,17 +278,28 @@ func (s *EmitterService) CreateSeries(emitterID uuid.UUID, req *models.CreateSer
 		return nil, fmt.Errorf("error getting emitter: %w", err)
 	}
 	
-	s.logger.Infof("Emitter found: %+v", emitter)
+	s.logger.Infof("Emitter found: %s", emitter.ID)
+
+	// Obtener la sucursal de la serie
+	branch, err := s.branchService.Resolve(emitter, req.Branch)
+	if err != nil {
+		return nil, fmt.Errorf("error getting branch: %w", err)
+	}
 
 	// Validar que el punto de facturación existe
-	if req.PtoFacDF != emitter.PtoFacDefault {
+	if req.PtoFacDF != branch.PtoFacDefault {
 		// TODO: Validar contra lista de puntos de facturación válidos
-		s.logger.Warnf("Using non-default punto de facturación: %s (default: %s)", req.PtoFacDF, emitter.PtoFacDefault)
+		s.logger.Warnf("Using non-default punto de facturación: %s (default: %s)", req.PtoFacDF, branch.PtoFacDefault)
+	}
+
+	// Validar rango autorizado
+	if err := validateSeriesRange(req); err != nil {
+		return nil, fmt.Errorf("validation error: invalid series range: %w", err)
 	}
 
 	// Crear serie
 	s.logger.Infof("Creating series with emitterID=%s, req=%+v", emitterID, req)
-	series, err := s.emitterRepo.CreateSeries(emitterID, req)
+	series, err := s.emitterRepo.CreateSeries(emitterID, branch.ID, environment.IAmb(emitter), req, actor)
 	if err != nil {
 		s.logger.Errorf("Error creating series: %v", err)
 		return nil, fmt.Errorf("error creating series: %w", err)
@@ -141,24 +309,84 @@ func (s *EmitterService) CreateSeries(emitterID uuid.UUID, req *models.CreateSer
 
 	s.logger.WithFields(logrus.Fields{
 		"emitter_id": emitterID,
+		"branch":     branch.Code,
 		"pto_fac_df": req.PtoFacDF,
 		"doc_kind":   req.DocKind,
+		"i_amb":      series.IAmb,
 		"series_id":  series.ID,
 	}).Info("Series created successfully")
 
 	return series, nil
 }
 
-// CreateAPIKey crea una nueva API key para un emisor
-func (s *EmitterService) CreateAPIKey(emitterID uuid.UUID, req *models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error) {
+// DeactivateSeries desactiva una serie: deja de asignar folios pero conserva su numeración
+func (s *EmitterService) DeactivateSeries(emitterID, seriesID uuid.UUID, actor string) (*models.EmitterSeries, error) {
+	series, err := s.emitterRepo.SetSeriesActive(emitterID, seriesID, false, actor)
+	if err != nil {
+		return nil, fmt.Errorf("error deactivating series: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"emitter_id": emitterID,
+		"series_id":  seriesID,
+		"pto_fac_df": series.PtoFacDF,
+		"doc_kind":   series.DocKind,
+	}).Info("Series deactivated")
+
+	return series, nil
+}
+
+// ReactivateSeries reactiva una serie; la numeración continúa desde el último folio asignado
+func (s *EmitterService) ReactivateSeries(emitterID, seriesID uuid.UUID, actor string) (*models.EmitterSeries, error) {
+	series, err := s.emitterRepo.SetSeriesActive(emitterID, seriesID, true, actor)
+	if err != nil {
+		return nil, fmt.Errorf("error reactivating series: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"emitter_id": emitterID,
+		"series_id":  seriesID,
+		"pto_fac_df": series.PtoFacDF,
+		"doc_kind":   series.DocKind,
+	}).Info("Series reactivated")
+
+	return series, nil
+}
+
+// UpdateSettings actualiza la configuración de un emisor a nombre de actor
+func (s *EmitterService) UpdateSettings(emitterID uuid.UUID, req *models.UpdateEmitterSettingsRequest, actor string) (*models.Emitter, error) {
+	if err := s.emitterRepo.UpdateSettings(emitterID, req, actor); err != nil {
+		return nil, fmt.Errorf("error updating emitter settings: %w", err)
+	}
+
+	emitter, err := s.emitterRepo.GetByID(emitterID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting emitter: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"emitter_id":         emitterID,
+		"auto_create_series": emitter.AutoCreateSeries,
+	}).Info("Emitter settings updated")
+
+	return emitter, nil
+}
+
+// CreateAPIKey crea una nueva API key para un emisor a nombre de actor
+func (s *EmitterService) CreateAPIKey(emitterID uuid.UUID, req *models.CreateAPIKeyRequest, actor string) (*models.CreateAPIKeyResponse, error) {
 	// Validar que el emisor existe
 	_, err := s.emitterRepo.GetByID(emitterID)
 	if err != nil {
 		return nil, fmt.Errorf("error getting emitter: %w", err)
 	}
 
+	environment := req.Environment
+	if environment == "" {
+		environment = models.APIKeyEnvironmentLive
+	}
+
 	// Crear API key usando el repositorio
-	apiKeyModel, apiKey, err := s.apiKeyRepo.Create(emitterID, req.Name, req.RateLimitPerMin)
+	apiKeyModel, apiKey, err := s.apiKeyRepo.Create(emitterID, req.Name, environment, req.RateLimitPerMin, actor)
 	if err != nil {
 		return nil, fmt.Errorf("error creating API key: %w", err)
 	}
@@ -167,12 +395,14 @@ func (s *EmitterService) CreateAPIKey(emitterID uuid.UUID, req *models.CreateAPI
 		ID:              apiKeyModel.ID,
 		Name:            apiKeyModel.Name,
 		APIKey:          apiKey, // Solo se retorna una vez
+		Environment:     apiKeyModel.Environment,
 		RateLimitPerMin: apiKeyModel.RateLimitPerMin,
 	}
 
 	s.logger.WithFields(logrus.Fields{
 		"emitter_id":     emitterID,
 		"api_key_name":   req.Name,
+		"environment":    environment,
 		"rate_limit":     req.RateLimitPerMin,
 		"api_key_id":     response.ID,
 	}).Info("API key created successfully")
@@ -180,8 +410,8 @@ func (s *EmitterService) CreateAPIKey(emitterID uuid.UUID, req *models.CreateAPI
 	return response, nil
 }
 
-// GetDashboard obtiene el dashboard de un emisor
-func (s *EmitterService) GetDashboard(emitterID uuid.UUID) (*models.DashboardResponse, error) {
+// GetDashboard obtiene el dashboard de un emisor con los documentos del ambiente de la API key
+func (s *EmitterService) GetDashboard(emitterID uuid.UUID, environment models.APIKeyEnvironment) (*models.DashboardResponse, error) {
 	// Validar que el emisor existe
 	emitter, err := s.emitterRepo.GetByID(emitterID)
 	if err != nil {
@@ -189,7 +419,7 @@ func (s *EmitterService) GetDashboard(emitterID uuid.UUID) (*models.DashboardRes
 	}
 
 	// Obtener dashboard del repositorio
-	response, err := s.emitterRepo.GetDashboard(emitterID)
+	response, err := s.emitterRepo.GetDashboard(emitterID, environment.IAmb(emitter))
 	if err != nil {
 		return nil, fmt.Errorf("error getting dashboard: %w", err)
 	}
@@ -204,29 +434,96 @@ func (s *EmitterService) GetDashboard(emitterID uuid.UUID) (*models.DashboardRes
 	return response, nil
 }
 
-// validateRUC valida el formato del RUC
-func (s *EmitterService) validateRUC(rucTipo, rucNumero, rucDV string) error {
-	// Validar tipo de RUC
-	if rucTipo != "1" && rucTipo != "2" && rucTipo != "3" {
-		return fmt.Errorf("invalid RUC type: %s (must be 1, 2, or 3)", rucTipo)
-	}
+var (
+	emitterEmailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	hexColorPattern     = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+	ptoFacPattern       = regexp.MustCompile(`^[0-9]{3}$`)
+	twoDigitCodePattern = regexp.MustCompile(`^[0-9]{2}$`)
+)
 
-	// Validar número de RUC (debe ser numérico y tener 8 dígitos)
-	if len(rucNumero) != 8 {
-		return fmt.Errorf("invalid RUC number length: %s (must be 8 digits)", rucNumero)
+// validateEmitterUpdate valida los campos enviados al actualizar un emisor
+func validateEmitterUpdate(req *models.UpdateEmitterRequest) error {
+	var errs models.FieldErrors
+	addError := func(field, issue string) {
+		errs = append(errs, models.ErrorDetail{Field: field, Issue: issue})
 	}
 
-	// Validar dígito verificador (debe ser numérico y tener 1 dígito)
-	if len(rucDV) != 1 {
-		return fmt.Errorf("invalid RUC DV length: %s (must be 1 digit)", rucDV)
+	if req.Name != nil && strings.TrimSpace(*req.Name) == "" {
+		addError("name", "Name cannot be empty")
+	}
+	if req.Email != nil && !emitterEmailPattern.MatchString(strings.TrimSpace(*req.Email)) {
+		addError("email", "Must be a valid email address")
+	}
+	if req.PtoFacDefault != nil && !ptoFacPattern.MatchString(*req.PtoFacDefault) {
+		addError("pto_fac_default", "Must be 3 digits")
+	}
+	if req.IAmb != nil && *req.IAmb != 1 && *req.IAmb != 2 {
+		addError("iamb", "Must be 1 (production) or 2 (testing)")
+	}
+	if req.ITpEmisDefault != nil && !twoDigitCodePattern.MatchString(*req.ITpEmisDefault) {
+		addError("itpemis_default", "Must be 2 digits")
+	}
+	if req.IDocDefault != nil && !twoDigitCodePattern.MatchString(*req.IDocDefault) {
+		addError("idoc_default", "Must be 2 digits")
+	}
+	if req.UBICode != nil && len(*req.UBICode) > 10 {
+		addError("ubi_code", "Must be at most 10 characters")
+	}
+	if req.Phone != nil && len(*req.Phone) > 20 {
+		addError("phone", "Must be at most 20 characters")
+	}
+	if req.BrandLogoURL != nil && *req.BrandLogoURL != "" {
+		if u, err := url.Parse(*req.BrandLogoURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			addError("brand_logo_url", "Must be an http(s) URL")
+		}
+	}
+	if req.BrandPrimaryColor != nil && !hexColorPattern.MatchString(*req.BrandPrimaryColor) {
+		addError("brand_primary_color", "Must be a hex color like #1A2B3C")
+	}
+	if req.PACAPIKey != nil && strings.TrimSpace(*req.PACAPIKey) == "" {
+		addError("pac_api_key", "PAC API key cannot be empty")
+	}
+	if req.PACSubscriptionKey != nil && strings.TrimSpace(*req.PACSubscriptionKey) == "" {
+		addError("pac_subscription_key", "PAC subscription key cannot be empty")
 	}
 
-	// TODO: Implementar validación del algoritmo de dígito verificador
-	// Por ahora solo validamos el formato
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
 
+// validateSeriesRange valida el rango autorizado opcional de una serie
+func validateSeriesRange(req *models.CreateSeriesRequest) error {
+	var errs models.FieldErrors
+	if req.RangeStart != nil && req.RangeEnd == nil {
+		errs = append(errs, models.ErrorDetail{Field: "range_end", Issue: "range_end is required when range_start is provided"})
+	}
+	if req.RangeEnd != nil {
+		start := 1
+		if req.RangeStart != nil {
+			start = *req.RangeStart
+		}
+		if *req.RangeEnd < start {
+			errs = append(errs, models.ErrorDetail{Field: "range_end", Issue: fmt.Sprintf("range_end must be greater than or equal to %d", start)})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
 	return nil
 }
 
+// validateRUC valida el RUC del emisor y su dígito verificador
+func (s *EmitterService) validateRUC(rucTipo, rucNumero, rucDV string) error {
+	result := ValidateRUC(rucTipo, rucNumero, rucDV)
+	if result.Valid {
+		return nil
+	}
+
+	return rucFieldErrors(result, "ruc_tipo", "ruc_numero", "ruc_dv")
+}
+
 // generateAPIKey genera una API key única
 func (s *EmitterService) generateAPIKey() string {
 	// Generar una clave de 32 caracteres
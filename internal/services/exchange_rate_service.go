@@ -0,0 +1,155 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hypernova-labs/dgi-service/internal/database"
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// ExchangeRateService maneja la lógica de negocio para tasas de cambio
+type ExchangeRateService struct {
+	exchangeRateRepo *database.ExchangeRateRepository
+	logger           *logrus.Logger
+}
+
+// NewExchangeRateService crea una nueva instancia del servicio
+func NewExchangeRateService(db *database.DB, logger *logrus.Logger) *ExchangeRateService {
+	return &ExchangeRateService{
+		exchangeRateRepo: database.NewExchangeRateRepository(db, logger),
+		logger:           logger,
+	}
+}
+
+// Create registra (o reemplaza) la tasa de una moneda para una fecha efectiva
+func (s *ExchangeRateService) Create(emitterID uuid.UUID, req *models.CreateExchangeRateRequest) (*models.ExchangeRate, error) {
+	currencyCode := normalizeCurrencyCode(req.CurrencyCode)
+	if err := validateCurrencyCode(currencyCode); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+	if models.IsFunctionalCurrency(currencyCode) {
+		return nil, fmt.Errorf("validation error: exchange rate for %s is always 1 (functional currency %s)", currencyCode, models.FunctionalCurrency)
+	}
+
+	effectiveDate := time.Now().UTC().Truncate(24 * time.Hour)
+	if req.EffectiveDate != "" {
+		parsed, err := time.Parse("2006-01-02", req.EffectiveDate)
+		if err != nil {
+			return nil, fmt.Errorf("validation error: invalid effective_date %s (expected YYYY-MM-DD)", req.EffectiveDate)
+		}
+		effectiveDate = parsed
+	}
+
+	rate := &models.ExchangeRate{
+		ID:            uuid.New(),
+		EmitterID:     emitterID,
+		CurrencyCode:  currencyCode,
+		Rate:          req.Rate,
+		EffectiveDate: effectiveDate,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+
+	if err := s.exchangeRateRepo.Upsert(rate); err != nil {
+		return nil, fmt.Errorf("error saving exchange rate: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"emitter_id":     emitterID,
+		"currency_code":  rate.CurrencyCode,
+		"rate":           rate.Rate,
+		"effective_date": rate.EffectiveDate.Format("2006-01-02"),
+	}).Info("Exchange rate saved successfully")
+
+	return rate, nil
+}
+
+// List obtiene las tasas de un emisor con paginación
+func (s *ExchangeRateService) List(emitterID uuid.UUID, currencyCode string, page, pageSize int) ([]models.ExchangeRate, int, error) {
+	rates, total, err := s.exchangeRateRepo.GetByEmitterID(emitterID, normalizeCurrencyCode(currencyCode), page, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting exchange rates: %w", err)
+	}
+
+	return rates, total, nil
+}
+
+// Delete elimina una tasa de cambio
+func (s *ExchangeRateService) Delete(emitterID, id uuid.UUID) error {
+	if err := s.exchangeRateRepo.Delete(emitterID, id); err != nil {
+		return fmt.Errorf("error deleting exchange rate: %w", err)
+	}
+
+	return nil
+}
+
+// ResolveRate determina la tasa a aplicar a un documento: la explícita del request,
+// 1 para la moneda funcional y las que están a la par (PAB) o la tasa vigente en la tabla del emisor
+func (s *ExchangeRateService) ResolveRate(emitterID uuid.UUID, currencyCode string, explicitRate *float64, at time.Time) (string, float64, error) {
+	currencyCode = normalizeCurrencyCode(currencyCode)
+	if currencyCode == "" {
+		currencyCode = models.FunctionalCurrency
+	}
+	if err := validateCurrencyCode(currencyCode); err != nil {
+		return "", 0, err
+	}
+
+	if models.IsFunctionalCurrency(currencyCode) {
+		if explicitRate != nil && *explicitRate != 1 {
+			return "", 0, fmt.Errorf("exchange rate for %s must be 1 (functional currency %s)", currencyCode, models.FunctionalCurrency)
+		}
+		return currencyCode, 1, nil
+	}
+
+	if explicitRate != nil {
+		return currencyCode, *explicitRate, nil
+	}
+
+	rate, err := s.exchangeRateRepo.GetEffective(emitterID, currencyCode, at)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return currencyCode, rate.Rate, nil
+}
+
+// roundCents redondea un monto a 2 decimales
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// ToFunctional convierte un monto en moneda del documento a moneda funcional (redondeado a 2 decimales)
+func ToFunctional(amount, exchangeRate float64) float64 {
+	return roundCents(amount * exchangeRate)
+}
+
+// ToFunctionalTotals convierte los totales de un documento a moneda funcional. El total es la suma
+// del neto y el ITBMS ya redondeados, para que cuadre con ellos al centavo.
+func ToFunctionalTotals(subtotal, itbmsAmount, exchangeRate float64) (float64, float64, float64) {
+	functionalSubtotal := ToFunctional(subtotal, exchangeRate)
+	functionalITBMS := ToFunctional(itbmsAmount, exchangeRate)
+	return functionalSubtotal, functionalITBMS, roundCents(functionalSubtotal + functionalITBMS)
+}
+
+// normalizeCurrencyCode normaliza un código ISO 4217
+func normalizeCurrencyCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// validateCurrencyCode valida el formato de un código ISO 4217
+func validateCurrencyCode(code string) error {
+	if len(code) != 3 {
+		return fmt.Errorf("invalid currency code: %s (must be 3 letters)", code)
+	}
+	for _, ch := range code {
+		if ch < 'A' || ch > 'Z' {
+			return fmt.Errorf("invalid currency code: %s (must be 3 letters)", code)
+		}
+	}
+	return nil
+}
@@ -2,229 +2,243 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/hypernova-labs/dgi-service/internal/database"
 	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/hypernova-labs/dgi-service/internal/storage"
 	"github.com/sirupsen/logrus"
 )
 
-// HybridStorageService maneja el almacenamiento híbrido de archivos
+// HybridStorageService guarda los archivos de las facturas en el BlobStore configurado
+// (STORAGE_TYPE) y sus metadatos en la BD local. Con metadataOnly la BD sólo guarda keys y
+// checksums; si no, conserva además una copia del contenido.
 type HybridStorageService struct {
-	supabaseClient   *database.SupabaseClient
+	store            storage.BlobStore
 	invoiceFilesRepo *database.InvoiceFilesRepository
+	metadataOnly     bool
+	signedURLTTL     time.Duration
 	logger           *logrus.Logger
-	bucketName       string
 }
 
-// NewHybridStorageService crea una nueva instancia del servicio
-func NewHybridStorageService(supabaseClient *database.SupabaseClient, invoiceFilesRepo *database.InvoiceFilesRepository, logger *logrus.Logger) *HybridStorageService {
+// NewHybridStorageService crea una nueva instancia del servicio; signedURLTTL es la validez de
+// las URLs prefirmadas de descarga
+func NewHybridStorageService(store storage.BlobStore, invoiceFilesRepo *database.InvoiceFilesRepository, metadataOnly bool, signedURLTTL time.Duration, logger *logrus.Logger) *HybridStorageService {
 	return &HybridStorageService{
-		supabaseClient:   supabaseClient,
+		store:            store,
 		invoiceFilesRepo: invoiceFilesRepo,
+		metadataOnly:     metadataOnly,
+		signedURLTTL:     signedURLTTL,
 		logger:           logger,
-		bucketName:       "invoice-files",
 	}
 }
 
-// StoreInvoiceFiles almacena los archivos de una factura en Supabase y metadatos en BD local
-func (s *HybridStorageService) StoreInvoiceFiles(ctx context.Context, invoiceID uuid.UUID, pdfData, xmlData []byte) (*models.InvoiceFilesResponse, error) {
-	// Crear nombres de archivo únicos
-	pdfFileName := fmt.Sprintf("invoices/%s/factura_%s.pdf", invoiceID, invoiceID)
-	xmlFileName := fmt.Sprintf("invoices/%s/factura_%s.xml", invoiceID, invoiceID)
+// FileDownload es un archivo a entregar: una URL prefirmada del storage o, si el backend no las
+// genera o el archivo sólo está en la BD, su contenido
+type FileDownload struct {
+	URL         string
+	ExpiresAt   time.Time
+	Data        []byte
+	FileName    string
+	ContentType string
+	SHA256      *string
+}
 
-	// Subir PDF a Supabase
-	pdfURL, err := s.supabaseClient.UploadFile(ctx, s.bucketName, pdfFileName, pdfData)
-	if err != nil {
-		return nil, fmt.Errorf("error uploading PDF to Supabase: %w", err)
+// signedURL pide al storage una URL prefirmada para key. Retorna "" si el backend no las genera
+// o falla, para que el archivo se sirva a través de la API.
+func signedURL(ctx context.Context, store storage.BlobStore, key, fileName string, ttl time.Duration, logger *logrus.Logger) (string, time.Time) {
+	if store == nil {
+		return "", time.Time{}
 	}
 
-	// Subir XML a Supabase
-	xmlURL, err := s.supabaseClient.UploadFile(ctx, s.bucketName, xmlFileName, xmlData)
+	expiresAt := time.Now().Add(ttl)
+	url, err := store.SignedURL(ctx, key, fileName, ttl)
 	if err != nil {
-		return nil, fmt.Errorf("error uploading XML to Supabase: %w", err)
-	}
-
-	// Convertir URLs S3 a URLs REST de Supabase para acceso público
-	// Cambiar de: https://ambtmugdpopskzxdafdm.storage.supabase.co/storage/v1/s3/invoice-files/...
-	// A: https://ambtmugdpopskzxdafdm.supabase.co/storage/v1/object/public/invoice-files/...
-	
-	// Extraer la parte del path del archivo
-	pdfPath := strings.TrimPrefix(pdfURL, "https://ambtmugdpopskzxdafdm.storage.supabase.co/storage/v1/s3/")
-	xmlPath := strings.TrimPrefix(xmlURL, "https://ambtmugdpopskzxdafdm.storage.supabase.co/storage/v1/s3/")
-	
-	// Crear URLs públicas usando la API REST de Supabase
-	publicPDFURL := fmt.Sprintf("https://ambtmugdpopskzxdafdm.supabase.co/storage/v1/object/public/%s", pdfPath)
-	publicXMLURL := fmt.Sprintf("https://ambtmugdpopskzxdafdm.supabase.co/storage/v1/object/public/%s", xmlPath)
-
-	// Crear registro en BD local con URLs públicas de Supabase
+		if !errors.Is(err, storage.ErrSignedURLNotSupported) {
+			logger.WithError(err).WithField("key", key).Warn("Failed to presign download URL, serving file through the API")
+		}
+		return "", time.Time{}
+	}
+	return url, expiresAt
+}
+
+// invoiceFileKey es la key de un archivo (pdf o xml) de una factura en el storage
+func invoiceFileKey(invoiceID uuid.UUID, fileType string) string {
+	return fmt.Sprintf("invoices/%s/factura_%s.%s", invoiceID, invoiceID, fileType)
+}
+
+// invoiceFileURL es la ruta de la API que descarga un archivo de una factura
+func invoiceFileURL(invoiceID uuid.UUID, fileType string) string {
+	return fmt.Sprintf("/v1/invoices/%s/files/%s", invoiceID, fileType)
+}
+
+// sha256Hex retorna el SHA-256 de data en hexadecimal
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// StoreInvoiceFiles registra en BD local los archivos generados de una factura ya subidos al
+// storage (pdfKey y xmlKey, las keys de sus versiones) con sus checksums y URLs de descarga
+func (s *HybridStorageService) StoreInvoiceFiles(ctx context.Context, generated *models.InvoiceFiles, pdfKey, xmlKey string) (*models.InvoiceFilesResponse, error) {
+	invoiceID := generated.InvoiceID
+
+	// Los archivos se descargan a través de la API, que redirige a una URL prefirmada del storage
+	pdfURL := invoiceFileURL(invoiceID, "pdf")
+	xmlURL := invoiceFileURL(invoiceID, "xml")
+	pdfSHA256 := sha256Hex(generated.PDFData)
+	xmlSHA256 := sha256Hex(generated.XMLData)
+
+	// Crear registro en BD local con las URLs de descarga
 	files := &models.InvoiceFiles{
-		ID:          uuid.New(),
+		ID:          generated.ID,
 		InvoiceID:   invoiceID,
-		PDFData:     nil, // No almacenamos datos en BD local
-		XMLData:     nil, // No almacenamos datos en BD local
-		PDFSize:     int64(len(pdfData)),
-		XMLSize:     int64(len(xmlData)),
-		PDFURL:      &publicPDFURL,
-		XMLURL:      &publicXMLURL,
-		GeneratedAt: time.Now(),
+		PDFSize:     int64(len(generated.PDFData)),
+		XMLSize:     int64(len(generated.XMLData)),
+		PDFURL:      &pdfURL,
+		XMLURL:      &xmlURL,
+		PDFKey:      &pdfKey,
+		XMLKey:      &xmlKey,
+		PDFSHA256:   &pdfSHA256,
+		XMLSHA256:   &xmlSHA256,
+		GeneratedAt: generated.GeneratedAt,
 		UpdatedAt:   time.Now(),
 	}
+	if !s.metadataOnly {
+		files.PDFData = generated.PDFData
+		files.XMLData = generated.XMLData
+	}
 
 	// Guardar metadatos en BD local
 	if err := s.invoiceFilesRepo.CreateOrUpdate(files); err != nil {
-		// Si falla, intentar limpiar archivos de Supabase
-		s.supabaseClient.DeleteFile(ctx, s.bucketName, pdfFileName)
-		s.supabaseClient.DeleteFile(ctx, s.bucketName, xmlFileName)
 		return nil, fmt.Errorf("error saving invoice files metadata: %w", err)
 	}
 
-	// Retornar respuesta con URLs públicas de Supabase
 	response := &models.InvoiceFilesResponse{
-		XMLFE:        &publicXMLURL,
-		XMLProtocolo: &publicXMLURL,
-		CAFEPDFURL:   &publicPDFURL,
-		Disposition:  "inline",
+		XMLFE:       &xmlURL,
+		CAFEPDFURL:  &pdfURL,
+		Disposition: "inline",
 	}
 
 	s.logger.WithFields(logrus.Fields{
-		"invoice_id": invoiceID,
-		"pdf_url":    publicPDFURL,
-		"xml_url":    publicXMLURL,
-		"pdf_size":   files.PDFSize,
-		"xml_size":   files.XMLSize,
-	}).Info("Invoice files stored in Supabase successfully")
+		"invoice_id":    invoiceID,
+		"pdf_key":       pdfKey,
+		"xml_key":       xmlKey,
+		"pdf_size":      files.PDFSize,
+		"xml_size":      files.XMLSize,
+		"metadata_only": s.metadataOnly,
+	}).Info("Invoice files stored successfully")
 
 	return response, nil
 }
 
-// GetInvoiceFiles obtiene los archivos de una factura desde Supabase
-func (s *HybridStorageService) GetInvoiceFiles(ctx context.Context, invoiceID uuid.UUID) (*models.InvoiceFilesResponse, error) {
-	// Obtener metadatos desde BD local
-	files, err := s.invoiceFilesRepo.GetByInvoiceID(invoiceID)
-	if err != nil {
-		return nil, fmt.Errorf("error getting invoice files metadata: %w", err)
-	}
+// MoveToStorage copia al storage los archivos de una factura que siguen en la BD, verifica cada
+// copia releyéndola y comparando su SHA-256, y sólo entonces deja el contenido en NULL en la BD.
+// Retorna false si el registro cambió mientras se copiaba; queda para la siguiente pasada.
+func (s *HybridStorageService) MoveToStorage(ctx context.Context, files *models.InvoiceFiles) (bool, error) {
+	moved := *files
 
-	// Verificar que las URLs existan
-	if files.PDFURL == nil || files.XMLURL == nil {
-		return nil, fmt.Errorf("invoice files not found in Supabase")
+	if files.PDFData != nil {
+		key, checksum, err := s.copyToStorage(ctx, files.InvoiceID, "pdf", files.PDFData, files.PDFSHA256)
+		if err != nil {
+			return false, err
+		}
+		url := invoiceFileURL(files.InvoiceID, "pdf")
+		moved.PDFKey, moved.PDFSHA256, moved.PDFURL = &key, &checksum, &url
 	}
 
-	// Retornar respuesta con URLs de Supabase
-	response := &models.InvoiceFilesResponse{
-		XMLFE:        files.XMLURL,
-		XMLProtocolo: files.XMLURL,
-		CAFEPDFURL:   files.PDFURL,
-		Disposition:  "inline",
+	if files.XMLData != nil {
+		key, checksum, err := s.copyToStorage(ctx, files.InvoiceID, "xml", files.XMLData, files.XMLSHA256)
+		if err != nil {
+			return false, err
+		}
+		url := invoiceFileURL(files.InvoiceID, "xml")
+		moved.XMLKey, moved.XMLSHA256, moved.XMLURL = &key, &checksum, &url
 	}
 
-	return response, nil
+	return s.invoiceFilesRepo.MarkMovedToStorage(&moved)
 }
 
-// DownloadInvoiceFile descarga un archivo específico desde Supabase
-func (s *HybridStorageService) DownloadInvoiceFile(ctx context.Context, invoiceID uuid.UUID, fileType string) ([]byte, string, error) {
-	// Obtener metadatos desde BD local
-	files, err := s.invoiceFilesRepo.GetByInvoiceID(invoiceID)
-	if err != nil {
-		return nil, "", fmt.Errorf("error getting invoice files metadata: %w", err)
+// copyToStorage sube un archivo guardado en la BD y verifica la copia por SHA-256. Si la BD ya
+// tenía un checksum, el contenido debe coincidir con él.
+func (s *HybridStorageService) copyToStorage(ctx context.Context, invoiceID uuid.UUID, fileType string, data []byte, expected *string) (string, string, error) {
+	checksum := sha256Hex(data)
+	if expected != nil && *expected != checksum {
+		return "", "", fmt.Errorf("%s checksum mismatch in database for invoice %s", fileType, invoiceID)
 	}
 
-	var fileName string
-	var bucketPath string
+	contentType := "application/pdf"
+	if fileType == "xml" {
+		contentType = "application/xml"
+	}
 
-	switch fileType {
-	case "pdf":
-		if files.PDFURL == nil {
-			return nil, "", fmt.Errorf("PDF file not found for invoice %s", invoiceID)
-		}
-		fileName = fmt.Sprintf("factura_%s.pdf", invoiceID)
-		bucketPath = fmt.Sprintf("invoices/%s/factura_%s.pdf", invoiceID, invoiceID)
-	case "xml":
-		if files.XMLURL == nil {
-			return nil, "", fmt.Errorf("XML file not found for invoice %s", invoiceID)
-		}
-		fileName = fmt.Sprintf("factura_%s.xml", invoiceID)
-		bucketPath = fmt.Sprintf("invoices/%s/factura_%s.xml", invoiceID, invoiceID)
-	default:
-		return nil, "", fmt.Errorf("invalid file type: %s", fileType)
+	key := invoiceFileKey(invoiceID, fileType)
+	if err := s.store.Put(ctx, key, data, contentType); err != nil {
+		return "", "", fmt.Errorf("error uploading %s to storage: %w", fileType, err)
 	}
 
-	// Descargar archivo desde Supabase
-	fileData, err := s.supabaseClient.DownloadFile(ctx, s.bucketName, bucketPath)
+	stored, err := s.store.Get(ctx, key)
 	if err != nil {
-		return nil, "", fmt.Errorf("error downloading file from Supabase: %w", err)
+		return "", "", fmt.Errorf("error verifying %s in storage: %w", fileType, err)
 	}
-
-	return fileData, fileName, nil
-}
-
-// DownloadFile descarga un archivo desde una URL de Supabase
-func (s *HybridStorageService) DownloadFile(ctx context.Context, url string) ([]byte, error) {
-	// Crear cliente HTTP
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+	if sha256Hex(stored) != checksum {
+		return "", "", fmt.Errorf("%s checksum mismatch in storage for invoice %s", fileType, invoiceID)
 	}
 
-	// Crear request
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
-	}
+	return key, checksum, nil
+}
 
-	// Realizar request
-	resp, err := client.Do(req)
+// GetInvoiceFiles obtiene las URLs de los archivos de una factura
+func (s *HybridStorageService) GetInvoiceFiles(ctx context.Context, invoiceID uuid.UUID) (*models.InvoiceFilesResponse, error) {
+	// Obtener metadatos desde BD local
+	files, err := s.invoiceFilesRepo.GetByInvoiceID(invoiceID)
 	if err != nil {
-		return nil, fmt.Errorf("error downloading file: %w", err)
+		return nil, fmt.Errorf("error getting invoice files metadata: %w", err)
 	}
-	defer resp.Body.Close()
 
-	// Verificar status code
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error downloading file: HTTP %d", resp.StatusCode)
+	// Verificar que las URLs existan
+	if files.PDFURL == nil || files.XMLURL == nil {
+		return nil, fmt.Errorf("invoice files not found in storage")
 	}
 
-	// Leer contenido
-	fileData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading file content: %w", err)
+	// Retornar respuesta con URLs de descarga
+	response := &models.InvoiceFilesResponse{
+		XMLFE:       files.XMLURL,
+		CAFEPDFURL:  files.PDFURL,
+		Disposition: "inline",
 	}
 
-	return fileData, nil
+	return response, nil
 }
 
-// InitializeBucket inicializa el bucket de Supabase si no existe
-func (s *HybridStorageService) InitializeBucket(ctx context.Context) error {
-	// Intentar crear el bucket (puede fallar si ya existe)
-	err := s.supabaseClient.CreateBucket(ctx, s.bucketName, true)
-	if err != nil {
-		s.logger.WithError(err).Warn("Bucket may already exist, continuing...")
-		
-		// Si el bucket ya existe, intentar configurar permisos públicos
-		if err := s.configurePublicAccess(ctx); err != nil {
-			s.logger.WithError(err).Warn("Could not configure public access for existing bucket")
-		}
-	} else {
-		// Si se creó exitosamente, verificar que los permisos públicos estén configurados
-		if err := s.configurePublicAccess(ctx); err != nil {
-			s.logger.WithError(err).Warn("Could not configure public access for new bucket")
-		}
+// fileKey es la key de un archivo (pdf o xml) de una factura en el storage. Los registros
+// anteriores a las keys guardadas usan la key por defecto de la factura.
+func fileKey(files *models.InvoiceFiles, fileType string) string {
+	if fileType == "pdf" && files.PDFKey != nil {
+		return *files.PDFKey
 	}
+	if fileType == "xml" && files.XMLKey != nil {
+		return *files.XMLKey
+	}
+	return invoiceFileKey(files.InvoiceID, fileType)
+}
 
-	s.logger.WithField("bucket", s.bucketName).Info("Supabase storage bucket initialized")
-	return nil
+// SignedURL genera una URL prefirmada para un archivo (pdf o xml) de una factura, válida durante
+// signedURLTTL. Retorna "" si el backend no genera URLs firmadas.
+func (s *HybridStorageService) SignedURL(ctx context.Context, files *models.InvoiceFiles, fileType, fileName string) (string, time.Time) {
+	return signedURL(ctx, s.store, fileKey(files, fileType), fileName, s.signedURLTTL, s.logger)
 }
 
-// configurePublicAccess configura el acceso público al bucket
-func (s *HybridStorageService) configurePublicAccess(ctx context.Context) error {
-	// Para Supabase, los archivos se suben como públicos por defecto
-	// No necesitamos configurar políticas S3 complejas
-	
-	s.logger.WithField("bucket", s.bucketName).Info("Supabase storage configured for public access")
-	return nil
+// ReadFile lee un archivo (pdf o xml) de una factura desde el storage
+func (s *HybridStorageService) ReadFile(ctx context.Context, files *models.InvoiceFiles, fileType string) ([]byte, error) {
+	fileData, err := s.store.Get(ctx, fileKey(files, fileType))
+	if err != nil {
+		return nil, fmt.Errorf("error downloading file from storage: %w", err)
+	}
+	return fileData, nil
 }
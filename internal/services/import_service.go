@@ -0,0 +1,338 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hypernova-labs/dgi-service/internal/database"
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxImportRows limita la cantidad de filas de datos por archivo
+	maxImportRows = 50000
+	// maxImportErrors limita la cantidad de errores guardados en el reporte
+	maxImportErrors = 5000
+)
+
+// Columnas esperadas por tipo de importación (la primera fila del archivo es el encabezado)
+var (
+	customerImportColumns  = []string{"name", "email", "phone", "address", "ubi_code", "tax_id", "tax_id_type", "tax_id_dv", "recipient_type", "foreign_id", "country_code"}
+	customerImportRequired = []string{"name", "email"}
+	productImportColumns   = []string{"sku", "description", "unit_price", "tax_rate", "cpbs_abr", "cpbs_cmp"}
+	productImportRequired  = []string{"sku", "description", "unit_price", "tax_rate"}
+)
+
+// ImportService maneja las importaciones masivas de clientes y productos
+type ImportService struct {
+	importJobRepo   *database.ImportJobRepository
+	customerRepo    *database.CustomerRepository
+	productRepo     *database.ProductRepository
+	customerService *CustomerService
+	productService  *ProductService
+	logger          *logrus.Logger
+}
+
+// NewImportService crea una nueva instancia del servicio
+func NewImportService(db *database.DB, logger *logrus.Logger) *ImportService {
+	return &ImportService{
+		importJobRepo:   database.NewImportJobRepository(db, logger),
+		customerRepo:    database.NewCustomerRepository(db, logger),
+		productRepo:     database.NewProductRepository(db, logger),
+		customerService: NewCustomerService(db, logger),
+		productService:  NewProductService(db, logger),
+		logger:          logger,
+	}
+}
+
+// StartImport registra un trabajo de importación y lo procesa en segundo plano; los cambios
+// quedan en el log de auditoría a nombre de actor
+func (s *ImportService) StartImport(emitterID uuid.UUID, resourceType models.ImportResourceType, fileName string, data []byte, actor string) (*models.ImportJob, error) {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(fileName)), ".")
+	if format != "csv" && format != "xlsx" {
+		return nil, fmt.Errorf("validation error: unsupported file format %q (must be .csv or .xlsx)", format)
+	}
+
+	// Parsear antes de aceptar el trabajo para rechazar archivos corruptos de inmediato
+	rows, err := readSpreadsheet(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+	if len(rows) < 2 {
+		return nil, fmt.Errorf("validation error: file has no data rows")
+	}
+	if len(rows)-1 > maxImportRows {
+		return nil, fmt.Errorf("validation error: too many rows (max %d)", maxImportRows)
+	}
+
+	columns, required := customerImportColumns, customerImportRequired
+	if resourceType == models.ImportResourceProducts {
+		columns, required = productImportColumns, productImportRequired
+	}
+	header, err := mapImportHeader(rows[0], columns, required)
+	if err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+
+	job := &models.ImportJob{
+		ID:           uuid.New(),
+		EmitterID:    emitterID,
+		ResourceType: resourceType,
+		FileName:     filepath.Base(fileName),
+		FileFormat:   format,
+		Status:       models.ImportStatusPending,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := s.importJobRepo.Create(job); err != nil {
+		return nil, fmt.Errorf("error creating import job: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"import_id":     job.ID,
+		"emitter_id":    emitterID,
+		"resource_type": resourceType,
+		"rows":          len(rows) - 1,
+	}).Info("Import job accepted")
+
+	go s.process(job, header, rows[1:], actor)
+
+	return job, nil
+}
+
+// GetJob obtiene un trabajo de importación de un emisor
+func (s *ImportService) GetJob(emitterID, id uuid.UUID) (*models.ImportJob, error) {
+	job, err := s.importJobRepo.GetByID(emitterID, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting import job: %w", err)
+	}
+
+	return job, nil
+}
+
+// process valida y aplica (upsert) cada fila, acumulando el reporte de errores
+func (s *ImportService) process(job *models.ImportJob, header map[string]int, rows [][]string, actor string) {
+	logger := s.logger.WithFields(logrus.Fields{
+		"import_id":  job.ID,
+		"emitter_id": job.EmitterID,
+	})
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("Import job panicked: %v", r)
+			s.finish(job, fmt.Errorf("internal error while processing import"))
+		}
+	}()
+
+	if err := s.importJobRepo.MarkProcessing(job.ID); err != nil {
+		logger.WithError(err).Error("Error marking import job as processing")
+	}
+
+	job.Status = models.ImportStatusProcessing
+	job.Errors = []models.ImportRowError{}
+
+	for i, row := range rows {
+		// Número de fila en el archivo (1 es el encabezado)
+		rowNumber := i + 2
+		if isEmptyRow(row) {
+			continue
+		}
+		job.TotalRows++
+
+		var created bool
+		var rowErr *models.ImportRowError
+		switch job.ResourceType {
+		case models.ImportResourceCustomers:
+			created, rowErr = s.importCustomerRow(job.EmitterID, header, row, actor)
+		case models.ImportResourceProducts:
+			created, rowErr = s.importProductRow(job.EmitterID, header, row, actor)
+		}
+
+		if rowErr != nil {
+			rowErr.Row = rowNumber
+			job.ErrorCount++
+			if len(job.Errors) < maxImportErrors {
+				job.Errors = append(job.Errors, *rowErr)
+			}
+			continue
+		}
+
+		if created {
+			job.CreatedCount++
+		} else {
+			job.UpdatedCount++
+		}
+	}
+
+	s.finish(job, nil)
+
+	logger.WithFields(logrus.Fields{
+		"total_rows": job.TotalRows,
+		"created":    job.CreatedCount,
+		"updated":    job.UpdatedCount,
+		"errors":     job.ErrorCount,
+	}).Info("Import job completed")
+}
+
+// finish guarda el estado final del trabajo
+func (s *ImportService) finish(job *models.ImportJob, jobErr error) {
+	now := time.Now()
+	job.CompletedAt = &now
+	job.Status = models.ImportStatusCompleted
+	if jobErr != nil {
+		job.Status = models.ImportStatusFailed
+		message := jobErr.Error()
+		job.ErrorMessage = &message
+	}
+
+	if err := s.importJobRepo.Complete(job); err != nil {
+		s.logger.WithError(err).WithField("import_id", job.ID).Error("Error saving import job result")
+	}
+}
+
+// importCustomerRow valida y hace upsert de una fila de clientes
+func (s *ImportService) importCustomerRow(emitterID uuid.UUID, header map[string]int, row []string, actor string) (bool, *models.ImportRowError) {
+	req := &models.CreateCustomerRequest{
+		Name:          importCell(row, header, "name"),
+		Email:         importCell(row, header, "email"),
+		Phone:         optionalImportCell(row, header, "phone"),
+		Address:       optionalImportCell(row, header, "address"),
+		UBICode:       optionalImportCell(row, header, "ubi_code"),
+		TaxID:         optionalImportCell(row, header, "tax_id"),
+		TaxIDType:     optionalImportCell(row, header, "tax_id_type"),
+		TaxIDDV:       optionalImportCell(row, header, "tax_id_dv"),
+		ForeignID:     optionalImportCell(row, header, "foreign_id"),
+		CountryCode:   optionalImportCell(row, header, "country_code"),
+		RecipientType: models.RecipientType(importCell(row, header, "recipient_type")),
+	}
+
+	if err := s.customerService.validateCustomerData(req); err != nil {
+		var fieldErrs models.FieldErrors
+		if errors.As(err, &fieldErrs) && len(fieldErrs) > 0 {
+			return false, &models.ImportRowError{Field: fieldErrs[0].Field, Reason: err.Error()}
+		}
+		return false, &models.ImportRowError{Reason: err.Error()}
+	}
+
+	// Las filas que coinciden (por RUC y luego email) actualizan el cliente existente
+	existing, err := s.customerService.Match(emitterID, req, models.CustomerMatchTaxID)
+	if err != nil {
+		s.logger.WithError(err).Warn("Error matching imported customer")
+		return false, &models.ImportRowError{Reason: "could not save customer"}
+	}
+
+	if existing != nil {
+		applyCustomerRequest(existing, req)
+		if _, err := s.customerRepo.Update(emitterID, existing, actor); err != nil {
+			s.logger.WithError(err).Warn("Error updating imported customer")
+			return false, &models.ImportRowError{Field: "tax_id", Reason: "could not save customer"}
+		}
+		return false, nil
+	}
+
+	if _, err := s.customerRepo.Create(req, emitterID, actor); err != nil {
+		s.logger.WithError(err).Warn("Error creating imported customer")
+		return false, &models.ImportRowError{Field: "tax_id", Reason: "could not save customer"}
+	}
+
+	return true, nil
+}
+
+// importProductRow valida y hace upsert de una fila de productos
+func (s *ImportService) importProductRow(emitterID uuid.UUID, header map[string]int, row []string, actor string) (bool, *models.ImportRowError) {
+	unitPrice, err := strconv.ParseFloat(strings.ReplaceAll(importCell(row, header, "unit_price"), ",", "."), 64)
+	if err != nil {
+		return false, &models.ImportRowError{Field: "unit_price", Reason: "unit price must be a number"}
+	}
+
+	req := &models.CreateProductRequest{
+		SKU:         importCell(row, header, "sku"),
+		Description: importCell(row, header, "description"),
+		UnitPrice:   unitPrice,
+		TaxRate:     normalizeTaxRate(importCell(row, header, "tax_rate")),
+		CPBSAbr:     optionalImportCell(row, header, "cpbs_abr"),
+		CPBSCmp:     optionalImportCell(row, header, "cpbs_cmp"),
+	}
+
+	if err := s.productService.validateProductData(req); err != nil {
+		return false, &models.ImportRowError{Reason: err.Error()}
+	}
+	if err := s.productService.validateCatalogCodes(req); err != nil {
+		return false, &models.ImportRowError{Reason: err.Error()}
+	}
+
+	created, err := s.productRepo.Upsert(req, emitterID, actor)
+	if err != nil {
+		s.logger.WithError(err).Warn("Error upserting imported product")
+		return false, &models.ImportRowError{Field: "sku", Reason: "could not save product"}
+	}
+
+	return created, nil
+}
+
+// mapImportHeader ubica las columnas conocidas en el encabezado y verifica las requeridas
+func mapImportHeader(header []string, columns, required []string) (map[string]int, error) {
+	positions := make(map[string]int, len(columns))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for _, column := range columns {
+			if name == column {
+				positions[column] = i
+			}
+		}
+	}
+
+	var missing []string
+	for _, column := range required {
+		if _, ok := positions[column]; !ok {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required columns: %s (expected header: %s)", strings.Join(missing, ", "), strings.Join(columns, ","))
+	}
+
+	return positions, nil
+}
+
+// importCell obtiene el valor de una columna de la fila
+func importCell(row []string, header map[string]int, column string) string {
+	idx, ok := header[column]
+	if !ok || idx >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[idx])
+}
+
+// optionalImportCell obtiene el valor de una columna opcional (nil si está vacía)
+func optionalImportCell(row []string, header map[string]int, column string) *string {
+	value := importCell(row, header, column)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// normalizeTaxRate completa con cero a la izquierda tasas escritas como número (ej. "1" -> "01")
+func normalizeTaxRate(rate string) string {
+	if len(rate) == 1 {
+		return "0" + rate
+	}
+	return rate
+}
+
+// isEmptyRow indica si todas las celdas de la fila están vacías
+func isEmptyRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
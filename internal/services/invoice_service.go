@@ -2,14 +2,22 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/hypernova-labs/dgi-service/internal/config"
 	"github.com/hypernova-labs/dgi-service/internal/database"
 	"github.com/hypernova-labs/dgi-service/internal/email"
 	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/hypernova-labs/dgi-service/internal/pac"
+	"github.com/hypernova-labs/dgi-service/internal/storage"
 	"github.com/hypernova-labs/dgi-service/internal/workflows"
 	"github.com/sirupsen/logrus"
 )
@@ -21,15 +29,23 @@ type InvoiceService struct {
 	customerRepo       *database.CustomerRepository
 	productRepo        *database.ProductRepository
 	invoiceFilesRepo   *database.InvoiceFilesRepository
+	idempotencyRepo    *database.IdempotencyRepository
+	artifactService    *ArtifactService
+	idempotencyCfg     config.IdempotencyConfig
 	inngestClient      *workflows.InngestClient
 	resendService      *email.ResendService
 	documentGenerator  *DocumentGenerator
 	storageService     *HybridStorageService
+	exchangeRateService *ExchangeRateService
+	catalogService    *CatalogService
+	customerService   *CustomerService
+	branchService     *BranchService
+	pacClient         *pac.Client
 	logger             *logrus.Logger
 }
 
 // NewInvoiceService crea una nueva instancia del servicio
-func NewInvoiceService(db *database.DB, inngestClient *workflows.InngestClient, resendService *email.ResendService, supabaseClient *database.SupabaseClient, logger *logrus.Logger) *InvoiceService {
+func NewInvoiceService(db *database.DB, inngestClient *workflows.InngestClient, resendService *email.ResendService, blobStore storage.BlobStore, storageCfg config.StorageConfig, pacClient *pac.Client, idempotencyCfg config.IdempotencyConfig, logger *logrus.Logger) *InvoiceService {
 	// Inicializar repositorios
 	invoiceRepo := database.NewInvoiceRepository(db, logger)
 	emitterRepo := database.NewEmitterRepository(db, logger)
@@ -40,14 +56,10 @@ func NewInvoiceService(db *database.DB, inngestClient *workflows.InngestClient,
 	// Inicializar servicios
 	documentGenerator := NewDocumentGenerator(logger)
 
-	// Inicializar servicio de storage híbrido si Supabase está disponible
+	// Inicializar servicio de storage híbrido si hay un storage disponible
 	var storageService *HybridStorageService
-	if supabaseClient != nil {
-		storageService = NewHybridStorageService(supabaseClient, invoiceFilesRepo, logger)
-		// Inicializar bucket de Supabase
-		if err := storageService.InitializeBucket(context.Background()); err != nil {
-			logger.Warnf("Could not initialize Supabase bucket: %v", err)
-		}
+	if blobStore != nil {
+		storageService = NewHybridStorageService(blobStore, invoiceFilesRepo, storageCfg.MetadataOnly, storageCfg.SignedURLTTL, logger)
 	}
 
 	return &InvoiceService{
@@ -56,25 +68,52 @@ func NewInvoiceService(db *database.DB, inngestClient *workflows.InngestClient,
 		customerRepo:      customerRepo,
 		productRepo:       productRepo,
 		invoiceFilesRepo:  invoiceFilesRepo,
+		idempotencyRepo:   database.NewIdempotencyRepository(db, logger),
+		artifactService:   NewArtifactService(db, blobStore, storageCfg.SignedURLTTL, logger),
+		idempotencyCfg:    idempotencyCfg,
 		inngestClient:     inngestClient,
 		resendService:     resendService,
 		documentGenerator: documentGenerator,
 		storageService:    storageService,
+		exchangeRateService: NewExchangeRateService(db, logger),
+		catalogService:    NewCatalogService(db, logger),
+		customerService:   NewCustomerService(db, logger),
+		branchService:     NewBranchService(db, logger),
+		pacClient:         pacClient,
 		logger:            logger,
 	}
 }
 
-// CreateInvoice crea un nuevo documento fiscal
-func (s *InvoiceService) CreateInvoice(emitterID uuid.UUID, req *models.CreateInvoiceRequest, idempotencyKey string) (*models.InvoiceResponse, error) {
-	// Verificar idempotencia si se proporciona clave
+// idempotencyLease es cuánto queda reservada una Idempotency-Key mientras se crea el documento;
+// si la petición se abandona, pasado este tiempo otro reintento puede tomarla
+const idempotencyLease = time.Minute
+
+// CreateInvoice crea un nuevo documento fiscal en el ambiente de la API key (environment). Los
+// clientes y series creados o actualizados junto con el documento quedan a nombre de actor.
+// Con idempotencyKey, un reintento con el mismo request recibe la respuesta original (Replayed)
+// y la misma key con otro request se rechaza.
+func (s *InvoiceService) CreateInvoice(emitterID uuid.UUID, environment models.APIKeyEnvironment, req *models.CreateInvoiceRequest, idempotencyKey, actor string) (_ *models.InvoiceResponse, err error) {
+	// Reservar la key del emisor antes de crear nada
+	var claim *models.IdempotencyKey
 	if idempotencyKey != "" {
-		existingInvoice, err := s.invoiceRepo.GetByIdempotencyKey(idempotencyKey)
+		var replay *models.InvoiceResponse
+		claim, replay, err = s.claimIdempotencyKey(emitterID, environment, req, idempotencyKey)
 		if err != nil {
-			return nil, fmt.Errorf("error checking idempotency: %w", err)
+			return nil, err
 		}
-		if existingInvoice != nil {
-			return nil, fmt.Errorf("idempotency key already used")
+		if replay != nil {
+			return replay, nil
 		}
+
+		// Si la creación falla, la key queda libre para reintentar
+		defer func() {
+			if err == nil {
+				return
+			}
+			if releaseErr := s.idempotencyRepo.Release(claim); releaseErr != nil {
+				s.logger.WithError(releaseErr).WithField("idempotency_key", idempotencyKey).Warn("Could not release idempotency key")
+			}
+		}()
 	}
 
 	// Obtener emisor
@@ -83,34 +122,58 @@ func (s *InvoiceService) CreateInvoice(emitterID uuid.UUID, req *models.CreateIn
 		return nil, fmt.Errorf("error getting emitter: %w", err)
 	}
 
-	// Obtener o crear cliente
-	customer, err := s.getOrCreateCustomer(req.Customer, emitterID)
+	// Ambiente del documento: las keys de prueba nunca emiten en producción
+	iamb := environment.IAmb(emitter)
+
+	// Obtener cliente referenciado o crearlo a partir de los datos enviados
+	customer, err := s.resolveCustomer(req, emitterID, actor)
 	if err != nil {
 		return nil, fmt.Errorf("error getting/creating customer: %w", err)
 	}
 
+	// Validar forma de pago contra el catálogo
+	if err := s.catalogService.ValidatePaymentMethod(req.Payment.Method); err != nil {
+		return nil, fmt.Errorf("error validating payment: %w", err)
+	}
+
+	// Completar ítems con los datos de los productos referenciados
+	itemReqs, products, err := s.resolveItems(emitterID, req.Items)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving items: %w", err)
+	}
+
+	// Resolver moneda del documento y tasa de cambio
+	currencyCode, exchangeRate, err := s.exchangeRateService.ResolveRate(emitterID, req.CurrencyCode, req.ExchangeRate, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error resolving exchange rate: %w", err)
+	}
+
+	// Obtener sucursal del documento (la principal del emisor si no se indica)
+	branchCode := ""
+	if req.Overrides != nil {
+		branchCode = req.Overrides.Branch
+	}
+	branch, err := s.branchService.Resolve(emitter, branchCode)
+	if err != nil {
+		return nil, fmt.Errorf("error getting branch: %w", err)
+	}
+
 	// Obtener serie para el documento
 	var ptoFacDF string
 	if req.Overrides != nil && req.Overrides.PtoFacDF != "" {
 		ptoFacDF = req.Overrides.PtoFacDF
 	} else {
-		// Usar el punto de facturación por defecto del emisor (ya obtenido arriba)
-		ptoFacDF = emitter.PtoFacDefault
+		// Usar el punto de facturación por defecto de la sucursal
+		ptoFacDF = branch.PtoFacDefault
 	}
 	
-	series, err := s.emitterRepo.GetSeries(emitterID, ptoFacDF, req.DocumentType)
+	series, err := s.resolveSeries(emitter, branch, iamb, ptoFacDF, req.DocumentType, actor)
 	if err != nil {
 		return nil, fmt.Errorf("error getting series: %w", err)
 	}
 
-	// Obtener siguiente número de documento
-	documentNumber, err := s.invoiceRepo.GetNextDocumentNumber(emitterID, series.PtoFacDF, req.DocumentType)
-	if err != nil {
-		return nil, fmt.Errorf("error getting next document number: %w", err)
-	}
-
-	// Calcular totales
-	subtotal, itbmsAmount, totalAmount, err := s.calculateTotals(req.Items)
+	// Calcular totales (en moneda del documento)
+	subtotal, itbmsAmount, totalAmount, err := s.calculateTotals(itemReqs)
 	if err != nil {
 		return nil, fmt.Errorf("error calculating totals: %w", err)
 	}
@@ -120,57 +183,50 @@ func (s *InvoiceService) CreateInvoice(emitterID uuid.UUID, req *models.CreateIn
 		return nil, fmt.Errorf("calculated total (%.2f) does not match payment amount (%.2f)", totalAmount, req.Payment.Amount)
 	}
 
+	// Totales en moneda funcional
+	functionalSubtotal, functionalITBMS, functionalTotal := ToFunctionalTotals(subtotal, itbmsAmount, exchangeRate)
+
 	// Crear invoice
 	invoice := &models.Invoice{
 		ID:              uuid.New(),
 		EmitterID:       emitterID,
 		SeriesID:        series.ID,
+		BranchID:        &branch.ID,
 		CustomerID:      customer.ID,
 		DocumentType:    req.DocumentType,
-		DocumentNumber:  documentNumber,
+		// DocumentNumber se asigna al insertar, dentro de la transacción
 		PtoFacDF:        series.PtoFacDF,
 		Status:          models.DocumentStatusReceived,
 		EmailStatus:     models.EmailStatusPending,
 		ReferenceCUFE:   s.getReferenceValue(req.Reference, "cufe"),
 		ReferenceNumber: s.getReferenceValue(req.Reference, "nrodf"),
 		ReferencePtoFac: s.getReferenceValue(req.Reference, "pto_fac_df"),
-		IAmb:            emitter.IAmb,
+		IAmb:            iamb,
 		ITpEmis:         s.getOverrideValue(s.getOverrideField(req.Overrides, "ITpEmis"), emitter.ITpEmisDefault),
 		IDoc:            s.getOverrideValue(s.getOverrideField(req.Overrides, "IDoc"), emitter.IDocDefault),
-		Subtotal:        subtotal,
-		ITBMSAmount:     itbmsAmount,
-		TotalAmount:     totalAmount,
+		Subtotal:        functionalSubtotal,
+		ITBMSAmount:     functionalITBMS,
+		TotalAmount:     functionalTotal,
+		CurrencyCode:    currencyCode,
+		ExchangeRate:    exchangeRate,
+		DocSubtotal:     subtotal,
+		DocITBMSAmount:  itbmsAmount,
+		DocTotalAmount:  totalAmount,
 		IdempotencyKey:  func() *string { if idempotencyKey == "" { return nil } else { return &idempotencyKey } }(),
+		// Snapshot de emisor, receptor y marca: el documento no cambia si luego se editan
+		Snapshot:        models.NewInvoiceSnapshot(emitter, branch, customer),
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
 	}
 
 	// Crear items
-	items := make([]models.InvoiceItem, len(req.Items))
-	for i, itemReq := range req.Items {
-		// Obtener o crear producto si se proporciona SKU o ProductID
-		var product *models.Product
-		if itemReq.ProductID != nil {
-			// Buscar producto por ID
-			productID, err := uuid.Parse(*itemReq.ProductID)
-			if err != nil {
-				return nil, fmt.Errorf("invalid product_id format: %s", *itemReq.ProductID)
-			}
-			product, err = s.productRepo.GetByID(productID)
-			if err != nil {
-				return nil, fmt.Errorf("product with ID %s not found: %w", *itemReq.ProductID, err)
-			}
-		} else if itemReq.SKU != nil {
-			// Buscar producto por SKU (mantener compatibilidad)
-			product, err = s.productRepo.GetBySKU(emitterID, *itemReq.SKU)
-			if err != nil {
-				s.logger.Warnf("Product with SKU %s not found, using request data", *itemReq.SKU)
-			}
-		}
+	items := make([]models.InvoiceItem, len(itemReqs))
+	for i, itemReq := range itemReqs {
+		product := products[i]
 
-		// Usar descripción del producto si está disponible, sino usar la del request
+		// Ítems por SKU usan la descripción del producto; con product_id ya viene resuelta
 		description := itemReq.Description
-		if product != nil && product.Description != "" {
+		if product != nil && product.Description != "" && itemReq.ProductID == nil {
 			description = product.Description
 		}
 
@@ -186,37 +242,48 @@ func (s *InvoiceService) CreateInvoice(emitterID uuid.UUID, req *models.CreateIn
 			Quantity:    itemReq.Quantity,
 			UnitPrice:   itemReq.UnitPrice,
 			ITBMSRate:   itemReq.TaxRate,
-			CPBSAbr:     s.getProductValue(product, "cpbs_abr", nil),
-			CPBSCmp:     s.getProductValue(product, "cpbs_cmp", nil),
+			CPBSAbr:     itemReq.CPBSAbr,
+			CPBSCmp:     itemReq.CPBSCmp,
 			LineTotal:   lineTotal,
 			CreatedAt:   time.Now(),
 		}
 	}
 
+	// Construir respuesta; se arma dentro de la transacción, una vez asignado el folio, para
+	// guardarla junto con el documento en la Idempotency-Key
+	var response *models.InvoiceResponse
+	buildResponse := func() interface{} {
+		response = &models.InvoiceResponse{
+			ID:           invoice.ID,
+			Status:       invoice.Status,
+			DocumentType: invoice.DocumentType,
+			Emitter: models.EmitterInfo{
+				RUC:    fmt.Sprintf("%s-%s-%s-%s", emitter.RUCTipo, emitter.RUCNumero, emitter.RUCDV, branch.Code),
+				PtoFac: invoice.PtoFacDF,
+				Number: invoice.DocumentNumber,
+			},
+			Totals:           documentTotals(invoice),
+			FunctionalTotals: functionalTotals(invoice),
+			ExchangeRate:     exchangeRatePtr(invoice),
+			Links: models.Links{
+				Self:  fmt.Sprintf("/v1/invoices/%s", invoice.ID),
+				Files: fmt.Sprintf("/v1/invoices/%s/files", invoice.ID),
+			},
+		}
+
+		// Avisar si el rango autorizado de la serie se está agotando
+		if warning := s.seriesRangeWarning(series, invoice.DocumentNumber); warning != "" {
+			response.Warnings = append(response.Warnings, warning)
+		}
+		return response
+	}
+
 	// Persistir en base de datos
-	if err := s.invoiceRepo.Create(invoice, items); err != nil {
+	if err := s.invoiceRepo.Create(invoice, items, actor, claim, buildResponse); err != nil {
 		return nil, fmt.Errorf("error creating invoice: %w", err)
 	}
-
-	// Construir respuesta
-	response := &models.InvoiceResponse{
-		ID:           invoice.ID,
-		Status:       invoice.Status,
-		DocumentType: invoice.DocumentType,
-		Emitter: models.EmitterInfo{
-			RUC:    fmt.Sprintf("%s-%s-%s-%s", emitter.RUCTipo, emitter.RUCNumero, emitter.RUCDV, emitter.SucEm),
-			PtoFac: invoice.PtoFacDF,
-			Number: invoice.DocumentNumber,
-		},
-		Totals: models.Totals{
-			Net:   invoice.Subtotal,
-			ITBMS: invoice.ITBMSAmount,
-			Total: invoice.TotalAmount,
-		},
-		Links: models.Links{
-			Self:  fmt.Sprintf("/v1/invoices/%s", invoice.ID),
-			Files: fmt.Sprintf("/v1/invoices/%s/files", invoice.ID),
-		},
+	if response == nil {
+		buildResponse()
 	}
 
 	s.logger.WithFields(logrus.Fields{
@@ -224,62 +291,140 @@ func (s *InvoiceService) CreateInvoice(emitterID uuid.UUID, req *models.CreateIn
 		"emitter_id": emitterID,
 		"document_number": invoice.DocumentNumber,
 		"total_amount": invoice.TotalAmount,
+		"i_amb": invoice.IAmb,
 	}).Info("Invoice created successfully")
 
-	// ENVÍO DIRECTO DE EMAIL (para testing sin workflow)
-	if s.resendService != nil {
-		go func() {
-			s.logger.WithField("invoice_id", invoice.ID).Info("Sending email directly via Resend (testing mode)")
-			
-			// Obtener datos del cliente y emisor para el email
-			customer, err := s.customerRepo.GetByID(invoice.CustomerID)
-			if err != nil {
-				s.logger.WithField("invoice_id", invoice.ID).Errorf("Failed to get customer for direct email: %v", err)
-				return
-			}
-			emitter, err := s.emitterRepo.GetByID(invoice.EmitterID)
-			if err != nil {
-				s.logger.WithField("invoice_id", invoice.ID).Errorf("Failed to get emitter for direct email: %v", err)
-				return
-			}
+	// El email, los workflows y los webhooks se disparan desde el outbox (evento invoice.created),
+	// escrito en la misma transacción que el documento
 
-			// Enviar email directamente usando Resend
-			err = s.resendService.SendInvoiceEmail(invoice, customer, emitter)
-			if err != nil {
-				s.logger.WithFields(logrus.Fields{
-					"invoice_id": invoice.ID,
-					"error": err,
-				}).Error("Failed to send email directly via Resend")
-				// Opcional: Actualizar estado de email a fallido en DB
-				if updateErr := s.invoiceRepo.UpdateEmailStatus(invoice.ID, models.EmailStatusFailed); updateErr != nil {
-					s.logger.WithField("invoice_id", invoice.ID).Errorf("Failed to update email status: %v", updateErr)
-				}
-				return
-			}
-			s.logger.WithFields(logrus.Fields{
-				"invoice_id": invoice.ID,
-				"customer_email": customer.Email,
-				"document_number": invoice.DocumentNumber,
-			}).Info("Email sent successfully via Resend (direct mode)")
-			// Opcional: Actualizar estado de email a enviado en DB
-			if err := s.invoiceRepo.UpdateEmailStatus(invoice.ID, models.EmailStatusSent); err != nil {
-				s.logger.WithField("invoice_id", invoice.ID).Errorf("Failed to update email status: %v", err)
-			}
-		}()
-	} else {
-		s.logger.Warn("Resend service not available - email not sent")
+	return response, nil
+}
+
+// claimIdempotencyKey reserva la Idempotency-Key del emisor para este request. Devuelve la
+// respuesta original si la key ya creó un documento con el mismo request, y un error si se usó
+// con otro request o si otra petición con la key sigue en curso.
+func (s *InvoiceService) claimIdempotencyKey(emitterID uuid.UUID, environment models.APIKeyEnvironment, req *models.CreateInvoiceRequest, key string) (*models.IdempotencyKey, *models.InvoiceResponse, error) {
+	fingerprint, err := requestFingerprint(environment, req)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return response, nil
+	record, claimed, err := s.idempotencyRepo.Claim(emitterID, key, fingerprint, s.idempotencyCfg.KeyTTL, idempotencyLease)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error checking idempotency: %w", err)
+	}
+	if claimed {
+		return record, nil, nil
+	}
+
+	if record.Fingerprint != fingerprint {
+		return nil, nil, fmt.Errorf("idempotency key already used with a different request")
+	}
+	if record.Response == nil {
+		return nil, nil, fmt.Errorf("idempotency key request in progress")
+	}
+
+	var response models.InvoiceResponse
+	if err := json.Unmarshal(record.Response, &response); err != nil {
+		return nil, nil, fmt.Errorf("error decoding idempotent response: %w", err)
+	}
+	response.Replayed = true
+
+	s.logger.WithFields(logrus.Fields{
+		"invoice_id":      response.ID,
+		"emitter_id":      emitterID,
+		"idempotency_key": key,
+	}).Info("Replaying idempotent invoice response")
+
+	return nil, &response, nil
+}
+
+// requestFingerprint identifica un request de creación por su contenido y ambiente, para
+// distinguir un reintento de otro documento enviado con la misma Idempotency-Key
+func requestFingerprint(environment models.APIKeyEnvironment, req *models.CreateInvoiceRequest) (string, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("error encoding request fingerprint: %w", err)
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(environment))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// UpdateStatus cambia el status de un documento a nombre de actor si la transición está permitida.
+// La transición queda en el historial del documento con reason y en el outbox para notificarla a los
+// workflows y a los webhooks suscritos.
+func (s *InvoiceService) UpdateStatus(id uuid.UUID, status models.DocumentStatus, actor, reason string) error {
+	if err := s.invoiceRepo.UpdateStatus(id, status, actor, reason); err != nil {
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"invoice_id": id,
+		"status":     status,
+		"actor":      actor,
+		"reason":     reason,
+	}).Info("Invoice status updated")
+
+	return nil
 }
 
-// GetInvoice obtiene un invoice por ID
+// SendInvoiceEmail envía el email de un documento a su receptor usando el snapshot del documento.
+// El relay del outbox lo llama al menos una vez por documento, así que no reenvía un email ya enviado.
+func (s *InvoiceService) SendInvoiceEmail(id uuid.UUID) error {
+	if s.resendService == nil {
+		s.logger.WithField("invoice_id", id).Warn("Resend service not available - email not sent")
+		return nil
+	}
+
+	invoice, err := s.invoiceRepo.GetByID(id)
+	if err != nil {
+		return fmt.Errorf("error getting invoice: %w", err)
+	}
+	if invoice.EmailStatus == models.EmailStatusSent {
+		return nil
+	}
+
+	if err := s.ensureSnapshot(invoice); err != nil {
+		return err
+	}
+
+	// Datos de emisor y receptor del snapshot del documento
+	emitter := invoice.Snapshot.EmitterData()
+	customer := &invoice.Snapshot.Customer
+
+	if err := s.resendService.SendInvoiceEmail(invoice, customer, emitter); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"invoice_id": invoice.ID,
+			"error":      err,
+		}).Error("Failed to send invoice email via Resend")
+		return s.invoiceRepo.UpdateEmailStatus(invoice.ID, models.EmailStatusFailed, err.Error())
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"invoice_id":      invoice.ID,
+		"customer_email":  customer.Email,
+		"document_number": invoice.DocumentNumber,
+	}).Info("Invoice email sent via Resend")
+
+	return s.invoiceRepo.UpdateEmailStatus(invoice.ID, models.EmailStatusSent, "")
+}
+
+// GetInvoice obtiene un invoice por ID con el historial de sus cambios de status
 func (s *InvoiceService) GetInvoice(id uuid.UUID) (*models.InvoiceStatusResponse, error) {
 	invoice, err := s.invoiceRepo.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
 
+	timeline, err := s.invoiceRepo.GetEvents(id)
+	if err != nil {
+		return nil, err
+	}
+
 	// Construir respuesta
 	response := &models.InvoiceStatusResponse{
 		ID:           invoice.ID,
@@ -292,12 +437,11 @@ func (s *InvoiceService) GetInvoice(id uuid.UUID) (*models.InvoiceStatusResponse
 			PtoFac: invoice.PtoFacDF,
 			Number: invoice.DocumentNumber,
 		},
-		Totals: models.Totals{
-			Net:   invoice.Subtotal,
-			ITBMS: invoice.ITBMSAmount,
-			Total: invoice.TotalAmount,
-		},
+		Totals:           documentTotals(invoice),
+		FunctionalTotals: functionalTotals(invoice),
+		ExchangeRate:     exchangeRatePtr(invoice),
 		CreatedAt: invoice.CreatedAt,
+		Timeline:  timeline,
 		Links: models.Links{
 			Files: fmt.Sprintf("/v1/invoices/%s/files", id),
 		},
@@ -311,10 +455,16 @@ func (s *InvoiceService) GetInvoiceFiles(id uuid.UUID) (*models.InvoiceFilesResp
 	// Verificar si ya existen archivos
 	existingFiles, err := s.invoiceFilesRepo.GetByInvoiceID(id)
 	if err == nil && existingFiles != nil {
+		// El protocolo es la última respuesta del PAC, no el XML del documento
+		protocolURL, err := s.artifactService.LatestURL(id, models.ArtifactProtocolXML)
+		if err != nil {
+			return nil, err
+		}
+
 		// Archivos ya existen, retornar respuesta con URLs
 		return &models.InvoiceFilesResponse{
 			XMLFE:       stringPtr(fmt.Sprintf("/v1/invoices/%s/files/xml", id)),
-			XMLProtocolo: stringPtr(fmt.Sprintf("/v1/invoices/%s/files/xml", id)),
+			XMLProtocolo: protocolURL,
 			CAFEPDFURL:   stringPtr(fmt.Sprintf("/v1/invoices/%s/files/pdf", id)),
 			Disposition:  "inline",
 		}, nil
@@ -326,14 +476,8 @@ func (s *InvoiceService) GetInvoiceFiles(id uuid.UUID) (*models.InvoiceFilesResp
 		return nil, fmt.Errorf("error getting invoice: %w", err)
 	}
 
-	customer, err := s.customerRepo.GetByID(invoice.CustomerID)
-	if err != nil {
-		return nil, fmt.Errorf("error getting customer: %w", err)
-	}
-
-	emitter, err := s.emitterRepo.GetByID(invoice.EmitterID)
-	if err != nil {
-		return nil, fmt.Errorf("error getting emitter: %w", err)
+	if err := s.ensureSnapshot(invoice); err != nil {
+		return nil, err
 	}
 
 	items, err := s.invoiceRepo.GetItemsByInvoiceID(id)
@@ -342,20 +486,32 @@ func (s *InvoiceService) GetInvoiceFiles(id uuid.UUID) (*models.InvoiceFilesResp
 	}
 
 	// Generar archivos
-	files, err := s.documentGenerator.GenerateInvoiceFiles(invoice, customer, emitter, items)
+	files, err := s.documentGenerator.GenerateInvoiceFiles(invoice, items)
 	if err != nil {
 		return nil, fmt.Errorf("error generating invoice files: %w", err)
 	}
 
-	// Si tenemos Supabase disponible, subir archivos al storage
-	if s.storageService != nil {
-		ctx := context.Background()
-		
+	// Cada archivo generado queda como una versión inmutable (en el storage si está disponible)
+	ctx := context.Background()
+	pdfArtifact, err := s.artifactService.Record(ctx, invoice, models.ArtifactCAFEPDF, files.PDFData, documentGeneratorVersion)
+	if err != nil {
+		return nil, err
+	}
+	xmlArtifact, err := s.artifactService.Record(ctx, invoice, models.ArtifactFEXML, files.XMLData, documentGeneratorVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	// Si las versiones quedaron en el storage, los archivos actuales apuntan a ellas; el servicio
+	// híbrido guarda los metadatos
+	stored := false
+	if s.storageService != nil && pdfArtifact.StorageKey != nil && xmlArtifact.StorageKey != nil {
 		// Usar el servicio híbrido para almacenar archivos
-		storageResponse, err := s.storageService.StoreInvoiceFiles(ctx, id, files.PDFData, files.XMLData)
+		storageResponse, err := s.storageService.StoreInvoiceFiles(ctx, files, *pdfArtifact.StorageKey, *xmlArtifact.StorageKey)
 		if err != nil {
-			s.logger.WithError(err).Warn("Failed to store files in Supabase, falling back to local storage")
+			s.logger.WithError(err).Warn("Failed to store files in storage, falling back to database")
 		} else {
+			stored = true
 			// Actualizar URLs en el modelo
 			if storageResponse.CAFEPDFURL != nil {
 				files.PDFURL = storageResponse.CAFEPDFURL
@@ -366,30 +522,39 @@ func (s *InvoiceService) GetInvoiceFiles(id uuid.UUID) (*models.InvoiceFilesResp
 			s.logger.WithFields(logrus.Fields{
 				"pdf_url": files.PDFURL,
 				"xml_url": files.XMLURL,
-			}).Info("Files stored in Supabase successfully")
+			}).Info("Files stored successfully")
 		}
 	}
 
-	// Guardar archivos en BD (UPSERT para evitar duplicados)
-	if err := s.invoiceFilesRepo.CreateOrUpdate(files); err != nil {
-		return nil, fmt.Errorf("error saving invoice files: %w", err)
+	// Sin storage, guardar archivos en BD (UPSERT para evitar duplicados); con STORAGE_METADATA_ONLY
+	// el migrador los mueve al storage cuando vuelva a estar disponible
+	if !stored {
+		pdfSHA256, xmlSHA256 := sha256Hex(files.PDFData), sha256Hex(files.XMLData)
+		files.PDFSHA256, files.XMLSHA256 = &pdfSHA256, &xmlSHA256
+		if err := s.invoiceFilesRepo.CreateOrUpdate(files); err != nil {
+			return nil, fmt.Errorf("error saving invoice files: %w", err)
+		}
+	}
+
+	protocolURL, err := s.artifactService.LatestURL(id, models.ArtifactProtocolXML)
+	if err != nil {
+		return nil, err
 	}
 
-	// Retornar respuesta con URLs (priorizar Supabase si está disponible)
+	// Retornar respuesta con URLs (priorizar las del storage si está disponible)
 	response := &models.InvoiceFilesResponse{
 		XMLFE:       stringPtr(fmt.Sprintf("/v1/invoices/%s/files/xml", id)),
-		XMLProtocolo: stringPtr(fmt.Sprintf("/v1/invoices/%s/files/xml", id)),
+		XMLProtocolo: protocolURL,
 		CAFEPDFURL:   stringPtr(fmt.Sprintf("/v1/invoices/%s/files/pdf", id)),
 		Disposition:  "inline",
 	}
 
-	// Si tenemos URLs de Supabase, usarlas en lugar de las locales
+	// Si tenemos URLs del storage, usarlas en lugar de las locales
 	if files.PDFURL != nil {
 		response.CAFEPDFURL = files.PDFURL
 	}
 	if files.XMLURL != nil {
 		response.XMLFE = files.XMLURL
-		response.XMLProtocolo = files.XMLURL
 	}
 
 	s.logger.WithFields(logrus.Fields{
@@ -401,65 +566,90 @@ func (s *InvoiceService) GetInvoiceFiles(id uuid.UUID) (*models.InvoiceFilesResp
 	return response, nil
 }
 
-// DownloadInvoiceFile descarga un archivo específico de la factura
-func (s *InvoiceService) DownloadInvoiceFile(id uuid.UUID, fileType string) ([]byte, string, error) {
+// DownloadInvoiceFile obtiene un archivo específico de la factura: una URL prefirmada si el
+// archivo está en un storage que las genera, o su contenido en otro caso
+func (s *InvoiceService) DownloadInvoiceFile(id uuid.UUID, fileType string) (*FileDownload, error) {
 	// Obtener archivos de la factura
 	files, err := s.invoiceFilesRepo.GetByInvoiceID(id)
 	if err != nil {
-		return nil, "", fmt.Errorf("error getting invoice files: %w", err)
+		return nil, fmt.Errorf("error getting invoice files: %w", err)
 	}
 
-	var fileData []byte
-	var fileName string
+	var localData []byte
+	var inStorage bool
+	download := &FileDownload{
+		FileName: fmt.Sprintf("factura_%s.%s", id.String(), fileType),
+	}
 
 	switch fileType {
 	case "pdf":
-		// Si tenemos datos locales, usarlos
-		if len(files.PDFData) > 0 {
-			fileData = files.PDFData
-		} else if files.PDFURL != nil {
-			// Si no hay datos locales pero hay URL de Supabase, descargar desde ahí
-			if s.storageService != nil {
-				downloadedData, err := s.storageService.DownloadFile(context.Background(), *files.PDFURL)
-				if err != nil {
-					return nil, "", fmt.Errorf("error downloading PDF from Supabase: %w", err)
-				}
-				fileData = downloadedData
-			} else {
-				return nil, "", fmt.Errorf("storage service not available")
-			}
-		} else {
-			return nil, "", fmt.Errorf("PDF file not found for invoice %s", id)
-		}
-		fileName = fmt.Sprintf("factura_%s.pdf", id.String())
+		localData = files.PDFData
+		inStorage = files.PDFKey != nil || files.PDFURL != nil
+		download.ContentType = "application/pdf"
+		download.SHA256 = files.PDFSHA256
 	case "xml":
-		// Si tenemos datos locales, usarlos
-		if len(files.XMLData) > 0 {
-			fileData = files.XMLData
-		} else if files.XMLURL != nil {
-			// Si no hay datos locales pero hay URL de Supabase, descargar desde ahí
-			if s.storageService != nil {
-				downloadedData, err := s.storageService.DownloadFile(context.Background(), *files.XMLURL)
-				if err != nil {
-					return nil, "", fmt.Errorf("error downloading XML from Supabase: %w", err)
-				}
-				fileData = downloadedData
-			} else {
-				return nil, "", fmt.Errorf("storage service not available")
-			}
-		} else {
-			return nil, "", fmt.Errorf("XML file not found for invoice %s", id)
-		}
-		fileName = fmt.Sprintf("factura_%s.xml", id.String())
+		localData = files.XMLData
+		inStorage = files.XMLKey != nil || files.XMLURL != nil
+		download.ContentType = "application/xml"
+		download.SHA256 = files.XMLSHA256
 	default:
-		return nil, "", fmt.Errorf("invalid file type: %s", fileType)
+		return nil, fmt.Errorf("invalid file type: %s", fileType)
+	}
+
+	if !inStorage && len(localData) == 0 {
+		return nil, fmt.Errorf("%s file not found for invoice %s", strings.ToUpper(fileType), id)
 	}
 
-	if len(fileData) == 0 {
-		return nil, "", fmt.Errorf("file %s not found for invoice %s", fileType, id)
+	ctx := context.Background()
+	if inStorage && s.storageService != nil {
+		// Preferir una URL prefirmada para no pasar el archivo por la API
+		download.URL, download.ExpiresAt = s.storageService.SignedURL(ctx, files, fileType, download.FileName)
+		if download.URL != "" {
+			return download, nil
+		}
 	}
 
-	return fileData, fileName, nil
+	// Sin URL firmada, usar la copia local o leer el archivo del storage
+	if len(localData) > 0 {
+		download.Data = localData
+	} else if s.storageService != nil {
+		download.Data, err = s.storageService.ReadFile(ctx, files, fileType)
+		if err != nil {
+			return nil, fmt.Errorf("error downloading %s from storage: %w", strings.ToUpper(fileType), err)
+		}
+	} else {
+		return nil, fmt.Errorf("storage service not available")
+	}
+
+	if len(download.Data) == 0 {
+		return nil, fmt.Errorf("file %s not found for invoice %s", fileType, id)
+	}
+
+	return download, nil
+}
+
+// CheckOwnership verifica que el documento exista y pertenezca al emisor; para otro emisor
+// responde como si no existiera
+func (s *InvoiceService) CheckOwnership(emitterID, id uuid.UUID) error {
+	invoice, err := s.invoiceRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if invoice.EmitterID != emitterID {
+		return fmt.Errorf("invoice not found: %s", id)
+	}
+	return nil
+}
+
+// ListArtifacts obtiene todas las versiones de los archivos de un documento del emisor
+func (s *InvoiceService) ListArtifacts(emitterID, id uuid.UUID) ([]models.InvoiceArtifact, error) {
+	return s.artifactService.List(emitterID, id)
+}
+
+// DownloadArtifact obtiene una versión de un archivo de un documento del emisor: una URL
+// prefirmada o su contenido
+func (s *InvoiceService) DownloadArtifact(emitterID, id uuid.UUID, kind models.ArtifactKind, version int) (*models.InvoiceArtifact, *FileDownload, error) {
+	return s.artifactService.Download(context.Background(), emitterID, id, kind, version)
 }
 
 // ResendEmail reenvía el email de un invoice
@@ -472,7 +662,7 @@ func (s *InvoiceService) ResendEmail(id uuid.UUID, req *models.EmailResendReques
 
 	// TODO: Implementar lógica de envío de email
 	// Por ahora solo actualizamos el estado
-	if err := s.invoiceRepo.UpdateEmailStatus(id, models.EmailStatusPending); err != nil {
+	if err := s.invoiceRepo.UpdateEmailStatus(id, models.EmailStatusPending, ""); err != nil {
 		return nil, fmt.Errorf("error updating email status: %w", err)
 	}
 
@@ -511,32 +701,393 @@ func (s *InvoiceService) RetryWorkflow(id uuid.UUID) (*models.RetryResponse, err
 	return response, nil
 }
 
-// getOrCreateCustomer obtiene o crea un cliente
-func (s *InvoiceService) getOrCreateCustomer(req models.CustomerRequest, emitterID uuid.UUID) (*models.Customer, error) {
-	// Intentar obtener cliente existente
-	customer, err := s.customerRepo.GetByEmail(emitterID, req.Email)
+// SendToPAC genera el XML de un documento y lo envía al PAC. El endpoint y las credenciales
+// se eligen por el iAmb del documento (pruebas o producción).
+func (s *InvoiceService) SendToPAC(ctx context.Context, id uuid.UUID) (*pac.SendResult, error) {
+	if s.pacClient == nil {
+		return nil, fmt.Errorf("PAC client not configured")
+	}
+
+	invoice, err := s.invoiceRepo.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting invoice: %w", err)
+	}
+
+	if err := s.ensureSnapshot(invoice); err != nil {
+		return nil, err
+	}
+
+	items, err := s.invoiceRepo.GetItemsByInvoiceID(id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting invoice items: %w", err)
+	}
+
+	// Las credenciales son las actuales del emisor, no las del snapshot
+	emitter, err := s.emitterRepo.GetByID(invoice.EmitterID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting emitter: %w", err)
+	}
+
+	xmlData, err := s.documentGenerator.GenerateInvoiceXML(invoice, items)
+	if err != nil {
+		return nil, fmt.Errorf("error generating invoice XML: %w", err)
+	}
+
+	return s.pacClient.SendDocument(ctx, emitter, invoice, xmlData)
+}
+
+// pacSubmitActor es el actor de las transiciones de status del envío al PAC
+var pacSubmitActor = models.SystemActor("pac-submit")
+
+// pacProtocolSource antecede el ambiente del PAC en la versión de generador de los protocolos
+const pacProtocolSource = "pac-"
+
+// pacSubmissionStaleAfter es cuánto tiene que llevar un documento en SENDING_TO_PAC para darlo por
+// interrumpido y reenviarlo. Es mayor que outboxLease: el relay cancela el envío al vencer su lease,
+// así que un envío en curso nunca se duplica.
+const pacSubmissionStaleAfter = 5 * outboxLease
+
+// SubmitToPAC lleva un documento recién creado por la preparación y el envío al PAC de su
+// ambiente (iAmb) y registra el resultado: AUTHORIZED con una respuesta 2xx, REJECTED con una 4xx.
+// El relay del outbox lo llama al menos una vez por documento: un documento ya resuelto no se
+// reenvía, y uno en SENDING_TO_PAC sólo se reenvía si el envío anterior quedó interrumpido
+// (pacSubmissionStaleAfter); mientras tanto retorna un error para que el relay reintente más tarde.
+// Cada respuesta del PAC se guarda como una versión del protocolo. Un error de red o una respuesta
+// 5xx dejan el documento en ERROR y retornan el error para que el relay reintente; sin endpoint
+// configurado para el ambiente no se envía nada.
+func (s *InvoiceService) SubmitToPAC(ctx context.Context, id uuid.UUID) error {
+	if s.pacClient == nil {
+		return nil
+	}
+
+	invoice, err := s.invoiceRepo.GetByID(id)
+	if err != nil {
+		return fmt.Errorf("error getting invoice: %w", err)
+	}
+
+	env := pac.EnvironmentForIAmb(invoice.IAmb)
+	logger := s.logger.WithFields(logrus.Fields{
+		"invoice_id":  id,
+		"environment": env,
+	})
+
+	if s.pacClient.Endpoint(env) == "" {
+		logger.Warn("PAC endpoint not configured for environment, invoice not submitted")
+		return nil
+	}
+
+	switch invoice.Status {
+	case models.DocumentStatusReceived:
+		if err := s.UpdateStatus(id, models.DocumentStatusPreparing, pacSubmitActor, ""); err != nil {
+			return err
+		}
+	case models.DocumentStatusPreparing, models.DocumentStatusSendingToPAC, models.DocumentStatusError:
+		// Reintento de un envío anterior que no terminó
+	default:
+		return nil
+	}
+
+	// Sólo un proceso a la vez envía el documento
+	claimed, current, err := s.invoiceRepo.ClaimStatus(id, models.DocumentStatusSendingToPAC,
+		[]models.DocumentStatus{models.DocumentStatusPreparing, models.DocumentStatusError},
+		pacSubmissionStaleAfter, pacSubmitActor, "Resubmitting interrupted PAC submission")
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		if current == models.DocumentStatusSendingToPAC {
+			return fmt.Errorf("invoice %s is already being sent to the PAC", id)
+		}
+		// Otro proceso ya lo resolvió
+		return nil
+	}
+
+	result, err := s.SendToPAC(ctx, id)
+	if err != nil {
+		// Faltan las credenciales del ambiente: reintentar no cambia nada
+		if strings.Contains(err.Error(), "credentials not configured") {
+			return s.UpdateStatus(id, models.DocumentStatusError, pacSubmitActor, err.Error())
+		}
+		if statusErr := s.UpdateStatus(id, models.DocumentStatusError, pacSubmitActor, err.Error()); statusErr != nil {
+			logger.WithError(statusErr).Error("Error recording PAC submission failure")
+		}
+		return err
+	}
+
+	// La respuesta del PAC queda como una versión del protocolo antes de registrar el resultado
+	if len(result.Body) > 0 {
+		if _, err := s.artifactService.Record(ctx, invoice, models.ArtifactProtocolXML, result.Body, pacProtocolSource+string(result.Environment)); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case result.StatusCode >= 200 && result.StatusCode < 300:
+		return s.UpdateStatus(id, models.DocumentStatusAuthorized, pacSubmitActor, "")
+	case result.StatusCode >= 400 && result.StatusCode < 500:
+		return s.UpdateStatus(id, models.DocumentStatusRejected, pacSubmitActor, pacResponseReason(result))
+	default:
+		reason := pacResponseReason(result)
+		if err := s.UpdateStatus(id, models.DocumentStatusError, pacSubmitActor, reason); err != nil {
+			return err
+		}
+		return fmt.Errorf("PAC submission failed: %s", reason)
+	}
+}
+
+// pacResponseReason resume una respuesta del PAC para el historial de status
+func pacResponseReason(result *pac.SendResult) string {
+	body := strings.TrimSpace(string(result.Body))
+	if len(body) > 500 {
+		body = body[:500]
+	}
+	if body == "" {
+		return fmt.Sprintf("PAC responded with status %d", result.StatusCode)
+	}
+	return fmt.Sprintf("PAC responded with status %d: %s", result.StatusCode, body)
+}
+
+// resolveItems completa los ítems que referencian un producto (por product_id o SKU)
+// y valida sus códigos contra los catálogos. Con product_id, la descripción, precio
+// y tasa omitidos se heredan del producto.
+func (s *InvoiceService) resolveItems(emitterID uuid.UUID, itemReqs []models.ItemRequest) ([]models.ItemRequest, []*models.Product, error) {
+	resolved := make([]models.ItemRequest, len(itemReqs))
+	products := make([]*models.Product, len(itemReqs))
+
+	for i, itemReq := range itemReqs {
+		var product *models.Product
+		if itemReq.ProductID != nil {
+			// Buscar producto por ID
+			productID, err := uuid.Parse(*itemReq.ProductID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid product_id format: %s", *itemReq.ProductID)
+			}
+			product, err = s.productRepo.GetByEmitterAndID(emitterID, productID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("product with ID %s not found: %w", *itemReq.ProductID, err)
+			}
+
+			// Heredar los datos no enviados
+			if itemReq.SKU == nil {
+				itemReq.SKU = &product.SKU
+			}
+			if itemReq.Description == "" {
+				itemReq.Description = product.Description
+			}
+			if itemReq.UnitPrice == 0 {
+				itemReq.UnitPrice = product.UnitPrice
+			}
+			if itemReq.TaxRate == "" {
+				itemReq.TaxRate = product.TaxRate
+			}
+		} else if itemReq.SKU != nil {
+			// Buscar producto por SKU (mantener compatibilidad)
+			var err error
+			product, err = s.productRepo.GetBySKU(emitterID, *itemReq.SKU)
+			if err != nil {
+				s.logger.Warnf("Product with SKU %s not found, using request data", *itemReq.SKU)
+			}
+		}
+
+		// Heredar códigos CPBS del producto si el ítem no los trae
+		if product != nil && itemReq.CPBSAbr == nil && itemReq.CPBSCmp == nil {
+			itemReq.CPBSAbr = product.CPBSAbr
+			itemReq.CPBSCmp = product.CPBSCmp
+		}
+
+		if itemReq.UnitPrice <= 0 {
+			return nil, nil, fmt.Errorf("item %d: unit price must be greater than 0", i+1)
+		}
+		if err := s.catalogService.ValidateTaxRate(itemReq.TaxRate); err != nil {
+			return nil, nil, fmt.Errorf("item %d: %w", i+1, err)
+		}
+		if err := s.catalogService.ValidateCPBS(itemReq.CPBSAbr, itemReq.CPBSCmp); err != nil {
+			return nil, nil, fmt.Errorf("item %d: %w", i+1, err)
+		}
+
+		resolved[i] = itemReq
+		products[i] = product
+	}
+
+	return resolved, products, nil
+}
+
+// resolveSeries obtiene la serie activa del documento en la sucursal y el ambiente. Si no existe y el
+// emisor tiene auto_create_series, la crea; una serie desactivada nunca se reemplaza.
+func (s *InvoiceService) resolveSeries(emitter *models.Emitter, branch *models.Branch, iamb int, ptoFacDF string, docKind models.DocumentType, actor string) (*models.EmitterSeries, error) {
+	series, err := s.emitterRepo.GetSeries(emitter.ID, branch.ID, iamb, ptoFacDF, docKind)
 	if err == nil {
-		return customer, nil
+		return series, nil
+	}
+	if !strings.Contains(err.Error(), "not found") {
+		return nil, err
 	}
 
-	// Crear nuevo cliente
+	if existing, findErr := s.emitterRepo.FindSeries(emitter.ID, branch.ID, iamb, ptoFacDF, docKind); findErr == nil && !existing.IsActive {
+		return nil, fmt.Errorf("series is inactive for branch %s, pto_fac_df %s, doc_kind %s", branch.Code, ptoFacDF, docKind)
+	}
+	if !emitter.AutoCreateSeries {
+		return nil, err
+	}
+
+	series, err = s.emitterRepo.CreateSeries(emitter.ID, branch.ID, iamb, &models.CreateSeriesRequest{PtoFacDF: ptoFacDF, DocKind: docKind}, actor)
+	if err != nil {
+		// Otra petición la creó al mismo tiempo
+		if strings.Contains(err.Error(), "already exists") {
+			return s.emitterRepo.GetSeries(emitter.ID, branch.ID, iamb, ptoFacDF, docKind)
+		}
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"emitter_id": emitter.ID,
+		"branch":     branch.Code,
+		"pto_fac_df": ptoFacDF,
+		"doc_kind":   docKind,
+		"i_amb":      iamb,
+		"series_id":  series.ID,
+	}).Info("Series auto-created on first use")
+
+	return series, nil
+}
+
+// seriesRangeWarning retorna un aviso si, tras asignar documentNumber, quedan pocos folios en el rango de la serie
+func (s *InvoiceService) seriesRangeWarning(series *models.EmitterSeries, documentNumber string) string {
+	number, err := strconv.Atoi(documentNumber)
+	if err != nil || series.RangeEnd == nil {
+		return ""
+	}
+
+	current := *series
+	current.NextNumber = number + 1
+	if !current.IsLowRemaining() {
+		return ""
+	}
+
+	remaining := *current.Remaining()
+	s.logger.WithFields(logrus.Fields{
+		"emitter_id": series.EmitterID,
+		"series_id":  series.ID,
+		"pto_fac_df": series.PtoFacDF,
+		"doc_kind":   series.DocKind,
+		"remaining":  remaining,
+	}).Warn("Series authorized range running low")
+
+	return fmt.Sprintf("Series %s/%s has %d folios remaining in its authorized range (ends at %d)", series.PtoFacDF, series.DocKind, remaining, *series.RangeEnd)
+}
+
+// resolveCustomer obtiene el cliente por customer_id o, si no se envía, por los datos del request.
+// El cliente retornado contiene los datos del receptor tal como se usan en este documento.
+func (s *InvoiceService) resolveCustomer(req *models.CreateInvoiceRequest, emitterID uuid.UUID, actor string) (*models.Customer, error) {
+	if req.CustomerID != nil {
+		customerID, err := uuid.Parse(*req.CustomerID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid customer_id: %s", *req.CustomerID)
+		}
+		return s.customerRepo.GetByEmitterAndID(emitterID, customerID)
+	}
+
+	if req.Customer == nil {
+		return nil, fmt.Errorf("customer or customer_id is required")
+	}
+
+	mode := req.CustomerMatch
+	if mode == "" {
+		mode = models.CustomerMatchTaxID
+	}
+
+	return s.getOrCreateCustomer(*req.Customer, mode, req.UpdateCustomer, emitterID, actor)
+}
+
+// getOrCreateCustomer busca el cliente según el modo indicado o lo crea. Un cliente existente
+// solo se actualiza si update es true; en caso contrario los datos enviados aplican solo al documento.
+func (s *InvoiceService) getOrCreateCustomer(req models.CustomerRequest, mode models.CustomerMatchMode, update bool, emitterID uuid.UUID, actor string) (*models.Customer, error) {
 	customerReq := &models.CreateCustomerRequest{
-		Name:    req.Name,
-		Email:   req.Email,
-		Phone:   req.Phone,
-		Address: req.Address,
-		UBICode: req.UBICode,
+		Name:          req.Name,
+		Email:         req.Email,
+		Phone:         req.Phone,
+		Address:       req.Address,
+		UBICode:       req.UBICode,
+		TaxID:         req.TaxID,
+		TaxIDType:     req.TaxIDType,
+		TaxIDDV:       req.TaxIDDV,
+		RecipientType: req.RecipientType,
+		ForeignID:     req.ForeignID,
+		CountryCode:   req.CountryCode,
+	}
+
+	// Validar RUC del receptor y campos requeridos según su tipo
+	if err := resolveTaxID(&customerReq.TaxID, &customerReq.TaxIDType, &customerReq.TaxIDDV, "customer."); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+	if err := validateRecipient(customerReq, "customer."); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+
+	// Buscar cliente existente
+	existing, err := s.customerService.Match(emitterID, customerReq, mode)
+	if err != nil {
+		return nil, fmt.Errorf("error matching customer: %w", err)
+	}
+
+	if existing == nil {
+		customer, err := s.customerRepo.Create(customerReq, emitterID, actor)
+		if err != nil {
+			return nil, fmt.Errorf("error creating customer: %w", err)
+		}
+		return customer, nil
+	}
+
+	customer := *existing
+	applyCustomerRequest(&customer, customerReq)
+
+	if update {
+		if _, err := s.customerRepo.Update(emitterID, &customer, actor); err != nil {
+			return nil, fmt.Errorf("error updating customer: %w", err)
+		}
+		s.logger.WithFields(logrus.Fields{
+			"emitter_id":  emitterID,
+			"customer_id": customer.ID,
+		}).Info("Customer updated from invoice data")
+	}
+
+	return &customer, nil
+}
+
+// ensureSnapshot congela los datos actuales de emisor y receptor en documentos emitidos antes de
+// existir el snapshot. Una vez guardado, el snapshot no se reemplaza.
+func (s *InvoiceService) ensureSnapshot(invoice *models.Invoice) error {
+	if invoice.Snapshot != nil {
+		return nil
 	}
 
-	customer, err = s.customerRepo.Create(customerReq, emitterID)
+	customer, err := s.customerRepo.GetByID(invoice.CustomerID)
+	if err != nil {
+		return fmt.Errorf("error getting customer: %w", err)
+	}
+	emitter, err := s.emitterRepo.GetByID(invoice.EmitterID)
 	if err != nil {
-		return nil, fmt.Errorf("error creating customer: %w", err)
+		return fmt.Errorf("error getting emitter: %w", err)
+	}
+
+	var branch *models.Branch
+	if invoice.BranchID != nil {
+		if branch, err = s.branchService.GetByID(invoice.EmitterID, *invoice.BranchID); err != nil {
+			return fmt.Errorf("error getting branch: %w", err)
+		}
+	}
+
+	invoice.Snapshot = models.NewInvoiceSnapshot(emitter, branch, customer)
+	if err := s.invoiceRepo.SaveSnapshot(invoice.ID, invoice.Snapshot); err != nil {
+		return err
 	}
 
-	return customer, nil
+	s.logger.WithField("invoice_id", invoice.ID).Info("Snapshot captured for legacy invoice")
+	return nil
 }
 
-// calculateTotals calcula los totales del documento
+// calculateTotals calcula los totales del documento. El neto y el ITBMS se redondean a 2 decimales
+// y el total es la suma de ambos ya redondeados, para que cuadre con ellos al centavo.
 func (s *InvoiceService) calculateTotals(items []models.ItemRequest) (subtotal, itbmsAmount, totalAmount float64, err error) {
 	s.logger.Infof("calculateTotals: processing %d items", len(items))
 	
@@ -567,7 +1118,9 @@ func (s *InvoiceService) calculateTotals(items []models.ItemRequest) (subtotal,
 		s.logger.Infof("Item %d: ITBMSRate=%.2f, LineITBMS=%.2f, TotalITBMS=%.2f", i+1, itbmsRate, lineITBMS, itbmsAmount)
 	}
 
-	totalAmount = subtotal + itbmsAmount
+	subtotal = roundCents(subtotal)
+	itbmsAmount = roundCents(itbmsAmount)
+	totalAmount = roundCents(subtotal + itbmsAmount)
 	s.logger.Infof("Final totals: Subtotal=%.2f, ITBMS=%.2f, Total=%.2f", subtotal, itbmsAmount, totalAmount)
 	return subtotal, itbmsAmount, totalAmount, nil
 }
@@ -616,17 +1169,36 @@ func (s *InvoiceService) getOverrideField(overrides *models.Overrides, field str
 	}
 }
 
-// getProductValue obtiene un valor del producto o el valor del request
-func (s *InvoiceService) getProductValue(product *models.Product, field string, requestValue *string) *string {
-	if product != nil {
-		switch field {
-		case "cpbs_abr":
-			return product.CPBSAbr
-		case "cpbs_cmp":
-			return product.CPBSCmp
-		}
+// documentTotals construye los totales en la moneda del documento
+func documentTotals(invoice *models.Invoice) models.Totals {
+	return models.Totals{
+		Currency: invoice.CurrencyCode,
+		Net:      invoice.DocSubtotal,
+		ITBMS:    invoice.DocITBMSAmount,
+		Total:    invoice.DocTotalAmount,
+	}
+}
+
+// functionalTotals construye los totales en moneda funcional si el documento está en moneda extranjera
+func functionalTotals(invoice *models.Invoice) *models.Totals {
+	if !invoice.IsForeignCurrency() {
+		return nil
+	}
+	return &models.Totals{
+		Currency: models.FunctionalCurrency,
+		Net:      invoice.Subtotal,
+		ITBMS:    invoice.ITBMSAmount,
+		Total:    invoice.TotalAmount,
+	}
+}
+
+// exchangeRatePtr retorna la tasa de cambio aplicada si el documento está en moneda extranjera
+func exchangeRatePtr(invoice *models.Invoice) *float64 {
+	if !invoice.IsForeignCurrency() {
+		return nil
 	}
-	return requestValue
+	rate := invoice.ExchangeRate
+	return &rate
 }
 
 // stringPtr convierte un string a *string
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"io"
+	"testing"
+
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+func TestCalculateTotalsRoundsToCents(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	service := &InvoiceService{logger: logger}
+
+	tests := []struct {
+		name                   string
+		items                  []models.ItemRequest
+		subtotal, itbms, total float64
+	}{
+		{
+			// 10.125 + 0.70875 = 10.83375 sin redondear; con las partes redondeadas, 10.13 + 0.71
+			name:     "total from rounded parts",
+			items:    []models.ItemRequest{{Quantity: 1, UnitPrice: 10.125, TaxRate: "01"}},
+			subtotal: 10.13, itbms: 0.71, total: 10.84,
+		},
+		{
+			name: "several rates",
+			items: []models.ItemRequest{
+				{Quantity: 3, UnitPrice: 0.35, TaxRate: "01"},
+				{Quantity: 2, UnitPrice: 1.5, TaxRate: "02"},
+				{Quantity: 1, UnitPrice: 4.99, TaxRate: "00"},
+			},
+			subtotal: 9.04, itbms: 0.37, total: 9.41,
+		},
+	}
+
+	for _, tt := range tests {
+		subtotal, itbms, total, err := service.calculateTotals(tt.items)
+		if err != nil {
+			t.Errorf("%s: calculateTotals error: %v", tt.name, err)
+			continue
+		}
+		if subtotal != tt.subtotal || itbms != tt.itbms || total != tt.total {
+			t.Errorf("%s: calculateTotals = %v, %v, %v; want %v, %v, %v", tt.name, subtotal, itbms, total, tt.subtotal, tt.itbms, tt.total)
+		}
+	}
+
+	if _, _, _, err := service.calculateTotals([]models.ItemRequest{{Quantity: 1, UnitPrice: 1, TaxRate: "99"}}); err == nil {
+		t.Error("calculateTotals with an unknown tax rate succeeded, want an error")
+	}
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hypernova-labs/dgi-service/internal/config"
+	"github.com/hypernova-labs/dgi-service/internal/database"
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/hypernova-labs/dgi-service/internal/workflows"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// outboxLease es cuánto queda reservado un evento tomado mientras se publica; la publicación se
+	// cancela al vencer, antes de que otra instancia pueda tomar el evento
+	outboxLease = time.Minute
+	// outboxBaseBackoff es la espera antes del primer reintento; se duplica en cada intento
+	outboxBaseBackoff = 5 * time.Second
+	// outboxMaxBackoff limita la espera entre reintentos; los eventos se reintentan sin límite
+	outboxMaxBackoff = 10 * time.Minute
+	// outboxCleanupInterval es cada cuánto se eliminan los eventos publicados vencidos
+	outboxCleanupInterval = time.Hour
+	// workflowEventPrefix antecede el tipo de evento en el nombre del evento de Inngest
+	workflowEventPrefix = "dgi/"
+)
+
+// outboxBackoff retorna la espera antes del siguiente intento tras attempts intentos fallidos
+func outboxBackoff(attempts int) time.Duration {
+	backoff := outboxBaseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= outboxMaxBackoff {
+			return outboxMaxBackoff
+		}
+	}
+	return backoff
+}
+
+// OutboxRelay publica los eventos del outbox al motor de workflows, a los webhooks, al PAC (al crear
+// un documento) y al envío directo de email (al autorizarse). La publicación es al menos una vez: si
+// falla cualquier destino el evento completo se reintenta, y cada destino descarta las repeticiones
+// por la dedup_key del evento.
+type OutboxRelay struct {
+	outboxRepo     *database.OutboxRepository
+	webhookService *WebhookService
+	invoiceService *InvoiceService
+	inngestClient  *workflows.InngestClient
+	cfg            config.OutboxConfig
+	logger         *logrus.Logger
+}
+
+// NewOutboxRelay crea una nueva instancia del relay. Sin cliente de Inngest los eventos
+// solo se publican a los webhooks y al envío de email.
+func NewOutboxRelay(db *database.DB, invoiceService *InvoiceService, inngestClient *workflows.InngestClient, cfg config.OutboxConfig, logger *logrus.Logger) *OutboxRelay {
+	return &OutboxRelay{
+		outboxRepo:     database.NewOutboxRepository(db, logger),
+		webhookService: NewWebhookService(db, logger),
+		invoiceService: invoiceService,
+		inngestClient:  inngestClient,
+		cfg:            cfg,
+		logger:         logger,
+	}
+}
+
+// Run publica los eventos pendientes cada RelayInterval hasta que ctx se cancela
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.RelayInterval)
+	defer ticker.Stop()
+
+	r.logger.WithField("interval", r.cfg.RelayInterval).Info("Outbox relay started")
+
+	var lastCleanup time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Outbox relay stopped")
+			return
+		case <-ticker.C:
+			if err := r.RelayPending(ctx); err != nil {
+				r.logger.WithError(err).Error("Error relaying outbox events")
+			}
+			if time.Since(lastCleanup) >= outboxCleanupInterval {
+				r.cleanup()
+				lastCleanup = time.Now()
+			}
+		}
+	}
+}
+
+// RelayPending publica hasta BatchSize eventos vencidos en orden de creación. Cada evento se toma
+// por separado justo antes de publicarlo, para que su lease cubra sólo su propia publicación y no
+// venza mientras se publican los anteriores del lote.
+func (r *OutboxRelay) RelayPending(ctx context.Context) error {
+	for i := 0; i < r.cfg.BatchSize; i++ {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		leaseUntil := time.Now().Add(outboxLease)
+		events, err := r.outboxRepo.ClaimDue(1, leaseUntil)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		r.relay(ctx, &events[0], leaseUntil)
+	}
+
+	return nil
+}
+
+// relay publica un evento tomado hasta leaseUntil y registra el resultado
+func (r *OutboxRelay) relay(ctx context.Context, event *models.OutboxEvent, leaseUntil time.Time) {
+	logger := r.logger.WithFields(logrus.Fields{
+		"outbox_id":    event.ID,
+		"event_type":   event.EventType,
+		"aggregate_id": event.AggregateID,
+		"attempt":      event.Attempts + 1,
+	})
+
+	// La publicación no sigue después de que otra instancia pueda tomar el evento
+	publishCtx, cancel := context.WithDeadline(ctx, leaseUntil)
+	defer cancel()
+
+	if err := r.publish(publishCtx, event); err != nil {
+		nextAttemptAt := time.Now().Add(outboxBackoff(event.Attempts + 1))
+		if markErr := r.outboxRepo.MarkFailed(event.ID, err.Error(), nextAttemptAt); markErr != nil {
+			logger.WithError(markErr).Error("Error marking outbox event as failed")
+		}
+		logger.WithError(err).WithField("next_attempt_at", nextAttemptAt).Warn("Outbox event publish failed, retry scheduled")
+		return
+	}
+
+	if err := r.outboxRepo.MarkPublished(event.ID); err != nil {
+		logger.WithError(err).Error("Error marking outbox event as published")
+		return
+	}
+	logger.Debug("Outbox event published")
+}
+
+// publish entrega un evento a todos sus destinos
+func (r *OutboxRelay) publish(ctx context.Context, event *models.OutboxEvent) error {
+	var data models.InvoiceOutboxData
+	if err := json.Unmarshal(event.Payload, &data); err != nil {
+		return fmt.Errorf("error decoding outbox event: %w", err)
+	}
+
+	// Motor de workflows
+	if r.inngestClient != nil {
+		var workflowData map[string]interface{}
+		if err := json.Unmarshal(event.Payload, &workflowData); err != nil {
+			return fmt.Errorf("error decoding outbox event: %w", err)
+		}
+		workflowData["emitter_id"] = event.EmitterID
+		workflowData["event_id"] = event.ID
+
+		if err := r.inngestClient.SendEvent(ctx, workflowEventPrefix+string(event.EventType), event.DedupKey, workflowData); err != nil {
+			return err
+		}
+	}
+
+	// Webhooks; el ID del evento del outbox es el ID del evento de webhook en cada reintento
+	if webhookType, ok := webhookEventForOutbox(event.EventType, &data); ok {
+		webhookEvent := &models.WebhookEvent{
+			ID:        event.ID,
+			Type:      webhookType,
+			EmitterID: event.EmitterID,
+			CreatedAt: event.CreatedAt,
+			Data:      data.WebhookData(event.EventType),
+		}
+		if err := r.webhookService.Publish(webhookEvent, event.DedupKey); err != nil {
+			return err
+		}
+	}
+
+	// Envío al PAC del ambiente del documento recién creado
+	if event.EventType == models.OutboxInvoiceCreated {
+		if err := r.invoiceService.SubmitToPAC(ctx, event.AggregateID); err != nil {
+			return err
+		}
+	}
+
+	// Email del documento una vez autorizado: no depende de cómo terminó el envío al PAC ni se
+	// envía para un documento que el PAC no autorizó
+	if event.EventType == models.OutboxInvoiceStatusChanged && data.Status == models.DocumentStatusAuthorized {
+		if err := r.invoiceService.SendInvoiceEmail(event.AggregateID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// webhookEventForOutbox retorna el evento de webhook que notifica un evento del outbox, si existe
+func webhookEventForOutbox(eventType models.OutboxEventType, data *models.InvoiceOutboxData) (models.WebhookEventType, bool) {
+	switch eventType {
+	case models.OutboxInvoiceStatusChanged:
+		return models.WebhookEventForStatus(data.Status)
+	case models.OutboxInvoiceEmailStatusChanged:
+		return models.WebhookEventForEmailStatus(data.EmailStatus)
+	}
+	return "", false
+}
+
+// cleanup elimina los eventos publicados más antiguos que la retención configurada
+func (r *OutboxRelay) cleanup() {
+	if r.cfg.Retention <= 0 {
+		return
+	}
+
+	deleted, err := r.outboxRepo.DeletePublishedBefore(time.Now().Add(-r.cfg.Retention))
+	if err != nil {
+		r.logger.WithError(err).Error("Error cleaning up outbox events")
+		return
+	}
+	if deleted > 0 {
+		r.logger.WithField("deleted", deleted).Info("Published outbox events cleaned up")
+	}
+}
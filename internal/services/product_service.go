@@ -12,20 +12,22 @@ import (
 
 // ProductService maneja la lógica de negocio para Product
 type ProductService struct {
-	productRepo *database.ProductRepository
-	logger      *logrus.Logger
+	productRepo    *database.ProductRepository
+	catalogService *CatalogService
+	logger         *logrus.Logger
 }
 
 // NewProductService crea una nueva instancia del servicio
 func NewProductService(db *database.DB, logger *logrus.Logger) *ProductService {
 	return &ProductService{
-		productRepo: database.NewProductRepository(db, logger),
-		logger:      logger,
+		productRepo:    database.NewProductRepository(db, logger),
+		catalogService: NewCatalogService(db, logger),
+		logger:         logger,
 	}
 }
 
-// Create crea un nuevo producto
-func (s *ProductService) Create(req *models.CreateProductRequest, emitterID uuid.UUID) (*models.Product, error) {
+// Create crea un nuevo producto a nombre de actor
+func (s *ProductService) Create(req *models.CreateProductRequest, emitterID uuid.UUID, actor string) (*models.Product, error) {
 	s.logger.Infof("Create: req=%+v, emitterID=%s", req, emitterID)
 	
 	// Validar datos del producto
@@ -34,6 +36,11 @@ func (s *ProductService) Create(req *models.CreateProductRequest, emitterID uuid
 		return nil, fmt.Errorf("validation error: %w", err)
 	}
 
+	// Validar códigos contra los catálogos DGI
+	if err := s.validateCatalogCodes(req); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+
 	// Verificar si el producto ya existe por SKU
 	if req.SKU != "" {
 		existingProduct, err := s.productRepo.GetBySKU(emitterID, req.SKU)
@@ -51,7 +58,7 @@ func (s *ProductService) Create(req *models.CreateProductRequest, emitterID uuid
 
 	// Crear nuevo producto
 	s.logger.Infof("Creating product with req=%+v, emitterID=%s", req, emitterID)
-	product, err := s.productRepo.Create(req, emitterID)
+	product, err := s.productRepo.Create(req, emitterID, actor)
 	if err != nil {
 		s.logger.Errorf("Error creating product: %v", err)
 		return nil, fmt.Errorf("error creating product: %w", err)
@@ -70,9 +77,9 @@ func (s *ProductService) Create(req *models.CreateProductRequest, emitterID uuid
 	return product, nil
 }
 
-// GetByID obtiene un producto por ID
-func (s *ProductService) GetByID(id uuid.UUID) (*models.Product, error) {
-	product, err := s.productRepo.GetByID(id)
+// GetByID obtiene un producto activo de un emisor
+func (s *ProductService) GetByID(emitterID, id uuid.UUID) (*models.Product, error) {
+	product, err := s.productRepo.GetByEmitterAndID(emitterID, id)
 	if err != nil {
 		return nil, fmt.Errorf("error getting product: %w", err)
 	}
@@ -90,44 +97,80 @@ func (s *ProductService) GetBySKU(emitterID uuid.UUID, sku string) (*models.Prod
 	return product, nil
 }
 
-// GetByEmitterID obtiene todos los productos de un emisor
-func (s *ProductService) GetByEmitterID(emitterID uuid.UUID) ([]models.Product, error) {
-	products, err := s.productRepo.GetByEmitterID(emitterID)
+// List obtiene los productos de un emisor con paginación
+func (s *ProductService) List(emitterID uuid.UUID, includeInactive bool, page, pageSize int) ([]models.Product, int, error) {
+	products, total, err := s.productRepo.List(emitterID, includeInactive, page, pageSize)
 	if err != nil {
-		return nil, fmt.Errorf("error getting products: %w", err)
+		return nil, 0, fmt.Errorf("error getting products: %w", err)
 	}
 
-	return products, nil
+	return products, total, nil
 }
 
-// Update actualiza un producto existente
-func (s *ProductService) Update(id uuid.UUID, req *models.CreateProductRequest) (*models.Product, error) {
-	// Validar datos del producto
-	if err := s.validateProductData(req); err != nil {
-		return nil, fmt.Errorf("validation error: %w", err)
-	}
-
+// Update actualiza parcialmente un producto de un emisor a nombre de actor
+func (s *ProductService) Update(emitterID, id uuid.UUID, req *models.UpdateProductRequest, actor string) (*models.Product, error) {
 	// Verificar que el producto existe
-	existingProduct, err := s.productRepo.GetByID(id)
+	product, err := s.productRepo.GetByEmitterAndID(emitterID, id)
 	if err != nil {
 		return nil, fmt.Errorf("error getting existing product: %w", err)
 	}
+	currentSKU := product.SKU
+
+	// Aplicar solo los campos enviados
+	if req.SKU != nil {
+		product.SKU = *req.SKU
+	}
+	if req.Description != nil {
+		product.Description = *req.Description
+	}
+	if req.CPBSAbr != nil {
+		product.CPBSAbr = req.CPBSAbr
+	}
+	if req.CPBSCmp != nil {
+		product.CPBSCmp = req.CPBSCmp
+	}
+	if req.UnitPrice != nil {
+		product.UnitPrice = *req.UnitPrice
+	}
+	if req.TaxRate != nil {
+		product.TaxRate = *req.TaxRate
+	}
+
+	// Validar datos resultantes
+	if err := s.validateProductData(&models.CreateProductRequest{
+		SKU:         product.SKU,
+		Description: product.Description,
+		CPBSAbr:     product.CPBSAbr,
+		CPBSCmp:     product.CPBSCmp,
+		UnitPrice:   product.UnitPrice,
+		TaxRate:     product.TaxRate,
+	}); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+	if err := s.validateCatalogCodes(&models.CreateProductRequest{
+		CPBSAbr: product.CPBSAbr,
+		CPBSCmp: product.CPBSCmp,
+		TaxRate: product.TaxRate,
+	}); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
 
 	// Verificar que el SKU no esté duplicado si se está cambiando
-	if req.SKU != existingProduct.SKU {
-		duplicateProduct, err := s.productRepo.GetBySKU(existingProduct.EmitterID, req.SKU)
+	if product.SKU != currentSKU {
+		duplicateProduct, err := s.productRepo.GetBySKU(emitterID, product.SKU)
 		if err == nil && duplicateProduct != nil && duplicateProduct.ID != id {
-			return nil, fmt.Errorf("product with SKU %s already exists", req.SKU)
+			return nil, fmt.Errorf("product with SKU %s already exists", product.SKU)
 		}
 	}
 
 	// Actualizar producto
-	product, err := s.productRepo.Update(id, req)
+	product, err = s.productRepo.Update(emitterID, product, actor)
 	if err != nil {
 		return nil, fmt.Errorf("error updating product: %w", err)
 	}
 
 	s.logger.WithFields(logrus.Fields{
+		"emitter_id":  emitterID,
 		"product_id":  id,
 		"sku":         product.SKU,
 		"description": product.Description,
@@ -137,27 +180,28 @@ func (s *ProductService) Update(id uuid.UUID, req *models.CreateProductRequest)
 	return product, nil
 }
 
-// Delete marca un producto como inactivo
-func (s *ProductService) Delete(id uuid.UUID) error {
-	err := s.productRepo.Delete(id)
+// Delete marca un producto de un emisor como inactivo a nombre de actor
+func (s *ProductService) Delete(emitterID, id uuid.UUID, actor string) error {
+	err := s.productRepo.Delete(emitterID, id, actor)
 	if err != nil {
 		return fmt.Errorf("error deleting product: %w", err)
 	}
 
 	s.logger.WithFields(logrus.Fields{
+		"emitter_id": emitterID,
 		"product_id": id,
 	}).Info("Product deleted successfully")
 
 	return nil
 }
 
-// SearchByDescription busca productos por descripción
-func (s *ProductService) SearchByDescription(emitterID uuid.UUID, description string) ([]models.Product, error) {
+// SearchByDescription busca productos por descripción o SKU
+func (s *ProductService) SearchByDescription(emitterID uuid.UUID, description string, limit int) ([]models.Product, error) {
 	if strings.TrimSpace(description) == "" {
 		return nil, fmt.Errorf("description search term is required")
 	}
 
-	products, err := s.productRepo.SearchByDescription(emitterID, description)
+	products, err := s.productRepo.SearchByDescription(emitterID, strings.TrimSpace(description), limit)
 	if err != nil {
 		return nil, fmt.Errorf("error searching products: %w", err)
 	}
@@ -227,6 +271,15 @@ func (s *ProductService) validateProductData(req *models.CreateProductRequest) e
 	return nil
 }
 
+// validateCatalogCodes valida la tasa de impuesto y los códigos CPBS contra los catálogos
+func (s *ProductService) validateCatalogCodes(req *models.CreateProductRequest) error {
+	if err := s.catalogService.ValidateTaxRate(req.TaxRate); err != nil {
+		return err
+	}
+
+	return s.catalogService.ValidateCPBS(req.CPBSAbr, req.CPBSCmp)
+}
+
 // CalculateLineTotal calcula el total de una línea de producto
 func (s *ProductService) CalculateLineTotal(quantity, unitPrice float64) float64 {
 	return quantity * unitPrice
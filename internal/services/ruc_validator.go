@@ -0,0 +1,293 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hypernova-labs/dgi-service/internal/models"
+)
+
+// Códigos de letra del RUC natural según la tabla de la DGI
+var rucLetterCodes = map[string]string{
+	"":   "00",
+	"E":  "50",
+	"N":  "40",
+	"PE": "75",
+	"AV": "15",
+	"PI": "79",
+	"NT": "43",
+}
+
+var (
+	rucDigitsPattern      = regexp.MustCompile(`^[0-9]+$`)
+	rucProvinceLetterPart = regexp.MustCompile(`^([0-9]{1,2})(AV|PI|NT)$`)
+)
+
+// ValidateRUC valida un RUC panameño (con guiones, ej. 155646463-2-2017 u 8-123-456)
+// y su dígito verificador. Para tipo 3 (extranjero) solo se valida el formato,
+// ya que la DGI no asigna DV a identificaciones extranjeras.
+func ValidateRUC(tipo, ruc, dv string) *models.RUCValidationResult {
+	ruc = strings.ToUpper(strings.TrimSpace(ruc))
+	dv = strings.TrimSpace(dv)
+
+	result := &models.RUCValidationResult{RUC: ruc, Tipo: tipo, DV: dv}
+	addError := func(field, issue string) {
+		result.Errors = append(result.Errors, models.ErrorDetail{Field: field, Issue: issue})
+	}
+
+	if tipo != "" && tipo != "1" && tipo != "2" && tipo != "3" {
+		addError("ruc_tipo", "Must be 1 (natural), 2 (jurídico) or 3 (extranjero)")
+	}
+	if ruc == "" {
+		addError("ruc", "RUC is required")
+		return result
+	}
+
+	// Identificación extranjera: sin DV
+	if tipo == "3" {
+		result.Kind = models.RUCKindExtranjero
+		if len(ruc) > 20 {
+			addError("ruc", "Foreign tax ID too long (max 20 characters)")
+		}
+		result.Valid = len(result.Errors) == 0
+		return result
+	}
+
+	expectedDV, kind, err := CalculateRUCDV(ruc)
+	if err != nil {
+		addError("ruc", err.Error())
+		return result
+	}
+	result.Kind = kind
+	result.ExpectedDV = expectedDV
+
+	// El tipo declarado debe ser consistente con el formato del RUC
+	switch {
+	case tipo == "1" && kind == models.RUCKindJuridica:
+		addError("ruc_tipo", "RUC has legal entity format but tipo is 1 (natural)")
+	case tipo == "2" && kind == models.RUCKindNatural:
+		addError("ruc_tipo", "RUC has natural person format but tipo is 2 (jurídico)")
+	}
+
+	if dv == "" {
+		addError("dv", "DV is required")
+	} else if len(dv) != 2 || !rucDigitsPattern.MatchString(dv) {
+		addError("dv", "DV must be 2 digits")
+	} else if dv != expectedDV {
+		addError("dv", fmt.Sprintf("Invalid check digit (expected %s)", expectedDV))
+	}
+
+	result.Valid = len(result.Errors) == 0
+	return result
+}
+
+// CalculateRUCDV calcula el dígito verificador (2 dígitos) de un RUC panameño e identifica
+// su clase: jurídica (tomo/ficha-folio-asiento), natural (provincia-tomo-asiento, con
+// letras E, N, PE, AV o PI) o NT
+func CalculateRUCDV(ruc string) (string, models.RUCKind, error) {
+	ructb, kind, legacy, err := normalizeRUC(strings.ToUpper(strings.TrimSpace(ruc)))
+	if err != nil {
+		return "", "", err
+	}
+
+	dv1 := rucCheckDigit(ructb, legacy)
+	dv2 := rucCheckDigit(ructb+strconv.Itoa(dv1), legacy)
+
+	return fmt.Sprintf("%d%d", dv1, dv2), kind, nil
+}
+
+// normalizeRUC convierte el RUC al formato numérico de la tabla DGI sobre el que se calcula el DV.
+// legacy indica un RUC jurídico antiguo, que conserva el salto de peso del algoritmo original.
+func normalizeRUC(ruc string) (string, models.RUCKind, bool, error) {
+	parts := strings.Split(ruc, "-")
+
+	// Provincia y letras juntas (ej. 8NT-1-12345) se separan (8-NT-1-12345)
+	if m := rucProvinceLetterPart.FindStringSubmatch(parts[0]); m != nil {
+		parts = append([]string{m[1], m[2]}, parts[1:]...)
+	}
+
+	for _, part := range parts {
+		if part == "" {
+			return "", "", false, fmt.Errorf("invalid RUC format: %s", ruc)
+		}
+	}
+
+	switch len(parts) {
+	case 3:
+		// Letra sin provincia: E-tomo-asiento, N-tomo-asiento, PE-tomo-asiento
+		if code, ok := rucLetterCodes[parts[0]]; ok && parts[0] != "" {
+			if parts[0] == "AV" || parts[0] == "PI" || parts[0] == "NT" {
+				return "", "", false, fmt.Errorf("invalid RUC format: %s (%s requires a province)", ruc, parts[0])
+			}
+			if !allDigits(parts[1:]) {
+				return "", "", false, fmt.Errorf("invalid RUC format: %s", ruc)
+			}
+			return naturalRUCTable(code, "0", parts[1], parts[2]), models.RUCKindNatural, false, nil
+		}
+
+		if !allDigits(parts) {
+			return "", "", false, fmt.Errorf("invalid RUC format: %s", ruc)
+		}
+
+		// Persona natural: provincia (1-2 dígitos)-tomo-asiento
+		if len(parts[0]) <= 2 {
+			if err := validateProvince(parts[0]); err != nil {
+				return "", "", false, err
+			}
+			return naturalRUCTable("00", parts[0], parts[1], parts[2]), models.RUCKindNatural, false, nil
+		}
+
+		// Persona jurídica: tomo/ficha (10)-folio/rollo (4)-asiento/imagen (6)
+		if len(parts[0]) > 10 || len(parts[1]) > 4 || len(parts[2]) > 6 {
+			return "", "", false, fmt.Errorf("invalid RUC format: %s (segment too long)", ruc)
+		}
+		ructb := padRUC(parts[0], 10) + padRUC(parts[1], 4) + padRUC(parts[2], 6)
+		legacy := ructb[3] == '0' && ructb[4] == '0' && ructb[5] < '5'
+		return ructb, models.RUCKindJuridica, legacy, nil
+
+	case 4:
+		// Provincia-letras-tomo-asiento: 8-AV-123-456, 8-PI-123-456, 8-NT-123-456
+		code, ok := rucLetterCodes[parts[1]]
+		if !ok || (parts[1] != "AV" && parts[1] != "PI" && parts[1] != "NT") {
+			return "", "", false, fmt.Errorf("invalid RUC format: %s (unknown letters %s)", ruc, parts[1])
+		}
+		if !allDigits([]string{parts[0], parts[2], parts[3]}) {
+			return "", "", false, fmt.Errorf("invalid RUC format: %s", ruc)
+		}
+		if err := validateProvince(parts[0]); err != nil {
+			return "", "", false, err
+		}
+
+		kind := models.RUCKindNatural
+		if parts[1] == "NT" {
+			kind = models.RUCKindNT
+		}
+		return naturalRUCTable(code, parts[0], parts[2], parts[3]), kind, false, nil
+	}
+
+	return "", "", false, fmt.Errorf("invalid RUC format: %s (expected segments separated by '-')", ruc)
+}
+
+// naturalRUCTable arma el RUC natural en formato de tabla: 5 + letras + provincia + tomo + asiento
+func naturalRUCTable(letterCode, province, tomo, asiento string) string {
+	return "5" + letterCode + padRUC(province, 2) + padRUC(tomo, 3) + padRUC(asiento, 5)
+}
+
+// rucCheckDigit calcula un dígito del DV por módulo 11 con pesos crecientes desde la derecha
+func rucCheckDigit(ructb string, legacy bool) int {
+	weight := 2
+	sum := 0
+	for i := len(ructb) - 1; i >= 0; i-- {
+		// El algoritmo original de la DGI repite el peso 11 en RUC jurídicos antiguos
+		if legacy && weight == 12 {
+			legacy = false
+			weight--
+		}
+		sum += weight * int(ructb[i]-'0')
+		weight++
+	}
+
+	remainder := sum % 11
+	if remainder > 1 {
+		return 11 - remainder
+	}
+	return 0
+}
+
+// validateProvince valida el código de provincia de una cédula (1 a 13)
+func validateProvince(province string) error {
+	n, err := strconv.Atoi(province)
+	if err != nil || n < 1 || n > 13 {
+		return fmt.Errorf("invalid province code: %s (must be 1-13)", province)
+	}
+	return nil
+}
+
+// padRUC completa con ceros a la izquierda
+func padRUC(value string, width int) string {
+	if len(value) >= width {
+		return value
+	}
+	return strings.Repeat("0", width-len(value)) + value
+}
+
+// allDigits indica si todos los segmentos son numéricos
+func allDigits(parts []string) bool {
+	for _, part := range parts {
+		if !rucDigitsPattern.MatchString(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// rucFieldErrors convierte los errores de validación del RUC a FieldErrors usando
+// los nombres de campo del request que los originó
+func rucFieldErrors(result *models.RUCValidationResult, tipoField, rucField, dvField string) models.FieldErrors {
+	names := map[string]string{"ruc_tipo": tipoField, "ruc": rucField, "dv": dvField}
+
+	errs := make(models.FieldErrors, 0, len(result.Errors))
+	for _, detail := range result.Errors {
+		if name, ok := names[detail.Field]; ok && name != "" {
+			detail.Field = name
+		}
+		errs = append(errs, detail)
+	}
+	return errs
+}
+
+// resolveTaxID valida el RUC de un cliente o receptor. Si no se envía el DV se calcula, y si no se
+// envía el tipo se deduce del formato (excepto NT, que puede ser natural o jurídico). Los campos
+// se actualizan con los valores normalizados; fieldPrefix antepone el nombre del objeto en los errores.
+func resolveTaxID(taxID, taxIDType, taxIDDV **string, fieldPrefix string) error {
+	if *taxID == nil || strings.TrimSpace(**taxID) == "" {
+		if *taxIDDV != nil {
+			return models.FieldErrors{{Field: fieldPrefix + "tax_id", Issue: "tax_id is required when tax_id_dv is provided"}}
+		}
+		*taxID = nil
+		return nil
+	}
+
+	tipo := ""
+	if *taxIDType != nil {
+		tipo = **taxIDType
+	}
+	dv := ""
+	if *taxIDDV != nil {
+		dv = **taxIDDV
+	}
+
+	result := ValidateRUC(tipo, **taxID, dv)
+	if tipo == "3" && dv != "" {
+		result.Errors = append(result.Errors, models.ErrorDetail{Field: "dv", Issue: "Foreign tax IDs have no DV"})
+		result.Valid = false
+	}
+	if *taxIDDV == nil && result.ExpectedDV != "" {
+		result = ValidateRUC(tipo, **taxID, result.ExpectedDV)
+	}
+	if !result.Valid {
+		return rucFieldErrors(result, fieldPrefix+"tax_id_type", fieldPrefix+"tax_id", fieldPrefix+"tax_id_dv")
+	}
+
+	ruc := result.RUC
+	*taxID = &ruc
+	if result.ExpectedDV != "" {
+		expectedDV := result.ExpectedDV
+		*taxIDDV = &expectedDV
+	}
+	if tipo == "" {
+		switch result.Kind {
+		case models.RUCKindNatural:
+			tipo = "1"
+		case models.RUCKindJuridica:
+			tipo = "2"
+		}
+	}
+	if tipo != "" {
+		*taxIDType = &tipo
+	}
+
+	return nil
+}
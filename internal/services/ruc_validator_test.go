@@ -0,0 +1,134 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/hypernova-labs/dgi-service/internal/models"
+)
+
+func TestCalculateRUCDV(t *testing.T) {
+	tests := []struct {
+		ruc  string
+		dv   string
+		kind models.RUCKind
+	}{
+		// Personas jurídicas con DV conocido
+		{"155596713-2-2015", "59", models.RUCKindJuridica},
+		{"155646463-2-2017", "86", models.RUCKindJuridica},
+		{"2588017-1-831938", "20", models.RUCKindJuridica},
+		// Personas naturales
+		{"8-442-445", "53", models.RUCKindNatural},
+		{"1-184-921", "67", models.RUCKindNatural},
+		{"4-700-1412", "81", models.RUCKindNatural},
+		{"8-AV-1-123", "70", models.RUCKindNatural},
+		{"8-PI-1-1234", "03", models.RUCKindNatural},
+		{"N-19-1234", "87", models.RUCKindNatural},
+		{"PE-10-1234", "50", models.RUCKindNatural},
+		// Extranjeros con cédula (E)
+		{"E-8-127702", "25", models.RUCKindNatural},
+		// NT, con la provincia separada o junta y en minúsculas
+		{"8-NT-1-13656", "20", models.RUCKindNT},
+		{"8NT-1-13656", "20", models.RUCKindNT},
+		{" 8nt-1-13656 ", "20", models.RUCKindNT},
+	}
+
+	for _, tt := range tests {
+		dv, kind, err := CalculateRUCDV(tt.ruc)
+		if err != nil {
+			t.Errorf("CalculateRUCDV(%q) error: %v", tt.ruc, err)
+			continue
+		}
+		if dv != tt.dv || kind != tt.kind {
+			t.Errorf("CalculateRUCDV(%q) = %s (%s), want %s (%s)", tt.ruc, dv, kind, tt.dv, tt.kind)
+		}
+	}
+}
+
+func TestCalculateRUCDVRejectsMalformedRUCs(t *testing.T) {
+	for _, ruc := range []string{
+		"",
+		"   ",
+		"-",
+		"--",
+		"8",
+		"8-442",
+		"8--445",
+		"-442-445",
+		"8-442-",
+		"8-442-445-1-2",
+		"8-442-44X",
+		"8-４４２-445",           // dígitos no ASCII
+		"0-442-445",           // provincia fuera de rango
+		"14-442-445",          // provincia fuera de rango
+		"AV-1-123",            // AV requiere provincia
+		"NT-1-13656",          // NT requiere provincia
+		"8-XX-1-123",          // letras desconocidas
+		"8-E-1-123",           // E no lleva provincia
+		"14-NT-1-13656",       // provincia fuera de rango
+		"8-NT-A-13656",        // tomo no numérico
+		"E-A-127702",          // tomo no numérico
+		"ABC-1-2",             // jurídica no numérica
+		"12345678901-1-1",     // tomo/ficha de más de 10 dígitos
+		"155646463-12345-1",   // folio de más de 4 dígitos
+		"155646463-2-1234567", // asiento de más de 6 dígitos
+		"8NT",
+		"NT",
+	} {
+		dv, kind, err := CalculateRUCDV(ruc)
+		if err == nil {
+			t.Errorf("CalculateRUCDV(%q) = %s (%s), want an error", ruc, dv, kind)
+		}
+	}
+}
+
+func TestNormalizeRUC(t *testing.T) {
+	tests := []struct {
+		ruc    string
+		ructb  string
+		kind   models.RUCKind
+		legacy bool
+	}{
+		// Jurídica: tomo/ficha (10) + folio/rollo (4) + asiento/imagen (6)
+		{"155646463-2-2017", "01556464630002002017", models.RUCKindJuridica, false},
+		// Jurídica antigua: conserva el salto de peso del algoritmo original
+		{"123-45-6789", "00000001230045006789", models.RUCKindJuridica, true},
+		// Natural: 5 + letras + provincia (2) + tomo (3) + asiento (5)
+		{"8-442-445", "5000844200445", models.RUCKindNatural, false},
+		{"E-8-12770", "5500000812770", models.RUCKindNatural, false},
+		{"8-AV-1-123", "5150800100123", models.RUCKindNatural, false},
+		{"8-NT-1-13656", "5430800113656", models.RUCKindNT, false},
+	}
+
+	for _, tt := range tests {
+		ructb, kind, legacy, err := normalizeRUC(tt.ruc)
+		if err != nil {
+			t.Errorf("normalizeRUC(%q) error: %v", tt.ruc, err)
+			continue
+		}
+		if ructb != tt.ructb || kind != tt.kind || legacy != tt.legacy {
+			t.Errorf("normalizeRUC(%q) = %s, %s, legacy %v; want %s, %s, legacy %v", tt.ruc, ructb, kind, legacy, tt.ructb, tt.kind, tt.legacy)
+		}
+	}
+}
+
+func TestRUCCheckDigit(t *testing.T) {
+	tests := []struct {
+		ructb  string
+		legacy bool
+		digit  int
+	}{
+		{"1", false, 9},           // 1×2 = 2; 11 - 2
+		{"5", false, 1},           // 5×2 = 10; 11 - 10
+		{"6", false, 0},           // 6×2 = 12; resto 1 da 0
+		{"0", false, 0},           // resto 0
+		{"11", false, 6},          // 1×3 + 1×2 = 5; 11 - 5
+		{"20000000000", false, 9}, // 2×12 = 24; 11 - 2
+		{"20000000000", true, 0},  // 2×11 = 22 (el peso 11 se repite); resto 0
+	}
+
+	for _, tt := range tests {
+		if digit := rucCheckDigit(tt.ructb, tt.legacy); digit != tt.digit {
+			t.Errorf("rucCheckDigit(%q, %v) = %d, want %d", tt.ructb, tt.legacy, digit, tt.digit)
+		}
+	}
+}
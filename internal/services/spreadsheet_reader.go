@@ -0,0 +1,235 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// readSpreadsheet lee un archivo CSV o XLSX y retorna sus filas (incluyendo el encabezado)
+func readSpreadsheet(data []byte, format string) ([][]string, error) {
+	switch format {
+	case "csv":
+		return readCSV(data)
+	case "xlsx":
+		return readXLSX(data)
+	default:
+		return nil, fmt.Errorf("unsupported file format: %s", format)
+	}
+}
+
+// readCSV lee un archivo CSV separado por comas o punto y coma
+func readCSV(data []byte) ([][]string, error) {
+	// Quitar BOM de UTF-8 (exportaciones de Excel)
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	// Excel en configuración regional española exporta con ';'
+	if firstLine, _, _ := bytes.Cut(data, []byte("\n")); bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error parsing CSV: %w", err)
+	}
+
+	return rows, nil
+}
+
+// Estructuras mínimas de SpreadsheetML para leer la primera hoja de un XLSX
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxStringItem `xml:"si"`
+}
+
+type xlsxStringItem struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Index int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string          `xml:"r,attr"`
+			Type   string          `xml:"t,attr"`
+			Value  string          `xml:"v"`
+			Inline *xlsxStringItem `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX lee la primera hoja de un libro XLSX
+func readXLSX(data []byte) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("error opening XLSX: %w", err)
+	}
+
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	// Tabla de strings compartidos (opcional)
+	var sharedStrings []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		var sst xlsxSharedStrings
+		if err := decodeXLSXPart(f, &sst); err != nil {
+			return nil, err
+		}
+		for _, item := range sst.Items {
+			sharedStrings = append(sharedStrings, item.String())
+		}
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var sheet xlsxWorksheet
+	if err := decodeXLSXPart(files[sheetPath], &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, row := range sheet.Rows {
+		// Respetar filas vacías intermedias para que los números de fila coincidan
+		for row.Index > len(rows)+1 {
+			rows = append(rows, []string{})
+		}
+
+		var values []string
+		for i, cell := range row.Cells {
+			col := i
+			if cell.Ref != "" {
+				col = columnIndex(cell.Ref)
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				idx, err := strconv.Atoi(cell.Value)
+				if err != nil || idx < 0 || idx >= len(sharedStrings) {
+					return nil, fmt.Errorf("invalid shared string reference in cell %s", cell.Ref)
+				}
+				values[col] = sharedStrings[idx]
+			case "inlineStr":
+				if cell.Inline != nil {
+					values[col] = cell.Inline.String()
+				}
+			default:
+				values[col] = cell.Value
+			}
+		}
+		rows = append(rows, values)
+	}
+
+	return rows, nil
+}
+
+// String concatena el texto de un string compartido (simple o con formato)
+func (item xlsxStringItem) String() string {
+	if len(item.Runs) == 0 {
+		return item.Text
+	}
+	var sb strings.Builder
+	for _, run := range item.Runs {
+		sb.WriteString(run.Text)
+	}
+	return sb.String()
+}
+
+// firstSheetPath resuelve la ruta de la primera hoja del libro
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	workbookFile, ok := files["xl/workbook.xml"]
+	relsFile, relsOK := files["xl/_rels/workbook.xml.rels"]
+	if ok && relsOK {
+		var workbook xlsxWorkbook
+		var rels xlsxRelationships
+		if err := decodeXLSXPart(workbookFile, &workbook); err != nil {
+			return "", err
+		}
+		if err := decodeXLSXPart(relsFile, &rels); err != nil {
+			return "", err
+		}
+		if len(workbook.Sheets) > 0 {
+			for _, rel := range rels.Relationships {
+				if rel.ID != workbook.Sheets[0].RID {
+					continue
+				}
+				target := strings.TrimPrefix(rel.Target, "/")
+				if !strings.HasPrefix(target, "xl/") {
+					target = path.Join("xl", target)
+				}
+				if _, exists := files[target]; exists {
+					return target, nil
+				}
+			}
+		}
+	}
+
+	if _, exists := files[fallback]; exists {
+		return fallback, nil
+	}
+
+	return "", fmt.Errorf("XLSX file has no worksheets")
+}
+
+// decodeXLSXPart decodifica una parte XML del archivo XLSX
+func decodeXLSXPart(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	if err := xml.NewDecoder(io.LimitReader(rc, 100<<20)).Decode(v); err != nil {
+		return fmt.Errorf("error parsing %s: %w", f.Name, err)
+	}
+
+	return nil
+}
+
+// columnIndex convierte una referencia de celda (ej. "AB12") a índice de columna base 0
+func columnIndex(ref string) int {
+	col := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		col = col*26 + int(ch-'A'+1)
+	}
+	return col - 1
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/hypernova-labs/dgi-service/internal/config"
+	"github.com/hypernova-labs/dgi-service/internal/database"
+	"github.com/hypernova-labs/dgi-service/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// StorageMigrator mueve al storage de archivos los PDF/XML y las versiones de archivos que todavía
+// están guardados en la BD (documentos anteriores a STORAGE_METADATA_ONLY o generados mientras el
+// storage no respondía)
+type StorageMigrator struct {
+	invoiceFilesRepo *database.InvoiceFilesRepository
+	artifactRepo     *database.InvoiceArtifactRepository
+	storageService   *HybridStorageService
+	artifactService  *ArtifactService
+	cfg              config.StorageConfig
+	logger           *logrus.Logger
+}
+
+// NewStorageMigrator crea una nueva instancia del migrador
+func NewStorageMigrator(db *database.DB, store storage.BlobStore, cfg config.StorageConfig, logger *logrus.Logger) *StorageMigrator {
+	invoiceFilesRepo := database.NewInvoiceFilesRepository(db, logger)
+	return &StorageMigrator{
+		invoiceFilesRepo: invoiceFilesRepo,
+		artifactRepo:     database.NewInvoiceArtifactRepository(db, logger),
+		storageService:   NewHybridStorageService(store, invoiceFilesRepo, true, cfg.SignedURLTTL, logger),
+		artifactService:  NewArtifactService(db, store, cfg.SignedURLTTL, logger),
+		cfg:              cfg,
+		logger:           logger,
+	}
+}
+
+// Run migra los archivos pendientes cada MigrationInterval hasta que ctx se cancela
+func (m *StorageMigrator) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.MigrationInterval)
+	defer ticker.Stop()
+
+	m.logger.WithField("interval", m.cfg.MigrationInterval).Info("Storage migrator started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			m.logger.Info("Storage migrator stopped")
+			return
+		case <-ticker.C:
+			if err := m.MigratePending(ctx); err != nil {
+				m.logger.WithError(err).Error("Error migrating invoice files to storage")
+			}
+		}
+	}
+}
+
+// MigratePending mueve un lote de archivos y otro de versiones, de los más antiguos a los más
+// recientes. Lo que falla queda en la BD y se reintenta en la siguiente pasada.
+func (m *StorageMigrator) MigratePending(ctx context.Context) error {
+	pending, err := m.invoiceFilesRepo.ListWithDatabaseBlobs(m.cfg.MigrationBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for i := range pending {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		files := &pending[i]
+		logger := m.logger.WithField("invoice_id", files.InvoiceID)
+
+		moved, err := m.storageService.MoveToStorage(ctx, files)
+		if err != nil {
+			logger.WithError(err).Warn("Could not move invoice files to storage")
+			continue
+		}
+		if !moved {
+			logger.Debug("Invoice files changed during migration, retrying in the next pass")
+			continue
+		}
+		logger.Info("Invoice files moved to storage")
+	}
+
+	return m.migrateArtifacts(ctx)
+}
+
+// migrateArtifacts mueve un lote de versiones de archivos, de las más antiguas a las más recientes
+func (m *StorageMigrator) migrateArtifacts(ctx context.Context) error {
+	pending, err := m.artifactRepo.ListWithDatabaseBlobs(m.cfg.MigrationBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for i := range pending {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		artifact := &pending[i]
+		logger := m.logger.WithFields(logrus.Fields{
+			"invoice_id": artifact.InvoiceID,
+			"kind":       artifact.Kind,
+			"version":    artifact.Version,
+		})
+
+		if err := m.artifactService.MoveToStorage(ctx, artifact); err != nil {
+			logger.WithError(err).Warn("Could not move invoice artifact to storage")
+			continue
+		}
+		logger.Info("Invoice artifact moved to storage")
+	}
+
+	return nil
+}
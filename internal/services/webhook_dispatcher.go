@@ -0,0 +1,383 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hypernova-labs/dgi-service/internal/config"
+	"github.com/hypernova-labs/dgi-service/internal/database"
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// webhookBaseBackoff es la espera antes del primer reintento; se duplica en cada intento
+	webhookBaseBackoff = 30 * time.Second
+	// webhookMaxBackoff limita la espera entre reintentos
+	webhookMaxBackoff = 6 * time.Hour
+	// webhookImmediateLimit es cuántas entregas inmediatas (replay y eventos de prueba) acepta un
+	// endpoint por webhookImmediateWindow, para que no sirvan para sondear el receptor a demanda
+	webhookImmediateLimit  = 5
+	webhookImmediateWindow = time.Minute
+	// webhookMaxResponseBody limita cuánto de la respuesta del receptor se guarda en el historial;
+	// basta para diagnosticar el error sin exponer en el log el contenido de cualquier URL
+	webhookMaxResponseBody = 512
+)
+
+// Headers de cada entrega de webhook
+const (
+	WebhookHeaderDeliveryID = "X-Webhook-Id"
+	WebhookHeaderEventID    = "X-Webhook-Event-Id"
+	WebhookHeaderEvent      = "X-Webhook-Event"
+	WebhookHeaderSignature  = "X-Webhook-Signature"
+)
+
+// SignWebhookPayload calcula el header de firma de una entrega: t=<unix>,v1=<HMAC-SHA256 en hex>
+// sobre "<t>.<cuerpo>" con el secreto del endpoint. El receptor recalcula la firma y compara
+// el timestamp para rechazar entregas repetidas.
+func SignWebhookPayload(secret string, timestamp time.Time, payload []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// isPublicWebhookIP indica si una dirección puede recibir webhooks: no se entregan a loopback,
+// redes privadas, link-local (p. ej. el metadata service 169.254.169.254), multicast ni a la
+// dirección no especificada
+func isPublicWebhookIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+// webhookDialControl rechaza las conexiones a direcciones no públicas. Corre al conectar, con la
+// IP ya resuelta, así que un DNS que cambia después de registrar el endpoint no la evade.
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid webhook address %q: %w", address, err)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicWebhookIP(ip) {
+		return fmt.Errorf("webhook address %s is not a public address", host)
+	}
+	return nil
+}
+
+// newWebhookHTTPClient crea el cliente de las entregas: sólo conecta a direcciones públicas, sin
+// proxy (que conectaría por nosotros) y sin seguir redirecciones
+func newWebhookHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: webhookDialControl}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// webhookBackoff retorna la espera antes del siguiente intento tras attempts intentos fallidos
+func webhookBackoff(attempts int) time.Duration {
+	backoff := webhookBaseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= webhookMaxBackoff {
+			return webhookMaxBackoff
+		}
+	}
+	return backoff
+}
+
+// WebhookDispatcher entrega en segundo plano los eventos encolados en la tabla webhooks
+type WebhookDispatcher struct {
+	webhookRepo *database.WebhookRepository
+	httpClient  *http.Client
+	cfg         config.WebhookConfig
+	logger      *logrus.Logger
+}
+
+// NewWebhookDispatcher crea una nueva instancia del dispatcher
+func NewWebhookDispatcher(db *database.DB, cfg config.WebhookConfig, logger *logrus.Logger) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		webhookRepo: database.NewWebhookRepository(db, logger),
+		httpClient:  newWebhookHTTPClient(cfg.Timeout),
+		cfg:         cfg,
+		logger:      logger,
+	}
+}
+
+// Run procesa entregas pendientes cada DispatchInterval hasta que ctx se cancela
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.DispatchInterval)
+	defer ticker.Stop()
+
+	d.logger.WithField("interval", d.cfg.DispatchInterval).Info("Webhook dispatcher started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.logger.Info("Webhook dispatcher stopped")
+			return
+		case <-ticker.C:
+			if err := d.DispatchDue(ctx); err != nil {
+				d.logger.WithError(err).Error("Error dispatching webhooks")
+			}
+		}
+	}
+}
+
+// DispatchDue toma las entregas vencidas y las envía en paralelo
+func (d *WebhookDispatcher) DispatchDue(ctx context.Context) error {
+	// El lease cubre el envío completo; si el proceso muere, otra pasada las reintenta al vencer
+	deliveries, err := d.webhookRepo.ClaimDueDeliveries(d.cfg.BatchSize, d.leaseUntil())
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		wg.Add(1)
+		go func(delivery *models.WebhookDelivery) {
+			defer wg.Done()
+			d.dispatch(ctx, delivery)
+		}(&deliveries[i])
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// Replay reenvía de inmediato el evento de una entrega como una entrega nueva de un solo intento
+// y retorna su resultado. La entrega original no cambia.
+func (d *WebhookDispatcher) Replay(ctx context.Context, emitterID, id uuid.UUID) (*models.WebhookDelivery, error) {
+	original, err := d.webhookRepo.GetDelivery(emitterID, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting webhook delivery: %w", err)
+	}
+
+	endpoint, err := d.webhookRepo.GetEndpoint(emitterID, original.EndpointID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting webhook endpoint: %w", err)
+	}
+
+	delivery, err := d.deliverOnce(ctx, endpoint, original.EventID, original.EventType, original.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	d.logger.WithFields(logrus.Fields{
+		"emitter_id":  emitterID,
+		"original_id": id,
+		"delivery_id": delivery.ID,
+		"status":      delivery.Status,
+	}).Info("Webhook delivery replayed")
+
+	return delivery, nil
+}
+
+// SendTestEvent envía de inmediato un evento webhook.test a un endpoint (aunque esté inactivo)
+// y retorna su resultado, para verificar la URL y la validación de la firma del receptor.
+func (d *WebhookDispatcher) SendTestEvent(ctx context.Context, emitterID, endpointID uuid.UUID) (*models.WebhookDelivery, error) {
+	endpoint, err := d.webhookRepo.GetEndpoint(emitterID, endpointID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting webhook endpoint: %w", err)
+	}
+
+	event := models.WebhookEvent{
+		ID:        uuid.New(),
+		Type:      models.WebhookEventTest,
+		EmitterID: emitterID,
+		CreatedAt: time.Now(),
+		Data: models.WebhookTestData{
+			EndpointID: endpointID,
+			Message:    "Test event sent from the webhooks API",
+		},
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling webhook event: %w", err)
+	}
+
+	delivery, err := d.deliverOnce(ctx, endpoint, event.ID, event.Type, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	d.logger.WithFields(logrus.Fields{
+		"emitter_id":  emitterID,
+		"endpoint_id": endpointID,
+		"delivery_id": delivery.ID,
+		"status":      delivery.Status,
+	}).Info("Webhook test event sent")
+
+	return delivery, nil
+}
+
+// deliverOnce registra una entrega de un solo intento y la envía sin esperar al ciclo del dispatcher.
+// Cada endpoint acepta webhookImmediateLimit por webhookImmediateWindow; pasado el límite retorna
+// un *models.RateLimitError sin enviar nada.
+func (d *WebhookDispatcher) deliverOnce(ctx context.Context, endpoint *models.WebhookEndpoint, eventID uuid.UUID, eventType models.WebhookEventType, payload []byte) (*models.WebhookDelivery, error) {
+	// El lease evita que el ciclo del dispatcher la tome mientras se envía aquí
+	leaseUntil := d.leaseUntil()
+	delivery := &models.WebhookDelivery{
+		ID:          uuid.New(),
+		EmitterID:   endpoint.EmitterID,
+		EndpointID:  endpoint.ID,
+		EventID:     eventID,
+		EventType:   eventType,
+		Payload:     payload,
+		Status:      models.WebhookDeliveryPending,
+		MaxAttempts: 1,
+		NextRetryAt: &leaseUntil,
+		CreatedAt:   time.Now(),
+	}
+	if err := d.webhookRepo.EnqueueImmediateDelivery(delivery, webhookImmediateLimit, webhookImmediateWindow); err != nil {
+		return nil, err
+	}
+
+	d.deliver(ctx, endpoint, delivery)
+
+	return getDeliveryWithAttempts(d.webhookRepo, endpoint.EmitterID, delivery.ID)
+}
+
+// leaseUntil retorna hasta cuándo una entrega tomada queda reservada para el envío en curso
+func (d *WebhookDispatcher) leaseUntil() time.Time {
+	return time.Now().Add(2 * d.cfg.Timeout)
+}
+
+// dispatch envía una entrega tomada de la cola; si su endpoint ya no existe o está inactivo pasa a dead-letter
+func (d *WebhookDispatcher) dispatch(ctx context.Context, delivery *models.WebhookDelivery) {
+	endpoint, err := d.webhookRepo.GetEndpoint(delivery.EmitterID, delivery.EndpointID)
+	if err == nil && !endpoint.IsActive {
+		err = fmt.Errorf("webhook endpoint is inactive")
+	}
+	if err != nil {
+		attempt := d.newAttempt(delivery, "")
+		attempt.Error = stringPtr(err.Error())
+		d.fail(delivery, attempt, false)
+		return
+	}
+
+	d.deliver(ctx, endpoint, delivery)
+}
+
+// newAttempt crea el registro del siguiente intento de una entrega
+func (d *WebhookDispatcher) newAttempt(delivery *models.WebhookDelivery, requestURL string) *models.WebhookDeliveryAttempt {
+	return &models.WebhookDeliveryAttempt{
+		ID:             uuid.New(),
+		DeliveryID:     delivery.ID,
+		Attempt:        delivery.Attempts + 1,
+		RequestURL:     requestURL,
+		RequestHeaders: map[string]string{},
+		CreatedAt:      time.Now(),
+	}
+}
+
+// deliver envía una entrega y registra el intento: entregada, reintento con backoff o dead-letter
+func (d *WebhookDispatcher) deliver(ctx context.Context, endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery) {
+	attempt := d.newAttempt(delivery, endpoint.URL)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		attempt.Error = stringPtr(fmt.Sprintf("error building webhook request: %v", err))
+		d.fail(delivery, attempt, false)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "dgi-service-webhooks/1.0")
+	req.Header.Set(WebhookHeaderDeliveryID, delivery.ID.String())
+	req.Header.Set(WebhookHeaderEventID, delivery.EventID.String())
+	req.Header.Set(WebhookHeaderEvent, string(delivery.EventType))
+	req.Header.Set(WebhookHeaderSignature, SignWebhookPayload(endpoint.Secret, time.Now(), delivery.Payload))
+	for name := range req.Header {
+		attempt.RequestHeaders[name] = req.Header.Get(name)
+	}
+
+	start := time.Now()
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		attempt.LatencyMs = time.Since(start).Milliseconds()
+		attempt.Error = stringPtr(fmt.Sprintf("error sending webhook: %v", err))
+		d.fail(delivery, attempt, true)
+		return
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookMaxResponseBody))
+	attempt.LatencyMs = time.Since(start).Milliseconds()
+
+	statusCode := resp.StatusCode
+	attempt.ResponseStatusCode = &statusCode
+	attempt.ResponseBody = stringPtr(string(body))
+
+	if !attempt.Succeeded() {
+		attempt.Error = stringPtr(fmt.Sprintf("receiver responded %d", resp.StatusCode))
+		d.fail(delivery, attempt, true)
+		return
+	}
+
+	if err := d.webhookRepo.MarkDelivered(attempt); err != nil {
+		d.attemptLogger(delivery, attempt).WithError(err).Error("Error marking webhook as delivered")
+		return
+	}
+	d.attemptLogger(delivery, attempt).Info("Webhook delivered")
+}
+
+// fail registra un intento fallido; la entrega pasa a dead-letter si no es reintentable o agotó sus intentos
+func (d *WebhookDispatcher) fail(delivery *models.WebhookDelivery, attempt *models.WebhookDeliveryAttempt, retryable bool) {
+	logger := d.attemptLogger(delivery, attempt)
+
+	var nextRetryAt *time.Time
+	if retryable && attempt.Attempt < delivery.MaxAttempts {
+		next := time.Now().Add(webhookBackoff(attempt.Attempt))
+		nextRetryAt = &next
+	}
+
+	if err := d.webhookRepo.MarkFailed(attempt, nextRetryAt); err != nil {
+		logger.WithError(err).Error("Error marking webhook as failed")
+		return
+	}
+
+	if nextRetryAt == nil {
+		logger.Warn("Webhook moved to dead-letter")
+		return
+	}
+	logger.WithField("next_retry_at", nextRetryAt).Warn("Webhook delivery failed, retry scheduled")
+}
+
+// attemptLogger retorna un logger con los datos de un intento
+func (d *WebhookDispatcher) attemptLogger(delivery *models.WebhookDelivery, attempt *models.WebhookDeliveryAttempt) *logrus.Entry {
+	fields := logrus.Fields{
+		"delivery_id": delivery.ID,
+		"endpoint_id": delivery.EndpointID,
+		"event_type":  delivery.EventType,
+		"attempt":     attempt.Attempt,
+		"latency_ms":  attempt.LatencyMs,
+	}
+	if attempt.ResponseStatusCode != nil {
+		fields["status_code"] = *attempt.ResponseStatusCode
+	}
+	if attempt.Error != nil {
+		fields["error"] = *attempt.Error
+	}
+	return d.logger.WithFields(fields)
+}
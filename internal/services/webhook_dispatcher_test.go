@@ -0,0 +1,89 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhookDialControl(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+		{"127.0.0.1:80", false},
+		{"[::1]:80", false},
+		{"10.0.0.5:80", false},
+		{"172.16.0.1:80", false},
+		{"192.168.1.10:8080", false},
+		{"169.254.169.254:80", false},
+		{"[fe80::1]:80", false},
+		{"[fd00::1]:80", false},
+		{"0.0.0.0:80", false},
+		{"[::]:80", false},
+		{"[::ffff:127.0.0.1]:80", false},
+		{"not-an-address", false},
+	}
+
+	for _, tt := range tests {
+		err := webhookDialControl("tcp", tt.address, nil)
+		if tt.allowed && err != nil {
+			t.Errorf("webhookDialControl(%q) = %v, want allowed", tt.address, err)
+		}
+		if !tt.allowed && err == nil {
+			t.Errorf("webhookDialControl(%q) allowed, want rejected", tt.address)
+		}
+	}
+}
+
+func TestValidateWebhookEndpointRejectsNonPublicHosts(t *testing.T) {
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{"https://example.com/webhooks", true},
+		{"http://93.184.216.34/hook", true},
+		{"http://localhost:8080/hook", false},
+		{"http://api.localhost/hook", false},
+		{"http://127.0.0.1/hook", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"http://10.1.2.3/hook", false},
+		{"http://[::1]/hook", false},
+		{"ftp://example.com/hook", false},
+	}
+
+	for _, tt := range tests {
+		rawURL := tt.url
+		err := validateWebhookEndpoint(&rawURL, nil)
+		if tt.valid && err != nil {
+			t.Errorf("validateWebhookEndpoint(%q) = %v, want valid", tt.url, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("validateWebhookEndpoint(%q) valid, want an error", tt.url)
+		}
+	}
+}
+
+func TestWebhookHTTPClientRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := newWebhookHTTPClient(time.Second).Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatalf("request to %s succeeded, want the loopback address rejected", server.URL)
+	}
+}
+
+func TestWebhookHTTPClientDoesNotFollowRedirects(t *testing.T) {
+	client := newWebhookHTTPClient(time.Second)
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/hook", nil)
+	if err := client.CheckRedirect(req, []*http.Request{req}); err != http.ErrUseLastResponse {
+		t.Errorf("CheckRedirect = %v, want http.ErrUseLastResponse", err)
+	}
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hypernova-labs/dgi-service/internal/database"
+	"github.com/hypernova-labs/dgi-service/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// webhookSecretPrefix identifica los secretos de firma de webhooks
+const webhookSecretPrefix = "whsec_"
+
+// WebhookService maneja los endpoints de webhooks de los emisores y encola sus eventos
+type WebhookService struct {
+	webhookRepo *database.WebhookRepository
+	logger      *logrus.Logger
+}
+
+// NewWebhookService crea una nueva instancia del servicio
+func NewWebhookService(db *database.DB, logger *logrus.Logger) *WebhookService {
+	return &WebhookService{
+		webhookRepo: database.NewWebhookRepository(db, logger),
+		logger:      logger,
+	}
+}
+
+// CreateEndpoint registra un endpoint de webhooks; el secreto de firma se devuelve sólo en esta respuesta
+func (s *WebhookService) CreateEndpoint(emitterID uuid.UUID, req *models.CreateWebhookEndpointRequest) (*models.CreateWebhookEndpointResponse, error) {
+	if err := validateWebhookEndpoint(&req.URL, &req.EventTypes); err != nil {
+		return nil, err
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	endpoint := &models.WebhookEndpoint{
+		ID:          uuid.New(),
+		EmitterID:   emitterID,
+		URL:         strings.TrimSpace(req.URL),
+		Secret:      secret,
+		EventTypes:  req.EventTypes,
+		Description: req.Description,
+		IsActive:    true,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := s.webhookRepo.CreateEndpoint(endpoint); err != nil {
+		return nil, fmt.Errorf("error creating webhook endpoint: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"emitter_id":  emitterID,
+		"endpoint_id": endpoint.ID,
+		"event_types": endpoint.EventTypes,
+	}).Info("Webhook endpoint created successfully")
+
+	return &models.CreateWebhookEndpointResponse{WebhookEndpoint: *endpoint, Secret: secret}, nil
+}
+
+// GetEndpoint obtiene un endpoint de webhooks de un emisor
+func (s *WebhookService) GetEndpoint(emitterID, id uuid.UUID) (*models.WebhookEndpoint, error) {
+	endpoint, err := s.webhookRepo.GetEndpoint(emitterID, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting webhook endpoint: %w", err)
+	}
+	return endpoint, nil
+}
+
+// ListEndpoints obtiene los endpoints de webhooks de un emisor
+func (s *WebhookService) ListEndpoints(emitterID uuid.UUID) ([]models.WebhookEndpoint, error) {
+	endpoints, err := s.webhookRepo.ListEndpoints(emitterID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing webhook endpoints: %w", err)
+	}
+	return endpoints, nil
+}
+
+// UpdateEndpoint actualiza parcialmente un endpoint de webhooks de un emisor
+func (s *WebhookService) UpdateEndpoint(emitterID, id uuid.UUID, req *models.UpdateWebhookEndpointRequest) (*models.WebhookEndpoint, error) {
+	if err := validateWebhookEndpoint(req.URL, req.EventTypes); err != nil {
+		return nil, err
+	}
+
+	endpoint, err := s.webhookRepo.GetEndpoint(emitterID, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting existing webhook endpoint: %w", err)
+	}
+
+	// Aplicar solo los campos enviados
+	if req.URL != nil {
+		endpoint.URL = strings.TrimSpace(*req.URL)
+	}
+	if req.EventTypes != nil {
+		endpoint.EventTypes = *req.EventTypes
+	}
+	if req.Description != nil {
+		endpoint.Description = req.Description
+	}
+	if req.IsActive != nil {
+		endpoint.IsActive = *req.IsActive
+	}
+
+	endpoint, err = s.webhookRepo.UpdateEndpoint(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("error updating webhook endpoint: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"emitter_id":  emitterID,
+		"endpoint_id": id,
+		"is_active":   endpoint.IsActive,
+	}).Info("Webhook endpoint updated successfully")
+
+	return endpoint, nil
+}
+
+// DeleteEndpoint elimina un endpoint de webhooks de un emisor junto con sus entregas
+func (s *WebhookService) DeleteEndpoint(emitterID, id uuid.UUID) error {
+	if err := s.webhookRepo.DeleteEndpoint(emitterID, id); err != nil {
+		return fmt.Errorf("error deleting webhook endpoint: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"emitter_id":  emitterID,
+		"endpoint_id": id,
+	}).Info("Webhook endpoint deleted successfully")
+
+	return nil
+}
+
+// ListDeliveries obtiene el log de entregas de un emisor con el historial de intentos de cada una
+func (s *WebhookService) ListDeliveries(emitterID uuid.UUID, filter models.WebhookDeliveryFilter, page, pageSize int) ([]models.WebhookDelivery, int, error) {
+	deliveries, total, err := s.webhookRepo.ListDeliveries(emitterID, filter, page, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing webhook deliveries: %w", err)
+	}
+
+	if err := s.webhookRepo.LoadAttempts(deliveries); err != nil {
+		return nil, 0, fmt.Errorf("error getting webhook delivery attempts: %w", err)
+	}
+
+	return deliveries, total, nil
+}
+
+// GetDelivery obtiene una entrega de un emisor con su historial de intentos
+func (s *WebhookService) GetDelivery(emitterID, id uuid.UUID) (*models.WebhookDelivery, error) {
+	return getDeliveryWithAttempts(s.webhookRepo, emitterID, id)
+}
+
+// Redeliver vuelve a encolar una entrega de un emisor, también si está en dead-letter
+func (s *WebhookService) Redeliver(emitterID, id uuid.UUID) (*models.WebhookDelivery, error) {
+	delivery, err := s.webhookRepo.Redeliver(emitterID, id)
+	if err != nil {
+		return nil, fmt.Errorf("error redelivering webhook: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"emitter_id":  emitterID,
+		"delivery_id": id,
+		"event_type":  delivery.EventType,
+	}).Info("Webhook redelivery requested")
+
+	return delivery, nil
+}
+
+// Publish encola un evento para cada endpoint activo del emisor suscrito a él; la entrega la
+// hace el WebhookDispatcher. Con dedupKey, publicar de nuevo el mismo evento (p. ej. cuando el
+// relay del outbox reintenta) no duplica las entregas ya encoladas.
+func (s *WebhookService) Publish(event *models.WebhookEvent, dedupKey string) error {
+	endpoints, err := s.webhookRepo.ListSubscribedEndpoints(event.EmitterID, event.Type)
+	if err != nil {
+		return fmt.Errorf("error getting subscribed webhook endpoints: %w", err)
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error marshaling webhook event: %w", err)
+	}
+
+	now := time.Now()
+	for _, endpoint := range endpoints {
+		delivery := &models.WebhookDelivery{
+			ID:          uuid.New(),
+			EmitterID:   event.EmitterID,
+			EndpointID:  endpoint.ID,
+			EventID:     event.ID,
+			EventType:   event.Type,
+			Payload:     payload,
+			Status:      models.WebhookDeliveryPending,
+			MaxAttempts: models.DefaultWebhookMaxAttempts,
+			NextRetryAt: &now,
+			CreatedAt:   now,
+		}
+		if dedupKey != "" {
+			deliveryKey := dedupKey + ":" + endpoint.ID.String()
+			delivery.DedupKey = &deliveryKey
+		}
+		if err := s.webhookRepo.EnqueueDelivery(delivery); err != nil {
+			return err
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"emitter_id": event.EmitterID,
+		"event_id":   event.ID,
+		"event_type": event.Type,
+		"endpoints":  len(endpoints),
+	}).Info("Webhook event enqueued")
+
+	return nil
+}
+
+// validateWebhookEndpoint valida la URL y los eventos enviados al crear o actualizar un endpoint
+func validateWebhookEndpoint(rawURL *string, eventTypes *[]models.WebhookEventType) error {
+	var errs models.FieldErrors
+	addError := func(field, issue string) {
+		errs = append(errs, models.ErrorDetail{Field: field, Issue: issue})
+	}
+
+	if rawURL != nil {
+		parsed, err := url.Parse(strings.TrimSpace(*rawURL))
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			addError("url", "Must be a valid http or https URL")
+		} else if !isPublicWebhookHost(parsed.Hostname()) {
+			addError("url", "Must point to a public address")
+		}
+	}
+	if eventTypes != nil {
+		if len(*eventTypes) == 0 {
+			addError("event_types", "Must include at least one event type")
+		}
+		for _, eventType := range *eventTypes {
+			if !eventType.IsValid() {
+				addError("event_types", fmt.Sprintf("Unknown event type %q", eventType))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("validation error: %w", errs)
+	}
+	return nil
+}
+
+// isPublicWebhookHost descarta al registrar un endpoint los hosts que nunca son públicos
+// (localhost o una IP privada). Los nombres se vuelven a validar con la IP resuelta al entregar.
+func isPublicWebhookHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return isPublicWebhookIP(ip)
+	}
+	return true
+}
+
+// generateWebhookSecret genera un secreto aleatorio para firmar las entregas
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("error generating webhook secret: %w", err)
+	}
+	return webhookSecretPrefix + hex.EncodeToString(buf), nil
+}
+
+// getDeliveryWithAttempts obtiene una entrega junto con su historial de intentos
+func getDeliveryWithAttempts(webhookRepo *database.WebhookRepository, emitterID, id uuid.UUID) (*models.WebhookDelivery, error) {
+	delivery, err := webhookRepo.GetDelivery(emitterID, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting webhook delivery: %w", err)
+	}
+
+	deliveries := []models.WebhookDelivery{*delivery}
+	if err := webhookRepo.LoadAttempts(deliveries); err != nil {
+		return nil, fmt.Errorf("error getting webhook delivery attempts: %w", err)
+	}
+
+	return &deliveries[0], nil
+}
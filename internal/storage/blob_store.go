@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hypernova-labs/dgi-service/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrNotFound indica que no existe un objeto con la key pedida
+var ErrNotFound = errors.New("blob not found")
+
+// ErrSignedURLNotSupported indica que el backend no puede generar URLs firmadas; el archivo
+// debe servirse a través de la API
+var ErrSignedURLNotSupported = errors.New("signed URLs not supported by this storage backend")
+
+// BlobStore guarda los archivos de los documentos (PDF/XML) por key, p. ej.
+// "invoices/<id>/factura_<id>.pdf". Las implementaciones son seguras para uso concurrente.
+type BlobStore interface {
+	// Put guarda data en key, reemplazando el objeto si ya existe
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get lee el objeto en key; ErrNotFound si no existe
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete elimina el objeto en key; no falla si no existe
+	Delete(ctx context.Context, key string) error
+	// SignedURL devuelve una URL temporal de descarga válida durante ttl; si fileName no está
+	// vacío, la descarga usa ese nombre. ErrSignedURLNotSupported si el backend no las genera
+	SignedURL(ctx context.Context, key, fileName string, ttl time.Duration) (string, error)
+	// Exists indica si hay un objeto en key
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+// New crea el backend seleccionado por STORAGE_TYPE. El backend supabase usa las credenciales
+// S3 de Supabase Storage y su bucket de documentos.
+func New(ctx context.Context, cfg config.StorageConfig, supabaseCfg config.SupabaseConfig, logger *logrus.Logger) (BlobStore, error) {
+	store, err := newStore(ctx, cfg, supabaseCfg, logger)
+	if err != nil {
+		// Evita devolver un puntero nil envuelto en una interfaz no nil
+		return nil, err
+	}
+	return store, nil
+}
+
+// newStore construye el backend de cfg.Type
+func newStore(ctx context.Context, cfg config.StorageConfig, supabaseCfg config.SupabaseConfig, logger *logrus.Logger) (BlobStore, error) {
+	switch cfg.Type {
+	case config.StorageTypeLocal:
+		return NewLocalStore(cfg.Path, logger)
+	case config.StorageTypeS3:
+		return NewS3Store(ctx, S3Options{
+			Endpoint:        cfg.Endpoint,
+			Region:          cfg.Region,
+			Bucket:          cfg.Bucket,
+			AccessKeyID:     cfg.AccessKeyID,
+			SecretAccessKey: cfg.SecretAccessKey,
+			UsePathStyle:    cfg.UsePathStyle,
+		}, logger)
+	case config.StorageTypeSupabase:
+		if !supabaseCfg.HasStorageCredentials() {
+			return nil, fmt.Errorf("supabase storage requires SUPABASE_STORAGE_ENDPOINT, SUPABASE_ACCESS_KEY_ID and SUPABASE_SECRET_ACCESS_KEY")
+		}
+		return NewS3Store(ctx, S3Options{
+			Endpoint:        supabaseCfg.StorageEndpoint,
+			Region:          supabaseCfg.StorageRegion,
+			Bucket:          supabaseBucket,
+			AccessKeyID:     supabaseCfg.AccessKeyID,
+			SecretAccessKey: supabaseCfg.SecretAccessKey,
+			UsePathStyle:    true,
+		}, logger)
+	case config.StorageTypeMemory:
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage type: %s", cfg.Type)
+	}
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LocalStore guarda los objetos como archivos bajo un directorio raíz (STORAGE_PATH)
+type LocalStore struct {
+	root   string
+	logger *logrus.Logger
+}
+
+// NewLocalStore crea el backend local, creando el directorio raíz si no existe
+func NewLocalStore(root string, logger *logrus.Logger) (*LocalStore, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("error creating storage directory: %w", err)
+	}
+
+	logger.WithField("path", root).Info("Local file storage initialized")
+	return &LocalStore{root: root, logger: logger}, nil
+}
+
+// path resuelve la key dentro del directorio raíz, rechazando keys que escapen de él
+func (s *LocalStore) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if key == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid storage key: %s", key)
+	}
+	return filepath.Join(s.root, clean), nil
+}
+
+// Put escribe el archivo en un temporal y lo renombra, para que una lectura concurrente
+// nunca vea un archivo a medio escribir
+func (s *LocalStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("error creating storage directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("error creating file %s: %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing file %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing file %s: %w", key, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error writing file %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// Get lee el archivo de la key
+func (s *LocalStore) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		return nil, fmt.Errorf("error reading file %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// Delete elimina el archivo de la key
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error deleting file %s: %w", key, err)
+	}
+	return nil
+}
+
+// SignedURL no está disponible: los archivos locales se sirven a través de la API
+func (s *LocalStore) SignedURL(ctx context.Context, key, fileName string, ttl time.Duration) (string, error) {
+	return "", ErrSignedURLNotSupported
+}
+
+// Exists indica si existe el archivo de la key
+func (s *LocalStore) Exists(ctx context.Context, key string) (bool, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error checking file %s: %w", key, err)
+	}
+	return true, nil
+}
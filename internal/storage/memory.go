@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryStore guarda los objetos en memoria; para desarrollo y pruebas, se pierden al reiniciar
+type MemoryStore struct {
+	mu      sync.RWMutex
+	objects map[string][]byte
+}
+
+// NewMemoryStore crea un backend en memoria vacío
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{objects: make(map[string][]byte)}
+}
+
+// Put guarda una copia de data en key
+func (s *MemoryStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = append([]byte(nil), data...)
+	return nil
+}
+
+// Get devuelve una copia del objeto en key
+func (s *MemoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// Delete elimina el objeto en key
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
+
+// SignedURL no está disponible: los objetos en memoria se sirven a través de la API
+func (s *MemoryStore) SignedURL(ctx context.Context, key, fileName string, ttl time.Duration) (string, error) {
+	return "", ErrSignedURLNotSupported
+}
+
+// Exists indica si hay un objeto en key
+func (s *MemoryStore) Exists(ctx context.Context, key string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.objects[key]
+	return ok, nil
+}
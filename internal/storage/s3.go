@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/sirupsen/logrus"
+)
+
+// supabaseBucket es el bucket de documentos en Supabase Storage
+const supabaseBucket = "invoice-files"
+
+// S3Options configura un backend S3 compatible (AWS, MinIO, Supabase Storage)
+type S3Options struct {
+	// Endpoint vacío usa el endpoint de AWS para Region
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// UsePathStyle es necesario para MinIO y Supabase
+	UsePathStyle bool
+}
+
+// S3Store guarda los objetos en un bucket S3 compatible
+type S3Store struct {
+	client  *s3.Client
+	presign *s3.PresignClient
+	bucket  string
+	logger  *logrus.Logger
+}
+
+// NewS3Store crea el backend S3 y crea el bucket si todavía no existe
+func NewS3Store(ctx context.Context, opts S3Options, logger *logrus.Logger) (*S3Store, error) {
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("s3 storage requires a bucket")
+	}
+
+	loadOptions := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithRegion(opts.Region),
+	}
+	if opts.AccessKeyID != "" {
+		loadOptions = append(loadOptions, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(opts.AccessKeyID, opts.SecretAccessKey, ""),
+		))
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, loadOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating AWS config: %w", err)
+	}
+
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if opts.Endpoint != "" {
+			o.BaseEndpoint = aws.String(opts.Endpoint)
+		}
+		o.UsePathStyle = opts.UsePathStyle
+	})
+
+	store := &S3Store{
+		client:  client,
+		presign: s3.NewPresignClient(client),
+		bucket:  opts.Bucket,
+		logger:  logger,
+	}
+
+	if err := store.ensureBucket(ctx); err != nil {
+		return nil, err
+	}
+
+	logger.WithFields(logrus.Fields{
+		"endpoint": opts.Endpoint,
+		"bucket":   opts.Bucket,
+	}).Info("S3 file storage initialized")
+
+	return store, nil
+}
+
+// ensureBucket verifica el bucket y lo crea si no existe
+func (s *S3Store) ensureBucket(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(s.bucket)})
+	if err == nil {
+		return nil
+	}
+	if !isS3NotFound(err) {
+		return fmt.Errorf("error checking bucket %s: %w", s.bucket, err)
+	}
+
+	if _, err := s.client.CreateBucket(ctx, &s3.CreateBucketInput{Bucket: aws.String(s.bucket)}); err != nil {
+		return fmt.Errorf("error creating bucket %s: %w", s.bucket, err)
+	}
+
+	s.logger.WithField("bucket", s.bucket).Info("Storage bucket created")
+	return nil
+}
+
+// Put sube el objeto al bucket
+func (s *S3Store) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          bytes.NewReader(data),
+		ContentType:   aws.String(contentType),
+		ContentLength: aws.Int64(int64(len(data))),
+	})
+	if err != nil {
+		return fmt.Errorf("error uploading %s: %w", key, err)
+	}
+	return nil
+}
+
+// Get descarga el objeto del bucket
+func (s *S3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isS3NotFound(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		return nil, fmt.Errorf("error downloading %s: %w", key, err)
+	}
+	defer result.Body.Close()
+
+	data, err := io.ReadAll(result.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// Delete elimina el objeto del bucket
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting %s: %w", key, err)
+	}
+	return nil
+}
+
+// SignedURL genera una URL GET prefirmada para el objeto; el bucket no necesita ser público
+func (s *S3Store) SignedURL(ctx context.Context, key, fileName string, ttl time.Duration) (string, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+	if fileName != "" {
+		input.ResponseContentDisposition = aws.String(fmt.Sprintf("inline; filename=%s", fileName))
+	}
+
+	request, err := s.presign.PresignGetObject(ctx, input, s3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", fmt.Errorf("error presigning %s: %w", key, err)
+	}
+	return request.URL, nil
+}
+
+// Exists consulta los metadatos del objeto sin descargarlo
+func (s *S3Store) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isS3NotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error checking %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// isS3NotFound reconoce las respuestas de objeto o bucket inexistente (HEAD responde NotFound
+// sin cuerpo; GET responde NoSuchKey)
+func isS3NotFound(err error) bool {
+	var notFound *types.NotFound
+	var noSuchKey *types.NoSuchKey
+	var noSuchBucket *types.NoSuchBucket
+	return errors.As(err, &notFound) || errors.As(err, &noSuchKey) || errors.As(err, &noSuchBucket)
+}
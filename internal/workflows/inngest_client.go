@@ -1,6 +1,7 @@
 package workflows
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/hypernova-labs/dgi-service/internal/config"
@@ -54,6 +55,21 @@ func (c *InngestClient) RegisterWorkflows(emailService *email.ResendService, inv
 	return nil
 }
 
+// SendEvent publica un evento en Inngest. dedupKey se usa como ID del evento para que
+// Inngest descarte las publicaciones repetidas del mismo evento.
+func (c *InngestClient) SendEvent(ctx context.Context, name, dedupKey string, data map[string]interface{}) error {
+	evt := inngestgo.Event{
+		ID:   &dedupKey,
+		Name: name,
+		Data: data,
+	}
+
+	if _, err := c.client.Send(ctx, evt); err != nil {
+		return fmt.Errorf("error sending event to Inngest: %w", err)
+	}
+	return nil
+}
+
 // GetClient retorna el cliente de Inngest
 func (c *InngestClient) GetClient() inngestgo.Client {
 	return c.client